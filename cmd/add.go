@@ -0,0 +1,440 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	addName           string
+	addVCS            string
+	addRepo           string
+	addBranch         string
+	addPath           string
+	addBackupPaths    []string
+	addRetention      int
+	addDumps          []string
+	addGitState       bool
+	addSkipFeatures   []string
+	addArchiveMode    string
+	addExclude        []string
+	addNoSuggest      bool
+	addNonInteractive bool
+
+	// ja: addFlagChanged はフラグが明示的に指定されたかを返します（Run で cobra のものに差し替え）
+	// en: addFlagChanged reports whether a flag was given explicitly (replaced with cobra's in Run)
+	addFlagChanged = func(string) bool { return false }
+)
+
+// ja: addCmd は add コマンドを表します
+// en: addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add [dir]",
+	Short: i18n.T("add.short"),
+	Long:  i18n.T("add.long"),
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		addFlagChanged = cmd.Flags().Changed
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if err := runAdd(dir); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+	addCmd.Flags().StringVarP(&addName, "name", "n", "", i18n.T("add.flag.name"))
+	addCmd.Flags().StringVar(&addVCS, "vcs", "", i18n.T("add.flag.vcs"))
+	addCmd.Flags().StringVar(&addRepo, "repo", "", i18n.T("add.flag.repo"))
+	addCmd.Flags().StringVarP(&addBranch, "branch", "b", "", i18n.T("add.flag.branch"))
+	addCmd.Flags().StringVar(&addPath, "path", "", i18n.T("add.flag.path"))
+	addCmd.Flags().StringSliceVar(&addBackupPaths, "backup-path", nil, i18n.T("add.flag.backupPath"))
+	addCmd.Flags().IntVar(&addRetention, "retention", 0, i18n.T("add.flag.retention"))
+	addCmd.Flags().StringArrayVar(&addDumps, "dump", nil, i18n.T("add.flag.dump"))
+	addCmd.Flags().BoolVar(&addGitState, "include-git-state", false, i18n.T("add.flag.includeGitState"))
+	addCmd.Flags().StringSliceVar(&addSkipFeatures, "skip-git-feature", nil, i18n.T("add.flag.skipGitFeature"))
+	addCmd.Flags().StringVar(&addArchiveMode, "archive-mode", "", i18n.T("add.flag.archiveMode"))
+	addCmd.Flags().StringSliceVar(&addExclude, "exclude", nil, i18n.T("add.flag.exclude"))
+	addCmd.Flags().BoolVar(&addNoSuggest, "no-suggest", false, i18n.T("add.flag.noSuggest"))
+	addCmd.Flags().BoolVarP(&addNonInteractive, "non-interactive", "y", false, i18n.T("add.flag.nonInteractive"))
+}
+
+// ja: runAdd は dir のチェックアウトを調べ、プロジェクトを設定ファイルに追加します
+// en: runAdd inspects the checkout at dir and adds a project to the config file
+func runAdd(dir string) (err error) {
+	journal := startOperation("add", "")
+	defer func() { journal.finish(err) }()
+
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	// ja: チェックアウトを調べて既定値を決める
+	// en: Inspect the checkout to determine defaults
+	projectDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if !dirExists(projectDir) {
+		return fmt.Errorf(i18n.T("add.noDir"), projectDir)
+	}
+
+	// ja: VCS が指定されなければ検出し、リポジトリとブランチの推測と提案はその VCS に任せる
+	// en: Detect the VCS unless one is given, and leave guessing the repository and branch and the suggestions to it
+	vcsName := addVCS
+	if vcsName == "" {
+		vcsName = detectVCS(gitRunner, projectDir)
+	}
+
+	project := Project{
+		Name:            filepath.Base(projectDir),
+		Path:            collapseHome(projectDir),
+		BackupPaths:     addBackupPaths,
+		BackupRetention: addRetention,
+		IncludeGitState: addGitState,
+		SkipGitFeatures: addSkipFeatures,
+		ArchiveMode:     addArchiveMode,
+		Exclude:         addExclude,
+	}
+	if vcsName != vcsGit {
+		project.VCS = vcsName
+	}
+	v, err := newVCS(&project, gitRunner)
+	if err != nil {
+		return err
+	}
+	project.Repo, project.Branch = v.detect(projectDir)
+
+	if addName != "" {
+		project.Name = addName
+	}
+	if addRepo != "" {
+		project.Repo = addRepo
+	}
+	if addBranch != "" {
+		project.Branch = addBranch
+	}
+	if addFlagChanged("path") {
+		project.Path = addPath
+	}
+	for _, raw := range addDumps {
+		dump, err := parseDumpFlag(raw)
+		if err != nil {
+			return err
+		}
+		project.Dumps = append(project.Dumps, dump)
+	}
+
+	var suggestions []string
+	if !addNoSuggest {
+		suggestions = v.suggestBackupPaths(projectDir)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if addNonInteractive {
+		project.BackupPaths = appendUnique(project.BackupPaths, suggestions...)
+	} else {
+		if err := promptProject(reader, &project, suggestions, addFlagChanged); err != nil {
+			return err
+		}
+	}
+
+	// ja: 既存のプロジェクトと同じ規則で検証
+	// en: Validate with the same rules as existing projects
+	projectNames := make(map[string]bool, len(config.Projects))
+	for _, existing := range config.Projects {
+		projectNames[existing.Name] = true
+	}
+	if err := validateProject(&project, len(config.Projects), projectNames); err != nil {
+		return err
+	}
+
+	// ja: 1.1.0 で加わったキーを書き込む場合は、古いバイナリに無視されないよう version も上げる
+	// en: When writing keys added in 1.1.0, raise the version too so that older binaries do not ignore them
+	appendProject := func(file *configfile.File) error {
+		if err := bumpConfigVersion(file, writtenProjectKeys(&project)); err != nil {
+			return err
+		}
+		return file.AppendProject(project)
+	}
+	if dryRun {
+		change, err := planConfigEdit(store, "add", project.Name, appendProject)
+		if err != nil {
+			return err
+		}
+		return showConfigChange(change)
+	}
+
+	if !addNonInteractive {
+		entry, err := renderProjectEntry(project)
+		if err != nil {
+			return fmt.Errorf(i18n.T("add.marshalError"), err)
+		}
+		fmt.Println()
+		fmt.Print(entry)
+		fmt.Println()
+
+		confirmed, err := promptYesNo(reader, fmt.Sprintf(i18n.T("add.confirmPrompt"), store.Path()), true)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			journal.cancel()
+			fmt.Println(i18n.T("add.cancelled"))
+			return nil
+		}
+	}
+
+	journal.entry.Project = project.Name
+	if err := store.Edit(appendProject); err != nil {
+		return err
+	}
+	journal.touch(store.Path(), fileModified)
+
+	fmt.Printf(i18n.T("add.success")+"\n", project.Name, store.Path())
+	return nil
+}
+
+// ja: suggestIgnoredFiles は git で無視されているファイルのうち、秘密情報やデータベースらしいものを返します
+// en: suggestIgnoredFiles returns the git-ignored files that look like secrets or databases
+func suggestIgnoredFiles(runner commandRunner, dir string) []string {
+	out, err := gitOutput(runner, dir, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil || out == "" {
+		return nil
+	}
+
+	var suggestions []string
+	for _, line := range strings.Split(out, "\n") {
+		path := strings.TrimSpace(line)
+		// ja: --directory により無視されたディレクトリは末尾に / が付いてまとめられる（node_modules など）
+		// en: --directory collapses ignored directories with a trailing / (node_modules and the like)
+		if path == "" || strings.HasSuffix(path, "/") {
+			continue
+		}
+		if looksLikeSecret(path) || looksLikeDatabase(path) {
+			suggestions = append(suggestions, path)
+		}
+	}
+	return suggestions
+}
+
+// ja: dumpCommandKeys はカンマを含められる dumps のキーです
+// en: dumpCommandKeys are the dumps keys whose values may contain commas
+var dumpCommandKeys = []string{"command", "restore_command"}
+
+// ja: parseDumpFlag は --dump の値（"name=app.sql,provider=postgres,database=app" の形式）を Dump に変換します
+// ja: キーは dumps の YAML のキー名です。command と restore_command では、キーが続かないカンマを値の一部として扱います
+// en: parseDumpFlag turns a --dump value ("name=app.sql,provider=postgres,database=app") into a Dump
+// en: Keys are the YAML keys of dumps; in command and restore_command, a comma not followed by a key is part of the value
+func parseDumpFlag(raw string) (Dump, error) {
+	var dump Dump
+	keys := fieldKeys(&dump)
+
+	var pairs []string
+	for _, part := range strings.Split(raw, ",") {
+		if len(pairs) > 0 {
+			key, _, _ := strings.Cut(part, "=")
+			previous, _, _ := strings.Cut(pairs[len(pairs)-1], "=")
+			if !containsString(keys, strings.TrimSpace(key)) && containsString(dumpCommandKeys, strings.TrimSpace(previous)) {
+				pairs[len(pairs)-1] += "," + part
+				continue
+			}
+		}
+		pairs = append(pairs, part)
+	}
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return Dump{}, fmt.Errorf(i18n.T("add.invalidDump"), raw)
+		}
+		field, err := lookupField(&dump, strings.TrimSpace(key))
+		if err != nil {
+			return Dump{}, err
+		}
+		if err := field.apply(fieldAssign, strings.TrimSpace(value)); err != nil {
+			return Dump{}, err
+		}
+	}
+	return dump, nil
+}
+
+// ja: promptProject は各フィールドを対話的に確認します（フラグで指定されたものは尋ねません）
+// en: promptProject asks for each field interactively (fields given as flags are not asked)
+func promptProject(reader *bufio.Reader, project *Project, suggestions []string, flagChanged func(string) bool) error {
+	fields := []struct {
+		flag   string
+		label  string
+		target *string
+	}{
+		{flag: "name", label: i18n.T("add.prompt.name"), target: &project.Name},
+		{flag: "repo", label: i18n.T("add.prompt.repo"), target: &project.Repo},
+		{flag: "branch", label: i18n.T("add.prompt.branch"), target: &project.Branch},
+		{flag: "path", label: i18n.T("add.prompt.path"), target: &project.Path},
+	}
+	for _, field := range fields {
+		if flagChanged(field.flag) {
+			continue
+		}
+		if project.VCS == vcsNone && (field.flag == "repo" || field.flag == "branch") {
+			continue
+		}
+		value, err := promptString(reader, field.label, *field.target)
+		if err != nil {
+			return err
+		}
+		*field.target = value
+	}
+
+	for _, suggestion := range suggestions {
+		if containsString(project.BackupPaths, suggestion) {
+			continue
+		}
+		include, err := promptYesNo(reader, fmt.Sprintf(i18n.T("add.prompt.suggestion"), suggestion), true)
+		if err != nil {
+			return err
+		}
+		if include {
+			project.BackupPaths = append(project.BackupPaths, suggestion)
+		}
+	}
+
+	if !flagChanged("backup-path") {
+		extra, err := promptString(reader, i18n.T("add.prompt.backupPaths"), "")
+		if err != nil {
+			return err
+		}
+		for _, path := range strings.Split(extra, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				project.BackupPaths = appendUnique(project.BackupPaths, path)
+			}
+		}
+	}
+
+	if !flagChanged("retention") {
+		value, err := promptString(reader, i18n.T("add.prompt.retention"), strconv.Itoa(project.BackupRetention))
+		if err != nil {
+			return err
+		}
+		retention, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf(i18n.T("add.invalidNumber"), value)
+		}
+		project.BackupRetention = retention
+	}
+
+	return nil
+}
+
+// ja: promptString は既定値付きで 1 行の入力を求めます（空入力の場合は既定値）
+// en: promptString asks for a line of input with a default (used on empty input)
+func promptString(reader *bufio.Reader, label, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Printf("%s [%s]: ", label, defaultValue)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+
+	response, err := reader.ReadString('\n')
+	if err != nil && response == "" {
+		return "", fmt.Errorf(i18n.T("add.readInputError"), err)
+	}
+
+	if response = strings.TrimSpace(response); response == "" {
+		return defaultValue, nil
+	}
+	return response, nil
+}
+
+// ja: promptYesNo は y/n の確認を求めます（空入力の場合は既定値）
+// en: promptYesNo asks a yes/no question (the default is used on empty input)
+func promptYesNo(reader *bufio.Reader, question string, defaultYes bool) (bool, error) {
+	fmt.Print(question)
+
+	response, err := reader.ReadString('\n')
+	if err != nil && response == "" {
+		return false, fmt.Errorf(i18n.T("add.readInputError"), err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// ja: collapseHome はホームディレクトリ配下のパスを ~ で始まる形にします
+// en: collapseHome rewrites a path under the home directory to start with ~
+func collapseHome(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(homeDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	if rel == "." {
+		return "~"
+	}
+	return "~/" + filepath.ToSlash(rel)
+}
+
+// ja: containsString はスライスに値が含まれるかを返します
+// en: containsString reports whether the slice contains the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ja: appendUnique は重複しない値のみを追加します
+// en: appendUnique appends only the values not already present
+func appendUnique(values []string, additions ...string) []string {
+	for _, addition := range additions {
+		if !containsString(values, addition) {
+			values = append(values, addition)
+		}
+	}
+	return values
+}
+
+// ja: renderProjectEntry はプロジェクトを projects リストの 1 要素として YAML に変換します
+// en: renderProjectEntry renders a project as a single YAML item of the projects list
+func renderProjectEntry(project Project) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode([]Project{project}); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
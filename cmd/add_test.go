@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yk-lab/toske/toske"
+	"gopkg.in/yaml.v3"
+)
+
+// setupAddTest creates a checkout dir, a config file with the init template and a fake git.
+func setupAddTest(t *testing.T) (checkoutDir, configPath string) {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	checkoutDir = filepath.Join(tempDir, "src", "new-app")
+	if err := os.MkdirAll(checkoutDir, 0755); err != nil {
+		t.Fatalf("Failed to create checkout directory: %v", err)
+	}
+
+	t.Cleanup(setupTestConfig(t, getConfigTemplate()))
+	configPath = cfgFile
+
+	originalRunner := gitRunner
+	gitRunner = &fakeGitRunner{responses: map[string]string{
+		"rev-parse --is-inside-work-tree":                            "true",
+		"remote get-url origin":                                      "git@github.com:user/new-app.git",
+		"rev-parse --abbrev-ref HEAD":                                "develop",
+		"ls-files --others --ignored --exclude-standard --directory": "node_modules/\n.env\nlog/development.log\ndb/development.sqlite3\n.env.example",
+	}}
+	t.Cleanup(func() { gitRunner = originalRunner })
+
+	originalName, originalVCS, originalRepo, originalBranch, originalPath := addName, addVCS, addRepo, addBranch, addPath
+	originalBackupPaths, originalRetention, originalDumps := addBackupPaths, addRetention, addDumps
+	originalGitState, originalSkipFeatures := addGitState, addSkipFeatures
+	originalArchiveMode, originalExclude := addArchiveMode, addExclude
+	originalNoSuggest, originalNonInteractive, originalChanged := addNoSuggest, addNonInteractive, addFlagChanged
+	t.Cleanup(func() {
+		addName, addVCS, addRepo, addBranch, addPath = originalName, originalVCS, originalRepo, originalBranch, originalPath
+		addBackupPaths, addRetention, addDumps = originalBackupPaths, originalRetention, originalDumps
+		addGitState, addSkipFeatures = originalGitState, originalSkipFeatures
+		addArchiveMode, addExclude = originalArchiveMode, originalExclude
+		addNoSuggest, addNonInteractive, addFlagChanged = originalNoSuggest, originalNonInteractive, originalChanged
+	})
+	addName, addVCS, addRepo, addBranch, addPath = "", "", "", "", ""
+	addBackupPaths, addRetention, addDumps = nil, 0, nil
+	addGitState, addSkipFeatures = false, nil
+	addArchiveMode, addExclude = "", nil
+	addNoSuggest, addNonInteractive = false, false
+	addFlagChanged = func(string) bool { return false }
+
+	return checkoutDir, configPath
+}
+
+// loadProjectsFromFile parses the projects of a config file.
+func loadProjectsFromFile(t *testing.T, configPath string) []Project {
+	t.Helper()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Config is no longer valid YAML: %v\n%s", err, data)
+	}
+	return config.Projects
+}
+
+func TestRunAddNonInteractive(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive = true
+	addBackupPaths = []string{"storage/"}
+	addRetention = 5
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	if len(projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(projects))
+	}
+
+	expected := Project{
+		Name:            "new-app",
+		Repo:            "git@github.com:user/new-app.git",
+		Branch:          "develop",
+		Path:            "~/src/new-app",
+		BackupPaths:     []string{"storage/", ".env", "db/development.sqlite3"},
+		BackupRetention: 5,
+	}
+	if !reflect.DeepEqual(projects[1], expected) {
+		t.Errorf("Expected %+v, got %+v", expected, projects[1])
+	}
+
+	// ja: テンプレートのコメントと書式はそのまま残ること
+	// en: Comments and formatting of the template must survive
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	content := string(data)
+	template := getConfigTemplate()
+	head := template[:strings.Index(template, "\n# ja:")]
+	tail := template[strings.Index(template, "\n# ja:"):]
+	if !strings.HasPrefix(content, head) || !strings.HasSuffix(content, tail) {
+		t.Errorf("Expected the template to be kept around the new entry, got:\n%s", content)
+	}
+	if !strings.Contains(content, "  - name: new-app\n    repo: git@github.com:user/new-app.git\n") {
+		t.Errorf("Expected the new entry to use the existing indentation, got:\n%s", content)
+	}
+}
+
+func TestRunAddInteractive(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+
+	// ja: 名前を変更し、リポジトリ・ブランチ・パスは既定値、.env は採用、sqlite は不採用、
+	// ja: 追加パスを 1 つ、保持数 3、最後に確認
+	// en: Rename, keep the repo/branch/path defaults, accept .env, reject the sqlite file,
+	// en: add one extra path, keep 3 backups, then confirm
+	input := "renamed\n\n\n\ny\nn\nconfig/master.key\n3\ny\n"
+	tmpfile := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(tmpfile, []byte(input), 0644); err != nil {
+		t.Fatalf("Failed to write stdin: %v", err)
+	}
+	stdin, err := os.Open(tmpfile)
+	if err != nil {
+		t.Fatalf("Failed to open stdin: %v", err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() {
+		os.Stdin = oldStdin
+		stdin.Close()
+	}()
+
+	output, err := captureStdout(t, func() error { return runAdd(checkoutDir) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Back up .env?") {
+		t.Errorf("Expected a suggestion prompt, got:\n%s", output)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	added := projects[len(projects)-1]
+	if added.Name != "renamed" || added.Branch != "develop" || added.BackupRetention != 3 {
+		t.Errorf("Unexpected project: %+v", added)
+	}
+	if !reflect.DeepEqual(added.BackupPaths, []string{".env", "config/master.key"}) {
+		t.Errorf("Unexpected backup paths: %v", added.BackupPaths)
+	}
+}
+
+func TestRunAddWithoutVCS(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive = true
+	addNoSuggest = true
+	addVCS = vcsNone
+	runner := gitRunner.(*fakeGitRunner)
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("Expected git not to run with --vcs none, got %v", runner.calls)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	added := projects[len(projects)-1]
+	if added.VCS != vcsNone || added.Repo != "" || added.Branch != "" {
+		t.Errorf("Expected a project without repository and branch, got %+v", added)
+	}
+}
+
+func TestRunAddDetectsMissingVCS(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive = true
+	// ja: git のリポジトリではないディレクトリ
+	// en: A directory that is not a git repository
+	runner := &fakeGitRunner{}
+	gitRunner = runner
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	added := projects[len(projects)-1]
+	if added.VCS != vcsNone || added.Repo != "" || added.Branch != "" || len(added.BackupPaths) != 0 {
+		t.Errorf("Expected an unversioned project without repository, branch or suggestions, got %+v", added)
+	}
+	if !reflect.DeepEqual(runner.calls, []string{"rev-parse --is-inside-work-tree"}) {
+		t.Errorf("Expected git to be asked only whether this is a repository, got %v", runner.calls)
+	}
+}
+
+func TestRunAddErrors(t *testing.T) {
+	t.Run("duplicate name", func(t *testing.T) {
+		checkoutDir, configPath := setupAddTest(t)
+		addNonInteractive = true
+		addName = "sample-project"
+
+		before, _ := os.ReadFile(configPath)
+		_, err := captureStdout(t, func() error { return runAdd(checkoutDir) })
+		if err == nil || !strings.Contains(err.Error(), "duplicate project name") {
+			t.Errorf("Expected duplicate name error, got: %v", err)
+		}
+		after, _ := os.ReadFile(configPath)
+		if string(before) != string(after) {
+			t.Error("Config must not change when validation fails")
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		checkoutDir, _ := setupAddTest(t)
+		addNonInteractive = true
+
+		_, err := captureStdout(t, func() error { return runAdd(filepath.Join(checkoutDir, "missing")) })
+		if err == nil {
+			t.Error("Expected error but got nil")
+		}
+	})
+}
+
+func TestRunAddWithDumps(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive, addNoSuggest = true, true
+	addDumps = []string{
+		"name=app.sql,provider=postgres,database=app_dev,port=5433",
+		"name=cache.rdb,provider=command,command=redis-cli --rdb - | gzip -c, -9",
+	}
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	expected := []Dump{
+		{Name: "app.sql", Provider: "postgres", Database: "app_dev", Port: 5433},
+		{Name: "cache.rdb", Provider: "command", Command: "redis-cli --rdb - | gzip -c, -9"},
+	}
+	if len(projects) != 2 || !reflect.DeepEqual(projects[1].Dumps, expected) {
+		t.Errorf("Expected dumps %+v, got %+v", expected, projects)
+	}
+}
+
+func TestParseDumpFlagErrors(t *testing.T) {
+	for _, raw := range []string{"app.sql", "name=app.sql,driver=postgres", "name=app.sql,port=abc"} {
+		if _, err := parseDumpFlag(raw); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+}
+
+func TestRunAddIncludeGitState(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive, addNoSuggest, addGitState = true, true, true
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	projects := loadProjectsFromFile(t, configPath)
+	if len(projects) != 2 || !projects[1].IncludeGitState {
+		t.Errorf("Expected include_git_state to be set, got %+v", projects)
+	}
+}
+
+func TestRunAddSkipGitFeatures(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive, addNoSuggest = true, true
+	addSkipFeatures = []string{gitFeatureLFS, gitFeatureSubmodules}
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	projects := loadProjectsFromFile(t, configPath)
+	if len(projects) != 2 || !reflect.DeepEqual(projects[1].SkipGitFeatures, addSkipFeatures) {
+		t.Errorf("Expected skip_git_features %v, got %+v", addSkipFeatures, projects)
+	}
+
+	// ja: 不明な機能名は既存のプロジェクトと同じく検証で拒否されること
+	// en: Unknown feature names must be rejected by validation, as for existing projects
+	addName, addSkipFeatures = "other-app", []string{"hooks"}
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err == nil {
+		t.Error("Expected an error for an unknown git feature")
+	}
+}
+
+func TestRunAddArchiveModeFull(t *testing.T) {
+	checkoutDir, configPath := setupAddTest(t)
+	addNonInteractive, addNoSuggest = true, true
+	addArchiveMode, addExclude = toske.ArchiveModeFull, []string{"node_modules", "dist/"}
+
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	projects := loadProjectsFromFile(t, configPath)
+	if len(projects) != 2 || projects[1].ArchiveMode != toske.ArchiveModeFull || !reflect.DeepEqual(projects[1].Exclude, addExclude) {
+		t.Errorf("Expected archive_mode full with exclude %v, got %+v", addExclude, projects)
+	}
+
+	addName, addArchiveMode = "other-app", "everything"
+	if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err == nil {
+		t.Error("Expected an error for an unknown archive mode")
+	}
+}
@@ -1,37 +1,22 @@
 package cmd
 
 import (
-	"archive/tar"
-	"compress/gzip"
+	"bufio"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
-	"sort"
-	"time"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/yk-lab/toske/i18n"
-	"gopkg.in/yaml.v3"
+	"github.com/yk-lab/toske/toske"
 )
 
-var projectName string
-
-// ja: BackupMetadata はバックアップのメタデータを表します
-// en: BackupMetadata represents backup metadata
-type BackupMetadata struct {
-	Project string         `yaml:"project"`
-	Backups []BackupRecord `yaml:"backups"`
-}
-
-// ja: BackupRecord は個々のバックアップ記録を表します
-// en: BackupRecord represents an individual backup record
-type BackupRecord struct {
-	Filename  string    `yaml:"filename"`
-	Timestamp time.Time `yaml:"timestamp"`
-	Files     []string  `yaml:"files"`
-}
+var (
+	projectName      string
+	backupRemoveTree bool
+	backupForce      bool
+)
 
 // ja: backupCmd は backup コマンドを表します
 // en: backupCmd represents the backup command
@@ -50,323 +35,217 @@ var backupCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(backupCmd)
 	backupCmd.Flags().StringVarP(&projectName, "project", "p", "", i18n.T("backup.flag.project"))
+	backupCmd.Flags().BoolVar(&backupRemoveTree, "remove-tree", false, i18n.T("backup.flag.removeTree"))
+	backupCmd.Flags().BoolVarP(&backupForce, "force", "f", false, i18n.T("backup.flag.force"))
 }
 
-func runBackup() error {
+func runBackup() (err error) {
 	// ja: プロジェクト名が指定されているかチェック
 	// en: Check if project name is specified
 	if projectName == "" {
 		return fmt.Errorf("%s", i18n.T("backup.noProjectFlag"))
 	}
 
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
-	}
-
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("backup.noConfig"), configPath)
-	}
+	journal := startOperation("backup", projectName)
+	defer func() { journal.finish(err) }()
 
 	// ja: 設定ファイルを読み込む
 	// en: Load configuration file
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return fmt.Errorf(i18n.T("backup.readError"), err)
-	}
-
-	// ja: 設定を構造体にアンマーシャル
-	// en: Unmarshal config into struct
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return fmt.Errorf(i18n.T("backup.parseError"), err)
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
 	}
 
 	// ja: 指定されたプロジェクトを検索
 	// en: Find the specified project
-	var project *Project
-	for i := range config.Projects {
-		if config.Projects[i].Name == projectName {
-			project = &config.Projects[i]
-			break
-		}
-	}
-
-	if project == nil {
-		return fmt.Errorf(i18n.T("backup.projectNotFound"), projectName)
-	}
-
-	// ja: バックアップ対象ファイルがあるかチェック
-	// en: Check if there are files to backup
-	if len(project.BackupPaths) == 0 {
-		return fmt.Errorf(i18n.T("backup.noBackupPaths"), projectName)
-	}
-
-	fmt.Printf(i18n.T("backup.creatingBackup")+"\n", project.Name)
-
-	// ja: バックアップディレクトリを作成
-	// en: Create backup directory
-	homeDir, err := os.UserHomeDir()
+	project, err := store.FindProject(config, projectName)
 	if err != nil {
 		return err
 	}
 
-	backupDir := filepath.Join(homeDir, ".config", "toske", "backups", project.Name)
-	fmt.Printf(i18n.T("backup.creatingDir")+"\n", backupDir)
-
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
-		return fmt.Errorf(i18n.T("backup.createDirError"), err)
+	target, err := backupTarget(project)
+	if err != nil {
+		return err
 	}
-
-	// ja: バックアップアーカイブを作成
-	// en: Create backup archive
-	timestamp := time.Now()
-	// ja: マイクロ秒を含めることで、同一秒内の複数実行でもファイル名の衝突を防ぐ
-	// en: Include microseconds to prevent filename collisions when multiple runs occur within the same second
-	archiveFilename := fmt.Sprintf("backup_%s.tar.gz", timestamp.Format("20060102_150405.000000"))
-	archivePath := filepath.Join(backupDir, archiveFilename)
-
-	fmt.Printf(i18n.T("backup.creatingArchive")+"\n", archiveFilename)
-
-	backedUpFiles, err := createBackupArchive(archivePath, project.BackupPaths)
+	v, err := newVCS(project, gitRunner)
 	if err != nil {
-		return fmt.Errorf(i18n.T("backup.archiveError"), err)
+		return err
 	}
 
-	// ja: メタデータファイルを更新
-	// en: Update metadata file
-	fmt.Println(i18n.T("backup.updatingMetadata"))
-	if err := updateMetadata(backupDir, project.Name, archiveFilename, timestamp, backedUpFiles); err != nil {
-		return fmt.Errorf(i18n.T("backup.metadataError"), err)
+	// ja: ディレクトリを削除できるかは、VCS の確認も含めてバックアップを始める前に確かめる
+	// en: Check that the directory may be removed, including the VCS checks, before starting the backup
+	if backupRemoveTree {
+		if err := checkRemovableTree(project, target, store.Path()); err != nil {
+			return err
+		}
+		if err := v.checkDelete(project, backupForce); err != nil {
+			return err
+		}
 	}
 
-	// ja: backup_retention に基づいて古いバックアップを削除
-	// en: Prune old backups based on backup_retention
-	if project.BackupRetention > 0 {
-		fmt.Printf(i18n.T("backup.pruningOldBackups")+"\n", project.BackupRetention)
-		if err := pruneOldBackups(backupDir, project.BackupRetention); err != nil {
-			fmt.Fprintf(os.Stderr, i18n.T("backup.pruneError")+"\n", err)
+	if dryRun {
+		plan, err := toske.PlanBackup(target, toskeOptions())
+		if err != nil {
+			return err
 		}
+		return showBackupPlan(plan, backupRemoveTree)
 	}
 
-	fmt.Println()
-	fmt.Println(i18n.T("backup.success"))
-	fmt.Printf(i18n.T("backup.backupLocation")+"\n", archivePath)
+	statusf(i18n.T("backup.creatingBackup")+"\n", project.Name)
 
-	return nil
-}
-
-// ja: createBackupArchive はバックアップアーカイブを作成します
-// en: createBackupArchive creates a backup archive
-func createBackupArchive(archivePath string, backupPaths []string) ([]string, error) {
-	// ja: アーカイブファイルを作成
-	// en: Create archive file
-	archiveFile, err := os.Create(archivePath)
-	if err != nil {
-		return nil, err
-	}
-	defer archiveFile.Close()
+	// ja: チェックアウトの状態を記録とマニフェストに残す（記録するものがなければ残さない）
+	// en: Keep the state of the checkout in the record and the manifest (when there is anything to record)
+	target.Git = v.captureState(target.Dir)
+	v.warnBackup(project, target.Dir)
 
-	// ja: gzip ライターを作成
-	// en: Create gzip writer
-	gzipWriter := gzip.NewWriter(archiveFile)
-	defer gzipWriter.Close()
+	// ja: バックアップの作成は toske パッケージに任せ、進捗だけを表示する
+	// ja: Ctrl-C で中断された場合、作成途中のアーカイブは toske パッケージが削除する
+	// en: Leave the backup itself to the toske package and only report its progress
+	// en: When interrupted with Ctrl-C, the toske package removes the unfinished archive
+	ctx, stop := interruptContext()
+	defer stop()
 
-	// ja: tar ライターを作成
-	// en: Create tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+	progress := newProgressReporter()
+	opts := toskeOptions()
+	opts.Progress = progress.report
 
-	// ja: カレントディレクトリを取得
-	// en: Get current directory
-	currentDir, err := os.Getwd()
+	result, err := toske.Backup(ctx, target, opts)
+	progress.finish()
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			journal.interrupt()
+			return fmt.Errorf("%s", i18n.T("backup.interrupted"))
+		}
+		return err
 	}
 
-	var backedUpFiles []string
-
-	// ja: 各バックアップ対象パスを処理
-	// en: Process each backup path
-	for _, backupPath := range backupPaths {
-		fullPath := filepath.Join(currentDir, backupPath)
+	journal.entry.Archive = result.Archive
+	journal.touchArchive(result.Archive, fileCreated, result.SHA256)
+	for _, pruned := range result.Pruned {
+		journal.touch(filepath.Join(filepath.Dir(result.Archive), pruned), fileRemoved)
+	}
 
-		// ja: ファイルまたはディレクトリが存在するかチェック
-		// en: Check if file or directory exists
-		info, err := os.Stat(fullPath)
-		if os.IsNotExist(err) {
-			fmt.Printf(i18n.T("backup.fileNotFound")+"\n", backupPath)
-			continue
-		}
+	// ja: 確認済みのアーカイブができてから、ディレクトリを削除する
+	// en: Remove the directory only once the verified archive is in place
+	if backupRemoveTree {
+		removed, err := removeTree(target, result, opts)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		// ja: ファイルまたはディレクトリをアーカイブに追加
-		// en: Add file or directory to archive
-		if info.IsDir() {
-			err = addDirToArchive(tarWriter, fullPath, backupPath)
+		if removed {
+			journal.touch(target.Dir, fileRemoved)
 		} else {
-			err = addFileToArchive(tarWriter, fullPath, backupPath)
-			fmt.Printf(i18n.T("backup.addingFile")+"\n", backupPath)
+			journal.note("kept " + target.Dir)
 		}
-
-		if err != nil {
-			return nil, err
-		}
-
-		backedUpFiles = append(backedUpFiles, backupPath)
 	}
 
-	return backedUpFiles, nil
-}
-
-// ja: addFileToArchive はファイルをアーカイブに追加します
-// en: addFileToArchive adds a file to the archive
-func addFileToArchive(tarWriter *tar.Writer, fullPath, archivePath string) error {
-	file, err := os.Open(fullPath)
-	if err != nil {
-		return err
+	if isStructuredOutput() {
+		result.Files = nonNil(result.Files)
+		result.Skipped = nonNil(result.Skipped)
+		result.Dumps = nonNil(result.Dumps)
+		result.Pruned = nonNil(result.Pruned)
+		result.Unsupported = nonNil(result.Unsupported)
+		return writeResult(result)
 	}
-	defer file.Close()
 
-	info, err := file.Stat()
-	if err != nil {
-		return err
+	if outputVerbosity() == verbosityQuiet {
+		return nil
 	}
 
-	// ja: tar アーカイブではパスを POSIX スタイル（スラッシュ）に正規化
-	// en: Normalize path to POSIX style (forward slashes) for tar archive portability
-	header := &tar.Header{
-		Name:    filepath.ToSlash(archivePath),
-		Size:    info.Size(),
-		Mode:    int64(info.Mode()),
-		ModTime: info.ModTime(),
+	fmt.Println()
+	fmt.Println(i18n.T("backup.success"))
+	fmt.Printf(i18n.T("backup.backupLocation")+"\n", result.Archive)
+	if result.Verified {
+		fmt.Println(i18n.T("backup.archiveVerified"))
 	}
-
-	if err := tarWriter.WriteHeader(header); err != nil {
-		return err
+	if len(result.Unsupported) > 0 {
+		fmt.Printf(i18n.T("backup.unsupportedSkipped")+"\n", len(result.Unsupported))
 	}
-
-	_, err = io.Copy(tarWriter, file)
-	return err
-}
-
-// ja: addDirToArchive はディレクトリを再帰的にアーカイブに追加します
-// en: addDirToArchive recursively adds a directory to the archive
-func addDirToArchive(tarWriter *tar.Writer, fullPath, archivePath string) error {
-	return filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// ja: ディレクトリ自体はスキップ
-		// en: Skip directories themselves
-		if info.IsDir() {
-			return nil
-		}
-
-		// ja: アーカイブ内のパスを計算
-		// en: Calculate path in archive
-		relPath, err := filepath.Rel(fullPath, path)
-		if err != nil {
-			return err
+	if result.TreeRemoved {
+		fmt.Printf(i18n.T("backup.treeRemoved")+"\n", target.Dir)
+	}
+	if work := result.GitWork; work != nil {
+		fmt.Printf(i18n.T("backup.gitStateSaved")+"\n", len(work.Branches), work.Stashes, len(work.Untracked))
+		if work.Patch {
+			fmt.Println(i18n.T("backup.gitPatchSaved"))
 		}
-		archiveFilePath := filepath.Join(archivePath, relPath)
-
-		fmt.Printf(i18n.T("backup.addingFile")+"\n", archiveFilePath)
+	}
 
-		return addFileToArchive(tarWriter, path, archiveFilePath)
-	})
+	return nil
 }
 
-// ja: updateMetadata はメタデータファイルを更新します
-// en: updateMetadata updates the metadata file
-func updateMetadata(backupDir, projectName, archiveFilename string, timestamp time.Time, files []string) error {
-	metadataPath := filepath.Join(backupDir, "backups.yaml")
-
-	// ja: 既存のメタデータを読み込む
-	// en: Load existing metadata
-	var metadata BackupMetadata
-	if data, err := os.ReadFile(metadataPath); err == nil {
-		if err := yaml.Unmarshal(data, &metadata); err != nil {
-			return err
-		}
+// ja: checkRemovableTree は --remove-tree でプロジェクトのディレクトリを削除してよいかを確かめます
+// ja: archive_mode full 以外のプロジェクト、path のないプロジェクト（カレントディレクトリ）、ホームやルート、
+// ja: 設定ファイルやバックアップを含むディレクトリは削除しません
+// en: checkRemovableTree checks that --remove-tree may remove the project directory
+// en: It refuses projects not using archive_mode full, projects without a path (the current directory),
+// en: the home or root directory, and a directory holding the config file or the backups
+func checkRemovableTree(project *Project, target toske.Project, configPath string) error {
+	dir := target.Dir
+	if project.ArchiveMode != toske.ArchiveModeFull {
+		return fmt.Errorf(i18n.T("backup.removeTreeNeedsFull"), project.Name)
 	}
-
-	// ja: プロジェクト名を設定
-	// en: Set project name
-	metadata.Project = projectName
-
-	// ja: 新しいバックアップ記録を追加
-	// en: Add new backup record
-	metadata.Backups = append(metadata.Backups, BackupRecord{
-		Filename:  archiveFilename,
-		Timestamp: timestamp,
-		Files:     files,
-	})
-
-	// ja: タイムスタンプでソート（新しい順）
-	// en: Sort by timestamp (newest first)
-	sort.Slice(metadata.Backups, func(i, j int) bool {
-		return metadata.Backups[i].Timestamp.After(metadata.Backups[j].Timestamp)
-	})
-
-	// ja: メタデータファイルに書き込む
-	// en: Write metadata file
-	data, err := yaml.Marshal(&metadata)
-	if err != nil {
-		return err
+	if project.Path == "" {
+		return fmt.Errorf(i18n.T("backup.removeTreeNeedsPath"), project.Name)
 	}
 
-	return os.WriteFile(metadataPath, data, 0644)
-}
-
-// ja: pruneOldBackups は古いバックアップを削除します
-// en: pruneOldBackups removes old backups
-func pruneOldBackups(backupDir string, retention int) error {
-	metadataPath := filepath.Join(backupDir, "backups.yaml")
-
-	// ja: メタデータを読み込む
-	// en: Load metadata
-	data, err := os.ReadFile(metadataPath)
-	if err != nil {
-		return err
+	homeDir, _ := os.UserHomeDir()
+	if filepath.Dir(dir) == dir || (homeDir != "" && filepath.Clean(homeDir) == dir) {
+		return fmt.Errorf(i18n.T("backup.removeTreeRefused"), dir)
 	}
-
-	var metadata BackupMetadata
-	if err := yaml.Unmarshal(data, &metadata); err != nil {
-		return err
+	if configPath, err := filepath.Abs(configPath); err == nil {
+		if rel, err := filepath.Rel(dir, configPath); err == nil && !strings.HasPrefix(rel, "..") {
+			return fmt.Errorf(i18n.T("backup.removeTreeHoldsBackups"), dir, configPath)
+		}
 	}
+	return toske.CheckRemoveTree(target, toskeOptions())
+}
 
-	// ja: 保持件数を超えるバックアップを削除
-	// en: Delete backups exceeding retention count
-	if len(metadata.Backups) > retention {
-		for _, backup := range metadata.Backups[retention:] {
-			archivePath := filepath.Join(backupDir, backup.Filename)
-			if err := os.Remove(archivePath); err != nil && !os.IsNotExist(err) {
-				return err
-			}
-		}
+// ja: removeTree は確認（--force の場合は省略）の後に、バックアップしたディレクトリを削除します
+// ja: 確認で削除しなかった場合は false を返します
+// en: removeTree removes the backed up directory after a confirmation (skipped with --force)
+// en: Returns false when the confirmation declined the removal
+func removeTree(target toske.Project, result *toske.BackupResult, opts toske.Options) (bool, error) {
+	// ja: アーカイブできなかったファイルがあれば、確認する前に断る
+	// en: Refuse before asking when some files could not be archived
+	if len(result.Unsupported) > 0 {
+		return false, fmt.Errorf(i18n.T("backup.removeTreeUnsupported"), target.Dir, len(result.Unsupported))
+	}
 
-		// ja: メタデータを更新
-		// en: Update metadata
-		metadata.Backups = metadata.Backups[:retention]
-		data, err := yaml.Marshal(&metadata)
+	if !backupForce {
+		msgf(i18n.T("backup.confirmRemoveTree"), target.Dir)
+		response, err := bufio.NewReader(os.Stdin).ReadString('\n')
 		if err != nil {
-			return err
+			return false, fmt.Errorf(i18n.T("backup.readInputError"), err)
 		}
-
-		if err := os.WriteFile(metadataPath, data, 0644); err != nil {
-			return err
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			msgf(i18n.T("backup.removeTreeCancelled")+"\n", target.Dir)
+			return false, nil
 		}
 	}
 
-	return nil
+	statusf(i18n.T("backup.removingTree")+"\n", target.Dir)
+	if err := toske.RemoveTree(target, result, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ja: toskeOptions は CLI から toske パッケージを呼び出す際のオプションを返します
+// en: toskeOptions returns the options used when the CLI calls the toske package
+// ja: 進捗を表示する場合は Progress に progressReporter.report を設定してください
+// en: Set Progress to progressReporter.report to display the progress
+func toskeOptions() toske.Options {
+	return toske.Options{
+		Runner: loggingRunner{runner: dumpRunner},
+		Build:  toske.BuildInfo{Version: Version, Commit: Commit},
+	}
+}
+
+// ja: getBackupDir はプロジェクトのバックアップディレクトリを返します
+// en: getBackupDir returns the backup directory of a project
+func getBackupDir(projectName string) (string, error) {
+	return toskeOptions().BackupDir(projectName)
 }
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/yk-lab/toske/toske"
 	"gopkg.in/yaml.v3"
 )
 
@@ -226,7 +227,7 @@ projects:
 		t.Fatalf("Failed to read metadata file: %v", err)
 	}
 
-	var metadata BackupMetadata
+	var metadata toske.BackupMetadata
 	if err := yaml.Unmarshal(data, &metadata); err != nil {
 		t.Fatalf("Failed to parse metadata: %v", err)
 	}
@@ -348,7 +349,7 @@ projects:
 		t.Fatalf("Failed to read metadata file: %v", err)
 	}
 
-	var metadata BackupMetadata
+	var metadata toske.BackupMetadata
 	if err := yaml.Unmarshal(data, &metadata); err != nil {
 		t.Fatalf("Failed to parse metadata: %v", err)
 	}
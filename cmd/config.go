@@ -3,8 +3,23 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
 )
 
+// ja: configCmd は設定ファイルを管理するサブコマンドをまとめます
+// en: configCmd groups the subcommands that manage the configuration file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: i18n.T("configCmd.short"),
+	Long:  i18n.T("configCmd.long"),
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
+
 // ja: getDefaultConfigPath はデフォルトの設定ファイルパスを返します
 // ja: 優先順位: TOSKE_CONFIG 環境変数 > 新パス（存在時） > レガシーパス（存在時） > 新パス（デフォルト）
 // en: getDefaultConfigPath returns the default configuration file path
@@ -0,0 +1,344 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: envOverridePrefix はプロジェクトのフィールドを上書きする環境変数の接頭辞です
+// ja: 例: TOSKE_PROJECT_MYAPP_BRANCH=develop、TOSKE_PROJECT_MYAPP_BACKUP_PATHS=.env,storage/
+// en: envOverridePrefix is the prefix of environment variables overriding project fields
+// en: e.g. TOSKE_PROJECT_MYAPP_BRANCH=develop, TOSKE_PROJECT_MYAPP_BACKUP_PATHS=.env,storage/
+const envOverridePrefix = "TOSKE_PROJECT_"
+
+// ja: configNotFoundError は設定ファイルが存在しない場合のエラーです
+// en: configNotFoundError is returned when the config file does not exist
+type configNotFoundError struct {
+	Path string
+}
+
+func (e *configNotFoundError) Error() string {
+	return fmt.Sprintf(i18n.T("config.noConfig"), e.Path)
+}
+
+// ja: configStore は設定ファイルの読み込み・プロジェクトの検索・保存を一手に引き受けます
+// ja: env が nil の場合、環境変数による上書きは行いません
+// en: configStore is the single place that loads the config file, finds projects and saves changes
+// en: Environment overrides are skipped when env is nil
+type configStore struct {
+	path string
+	env  func(key string) (string, bool)
+}
+
+// ja: newConfigStore は --config、TOSKE_CONFIG、既定のパスの順で決まる設定ファイルを扱うストアを返します
+// en: newConfigStore returns a store for the config file chosen by --config, TOSKE_CONFIG or the default path
+func newConfigStore() *configStore {
+	path := cfgFile
+	if path == "" {
+		path = getDefaultConfigPath()
+	}
+	return &configStore{path: path, env: os.LookupEnv}
+}
+
+// ja: Path は設定ファイルのパスを返します
+// en: Path returns the path of the config file
+func (s *configStore) Path() string {
+	return s.path
+}
+
+// ja: ensureExists は設定ファイルが存在しない場合に configNotFoundError を返します
+// ja: レガシーパスの設定ファイルを使用している場合は移行を促す警告を表示します
+// en: ensureExists returns a configNotFoundError when the config file does not exist
+// en: A config file at the legacy path triggers a warning suggesting to migrate it
+func (s *configStore) ensureExists() error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		return &configNotFoundError{Path: s.path}
+	}
+
+	// ja: レガシーパスを使用している場合は移行を促す警告を表示
+	// en: Show migration warning if using legacy path
+	if isLegacyConfigPath(s.path) {
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, i18n.T("config.legacyWarning"))
+		fmt.Fprintln(os.Stderr, i18n.T("config.legacyWarningDetail"))
+		fmt.Fprintln(os.Stderr, "")
+	}
+
+	return nil
+}
+
+// ja: LoadStored は設定ファイルに書かれている内容だけを読み込みます
+// ja: include や環境変数は反映しないため、設定ファイルを書き換えるコマンドはこちらを使います
+// en: LoadStored reads only what is written in the config file
+// en: Includes and environment overrides are not applied, so commands that rewrite the file use this
+func (s *configStore) LoadStored() (*Config, error) {
+	if err := s.ensureExists(); err != nil {
+		return nil, err
+	}
+
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	slog.Debug("loading config", "path", s.path)
+	v := viper.New()
+	v.SetConfigFile(s.path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf(i18n.T("config.readError"), err)
+	}
+
+	return decodeConfig(v)
+}
+
+// ja: LoadData は設定ファイルの内容の代わりに data を読み込み、include は設定ファイルの場所を基準に解決します
+// ja: 保存する前の編集結果を検証するためのもので、環境変数による上書きは適用しません
+// en: LoadData reads data in place of the config file's content, resolving includes from where the config file is
+// en: It is meant for checking an edit before it is saved, so environment overrides are not applied
+func (s *configStore) LoadData(data []byte) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType(configType(s.path))
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf(i18n.T("config.readError"), err)
+	}
+
+	config, err := decodeConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveConfig(s.path, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ja: decodeConfig は viper が読み込んだ設定を構造体にアンマーシャルします
+// en: decodeConfig unmarshals the config read by viper into a struct
+func decodeConfig(v *viper.Viper) (*Config, error) {
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf(i18n.T("config.parseError"), err)
+	}
+	return &config, nil
+}
+
+// ja: configType は設定ファイルの拡張子から viper の形式を決めます（拡張子がなければ YAML）
+// en: configType works out viper's format from the config file's extension (YAML when there is none)
+func configType(path string) string {
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		return ext
+	}
+	return "yaml"
+}
+
+// ja: Load は実際に使われる設定を読み込みます
+// ja: include したファイルと各リポジトリの .toske.yml をマージし、環境変数による上書きを適用します
+// en: Load reads the effective configuration
+// en: Included files and each repository's .toske.yml are merged, then environment overrides are applied
+func (s *configStore) Load() (*Config, error) {
+	config, err := s.LoadStored()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveConfig(s.path, config); err != nil {
+		return nil, err
+	}
+
+	if s.env != nil {
+		if err := applyEnvOverrides(config, s.env); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// ja: FindProject は名前でプロジェクトを探します
+// en: FindProject looks up a project by name
+func (s *configStore) FindProject(config *Config, name string) (*Project, error) {
+	index := findProjectIndex(config.Projects, name)
+	if index == -1 {
+		return nil, &toske.ProjectNotFoundError{Name: name}
+	}
+	return &config.Projects[index], nil
+}
+
+// ja: projectSource は name のプロジェクトを定義しているファイル（設定ファイル自身か include したファイル）のストアを返します
+// ja: プロジェクトを変更・削除するコマンドは、このストアで定義元のファイルを編集します
+// en: projectSource returns a store for the file defining the project name (the config file itself or an included file)
+// en: Commands changing or removing a project edit the defining file through this store
+func (s *configStore) projectSource(name string) (*configStore, error) {
+	config, err := s.LoadStored()
+	if err != nil {
+		return nil, err
+	}
+	if findProjectIndex(config.Projects, name) != -1 {
+		return s, nil
+	}
+
+	if err := resolveConfig(s.path, config); err != nil {
+		return nil, err
+	}
+	project, err := s.FindProject(config, name)
+	if err != nil {
+		return nil, err
+	}
+	// ja: name の最初の出所が、プロジェクトを定義しているファイル
+	// en: The first origin of name is the file defining the project
+	if sources := project.Origins["name"]; len(sources) > 0 {
+		return &configStore{path: sources[0], env: s.env}, nil
+	}
+	return s, nil
+}
+
+// ja: findProjectIndex はプロジェクト名からインデックスを検索します
+// en: findProjectIndex finds the index of a project by name
+func findProjectIndex(projects []Project, name string) int {
+	for i := range projects {
+		if projects[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ja: Edit は設定ファイルに edit を適用して保存します
+// ja: configfile で変更箇所の行のみを差し替えるため、コメントや書式、パーミッションは維持されます
+// en: Edit applies edit to the config file and saves it
+// en: configfile only splices the affected lines, so comments, formatting and the file mode are kept
+func (s *configStore) Edit(edit func(file *configfile.File) error) error {
+	file, err := configfile.Load(s.path)
+	if err != nil {
+		return fmt.Errorf(i18n.T("config.readError"), err)
+	}
+
+	if err := edit(file); err != nil {
+		return fmt.Errorf(i18n.T("config.editError"), err)
+	}
+
+	if err := file.Save(); err != nil {
+		return fmt.Errorf(i18n.T("config.writeError"), err)
+	}
+
+	return nil
+}
+
+// ja: PlanEdit は edit をメモリ上でだけ適用し、Edit で書き込まれる差分を返します（保存はしません）
+// en: PlanEdit applies edit in memory only and returns the diff Edit would write (nothing is saved)
+func (s *configStore) PlanEdit(edit func(file *configfile.File) error) (*configChange, error) {
+	file, err := configfile.Load(s.path)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("config.readError"), err)
+	}
+	before := append([]byte(nil), file.Bytes()...)
+
+	if err := edit(file); err != nil {
+		return nil, fmt.Errorf(i18n.T("config.editError"), err)
+	}
+
+	return &configChange{Path: s.path, Diff: diffLines(before, file.Bytes())}, nil
+}
+
+// ja: applyEnvOverrides は TOSKE_PROJECT_<名前>_<フィールド> 形式の環境変数でプロジェクトのフィールドを上書きします
+// ja: 名前とフィールドは大文字にし、英数字以外は _ に置き換えます（name と dumps は上書きできません）
+// en: applyEnvOverrides overrides project fields with TOSKE_PROJECT_<NAME>_<FIELD> environment variables
+// en: Names and fields are upper-cased with non-alphanumerics replaced by _ (name and dumps cannot be overridden)
+func applyEnvOverrides(config *Config, env func(string) (string, bool)) error {
+	// ja: 複数のプロジェクトに当てはまってしまう環境変数（my-app と my_app など）は、どちらにも適用しない
+	// en: A variable matching more than one project (my-app and my_app, say) is applied to neither
+	ambiguous := make(map[string]bool)
+	for _, collision := range findEnvOverrideCollisions(config.Projects) {
+		ambiguous[collision.Variable] = true
+	}
+
+	for i := range config.Projects {
+		project := &config.Projects[i]
+		for _, key := range fieldKeys(project) {
+			if key == "name" {
+				continue
+			}
+
+			name := envOverrideName(project.Name, key)
+			value, ok := env(name)
+			if !ok {
+				continue
+			}
+			if ambiguous[name] {
+				return fmt.Errorf(i18n.T("config.envAmbiguous"), name)
+			}
+
+			field, err := lookupField(project, key)
+			if err != nil {
+				return err
+			}
+			if kind := field.Value.Kind(); kind == reflect.Slice && field.Value.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			if err := field.apply(fieldAssign, value); err != nil {
+				return fmt.Errorf(i18n.T("config.envError"), name, err)
+			}
+
+			if project.Origins == nil {
+				project.Origins = make(map[string][]string)
+			}
+			project.Origins[key] = []string{"$" + name}
+		}
+	}
+	return nil
+}
+
+// ja: envOverrideCollision は 2 つのプロジェクトで同じになってしまう上書き用の環境変数です
+// en: envOverrideCollision is an override environment variable shared by two projects
+type envOverrideCollision struct {
+	Variable string
+	Projects [2]string
+}
+
+// ja: findEnvOverrideCollisions は別々のプロジェクトで同じになってしまう上書き用の環境変数をすべて返します
+// en: findEnvOverrideCollisions returns every override environment variable shared by distinct projects
+func findEnvOverrideCollisions(projects []Project) []envOverrideCollision {
+	var collisions []envOverrideCollision
+	owners := make(map[string]string)
+	for i := range projects {
+		project := &projects[i]
+		if project.Name == "" {
+			continue
+		}
+		for _, key := range fieldKeys(project) {
+			name := envOverrideName(project.Name, key)
+			if owner, seen := owners[name]; seen && owner != project.Name {
+				collisions = append(collisions, envOverrideCollision{Variable: name, Projects: [2]string{owner, project.Name}})
+				continue
+			}
+			owners[name] = project.Name
+		}
+	}
+	return collisions
+}
+
+// ja: envOverrideName はプロジェクトのフィールドを上書きする環境変数名を返します
+// en: envOverrideName returns the environment variable overriding a project field
+func envOverrideName(projectName, key string) string {
+	normalize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+				return r
+			default:
+				return '_'
+			}
+		}, s)
+	}
+	return envOverridePrefix + normalize(projectName) + "_" + normalize(key)
+}
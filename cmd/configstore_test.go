@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yk-lab/toske/configfile"
+)
+
+// mapEnv returns an environment lookup backed by a map.
+func mapEnv(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func TestEnvOverrideName(t *testing.T) {
+	tests := []struct {
+		project  string
+		key      string
+		expected string
+	}{
+		{project: "myapp", key: "branch", expected: "TOSKE_PROJECT_MYAPP_BRANCH"},
+		{project: "my-app", key: "backup_paths", expected: "TOSKE_PROJECT_MY_APP_BACKUP_PATHS"},
+		{project: "App.v2", key: "backup_retention", expected: "TOSKE_PROJECT_APP_V2_BACKUP_RETENTION"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := envOverrideName(tt.project, tt.key); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{
+			Version: "1.0.0",
+			Projects: []Project{
+				{Name: "my-app", Repo: "git@github.com:user/my-app.git", Branch: "main", BackupPaths: []string{".env"}, BackupRetention: 3},
+				{Name: "other", Repo: "git@github.com:user/other.git", Branch: "main"},
+			},
+		}
+	}
+
+	t.Run("overrides fields", func(t *testing.T) {
+		config := newConfig()
+		err := applyEnvOverrides(config, mapEnv(map[string]string{
+			"TOSKE_PROJECT_MY_APP_BRANCH":           "develop",
+			"TOSKE_PROJECT_MY_APP_BACKUP_PATHS":     ".env.local, storage/",
+			"TOSKE_PROJECT_MY_APP_BACKUP_RETENTION": "7",
+			"TOSKE_PROJECT_MY_APP_NAME":             "renamed",
+		}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		project := config.Projects[0]
+		if project.Name != "my-app" {
+			t.Errorf("Expected the name to stay, got %q", project.Name)
+		}
+		if project.Branch != "develop" || project.BackupRetention != 7 {
+			t.Errorf("Unexpected project: %+v", project)
+		}
+		if !reflect.DeepEqual(project.BackupPaths, []string{".env.local", "storage/"}) {
+			t.Errorf("Unexpected backup paths: %v", project.BackupPaths)
+		}
+		if got := project.Origins["branch"]; !reflect.DeepEqual(got, []string{"$TOSKE_PROJECT_MY_APP_BRANCH"}) {
+			t.Errorf("Expected the origin to name the variable, got %v", got)
+		}
+		if config.Projects[1].Branch != "main" {
+			t.Errorf("Other projects must not change, got %+v", config.Projects[1])
+		}
+	})
+
+	t.Run("invalid number", func(t *testing.T) {
+		err := applyEnvOverrides(newConfig(), mapEnv(map[string]string{"TOSKE_PROJECT_OTHER_BACKUP_RETENTION": "many"}))
+		if err == nil || !strings.Contains(err.Error(), "TOSKE_PROJECT_OTHER_BACKUP_RETENTION") {
+			t.Errorf("Expected an error naming the variable, got: %v", err)
+		}
+	})
+
+	t.Run("ambiguous variable", func(t *testing.T) {
+		config := newConfig()
+		config.Projects[1].Name = "my_app"
+		err := applyEnvOverrides(config, mapEnv(map[string]string{"TOSKE_PROJECT_MY_APP_BRANCH": "develop"}))
+		if err == nil || !strings.Contains(err.Error(), "TOSKE_PROJECT_MY_APP_BRANCH") {
+			t.Errorf("Expected the ambiguous variable to be refused, got: %v", err)
+		}
+		if config.Projects[0].Branch != "main" || config.Projects[1].Branch != "main" {
+			t.Errorf("Expected neither project to be overridden, got %+v", config.Projects)
+		}
+	})
+}
+
+func TestConfigStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, getConfigTemplate()))
+
+	t.Run("missing file", func(t *testing.T) {
+		store := &configStore{path: filepath.Join(t.TempDir(), "missing.yml")}
+		_, err := store.Load()
+		var notFound *configNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Errorf("Expected configNotFoundError, got: %v", err)
+		}
+	})
+
+	t.Run("load applies env overrides", func(t *testing.T) {
+		store := &configStore{path: cfgFile, env: mapEnv(map[string]string{"TOSKE_PROJECT_SAMPLE_PROJECT_BRANCH": "develop"})}
+		config, err := store.Load()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		project, err := store.FindProject(config, "sample-project")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if project.Branch != "develop" {
+			t.Errorf("Expected the override to apply, got %q", project.Branch)
+		}
+
+		stored, err := store.LoadStored()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stored.Projects[0].Branch != "main" {
+			t.Errorf("LoadStored must not apply overrides, got %q", stored.Projects[0].Branch)
+		}
+	})
+
+	t.Run("find missing project", func(t *testing.T) {
+		store := &configStore{path: cfgFile}
+		config, err := store.Load()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := store.FindProject(config, "nonexistent"); err == nil || !strings.Contains(err.Error(), "not found in configuration file") {
+			t.Errorf("Expected project not found error, got: %v", err)
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		store := &configStore{path: cfgFile}
+		if err := store.Edit(func(file *configfile.File) error {
+			return file.SetProjectField("sample-project", "branch", "release")
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		data, _ := os.ReadFile(cfgFile)
+		if !strings.Contains(string(data), "    branch: release\n") {
+			t.Errorf("Expected the edit to be saved, got:\n%s", data)
+		}
+
+		before := string(data)
+		err := store.Edit(func(file *configfile.File) error { return file.RemoveProject("nonexistent") })
+		if err == nil {
+			t.Error("Expected an error for a failed edit")
+		}
+		after, _ := os.ReadFile(cfgFile)
+		if string(after) != before {
+			t.Error("Config must not change when the edit fails")
+		}
+	})
+}
+
+func TestEnvOverridesApplyToCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, getConfigTemplate()))
+	t.Setenv("TOSKE_PROJECT_SAMPLE_PROJECT_BACKUP_PATHS", "storage/")
+
+	originalProject := getProjectName
+	defer func() { getProjectName = originalProject }()
+	getProjectName = "sample-project"
+
+	output, err := captureStdout(t, func() error { return runGet("backup_paths") })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "storage/\n" {
+		t.Errorf("Expected the override to be visible, got %q", output)
+	}
+}
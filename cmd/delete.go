@@ -7,9 +7,8 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/yk-lab/toske/configfile"
 	"github.com/yk-lab/toske/i18n"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -38,42 +37,52 @@ func init() {
 	deleteCmd.MarkFlagRequired("project")
 }
 
-func runDelete() error {
+func runDelete() (err error) {
 	// ja: プロジェクト名の前後の空白を削除
 	// en: Trim leading and trailing whitespace from project name
 	deleteProjectName = strings.TrimSpace(deleteProjectName)
 
+	journal := startOperation("delete", deleteProjectName)
+	defer func() { journal.finish(err) }()
+
 	// ja: プロジェクト名が空でないかチェック (Cobra の MarkFlagRequired のバックアップ)
 	// en: Check project name is not empty (backup for Cobra's MarkFlagRequired)
 	if deleteProjectName == "" {
 		return fmt.Errorf("%s", i18n.T("delete.noProjectFlag"))
 	}
 
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
+	// ja: プロジェクトを定義しているファイル（include したファイルの場合もある）を読み込み、指定されたプロジェクトを検索
+	// en: Load the file defining the project (which may be an included file) and find the specified project
+	store, err := newConfigStore().projectSource(deleteProjectName)
+	if err != nil {
+		return err
+	}
+	config, err := store.LoadStored()
+	if err != nil {
+		return err
+	}
+	project, err := store.FindProject(config, deleteProjectName)
+	if err != nil {
+		return err
 	}
 
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("delete.noConfig"), configPath)
+	// ja: 設定から外す前に、チェックアウトに残る作業を警告し、--force がなければ断る（vcs が不明でも設定からは外せるようにする）
+	// en: Warn about work left in the checkout before taking it out of the config, refusing without --force (an unknown vcs must not block this)
+	if v, err := newVCS(project, gitRunner); err == nil {
+		if err := v.checkDelete(project, deleteForce); err != nil {
+			return err
+		}
 	}
 
-	// ja: 設定ファイルを読み込む
-	// en: Load configuration file
-	config, err := loadDeleteConfig(configPath)
+	change, err := planConfigEdit(store, "delete", deleteProjectName, func(file *configfile.File) error { return file.RemoveProject(deleteProjectName) })
 	if err != nil {
 		return err
 	}
-
-	// ja: 指定されたプロジェクトを検索
-	// en: Find the specified project
-	projectIndex := findProjectIndex(config.Projects, deleteProjectName)
-	if projectIndex == -1 {
-		return fmt.Errorf(i18n.T("delete.projectNotFound"), deleteProjectName)
+	if dryRun {
+		return showConfigChange(change)
+	}
+	if !deleteForce {
+		printConfigChange(change)
 	}
 
 	// ja: 削除確認
@@ -83,54 +92,23 @@ func runDelete() error {
 		return err
 	}
 	if !confirmed {
+		journal.cancel()
 		fmt.Println(i18n.T("delete.cancelled"))
 		return nil
 	}
 
-	// ja: プロジェクトを削除
-	// en: Delete the project
-	config.Projects = append(config.Projects[:projectIndex], config.Projects[projectIndex+1:]...)
-
-	// ja: 設定ファイルを保存
-	// en: Save configuration file
-	if err := saveConfig(configPath, &config); err != nil {
+	// ja: プロジェクトを削除して設定ファイルを保存
+	// en: Delete the project and save the configuration file
+	if err := deleteProjectFromConfig(store.Path(), deleteProjectName); err != nil {
 		return err
 	}
+	journal.touch(store.Path(), fileModified)
 
 	fmt.Printf(i18n.T("delete.success")+"\n", deleteProjectName)
 
 	return nil
 }
 
-// ja: loadDeleteConfig は設定ファイルを読み込みます
-// en: loadDeleteConfig loads the configuration file
-func loadDeleteConfig(configPath string) (Config, error) {
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return Config{}, fmt.Errorf(i18n.T("delete.readError"), err)
-	}
-
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return Config{}, fmt.Errorf(i18n.T("delete.parseError"), err)
-	}
-
-	return config, nil
-}
-
-// ja: findProjectIndex はプロジェクト名からインデックスを検索します
-// en: findProjectIndex finds the index of a project by name
-func findProjectIndex(projects []Project, name string) int {
-	for i := range projects {
-		if projects[i].Name == name {
-			return i
-		}
-	}
-	return -1
-}
-
 // ja: confirmDeletion は削除の確認を行います
 // en: confirmDeletion confirms the deletion
 func confirmDeletion(projectName string, force bool) (bool, error) {
@@ -159,17 +137,11 @@ func confirmDeletion(projectName string, force bool) (bool, error) {
 	return response == "y" || response == "yes", nil
 }
 
-// ja: saveConfig は設定ファイルを保存します
-// en: saveConfig saves the configuration file
-func saveConfig(configPath string, config *Config) error {
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf(i18n.T("delete.marshalError"), err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf(i18n.T("delete.writeError"), err)
-	}
-
-	return nil
+// ja: deleteProjectFromConfig は設定ファイルからプロジェクトを削除して保存します
+// ja: 該当するプロジェクトの行のみを取り除くため、コメントや他のプロジェクトの書式、パーミッションは維持されます
+// en: deleteProjectFromConfig deletes the project from the configuration file and saves it
+// en: Only the project's lines are taken out, so comments, the formatting of other projects and the file mode are kept
+func deleteProjectFromConfig(configPath, projectName string) error {
+	store := &configStore{path: configPath}
+	return store.Edit(func(file *configfile.File) error { return file.RemoveProject(projectName) })
 }
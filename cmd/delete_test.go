@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -697,37 +698,52 @@ func TestConfirmDeletion(t *testing.T) {
 	}
 }
 
-// TestSaveConfigPermissions tests that config file is saved with 0600 permissions
-func TestSaveConfigPermissions(t *testing.T) {
+// TestDeleteProjectFromConfigKeepsFile tests that only the project's lines are removed and the file mode is kept
+func TestDeleteProjectFromConfigKeepsFile(t *testing.T) {
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yml")
 
-	config := Config{
-		Version: "1.0.0",
-		Projects: []Project{
-			{
-				Name:   "test",
-				Repo:   "git@github.com:user/test.git",
-				Branch: "main",
-			},
-		},
+	configData := `# ja: 設定ファイル
+# en: Configuration file
+version: 1.0.0
+projects:
+  - name: keep
+    repo: git@github.com:user/keep.git # comment
+    branch: main
+    custom_key: value
+  - name: test
+    repo: git@github.com:user/test.git
+    branch: main
+`
+	if err := os.WriteFile(configPath, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.Chmod(configPath, 0644); err != nil {
+		t.Fatalf("Failed to change file mode: %v", err)
 	}
 
-	err := saveConfig(configPath, &config)
-	if err != nil {
+	if err := deleteProjectFromConfig(configPath, "test"); err != nil {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	expected := configData[:strings.Index(configData, "  - name: test")]
+	if string(data) != expected {
+		t.Errorf("Expected only the project to be removed.\nExpected:\n%s\nGot:\n%s", expected, data)
+	}
+
 	info, err := os.Stat(configPath)
 	if err != nil {
 		t.Fatalf("Failed to stat config file: %v", err)
 	}
 
-	// Check file permissions
-	mode := info.Mode().Perm()
-	expected := os.FileMode(0600)
-	if mode != expected {
-		t.Errorf("Expected file permissions %o, got %o", expected, mode)
+	// Check file permissions are kept
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0644 {
+		t.Errorf("Expected file permissions to stay 644, got %o", info.Mode().Perm())
 	}
 }
 
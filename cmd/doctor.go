@@ -0,0 +1,578 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var doctorFix bool
+
+// ja: 診断結果の重要度
+// en: Finding severities
+const (
+	severityOK      = "ok"
+	severityInfo    = "info"
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+// ja: doctorFinding は doctor コマンドの診断結果 1 件です
+// en: doctorFinding is a single finding of the doctor command
+type doctorFinding struct {
+	Check      string `json:"check" yaml:"check"`
+	Severity   string `json:"severity" yaml:"severity"`
+	Message    string `json:"message" yaml:"message"`
+	Suggestion string `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+	Fixable    bool   `json:"fixable" yaml:"fixable"`
+	Fixed      bool   `json:"fixed" yaml:"fixed"`
+	FixError   string `json:"fix_error,omitempty" yaml:"fix_error,omitempty"`
+
+	// ja: fix は --fix で実行される安全な修復処理です（nil の場合は自動修復不可）
+	// en: fix is the safe repair run by --fix (nil when it cannot be repaired automatically)
+	fix func() error
+}
+
+// ja: doctorResult は doctor コマンドの構造化出力です
+// en: doctorResult is the structured output of the doctor command
+type doctorResult struct {
+	Findings []doctorFinding `json:"findings" yaml:"findings"`
+	Errors   int             `json:"errors" yaml:"errors"`
+	Warnings int             `json:"warnings" yaml:"warnings"`
+}
+
+// ja: doctorCmd は doctor コマンドを表します
+// en: doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: i18n.T("doctor.short"),
+	Long:  i18n.T("doctor.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runDoctor(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, i18n.T("doctor.flag.fix"))
+}
+
+func runDoctor() (err error) {
+	var findings []doctorFinding
+
+	// ja: 設定ファイルを読み込んで検証（git が必要かどうかも設定で決まる）
+	// en: Load and validate the config file (which also decides whether git is needed)
+	store := newConfigStore()
+	config, configFindings := checkConfigFile(store)
+	findings = append(findings, checkGit(gitRunner, config))
+	findings = append(findings, configFindings...)
+
+	if isLegacyConfigPath(store.Path()) {
+		findings = append(findings, checkLegacyConfig(store.Path()))
+	}
+
+	backupFindings, err := checkBackupDirs(config)
+	if err != nil {
+		return err
+	}
+	findings = append(findings, backupFindings...)
+
+	// ja: 安全に修復できるものを修復（--dry-run の場合は見つかった問題を表示するだけ）
+	// en: Repair what can be repaired safely (with --dry-run the findings are only shown)
+	if doctorFix && !dryRun {
+		journal := startOperation("doctor --fix", "")
+		defer func() { journal.finish(err) }()
+
+		for i := range findings {
+			if findings[i].fix == nil {
+				continue
+			}
+			if err := findings[i].fix(); err != nil {
+				findings[i].FixError = err.Error()
+				journal.note(fmt.Sprintf("%s: %s (%s)", findings[i].Check, findings[i].Message, err))
+				continue
+			}
+			findings[i].Fixed = true
+			journal.note(fmt.Sprintf("%s: %s", findings[i].Check, findings[i].Message))
+		}
+	}
+
+	result := doctorResult{Findings: findings}
+	fixable := 0
+	for _, finding := range findings {
+		if finding.Fixed {
+			continue
+		}
+		switch finding.Severity {
+		case severityError:
+			result.Errors++
+		case severityWarning:
+			result.Warnings++
+		}
+		if finding.Fixable {
+			fixable++
+		}
+	}
+
+	if isStructuredOutput() {
+		if err := writeResult(result); err != nil {
+			return err
+		}
+	} else {
+		printDoctorFindings(result, fixable)
+	}
+
+	if result.Errors > 0 {
+		return fmt.Errorf(i18n.T("doctor.problemsFound"), result.Errors)
+	}
+	return nil
+}
+
+// ja: checkGit は git が利用可能かとそのバージョンを確認します
+// en: checkGit checks that git is available and reports its version
+func checkGit(runner commandRunner, config *Config) doctorFinding {
+	// ja: git の VCS を使うプロジェクトがなければ git は不要（設定を読めない場合は念のため確認する）
+	// en: git is not needed unless a project uses the git VCS (it is still checked when the config cannot be read)
+	if config != nil && !usesGit(config) {
+		return doctorFinding{
+			Check:    "git",
+			Severity: severityInfo,
+			Message:  i18n.T("doctor.gitNotNeeded"),
+		}
+	}
+
+	out, err := gitOutput(runner, "", "--version")
+	if err != nil {
+		return doctorFinding{
+			Check:      "git",
+			Severity:   severityError,
+			Message:    fmt.Sprintf(i18n.T("doctor.gitMissing"), err),
+			Suggestion: i18n.T("doctor.gitMissingFix"),
+		}
+	}
+
+	return doctorFinding{
+		Check:    "git",
+		Severity: severityOK,
+		Message:  fmt.Sprintf(i18n.T("doctor.gitOK"), strings.TrimPrefix(out, "git version ")),
+	}
+}
+
+// ja: usesGit は git の VCS を使うプロジェクトがあるかを返します
+// en: usesGit reports whether any project uses the git VCS
+func usesGit(config *Config) bool {
+	for i := range config.Projects {
+		if v, err := newVCS(&config.Projects[i], nil); err == nil {
+			if _, ok := v.(*gitVCS); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ja: checkConfigFile は設定ファイルを読み込み、validateConfig と同じ規則で検証します
+// ja: 読み込めなかった場合は nil の設定を返します
+// en: checkConfigFile loads the config file and validates it with the same rules as validateConfig
+// en: Returns a nil config when it cannot be loaded
+func checkConfigFile(store *configStore) (*Config, []doctorFinding) {
+	config, err := store.Load()
+	var notFound *configNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, []doctorFinding{{
+			Check:      "config",
+			Severity:   severityError,
+			Message:    fmt.Sprintf(i18n.T("doctor.configMissing"), store.Path()),
+			Suggestion: i18n.T("doctor.configMissingFix"),
+		}}
+	}
+	if err != nil {
+		return nil, []doctorFinding{{
+			Check:      "config",
+			Severity:   severityError,
+			Message:    err.Error(),
+			Suggestion: i18n.T("doctor.configInvalidFix"),
+		}}
+	}
+
+	var findings []doctorFinding
+
+	// ja: 古いスキーマのままの場合は移行を勧める
+	// en: Suggest a migration when the file still uses an older schema
+	if steps, err := pendingMigrations(config.Version); err == nil && len(steps) > 0 {
+		findings = append(findings, doctorFinding{
+			Check:      "config",
+			Severity:   severityWarning,
+			Message:    fmt.Sprintf(i18n.T("doctor.configOutdated"), config.Version, latestConfigVersion()),
+			Suggestion: i18n.T("doctor.configOutdatedFix"),
+		})
+	}
+
+	errs := validateConfigErrors(config)
+	if len(errs) == 0 {
+		return config, append(findings, doctorFinding{
+			Check:    "config",
+			Severity: severityOK,
+			Message:  fmt.Sprintf(i18n.T("doctor.configOK"), store.Path(), len(config.Projects)),
+		})
+	}
+
+	for _, err := range errs {
+		findings = append(findings, doctorFinding{
+			Check:      "config",
+			Severity:   severityError,
+			Message:    err.Error(),
+			Suggestion: i18n.T("doctor.configInvalidFix"),
+		})
+	}
+	return config, findings
+}
+
+// ja: checkLegacyConfig はレガシーパスの設定ファイルについて報告します
+// ja: パスを明示的に指定していない場合に限り、新しいパスへの移動を自動修復として提供します
+// en: checkLegacyConfig reports a config file at the legacy path
+// en: Moving it to the new path is offered as a repair only when the path was not given explicitly
+func checkLegacyConfig(legacyPath string) doctorFinding {
+	finding := doctorFinding{
+		Check:    "legacy_config",
+		Severity: severityWarning,
+		Message:  fmt.Sprintf(i18n.T("doctor.legacyConfig"), legacyPath),
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return finding
+	}
+	newPath := filepath.Join(homeDir, ".config", "toske", "config.yml")
+	finding.Suggestion = fmt.Sprintf(i18n.T("doctor.legacyConfigFix"), newPath)
+
+	_, err = os.Stat(newPath)
+	if cfgFile != "" || os.Getenv("TOSKE_CONFIG") != "" || !os.IsNotExist(err) {
+		return finding
+	}
+
+	finding.Fixable = true
+	finding.fix = func() error {
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return err
+		}
+		return os.Rename(legacyPath, newPath)
+	}
+	return finding
+}
+
+// ja: checkBackupDirs はバックアップディレクトリの書き込み権限、孤立したディレクトリ、
+// ja: メタデータの整合性、秘密情報を含むアーカイブのパーミッションを確認します
+// en: checkBackupDirs checks backup directories for write access, orphans,
+// en: metadata consistency and the permissions of archives holding secrets
+func checkBackupDirs(config *Config) ([]doctorFinding, error) {
+	rootDir, err := toske.DefaultBackupRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	if !dirExists(rootDir) {
+		return []doctorFinding{{
+			Check:    "backup_dir",
+			Severity: severityOK,
+			Message:  fmt.Sprintf(i18n.T("doctor.noBackupDir"), rootDir),
+		}}, nil
+	}
+
+	if err := checkWritable(rootDir); err != nil {
+		return []doctorFinding{{
+			Check:      "backup_dir",
+			Severity:   severityError,
+			Message:    fmt.Sprintf(i18n.T("doctor.notWritable"), rootDir, err),
+			Suggestion: fmt.Sprintf(i18n.T("doctor.notWritableFix"), rootDir),
+		}}, nil
+	}
+
+	findings := []doctorFinding{{
+		Check:    "backup_dir",
+		Severity: severityOK,
+		Message:  fmt.Sprintf(i18n.T("doctor.backupDirOK"), rootDir),
+	}}
+
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		backupDir := filepath.Join(rootDir, entry.Name())
+
+		// ja: 設定を読み込めなかった場合、孤立の判定はできない
+		// en: Orphans cannot be detected when the config could not be loaded
+		if config != nil && findProjectIndex(config.Projects, entry.Name()) < 0 {
+			findings = append(findings, doctorFinding{
+				Check:      "orphan_backup_dir",
+				Severity:   severityWarning,
+				Message:    fmt.Sprintf(i18n.T("doctor.orphanBackupDir"), entry.Name()),
+				Suggestion: fmt.Sprintf(i18n.T("doctor.orphanBackupDirFix"), backupDir),
+			})
+		}
+
+		if err := checkWritable(backupDir); err != nil {
+			findings = append(findings, doctorFinding{
+				Check:      "backup_dir",
+				Severity:   severityError,
+				Message:    fmt.Sprintf(i18n.T("doctor.notWritable"), backupDir, err),
+				Suggestion: fmt.Sprintf(i18n.T("doctor.notWritableFix"), backupDir),
+			})
+		}
+
+		findings = append(findings, checkBackupMetadata(backupDir)...)
+		findings = append(findings, checkPartialArchives(backupDir)...)
+	}
+
+	return findings, nil
+}
+
+// ja: checkBackupMetadata はメタデータの記録とアーカイブファイルを突き合わせます
+// en: checkBackupMetadata cross-checks metadata records against the archive files
+func checkBackupMetadata(backupDir string) []doctorFinding {
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		return []doctorFinding{{
+			Check:      "metadata",
+			Severity:   severityError,
+			Message:    fmt.Sprintf(i18n.T("doctor.metadataError"), backupDir, err),
+			Suggestion: fmt.Sprintf(i18n.T("doctor.metadataErrorFix"), filepath.Join(backupDir, "backups.yaml")),
+		}}
+	}
+
+	var findings []doctorFinding
+	var missing []string
+	var exposed []string
+	for _, record := range metadata.Backups {
+		info, err := os.Stat(filepath.Join(backupDir, record.Filename))
+		if os.IsNotExist(err) {
+			missing = append(missing, record.Filename)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		// ja: Windows ではパーミッションビットが意味を持たない
+		// en: Permission bits are meaningless on Windows
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 && recordHoldsSecrets(record) {
+			exposed = append(exposed, record.Filename)
+		}
+	}
+
+	if len(missing) > 0 {
+		findings = append(findings, doctorFinding{
+			Check:      "missing_archive",
+			Severity:   severityWarning,
+			Message:    fmt.Sprintf(i18n.T("doctor.missingArchives"), metadata.Project, strings.Join(missing, ", ")),
+			Suggestion: i18n.T("doctor.missingArchivesFix"),
+			Fixable:    true,
+			fix: func() error {
+				return dropBackupRecords(backupDir, missing)
+			},
+		})
+	}
+
+	if len(exposed) > 0 {
+		findings = append(findings, doctorFinding{
+			Check:      "archive_permissions",
+			Severity:   severityWarning,
+			Message:    fmt.Sprintf(i18n.T("doctor.exposedArchives"), metadata.Project, strings.Join(exposed, ", ")),
+			Suggestion: i18n.T("doctor.exposedArchivesFix"),
+			Fixable:    true,
+			fix: func() error {
+				for _, filename := range exposed {
+					if err := os.Chmod(filepath.Join(backupDir, filename), 0600); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+
+	return findings
+}
+
+// ja: partialArchiveStaleAfter は作成途中のアーカイブを放棄されたものとみなすまでの、最終更新からの時間です
+// ja: 書き込み中のアーカイブは更新され続けるため、これより新しいものは別のバックアップやエクスポートが使っている可能性があります
+// en: partialArchiveStaleAfter is how long after its last write an unfinished archive is taken to be abandoned
+// en: An archive being written keeps getting modified, so anything newer may belong to a backup or export still running
+const partialArchiveStaleAfter = time.Hour
+
+// ja: checkPartialArchives は強制終了されたバックアップが残した作成途中のアーカイブを探します
+// ja: --fix で削除するのは partialArchiveStaleAfter より前から更新されていないものだけです
+// en: checkPartialArchives looks for unfinished archives left behind by a backup that was killed
+// en: --fix only removes those not modified for longer than partialArchiveStaleAfter
+func checkPartialArchives(backupDir string) []doctorFinding {
+	partials, err := filepath.Glob(filepath.Join(backupDir, "*"+toske.PartialSuffix))
+	if err != nil || len(partials) == 0 {
+		return nil
+	}
+
+	var stale, recent []string
+	for _, partial := range partials {
+		info, err := os.Stat(partial)
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > partialArchiveStaleAfter {
+			stale = append(stale, partial)
+		} else {
+			recent = append(recent, partial)
+		}
+	}
+
+	var findings []doctorFinding
+	if len(stale) > 0 {
+		findings = append(findings, doctorFinding{
+			Check:      "partial_archive",
+			Severity:   severityWarning,
+			Message:    fmt.Sprintf(i18n.T("doctor.partialArchives"), backupDir, joinBaseNames(stale)),
+			Suggestion: i18n.T("doctor.partialArchivesFix"),
+			Fixable:    true,
+			fix: func() error {
+				for _, partial := range stale {
+					// ja: 診断後に書き込みが再開されていないか、削除の直前にもう一度確かめる
+					// en: Check again right before removing, in case writing resumed after the diagnosis
+					info, err := os.Stat(partial)
+					if os.IsNotExist(err) {
+						continue
+					}
+					if err != nil {
+						return err
+					}
+					if time.Since(info.ModTime()) <= partialArchiveStaleAfter {
+						continue
+					}
+					if err := os.Remove(partial); err != nil && !os.IsNotExist(err) {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	if len(recent) > 0 {
+		findings = append(findings, doctorFinding{
+			Check:      "partial_archive",
+			Severity:   severityWarning,
+			Message:    fmt.Sprintf(i18n.T("doctor.partialArchivesInProgress"), backupDir, joinBaseNames(recent)),
+			Suggestion: fmt.Sprintf(i18n.T("doctor.partialArchivesInProgressFix"), partialArchiveStaleAfter),
+		})
+	}
+	return findings
+}
+
+// ja: joinBaseNames はパスのファイル名だけをカンマ区切りでつなげます
+// en: joinBaseNames joins the file names of paths with commas
+func joinBaseNames(paths []string) string {
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		names[i] = filepath.Base(p)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ja: recordHoldsSecrets はバックアップに秘密情報やデータベースのダンプが含まれるかを返します
+// en: recordHoldsSecrets reports whether a backup contains secrets or database dumps
+func recordHoldsSecrets(record toske.BackupRecord) bool {
+	if len(record.Dumps) > 0 {
+		return true
+	}
+	for _, file := range record.Files {
+		if looksLikeSecret(file) || looksLikeDatabase(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// ja: dropBackupRecords は指定したアーカイブの記録をメタデータから削除します
+// en: dropBackupRecords removes the records of the given archives from the metadata
+func dropBackupRecords(backupDir string, filenames []string) error {
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		return err
+	}
+
+	drop := make(map[string]bool, len(filenames))
+	for _, filename := range filenames {
+		drop[filename] = true
+	}
+
+	kept := metadata.Backups[:0]
+	for _, record := range metadata.Backups {
+		if !drop[record.Filename] {
+			kept = append(kept, record)
+		}
+	}
+	metadata.Backups = kept
+
+	return toske.SaveMetadata(backupDir, metadata)
+}
+
+// ja: checkWritable はディレクトリに一時ファイルを作成できるかを確認します
+// en: checkWritable checks that a temp file can be created in the directory
+func checkWritable(dir string) error {
+	tmpFile, err := os.CreateTemp(dir, ".toske-doctor-*")
+	if err != nil {
+		return err
+	}
+	tmpFile.Close()
+	return os.Remove(tmpFile.Name())
+}
+
+// ja: printDoctorFindings は診断結果を人間向けに表示します
+// en: printDoctorFindings prints the findings for humans
+func printDoctorFindings(result doctorResult, fixable int) {
+	for _, finding := range result.Findings {
+		switch finding.Severity {
+		case severityOK:
+			fmt.Printf("✓ %s\n", finding.Message)
+			continue
+		case severityInfo:
+			fmt.Printf("- %s\n", finding.Message)
+			continue
+		case severityWarning:
+			fmt.Printf("! %s\n", finding.Message)
+		default:
+			fmt.Printf("✗ %s\n", finding.Message)
+		}
+
+		switch {
+		case finding.Fixed:
+			fmt.Printf("    %s\n", i18n.T("doctor.fixed"))
+		case finding.FixError != "":
+			fmt.Printf("    "+i18n.T("doctor.fixFailed")+"\n", finding.FixError)
+		case finding.Suggestion != "":
+			fmt.Printf("    → %s\n", finding.Suggestion)
+		}
+	}
+
+	fmt.Println()
+	if result.Errors == 0 && result.Warnings == 0 {
+		fmt.Println(i18n.T("doctor.healthy"))
+		return
+	}
+	fmt.Printf(i18n.T("doctor.summary")+"\n", result.Errors, result.Warnings)
+	if fixable > 0 && !doctorFix {
+		fmt.Printf(i18n.T("doctor.fixHint")+"\n", fixable)
+	}
+}
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+// setupDoctorTest prepares a configured project whose metadata refers to a missing archive
+// and whose archive holding .env is world-readable, plus an orphan backup dir.
+func setupDoctorTest(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	if err := createTestBackup(tempDir, "alpha", []testFile{{name: ".env", content: "SECRET=value"}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+	if err := createTestBackup(tempDir, "ghost", []testFile{{name: "notes.txt", content: "old"}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+
+	backupDir := filepath.Join(tempDir, ".config", "toske", "backups", "alpha")
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(backupDir, metadata.Backups[0].Filename), 0644); err != nil {
+		t.Fatalf("Failed to change archive mode: %v", err)
+	}
+	metadata.Backups = append(metadata.Backups, toske.BackupRecord{
+		Filename:  "backup_20200101_000000.000000.tar.gz",
+		Timestamp: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Files:     []string{".env"},
+	})
+	if err := toske.SaveMetadata(backupDir, metadata); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+
+	configData := `version: 1.1.0
+projects:
+  - name: alpha
+    repo: git@github.com:user/alpha.git
+    branch: main
+    backup_paths:
+      - .env
+`
+	t.Cleanup(setupTestConfig(t, configData))
+
+	originalRunner := gitRunner
+	gitRunner = &fakeGitRunner{responses: map[string]string{"--version": "git version 2.43.0"}}
+	t.Cleanup(func() { gitRunner = originalRunner })
+
+	originalFix := doctorFix
+	t.Cleanup(func() { doctorFix = originalFix })
+	doctorFix = false
+
+	return backupDir
+}
+
+func findingsByCheck(findings []doctorFinding) map[string]doctorFinding {
+	byCheck := make(map[string]doctorFinding)
+	for _, finding := range findings {
+		if finding.Severity != severityOK {
+			byCheck[finding.Check] = finding
+		}
+	}
+	return byCheck
+}
+
+func TestRunDoctor(t *testing.T) {
+	setupDoctorTest(t)
+
+	output, err := captureStdout(t, runDoctor)
+	if err != nil {
+		t.Fatalf("Warnings alone should not fail, got: %v", err)
+	}
+
+	expectedStrings := []string{
+		"✓ git is available (version 2.43.0)",
+		"✓ Configuration file is valid",
+		"! Backups exist for project 'ghost'",
+		"! Metadata of 'alpha' refers to missing archives: backup_20200101_000000.000000.tar.gz",
+		"toske doctor --fix",
+	}
+	if runtime.GOOS != "windows" {
+		expectedStrings = append(expectedStrings, "! Archives of 'alpha' contain secrets")
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunDoctorFix(t *testing.T) {
+	backupDir := setupDoctorTest(t)
+	setOutputFormat(t, outputJSON)
+	doctorFix = true
+
+	output, err := captureStdout(t, runDoctor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var result doctorResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+
+	byCheck := findingsByCheck(result.Findings)
+	if !byCheck["missing_archive"].Fixed {
+		t.Errorf("Expected missing archive records to be fixed, got: %+v", byCheck["missing_archive"])
+	}
+	if byCheck["orphan_backup_dir"].Fixable || byCheck["orphan_backup_dir"].Fixed {
+		t.Error("Orphan backup dirs must not be repaired automatically")
+	}
+	if result.Warnings != 1 {
+		t.Errorf("Expected only the orphan warning to remain, got %d", result.Warnings)
+	}
+
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if len(metadata.Backups) != 1 {
+		t.Errorf("Expected the missing record to be dropped, got %d records", len(metadata.Backups))
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(backupDir, metadata.Backups[0].Filename))
+		if err != nil {
+			t.Fatalf("Failed to stat archive: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected archive mode 0600, got %o", info.Mode().Perm())
+		}
+	}
+
+	// ja: ゴーストディレクトリは削除されないこと
+	// en: The orphan directory must not be removed
+	if !dirExists(filepath.Join(filepath.Dir(backupDir), "ghost")) {
+		t.Error("Expected orphan backup dir to be kept")
+	}
+}
+
+func TestRunDoctorErrors(t *testing.T) {
+	t.Run("git missing and config missing", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("HOME", tempDir)
+		t.Setenv("USERPROFILE", tempDir) // Windows support
+
+		originalCfgFile := cfgFile
+		cfgFile = filepath.Join(tempDir, "missing.yml")
+		defer func() { cfgFile = originalCfgFile }()
+
+		originalRunner := gitRunner
+		gitRunner = &fakeGitRunner{responses: map[string]string{}}
+		defer func() { gitRunner = originalRunner }()
+
+		output, err := captureStdout(t, runDoctor)
+		if err == nil {
+			t.Fatal("Expected error but got nil")
+		}
+		if !strings.Contains(err.Error(), "2 problem(s)") {
+			t.Errorf("Expected 2 problems, got: %v", err)
+		}
+		for _, expected := range []string{"✗ git is not available", "✗ Configuration file does not exist", "toske init"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		tempDir := t.TempDir()
+		t.Setenv("HOME", tempDir)
+		t.Setenv("USERPROFILE", tempDir) // Windows support
+		defer setupTestConfig(t, "version: 1.0.0\nprojects:\n  - name: broken\n")()
+
+		originalRunner := gitRunner
+		gitRunner = &fakeGitRunner{responses: map[string]string{"--version": "git version 2.43.0"}}
+		defer func() { gitRunner = originalRunner }()
+
+		output, err := captureStdout(t, runDoctor)
+		if err == nil {
+			t.Fatal("Expected error but got nil")
+		}
+		if !strings.Contains(output, "toske edit") {
+			t.Errorf("Expected a suggestion to edit the config, got:\n%s", output)
+		}
+	})
+}
+
+func TestCheckLegacyConfigFix(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	t.Setenv("TOSKE_CONFIG", "")
+
+	originalCfgFile := cfgFile
+	cfgFile = ""
+	defer func() { cfgFile = originalCfgFile }()
+
+	legacyPath := filepath.Join(tempDir, ".toske.yaml")
+	if err := os.WriteFile(legacyPath, []byte("version: 1.0.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create legacy config: %v", err)
+	}
+
+	finding := checkLegacyConfig(legacyPath)
+	if !finding.Fixable {
+		t.Fatal("Expected legacy config to be fixable")
+	}
+	if err := finding.fix(); err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, ".config", "toske", "config.yml")); err != nil {
+		t.Errorf("Expected config to be moved to the new path: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("Expected legacy config to be gone")
+	}
+}
+
+func TestCheckGit(t *testing.T) {
+	gitConfig := &Config{Projects: []Project{{Name: "app"}, {Name: "notes", VCS: vcsNone}}}
+	noneConfig := &Config{Projects: []Project{{Name: "notes", VCS: vcsNone}}}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		runner   *fakeGitRunner
+		severity string
+		calls    int
+	}{
+		{name: "git project", config: gitConfig, runner: &fakeGitRunner{responses: map[string]string{"--version": "git version 2.43.0"}}, severity: severityOK, calls: 1},
+		{name: "git project without git", config: gitConfig, runner: &fakeGitRunner{}, severity: severityError, calls: 1},
+		{name: "unreadable config", runner: &fakeGitRunner{}, severity: severityError, calls: 1},
+		{name: "no git project", config: noneConfig, runner: &fakeGitRunner{}, severity: severityInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := checkGit(tt.runner, tt.config)
+			if finding.Severity != tt.severity {
+				t.Errorf("Expected severity %q, got %+v", tt.severity, finding)
+			}
+			if len(tt.runner.calls) != tt.calls {
+				t.Errorf("Expected %d git calls, got %v", tt.calls, tt.runner.calls)
+			}
+		})
+	}
+}
+
+func TestCheckPartialArchivesFix(t *testing.T) {
+	backupDir := t.TempDir()
+	stale := filepath.Join(backupDir, "backup_20250101_000000.000000.tar.gz"+toske.PartialSuffix)
+	recent := filepath.Join(backupDir, "backup_20250102_000000.000000.tar.gz"+toske.PartialSuffix)
+	for _, partial := range []string{stale, recent} {
+		if err := os.WriteFile(partial, []byte("half"), 0600); err != nil {
+			t.Fatalf("Failed to create partial archive: %v", err)
+		}
+	}
+	// ja: 中断されたバックアップのものとみなせるよう、片方だけ古くする
+	// en: Age only one of them so that it counts as left by an interrupted backup
+	past := time.Now().Add(-2 * partialArchiveStaleAfter)
+	if err := os.Chtimes(stale, past, past); err != nil {
+		t.Fatalf("Failed to change file times: %v", err)
+	}
+
+	findings := checkPartialArchives(backupDir)
+	if len(findings) != 2 {
+		t.Fatalf("Expected a finding for each kind of partial archive, got %+v", findings)
+	}
+	if !findings[0].Fixable || !strings.Contains(findings[0].Message, filepath.Base(stale)) || strings.Contains(findings[0].Message, filepath.Base(recent)) {
+		t.Errorf("Expected only the stale partial archive to be fixable, got %+v", findings[0])
+	}
+	if findings[1].Fixable || !strings.Contains(findings[1].Message, filepath.Base(recent)) {
+		t.Errorf("Expected the recent partial archive to be reported without a fix, got %+v", findings[1])
+	}
+
+	if err := findings[0].fix(); err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("Expected the stale partial archive to be removed")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("Expected the recent partial archive to be kept, got %v", err)
+	}
+	if findings := checkPartialArchives(backupDir); len(findings) != 1 || findings[0].Fixable {
+		t.Errorf("Expected only the recent partial archive after the fix, got %+v", findings)
+	}
+}
+
+func TestLooksLikeSecret(t *testing.T) {
+	tests := []struct {
+		path     string
+		secret   bool
+		database bool
+	}{
+		{path: ".env", secret: true},
+		{path: "config/.env.local", secret: true},
+		{path: ".env.example"},
+		{path: "certs/server.key", secret: true},
+		{path: "config/master.key", secret: true},
+		{path: "id_ed25519", secret: true},
+		{path: "db/development.sqlite3", database: true},
+		{path: "data.db", database: true},
+		{path: "README.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := looksLikeSecret(tt.path); got != tt.secret {
+				t.Errorf("looksLikeSecret(%q) = %v, want %v", tt.path, got, tt.secret)
+			}
+			if got := looksLikeDatabase(tt.path); got != tt.database {
+				t.Errorf("looksLikeDatabase(%q) = %v, want %v", tt.path, got, tt.database)
+			}
+		})
+	}
+}
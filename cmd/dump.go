@@ -0,0 +1,15 @@
+package cmd
+
+import "github.com/yk-lab/toske/toske"
+
+// ja: 外部コマンドの実行には toske パッケージの Command / Runner をそのまま使います
+// en: External commands use the Command / Runner of the toske package as is
+type (
+	runCommand    = toske.Command
+	commandRunner = toske.Runner
+	execRunner    = toske.ExecRunner
+)
+
+// ja: dumpRunner はダンププロバイダーが使用するコマンドランナーです
+// en: dumpRunner is the command runner used by dump providers
+var dumpRunner commandRunner = execRunner{}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records command invocations and plays back canned output.
+type fakeRunner struct {
+	calls  []runCommand
+	stdins []string
+	output string
+	err    error
+}
+
+func (f *fakeRunner) Run(c runCommand) error {
+	f.calls = append(f.calls, c)
+	if c.Stdin != nil {
+		data, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return err
+		}
+		f.stdins = append(f.stdins, string(data))
+	}
+	if c.Stdout != nil && f.output != "" {
+		if _, err := io.WriteString(c.Stdout, f.output); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+// TestBackupAndRestoreWithDumps tests that dumps are stored in the archive and fed back on restore
+func TestBackupAndRestoreWithDumps(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	if err := os.WriteFile(filepath.Join(workDir, ".env"), []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	configData := `version: 1.0.0
+projects:
+  - name: dump-project
+    repo: git@github.com:user/dump.git
+    branch: main
+    backup_paths:
+      - .env
+    dumps:
+      - name: app.sql
+        provider: postgres
+        database: app_dev
+`
+	defer setupTestConfig(t, configData)()
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	runner := &fakeRunner{output: "CREATE TABLE users();\n"}
+	originalRunner := dumpRunner
+	dumpRunner = runner
+	defer func() { dumpRunner = originalRunner }()
+
+	originalProjectName := projectName
+	projectName = "dump-project"
+	defer func() { projectName = originalProjectName }()
+
+	if _, err := captureStdout(t, runBackup); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	originalRestoreProjectName := restoreProjectName
+	originalForce := forceRestore
+	originalIndex := backupIndex
+	restoreProjectName = "dump-project"
+	forceRestore = true
+	backupIndex = 1
+	defer func() {
+		restoreProjectName = originalRestoreProjectName
+		forceRestore = originalForce
+		backupIndex = originalIndex
+	}()
+
+	output, err := captureStdout(t, runRestore)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if len(runner.stdins) != 1 || runner.stdins[0] != "CREATE TABLE users();\n" {
+		t.Errorf("Expected dump to be fed back on restore, got %q", runner.stdins)
+	}
+	if !strings.Contains(output, "app.sql") {
+		t.Errorf("Expected output to mention the dump, got: %s", output)
+	}
+
+	// ja: ダンプはファイルとして展開されないこと
+	// en: Dumps must not be extracted as files
+	if _, err := os.Stat(filepath.Join(workDir, ".toske")); !os.IsNotExist(err) {
+		t.Error("Expected .toske/ entries not to be extracted into the working directory")
+	}
+}
+
+func TestBackupDumpFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	configData := `version: 1.0.0
+projects:
+  - name: failing-dump
+    repo: git@github.com:user/dump.git
+    branch: main
+    dumps:
+      - name: app.sql
+        provider: mysql
+        database: app
+`
+	defer setupTestConfig(t, configData)()
+
+	originalRunner := dumpRunner
+	dumpRunner = &fakeRunner{err: io.ErrUnexpectedEOF}
+	defer func() { dumpRunner = originalRunner }()
+
+	originalProjectName := projectName
+	projectName = "failing-dump"
+	defer func() { projectName = originalProjectName }()
+
+	_, err := captureStdout(t, runBackup)
+	if err == nil {
+		t.Fatal("Expected error when the dump command fails")
+	}
+	if !strings.Contains(err.Error(), "app.sql") {
+		t.Errorf("Expected error to mention the dump name, got: %v", err)
+	}
+
+	// ja: 失敗したアーカイブは残さないこと
+	// en: The failed archive must not be left behind
+	backupDir := filepath.Join(tempDir, ".config", "toske", "backups", "failing-dump")
+	matches, _ := filepath.Glob(filepath.Join(backupDir, "backup_*.tar.gz"))
+	if len(matches) != 0 {
+		t.Errorf("Expected partial archive to be removed, found %v", matches)
+	}
+}
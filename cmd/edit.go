@@ -1,21 +1,36 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
 	"github.com/yk-lab/toske/i18n"
 )
 
+// ja: 編集後の設定が不正だった場合の選択肢
+// en: Choices offered when the edited config is invalid
+const (
+	editActionReopen  = "e"
+	editActionDiscard = "d"
+	editActionSave    = "s"
+)
+
 // ja: editCmd は edit コマンドを表します
 // en: editCmd represents the edit command
 var editCmd = &cobra.Command{
 	Use:   "edit",
 	Short: i18n.T("edit.short"),
 	Long:  i18n.T("edit.long"),
+	// ja: エディタで対話的に編集するため、事前に計画を示せない
+	// en: Editing happens interactively in an editor, so there is no plan to show up front
+	Annotations: map[string]string{dryRunAnnotation: dryRunUnsupported},
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runEdit(); err != nil {
 			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
@@ -28,20 +43,15 @@ func init() {
 	rootCmd.AddCommand(editCmd)
 }
 
-func runEdit() error {
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
-	}
+func runEdit() (err error) {
+	journal := startOperation("edit", "")
+	defer func() { journal.finish(err) }()
 
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		msg := fmt.Sprintf(i18n.T("edit.noConfig"), configPath)
-		return fmt.Errorf("%s", msg)
+	store := newConfigStore()
+	if err := store.ensureExists(); err != nil {
+		return err
 	}
+	configPath := store.Path()
 
 	// ja: エディタを決定
 	// en: Determine editor
@@ -50,14 +60,102 @@ func runEdit() error {
 		return fmt.Errorf("%s", i18n.T("edit.noEditor"))
 	}
 
-	// ja: エディタで設定ファイルを開く
-	// en: Open config file in editor
+	file, err := configfile.Load(configPath)
+	if err != nil {
+		return fmt.Errorf(i18n.T("edit.readError"), err)
+	}
+	original := file.Bytes()
+
+	// ja: visudo と同様に一時コピーを編集し、検証を通過した場合のみ元のファイルを置き換える
+	// en: Like visudo, edit a temp copy and only replace the real file once it validates
+	tmpPath, err := createEditCopy(configPath, original)
+	if err != nil {
+		return fmt.Errorf(i18n.T("edit.tempError"), err)
+	}
+	defer os.RemoveAll(filepath.Dir(tmpPath))
+
+	reader := bufio.NewReader(os.Stdin)
+	var edited []byte
+	for {
+		if err := openEditor(editor, tmpPath); err != nil {
+			return err
+		}
+
+		if edited, err = os.ReadFile(tmpPath); err != nil {
+			return fmt.Errorf(i18n.T("edit.readError"), err)
+		}
+		if bytes.Equal(edited, original) {
+			journal.cancel()
+			fmt.Println(i18n.T("edit.noChanges"))
+			return nil
+		}
+
+		errs := checkEditedConfig(store, edited)
+		if len(errs) == 0 {
+			break
+		}
+
+		fmt.Println(i18n.T("edit.invalid"))
+		for _, err := range errs {
+			fmt.Printf("  ✗ %v\n", err)
+		}
+
+		action, err := promptEditAction(reader)
+		if err != nil {
+			return err
+		}
+		if action == editActionDiscard {
+			journal.cancel()
+			fmt.Println(i18n.T("edit.discarded"))
+			return nil
+		}
+		if action == editActionSave {
+			break
+		}
+	}
+
+	if err := configfile.WriteAtomic(configPath, edited, file.Mode()); err != nil {
+		return fmt.Errorf(i18n.T("edit.writeError"), err)
+	}
+	journal.touch(configPath, fileModified)
+
+	fmt.Printf(i18n.T("edit.saved")+"\n", configPath)
+	return nil
+}
+
+// ja: createEditCopy は編集用の一時コピーを、専用の一時ディレクトリに設定ファイルと同じ名前で作成します
+// ja: 設定ファイルのディレクトリに置くと include のパターン（*.yml など）に一致してしまうため、外に置きます
+// ja: エディタのシンタックスハイライトのため拡張子は維持します。後片付けはディレクトリごと削除してください
+// en: createEditCopy creates a temp copy for editing, under the config file's name in a temp directory of its own
+// en: It is kept out of the config directory, where it would match include patterns (such as *.yml)
+// en: The extension is kept for editor syntax highlighting. Clean up by removing the whole directory
+func createEditCopy(configPath string, data []byte) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "toske-edit-*")
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(configPath)
+	if filepath.Ext(name) == "" {
+		name += ".yml"
+	}
+	tmpPath := filepath.Join(tmpDir, name)
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+	return tmpPath, nil
+}
+
+// ja: openEditor はエディタでファイルを開き、終了を待ちます
+// en: openEditor opens a file in the editor and waits for it to exit
+func openEditor(editor, path string) error {
 	fmt.Printf(i18n.T("edit.openingEditor")+"\n", editor)
 
 	// ja: エディタコマンドをパースして引数を分離
 	// en: Parse editor command to separate arguments
 	editorParts := strings.Fields(editor)
-	editorCmd := exec.Command(editorParts[0], append(editorParts[1:], configPath)...)
+	editorCmd := exec.Command(editorParts[0], append(editorParts[1:], path)...)
 	editorCmd.Stdin = os.Stdin
 	editorCmd.Stdout = os.Stdout
 	editorCmd.Stderr = os.Stderr
@@ -66,10 +164,46 @@ func runEdit() error {
 		msg := fmt.Sprintf(i18n.T("edit.editorError"), err)
 		return fmt.Errorf("%s", msg)
 	}
-
 	return nil
 }
 
+// ja: checkEditedConfig は他のコマンドと同じ手順（configStore での読み込みと validateConfig）で編集結果を検証します
+// ja: include は本来の設定ファイルの場所から解決し、include したファイルとの間の名前の重複も検出できるよう、
+// ja: マージした結果を検証します（環境変数による上書きは適用しません）
+// en: checkEditedConfig checks the edited content with the same pipeline as other commands (configStore, then validateConfig)
+// en: Includes are resolved from where the real config file is, and the merged result is checked so duplicate names
+// en: across included files are caught (environment overrides are not applied)
+func checkEditedConfig(store *configStore, data []byte) []error {
+	config, err := store.LoadData(data)
+	if err != nil {
+		return []error{err}
+	}
+
+	return validateConfigErrors(config)
+}
+
+// ja: promptEditAction は再編集・破棄・強制保存のいずれかを選ばせます
+// en: promptEditAction asks whether to re-open, discard or save anyway
+func promptEditAction(reader *bufio.Reader) (string, error) {
+	for {
+		fmt.Print(i18n.T("edit.prompt"))
+
+		response, err := reader.ReadString('\n')
+		if err != nil && response == "" {
+			return "", fmt.Errorf(i18n.T("edit.readInputError"), err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "", editActionReopen:
+			return editActionReopen, nil
+		case editActionDiscard:
+			return editActionDiscard, nil
+		case editActionSave:
+			return editActionSave, nil
+		}
+	}
+}
+
 // ja: getEditor はユーザーの環境に応じたエディタを返します
 // en: getEditor returns the editor based on user's environment
 func getEditor() string {
@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -196,3 +200,166 @@ func TestGetEditorWithFlags(t *testing.T) {
 		})
 	}
 }
+
+// setupEditTest writes the init template as the config and installs a fake editor.
+// Each run of the editor replaces the file it is given with the next entry of edits.
+func setupEditTest(t *testing.T, edits []string, input string) (configPath, editorLog string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: the fake editor is a shell script")
+	}
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	t.Cleanup(setupTestConfig(t, getConfigTemplate()))
+	configPath = cfgFile
+	if err := os.Chmod(configPath, 0644); err != nil {
+		t.Fatalf("Failed to chmod config: %v", err)
+	}
+
+	editsDir := filepath.Join(tempDir, "edits")
+	if err := os.MkdirAll(editsDir, 0755); err != nil {
+		t.Fatalf("Failed to create edits directory: %v", err)
+	}
+	for i, edit := range edits {
+		if err := os.WriteFile(filepath.Join(editsDir, fmt.Sprintf("%d.yml", i+1)), []byte(edit), 0644); err != nil {
+			t.Fatalf("Failed to write edit: %v", err)
+		}
+	}
+
+	// ja: 呼び出し回数を数え、n 回目の内容で対象ファイルを上書きするエディタ
+	// en: An editor that counts its runs and overwrites the target with the n-th edit
+	editorLog = filepath.Join(tempDir, "editor.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$1" >> %[1]q
+n=$(wc -l < %[1]q | tr -d ' ')
+cp %[2]q/$n.yml "$1"
+`, editorLog, editsDir)
+	editor := filepath.Join(tempDir, "editor.sh")
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	stdinPath := filepath.Join(tempDir, "stdin")
+	if err := os.WriteFile(stdinPath, []byte(input), 0644); err != nil {
+		t.Fatalf("Failed to write stdin: %v", err)
+	}
+	stdin, err := os.Open(stdinPath)
+	if err != nil {
+		t.Fatalf("Failed to open stdin: %v", err)
+	}
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	t.Cleanup(func() {
+		os.Stdin = oldStdin
+		stdin.Close()
+	})
+
+	return configPath, editorLog
+}
+
+func TestRunEditValidation(t *testing.T) {
+	valid := strings.Replace(getConfigTemplate(), "branch: main", "branch: develop", 1)
+	invalid := strings.Replace(getConfigTemplate(), "    repo: git@github.com:user/sample-project.git\n", "", 1)
+	broken := "version: 1.0.0\nprojects: [\n"
+
+	tests := []struct {
+		name         string
+		edits        []string
+		input        string
+		expectedRuns int
+		expected     string
+		expectedOut  string
+	}{
+		{name: "valid edit is saved", edits: []string{valid}, expectedRuns: 1, expected: valid, expectedOut: "Configuration saved"},
+		{name: "no changes", edits: []string{getConfigTemplate()}, expectedRuns: 1, expected: getConfigTemplate(), expectedOut: "No changes made"},
+		{name: "invalid edit is discarded", edits: []string{invalid}, input: "d\n", expectedRuns: 1, expected: getConfigTemplate(), expectedOut: "Changes discarded"},
+		{name: "invalid edit is saved anyway", edits: []string{invalid}, input: "s\n", expectedRuns: 1, expected: invalid, expectedOut: "Configuration saved"},
+		{name: "broken YAML is re-opened", edits: []string{broken, valid}, input: "\n", expectedRuns: 2, expected: valid, expectedOut: "has errors"},
+		{name: "unknown answer asks again", edits: []string{invalid, valid}, input: "x\ne\n", expectedRuns: 2, expected: valid, expectedOut: "repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, editorLog := setupEditTest(t, tt.edits, tt.input)
+
+			output, err := captureStdout(t, runEdit)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(output, tt.expectedOut) {
+				t.Errorf("Expected output containing %q, got:\n%s", tt.expectedOut, output)
+			}
+
+			data, _ := os.ReadFile(configPath)
+			if string(data) != tt.expected {
+				t.Errorf("Expected config:\n%s\ngot:\n%s", tt.expected, data)
+			}
+
+			// ja: エディタは設定ファイルではなく、設定ファイルのディレクトリの外にある一時コピーを開くこと
+			// en: The editor must open a temp copy outside the config directory rather than the config itself
+			logData, _ := os.ReadFile(editorLog)
+			runs := strings.Split(strings.TrimSpace(string(logData)), "\n")
+			if len(runs) != tt.expectedRuns {
+				t.Errorf("Expected %d editor runs, got %d", tt.expectedRuns, len(runs))
+			}
+			for _, run := range runs {
+				if run == configPath || filepath.Dir(run) == filepath.Dir(configPath) || filepath.Ext(run) != filepath.Ext(configPath) {
+					t.Errorf("Expected a temp copy outside the config directory, got %s", run)
+				}
+				if _, err := os.Stat(run); !os.IsNotExist(err) {
+					t.Errorf("Expected temp copy %s to be removed", run)
+				}
+			}
+
+			info, err := os.Stat(configPath)
+			if err != nil {
+				t.Fatalf("Failed to stat config: %v", err)
+			}
+			if info.Mode().Perm() != 0644 {
+				t.Errorf("Expected mode 0644 to be kept, got %v", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestRunEditInputClosed(t *testing.T) {
+	invalid := strings.Replace(getConfigTemplate(), "    repo: git@github.com:user/sample-project.git\n", "", 1)
+	configPath, _ := setupEditTest(t, []string{invalid}, "")
+
+	_, err := captureStdout(t, runEdit)
+	if err == nil {
+		t.Error("Expected error when input is closed, got nil")
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if string(data) != getConfigTemplate() {
+		t.Errorf("Config must not change, got:\n%s", data)
+	}
+}
+
+func TestRunEditWithGlobInclude(t *testing.T) {
+	valid := strings.Replace(getConfigTemplate(), "branch: main", "branch: develop", 1)
+	valid = strings.Replace(valid, "projects:\n", "include:\n  - \"*.yml\"\nprojects:\n", 1)
+	configPath, _ := setupEditTest(t, []string{valid}, "")
+
+	// ja: 設定ファイル自身も *.yml に一致するが、重複として扱われないこと
+	// en: The config itself matches *.yml too, but must not be taken as a duplicate
+	output, err := captureStdout(t, runEdit)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Configuration saved") {
+		t.Errorf("Expected the edit to be saved, got:\n%s", output)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if string(data) != valid {
+		t.Errorf("Expected config:\n%s\ngot:\n%s", valid, data)
+	}
+}
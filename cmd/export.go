@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var (
+	exportProjectName string
+	exportBackupIndex int
+	exportAllBackups  bool
+	exportAllProjects bool
+	exportOutput      string
+)
+
+// ja: exportCmd は export コマンドを表します
+// en: exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: i18n.T("export.short"),
+	Long:  i18n.T("export.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runExport(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportProjectName, "project", "p", "", i18n.T("export.flag.project"))
+	exportCmd.Flags().IntVarP(&exportBackupIndex, "backup", "b", 1, i18n.T("export.flag.backup"))
+	exportCmd.Flags().BoolVar(&exportAllBackups, "all", false, i18n.T("export.flag.all"))
+	exportCmd.Flags().BoolVar(&exportAllProjects, "all-projects", false, i18n.T("export.flag.allProjects"))
+	exportCmd.Flags().StringVarP(&exportOutput, "output-file", "o", "", i18n.T("export.flag.output"))
+}
+
+// ja: exportResult はエクスポートしたバンドルの内容です（--dry-run では計画として表示します）
+// en: exportResult describes an exported bundle (shown as the plan with --dry-run)
+type exportResult struct {
+	Bundle   string            `json:"bundle" yaml:"bundle"`
+	Projects []exportedProject `json:"projects" yaml:"projects"`
+}
+
+// ja: exportedProject はバンドルに含めたプロジェクトとアーカイブです
+// en: exportedProject is a project and the archives included in the bundle
+type exportedProject struct {
+	Name     string   `json:"name" yaml:"name"`
+	Archives []string `json:"archives" yaml:"archives"`
+}
+
+func runExport() (err error) {
+	if exportProjectName == "" && !exportAllProjects {
+		return errors.New(i18n.T("export.noProject"))
+	}
+	if exportProjectName != "" && exportAllProjects {
+		return errors.New(i18n.T("export.projectAndAll"))
+	}
+
+	journal := startOperation("export", exportProjectName)
+	defer func() { journal.finish(err) }()
+
+	// ja: include や .toske.yml は反映するが、環境変数による上書きはこのマシン固有のものなので含めない
+	// en: Includes and .toske.yml are applied, but environment overrides belong to this machine and are left out
+	store := &configStore{path: newConfigStore().Path()}
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	projects := config.Projects
+	if !exportAllProjects {
+		project, err := store.FindProject(config, exportProjectName)
+		if err != nil {
+			return err
+		}
+		projects = []Project{*project}
+	}
+
+	manifest, err := buildBundleManifest(projects, toskeOptions())
+	if err != nil {
+		return err
+	}
+
+	output := exportOutput
+	if output == "" {
+		output = defaultBundleName(exportProjectName, manifest.Created)
+	}
+	result := exportResult{Bundle: output}
+	for _, project := range manifest.Projects {
+		exported := exportedProject{Name: project.Project.Name, Archives: []string{}}
+		for _, record := range project.Backups {
+			exported.Archives = append(exported.Archives, record.Filename)
+		}
+		result.Projects = append(result.Projects, exported)
+	}
+
+	if dryRun {
+		return showPlan(result, func() { printExportResult(result) })
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	if err := writeBundle(output, manifest, toskeOptions(), ctx.Err); err != nil {
+		if ctx.Err() != nil {
+			journal.interrupt()
+			return errors.New(i18n.T("export.interrupted"))
+		}
+		return err
+	}
+	journal.entry.Archive = output
+	journal.touchArchive(output, fileCreated, "")
+
+	if isStructuredOutput() {
+		return writeResult(result)
+	}
+	if outputVerbosity() == verbosityQuiet {
+		return nil
+	}
+
+	printExportResult(result)
+	fmt.Println()
+	fmt.Printf(i18n.T("export.success")+"\n", output)
+	return nil
+}
+
+// ja: buildBundleManifest は projects と --backup / --all で選んだバックアップからマニフェストを作ります
+// ja: バックアップがないプロジェクトは、設定のエントリだけを含めます
+// en: buildBundleManifest builds the manifest from projects and the backups chosen with --backup / --all
+// en: Projects without backups are included with their config entry only
+func buildBundleManifest(projects []Project, opts toske.Options) (bundleManifest, error) {
+	manifest := bundleManifest{
+		Format:       bundleFormat,
+		Created:      time.Now(),
+		ToskeVersion: Version,
+	}
+
+	for _, project := range projects {
+		records, err := toske.ListBackups(toske.Project{Name: project.Name}, opts)
+		var noBackups *toske.NoBackupsError
+		if errors.As(err, &noBackups) {
+			records, err = nil, nil
+		}
+		if err != nil {
+			return manifest, err
+		}
+
+		if !exportAllBackups && len(records) > 0 {
+			record, err := toske.SelectBackup(records, exportBackupIndex)
+			if err != nil {
+				return manifest, fmt.Errorf("%s: %w", project.Name, err)
+			}
+			records = []toske.BackupRecord{record}
+		}
+
+		project.Origins = nil
+		manifest.Projects = append(manifest.Projects, bundleProject{Project: project, Backups: nonNil(records)})
+	}
+
+	return manifest, nil
+}
+
+// ja: defaultBundleName は -o が指定されていない場合のバンドル名を返します
+// en: defaultBundleName returns the bundle name used when -o is not given
+func defaultBundleName(projectName string, created time.Time) string {
+	if projectName != "" {
+		return projectName + bundleExtension
+	}
+	return "toske-export-" + created.Format("20060102_150405") + bundleExtension
+}
+
+// ja: writeBundle はマニフェストとアーカイブを path にバンドルとして書き込みます
+// ja: バンドルには .env などの秘密情報が含まれるため、所有者だけが読めるようにします
+// ja: 書き込み中は .partial に書き、失敗や中断（cancelled が nil 以外を返す）の場合は削除します
+// en: writeBundle writes the manifest and the archives to path as a bundle
+// en: Bundles hold secrets such as .env files, so only the owner can read them
+// en: It writes to a .partial file, which is removed on failure or interruption (cancelled returning non-nil)
+func writeBundle(path string, manifest bundleManifest, opts toske.Options, cancelled func() error) error {
+	partialPath := path + toske.PartialSuffix
+	err := writeBundleFile(partialPath, manifest, opts, cancelled)
+	if err == nil {
+		err = os.Rename(partialPath, path)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf(i18n.T("export.writeError"), err)
+	}
+	return nil
+}
+
+func writeBundleFile(path string, manifest bundleManifest, opts toske.Options, cancelled func() error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	if err := writeBundleManifest(tw, manifest); err != nil {
+		return err
+	}
+
+	for _, project := range manifest.Projects {
+		backupDir, err := opts.BackupDir(project.Project.Name)
+		if err != nil {
+			return err
+		}
+		for _, record := range project.Backups {
+			if err := cancelled(); err != nil {
+				return err
+			}
+			archivePath := filepath.Join(backupDir, record.Filename)
+			if err := addBundleArchive(tw, archivePath, bundleArchivePath(project.Project.Name, record.Filename)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ja: addBundleArchive はアーカイブを name としてバンドルに追加します
+// en: addBundleArchive adds an archive to the bundle as name
+func addBundleArchive(tw *tar.Writer, archivePath, name string) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, archive)
+	return err
+}
+
+// ja: printExportResult はバンドルに含めるプロジェクトとアーカイブを表示します
+// en: printExportResult prints the projects and archives included in the bundle
+func printExportResult(result exportResult) {
+	msgf(i18n.T("export.bundle")+"\n", result.Bundle)
+	for _, project := range result.Projects {
+		msgf(i18n.T("export.project")+"\n", project.Name, len(project.Archives))
+		for _, archive := range project.Archives {
+			msgf("    %s\n", archive)
+		}
+	}
+}
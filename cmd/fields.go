@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: set コマンドの操作
+// en: Operations of the set command
+const (
+	fieldAssign = "="
+	fieldAppend = "+="
+	fieldRemove = "-="
+)
+
+// ja: configField は YAML のキー名で参照される設定のフィールドです
+// en: configField is a config field referenced by its YAML key
+type configField struct {
+	Key       string
+	Value     reflect.Value
+	OmitEmpty bool
+}
+
+// ja: lookupField は構造体へのポインタから YAML のキー名でフィールドを探します
+// en: lookupField looks up a field by its YAML key in a pointer to a struct
+func lookupField(target any, key string) (configField, error) {
+	v := reflect.ValueOf(target).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name, options, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == key && name != "" && name != "-" {
+			return configField{Key: key, Value: v.Field(i), OmitEmpty: options == "omitempty"}, nil
+		}
+	}
+
+	return configField{}, fmt.Errorf(i18n.T("fields.unknownKey"), key, strings.Join(fieldKeys(target), ", "))
+}
+
+// ja: fieldKeys は構造体の YAML のキー名を定義順に返します
+// en: fieldKeys returns the YAML keys of a struct in declaration order
+func fieldKeys(target any) []string {
+	t := reflect.TypeOf(target).Elem()
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// ja: parseFieldAssignment は set コマンドの引数をキー、操作、値に分解します
+// ja: 受け付ける形式: "key value"、"key=value"、"key+=value"、"key-=value"
+// ja: 最初の = の位置で分けるため、値に += や -= が含まれていても構いません
+// en: parseFieldAssignment splits the set command's arguments into key, operation and value
+// en: Accepted forms: "key value", "key=value", "key+=value" and "key-=value"
+// en: It splits at the first =, so the value may itself contain += or -=
+func parseFieldAssignment(args []string) (key, op, value string, err error) {
+	if len(args) == 2 {
+		return args[0], fieldAssign, args[1], nil
+	}
+
+	key, value, found := strings.Cut(args[0], fieldAssign)
+	op = fieldAssign
+	for _, candidate := range []string{fieldAppend, fieldRemove} {
+		if prefix, ok := strings.CutSuffix(key, strings.TrimSuffix(candidate, fieldAssign)); ok {
+			key, op = prefix, candidate
+			break
+		}
+	}
+	if !found || key == "" {
+		return "", "", "", fmt.Errorf("%s", i18n.T("fields.invalidArgs"))
+	}
+	return key, op, value, nil
+}
+
+// ja: apply はフィールドに操作を適用します
+// ja: リストへの代入ではカンマ区切りの値を受け付けます（値の中のカンマは \, と書きます）
+// en: apply applies an operation to the field
+// en: Assignments to lists accept comma-separated values (a comma inside a value is written as \,)
+func (f configField) apply(op, raw string) error {
+	switch f.Value.Kind() {
+	case reflect.String:
+		if op != fieldAssign {
+			return fmt.Errorf(i18n.T("fields.notList"), f.Key)
+		}
+		f.Value.SetString(raw)
+
+	case reflect.Int:
+		if op != fieldAssign {
+			return fmt.Errorf(i18n.T("fields.notList"), f.Key)
+		}
+		if raw == "" {
+			f.Value.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf(i18n.T("fields.invalidNumber"), f.Key, raw)
+		}
+		f.Value.SetInt(int64(n))
+
+	case reflect.Bool:
+		if op != fieldAssign {
+			return fmt.Errorf(i18n.T("fields.notList"), f.Key)
+		}
+		if raw == "" {
+			f.Value.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf(i18n.T("fields.invalidBool"), f.Key, raw)
+		}
+		f.Value.SetBool(b)
+
+	case reflect.Slice:
+		if f.Value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf(i18n.T("fields.unsupportedKey"), f.Key)
+		}
+		current := f.Value.Interface().([]string)
+		values := splitList(raw)
+
+		var updated []string
+		switch op {
+		case fieldAssign:
+			updated = values
+		case fieldAppend:
+			updated = appendUnique(append([]string(nil), current...), values...)
+		case fieldRemove:
+			for _, value := range values {
+				if !containsString(current, value) {
+					return fmt.Errorf(i18n.T("fields.notInList"), value, f.Key)
+				}
+			}
+			for _, item := range current {
+				if !containsString(values, item) {
+					updated = append(updated, item)
+				}
+			}
+		}
+		f.Value.Set(reflect.ValueOf(updated))
+
+	default:
+		return fmt.Errorf(i18n.T("fields.unsupportedKey"), f.Key)
+	}
+
+	return nil
+}
+
+// ja: isEmpty はフィールドがゼロ値（空文字列、0、false、空リスト）かを返します
+// en: isEmpty reports whether the field holds its zero value (empty string, 0, false or empty list)
+func (f configField) isEmpty() bool {
+	if f.Value.Kind() == reflect.Slice {
+		return f.Value.Len() == 0
+	}
+	return f.Value.IsZero()
+}
+
+// ja: splitList はカンマ区切りの値を分割し、空の要素を取り除きます
+// ja: \, はカンマそのもの、\\ はバックスラッシュそのものとして扱います（それ以外のバックスラッシュはそのまま残します）
+// en: splitList splits comma-separated values and drops empty items
+// en: \, stands for a literal comma and \\ for a literal backslash (any other backslash is kept as is)
+func splitList(raw string) []string {
+	var values []string
+	var current strings.Builder
+	flush := func() {
+		if value := strings.TrimSpace(current.String()); value != "" {
+			values = append(values, value)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && i+1 < len(raw) && (raw[i+1] == ',' || raw[i+1] == '\\'):
+			i++
+			current.WriteByte(raw[i])
+		case raw[i] == ',':
+			flush()
+		default:
+			current.WriteByte(raw[i])
+		}
+	}
+	flush()
+	return values
+}
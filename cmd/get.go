@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+var getProjectName string
+
+// ja: getResult は get コマンドの構造化出力です
+// en: getResult is the structured output of the get command
+type getResult struct {
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+	Key     string `json:"key" yaml:"key"`
+	Value   any    `json:"value" yaml:"value"`
+}
+
+// ja: getCmd は get コマンドを表します
+// en: getCmd represents the get command
+var getCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: i18n.T("get.short"),
+	Long:  i18n.T("get.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runGet(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.Flags().StringVarP(&getProjectName, "project", "p", "", i18n.T("get.flag.project"))
+}
+
+func runGet(key string) error {
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	// ja: プロジェクトが指定されていればプロジェクトのキー、なければトップレベルのキーを参照
+	// en: Look up a project key when a project is given, otherwise a top-level key
+	var target any = config
+	if getProjectName != "" {
+		project, err := store.FindProject(config, getProjectName)
+		if err != nil {
+			return err
+		}
+		target = project
+	}
+
+	field, err := lookupField(target, key)
+	if err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		return writeResult(getResult{Project: getProjectName, Key: key, Value: field.Value.Interface()})
+	}
+
+	return printFieldValue(field)
+}
+
+// ja: printFieldValue は値をスクリプトで扱いやすい形で表示します
+// ja: スカラーはそのまま、文字列のリストは 1 行に 1 つ、それ以外は YAML で出力します
+// en: printFieldValue prints a value in a script-friendly form
+// en: Scalars are printed as is, string lists one per line and anything else as YAML
+func printFieldValue(field configField) error {
+	switch field.Value.Kind() {
+	case reflect.String, reflect.Int, reflect.Bool:
+		fmt.Println(field.Value.Interface())
+		return nil
+	case reflect.Slice:
+		if values, ok := field.Value.Interface().([]string); ok {
+			for _, value := range values {
+				fmt.Println(value)
+			}
+			return nil
+		}
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(field.Value.Interface()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
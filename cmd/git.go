@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: gitRunner は git コマンドの実行に使用するコマンドランナーです
+// en: gitRunner is the command runner used to invoke git
+var gitRunner commandRunner = execRunner{}
+
+// ja: gitOutput は dir で git コマンドを実行し、前後の空白を除いた標準出力を返します
+// en: gitOutput runs git in dir and returns its trimmed standard output
+func gitOutput(runner commandRunner, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := runner.Run(runCommand{
+		Name:   "git",
+		Args:   args,
+		Dir:    dir,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", &gitError{args: args, msg: msg, err: err}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ja: gitError は git コマンドの失敗を stderr の内容とともに表します
+// en: gitError describes a failed git command along with its stderr
+type gitError struct {
+	args []string
+	msg  string
+	err  error
+}
+
+func (e *gitError) Error() string {
+	return "git " + strings.Join(e.args, " ") + ": " + e.msg
+}
+
+func (e *gitError) Unwrap() error {
+	return e.err
+}
+
+// ja: checkoutStatus はローカルのチェックアウトの状態を表します
+// en: checkoutStatus describes the state of a local checkout
+type checkoutStatus struct {
+	Path        string `json:"path" yaml:"path"`
+	Present     bool   `json:"present" yaml:"present"`
+	IsRepo      bool   `json:"is_repo" yaml:"is_repo"`
+	Branch      string `json:"branch,omitempty" yaml:"branch,omitempty"`
+	Dirty       bool   `json:"dirty" yaml:"dirty"`
+	HasUpstream bool   `json:"has_upstream" yaml:"has_upstream"`
+	Ahead       int    `json:"ahead" yaml:"ahead"`
+	Behind      int    `json:"behind" yaml:"behind"`
+}
+
+// ja: inspectCheckout は dir のチェックアウトの状態を調べます
+// ja: git が使えない、またはリポジトリでない場合もエラーにはせず、分かった範囲の状態を返します
+// en: inspectCheckout inspects the checkout at dir
+// en: A missing git binary or a non-repository is not an error; whatever could be determined is returned
+func inspectCheckout(runner commandRunner, dir string) checkoutStatus {
+	status := checkoutStatus{Path: dir}
+
+	if !dirExists(dir) {
+		return status
+	}
+	status.Present = true
+
+	if out, err := gitOutput(runner, dir, "rev-parse", "--is-inside-work-tree"); err != nil || out != "true" {
+		return status
+	}
+	status.IsRepo = true
+
+	if branch, err := gitOutput(runner, dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		status.Branch = branch
+	}
+
+	if out, err := gitOutput(runner, dir, "status", "--porcelain"); err == nil {
+		status.Dirty = out != ""
+	}
+
+	// ja: 出力は "<behind>\t<ahead>" の形式
+	// en: Output has the form "<behind>\t<ahead>"
+	if out, err := gitOutput(runner, dir, "rev-list", "--left-right", "--count", "@{upstream}...HEAD"); err == nil {
+		fields := strings.Fields(out)
+		if len(fields) == 2 {
+			behind, errBehind := strconv.Atoi(fields[0])
+			ahead, errAhead := strconv.Atoi(fields[1])
+			if errBehind == nil && errAhead == nil {
+				status.HasUpstream = true
+				status.Behind = behind
+				status.Ahead = ahead
+			}
+		}
+	}
+
+	return status
+}
+
+// ja: captureGitState はバックアップに記録する dir のチェックアウトの状態を調べます
+// ja: git リポジトリでない、またはコミットがまだない場合は nil を返します
+// en: captureGitState inspects the checkout at dir for recording in a backup
+// en: Returns nil when dir is not a git repository or has no commit yet
+func captureGitState(runner commandRunner, dir string) *toske.GitState {
+	commit, err := gitOutput(runner, dir, "rev-parse", "HEAD")
+	if err != nil || commit == "" {
+		return nil
+	}
+	state := &toske.GitState{Commit: commit}
+
+	// ja: HEAD が切り離されている場合、--abbrev-ref は "HEAD" を返す
+	// en: With a detached HEAD, --abbrev-ref prints "HEAD"
+	if branch, err := gitOutput(runner, dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		state.Branch = branch
+	}
+	if out, err := gitOutput(runner, dir, "status", "--porcelain"); err == nil {
+		state.Dirty = out != ""
+	}
+	if remote, err := gitOutput(runner, dir, "remote", "get-url", "origin"); err == nil {
+		state.Remote = redactRemote(remote)
+	}
+	return state
+}
+
+// ja: redactRemote は HTTP(S) のリモート URL に含まれる認証情報（https://token@host/... など）を取り除きます
+// ja: ssh のユーザー名（git@ など）は認証情報ではないのでそのまま残します
+// en: redactRemote strips credentials embedded in an HTTP(S) remote URL (such as https://token@host/...)
+// en: SSH user names (git@, ...) are not credentials and are kept as is
+func redactRemote(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
+
+// ja: shortCommit は表示用にコミットハッシュを短くします
+// en: shortCommit abbreviates a commit hash for display
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// ja: checkoutDrift はバックアップ時のコミットと現在のチェックアウトのずれです
+// en: checkoutDrift is how far the current checkout is from the commit a backup was taken at
+type checkoutDrift struct {
+	Recorded toske.GitState
+	Head     string
+	// ja: Missing は記録されたコミットがローカルのリポジトリにないことを表します
+	// en: Missing means the recorded commit is not in the local repository
+	Missing bool
+	Ahead   int
+	Behind  int
+}
+
+// ja: compareCheckout は dir のチェックアウトを、バックアップ時に記録されたコミットと比べます
+// ja: 記録がない、git リポジトリでない、または同じコミットの場合は nil を返します
+// en: compareCheckout compares the checkout at dir with the commit recorded at backup time
+// en: Returns nil when nothing was recorded, dir is not a git repository, or it is at the same commit
+func compareCheckout(runner commandRunner, dir string, recorded *toske.GitState) *checkoutDrift {
+	if recorded == nil || recorded.Commit == "" {
+		return nil
+	}
+	head, err := gitOutput(runner, dir, "rev-parse", "HEAD")
+	if err != nil || head == recorded.Commit {
+		return nil
+	}
+
+	drift := &checkoutDrift{Recorded: *recorded, Head: head}
+	if _, err := gitOutput(runner, dir, "cat-file", "-e", recorded.Commit+"^{commit}"); err != nil {
+		drift.Missing = true
+		return drift
+	}
+
+	// ja: 出力は "<記録にだけあるコミット数>\t<HEAD にだけあるコミット数>" の形式
+	// en: Output has the form "<commits only in the recorded one>\t<commits only in HEAD>"
+	if out, err := gitOutput(runner, dir, "rev-list", "--left-right", "--count", recorded.Commit+"...HEAD"); err == nil {
+		if fields := strings.Fields(out); len(fields) == 2 {
+			drift.Behind, _ = strconv.Atoi(fields[0])
+			drift.Ahead, _ = strconv.Atoi(fields[1])
+		}
+	}
+	return drift
+}
+
+// ja: checkoutRecorded は記録されたコミットをチェックアウトします
+// ja: 記録されたブランチの先頭がそのコミットであればブランチを、そうでなければコミットを直接（detached HEAD で）チェックアウトします
+// en: checkoutRecorded checks out the recorded commit
+// en: When the recorded branch still points at that commit the branch is checked out, otherwise the commit itself (detached HEAD)
+func checkoutRecorded(runner commandRunner, dir string, recorded toske.GitState) error {
+	if recorded.Branch != "" {
+		if tip, err := gitOutput(runner, dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+recorded.Branch); err == nil && tip == recorded.Commit {
+			_, err := gitOutput(runner, dir, "checkout", "--quiet", recorded.Branch)
+			return err
+		}
+	}
+	_, err := gitOutput(runner, dir, "checkout", "--quiet", "--detach", recorded.Commit)
+	return err
+}
+
+// ja: cloneRepository は project の repo の branch を dir にクローンします（親ディレクトリは作成します）
+// ja: skip_git_features で submodules を外していなければ、サブモジュールも再帰的にクローンします
+// en: cloneRepository clones the branch of the project's repo into dir (creating the parent directory)
+// en: Submodules are cloned recursively unless submodules is listed in skip_git_features
+func cloneRepository(runner commandRunner, project *Project, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	args := []string{"clone", "--quiet", "--branch", project.Branch}
+	if !containsString(project.SkipGitFeatures, gitFeatureSubmodules) {
+		args = append(args, "--recurse-submodules")
+	}
+	_, err := gitOutput(runner, filepath.Dir(dir), append(args, project.Repo, dir)...)
+	return err
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: toske が個別に扱う git の機能の名前（skip_git_features で扱いを止められます）
+// en: Names of the git features toske handles explicitly (skip_git_features turns the handling off)
+const (
+	gitFeatureSubmodules = "submodules"
+	gitFeatureWorktrees  = "worktrees"
+	gitFeatureLFS        = "lfs"
+)
+
+// ja: gitFeatureNames は skip_git_features に指定できる名前です
+// en: gitFeatureNames are the names accepted in skip_git_features
+var gitFeatureNames = []string{gitFeatureSubmodules, gitFeatureWorktrees, gitFeatureLFS}
+
+// ja: gitFeatures はチェックアウトで使われている、単純なクローンでは済まない git の機能です
+// en: gitFeatures are the git features in use in a checkout that go beyond a plain clone
+type gitFeatures struct {
+	// ja: Submodules はサブモジュールのパスで、Uninitialized は初期化されていないものがあるかを表します
+	// en: Submodules are the submodule paths, and Uninitialized tells whether any of them is not initialized
+	Submodules    []string
+	Uninitialized bool
+	// ja: Worktrees はこのチェックアウト以外のワークツリーのパスです
+	// en: Worktrees are the paths of the worktrees other than this checkout
+	Worktrees []string
+	// ja: LFS は Git LFS で管理されたファイルがあるかを、LFSMissing は中身がまだ取得されていないファイルの数を表します
+	// en: LFS tells whether any file is managed by Git LFS, and LFSMissing how many of them have not been fetched yet
+	LFS          bool
+	LFSInstalled bool
+	LFSMissing   int
+}
+
+// ja: detectGitFeatures は dir のチェックアウトで使われている git の機能を調べます
+// ja: skip に含まれる機能は調べません。git リポジトリでない場合は何も見つかりません
+// en: detectGitFeatures works out which git features the checkout at dir uses
+// en: Features listed in skip are not looked at; nothing is found outside a git repository
+func detectGitFeatures(runner commandRunner, dir string, skip []string) gitFeatures {
+	var features gitFeatures
+
+	// ja: 各行は "<状態><コミット> <パス> (<説明>)" の形式で、状態 - は初期化されていないことを表す
+	// en: Each line has the form "<state><commit> <path> (<describe>)", where the state - means not initialized
+	if !containsString(skip, gitFeatureSubmodules) {
+		if out, err := gitOutput(runner, dir, "submodule", "status", "--recursive"); err == nil {
+			for _, line := range strings.Split(out, "\n") {
+				if fields := strings.Fields(line); len(fields) >= 2 {
+					features.Submodules = append(features.Submodules, fields[1])
+					if strings.HasPrefix(line, "-") {
+						features.Uninitialized = true
+					}
+				}
+			}
+		}
+	}
+
+	// ja: 先頭の worktree がメインのワークツリーで、dir 自身もどこかに含まれる
+	// en: The first worktree is the main one, and dir itself is listed somewhere too
+	if !containsString(skip, gitFeatureWorktrees) {
+		top, err := gitOutput(runner, dir, "rev-parse", "--show-toplevel")
+		out, listErr := gitOutput(runner, dir, "worktree", "list", "--porcelain")
+		if err == nil && listErr == nil {
+			for _, line := range strings.Split(out, "\n") {
+				if path, ok := strings.CutPrefix(line, "worktree "); ok && filepath.Clean(path) != filepath.Clean(top) {
+					features.Worktrees = append(features.Worktrees, path)
+				}
+			}
+		}
+	}
+
+	// ja: 属性で探すので、git-lfs がインストールされていなくても LFS の利用に気付ける
+	// ja: lfs ls-files の各行は "<oid> <*|-> <パス>" の形式で、- は中身がまだ取得されていないことを表す
+	// en: Looking by attribute notices LFS even when git-lfs is not installed
+	// en: Each line of lfs ls-files has the form "<oid> <*|-> <path>", where - means the content has not been fetched
+	if !containsString(skip, gitFeatureLFS) {
+		if out, err := gitOutput(runner, dir, "ls-files", "--", ":(attr:filter=lfs)"); err == nil && out != "" {
+			features.LFS = true
+			if out, err := gitOutput(runner, dir, "lfs", "ls-files"); err == nil {
+				features.LFSInstalled = true
+				for _, line := range strings.Split(out, "\n") {
+					if fields := strings.Fields(line); len(fields) >= 3 && fields[1] == "-" {
+						features.LFSMissing++
+					}
+				}
+			}
+		}
+	}
+
+	return features
+}
+
+// ja: warnGitFeatures はバックアップで扱いきれない git の機能を標準エラー出力に警告します
+// ja: サブモジュールとワークツリーは、include_git_state で保存できない作業が残る場合だけ警告します
+// en: warnGitFeatures warns on stderr about git features a backup cannot deal with
+// en: Submodules and worktrees are only warned about when they hold work include_git_state cannot save
+func warnGitFeatures(project *Project, features gitFeatures) {
+	if project.IncludeGitState && len(features.Submodules) > 0 {
+		fmt.Fprintf(os.Stderr, i18n.T("backup.submodulesWarning")+"\n", project.Name, strings.Join(features.Submodules, ", "))
+	}
+	if project.IncludeGitState && len(features.Worktrees) > 0 {
+		fmt.Fprintf(os.Stderr, i18n.T("backup.worktreesWarning")+"\n", project.Name, strings.Join(features.Worktrees, ", "))
+	}
+	if features.LFS && !features.LFSInstalled {
+		fmt.Fprintf(os.Stderr, i18n.T("gitFeatures.lfsNotInstalled")+"\n", project.Name)
+	}
+}
+
+// ja: printGitFeaturePlan は復元の後に行う git の機能の処理を表示します
+// en: printGitFeaturePlan prints what will be done for git features after restoring
+func printGitFeaturePlan(features gitFeatures, checkout bool) {
+	if len(features.Submodules) > 0 && (checkout || features.Uninitialized) {
+		msgln(i18n.T("plan.restoreSubmodules"))
+	}
+	if features.LFSMissing > 0 {
+		msgf(i18n.T("plan.restoreLFS")+"\n", features.LFSMissing)
+	}
+}
+
+// ja: syncGitFeatures は復元の後に、サブモジュールを更新し、取得されていない LFS のファイルを取得します
+// ja: サブモジュールは初期化されていないものがある場合か、別のコミットをチェックアウトした場合だけ更新します
+// ja: 失敗してもファイルの復元は済んでいるので、警告にとどめます
+// en: syncGitFeatures updates submodules and fetches LFS files that have not been fetched after a restore
+// en: Submodules are only updated when some are not initialized or another commit was checked out
+// en: Failures only produce warnings, as the files have been restored by then
+func syncGitFeatures(runner commandRunner, project *Project, dir string, checkout bool) {
+	features := detectGitFeatures(runner, dir, project.SkipGitFeatures)
+
+	if len(features.Submodules) > 0 && (checkout || features.Uninitialized) {
+		statusln(i18n.T("restore.updatingSubmodules"))
+		if _, err := gitOutput(runner, dir, "submodule", "update", "--init", "--recursive"); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("restore.submodulesError")+"\n", err)
+		}
+	}
+
+	switch {
+	case features.LFS && !features.LFSInstalled:
+		fmt.Fprintf(os.Stderr, i18n.T("gitFeatures.lfsNotInstalled")+"\n", project.Name)
+	case features.LFSMissing > 0:
+		statusf(i18n.T("restore.fetchingLFS")+"\n", features.LFSMissing)
+		if _, err := gitOutput(runner, dir, "lfs", "pull"); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("restore.lfsError")+"\n", err)
+		}
+	}
+}
+
+// ja: checkWorktrees は project のチェックアウトに、toske がバックアップしないワークツリーがあれば警告します
+// ja: force でなければ、ワークツリーがある場合はエラーを返します
+// en: checkWorktrees warns when the checkout of project has worktrees that toske does not back up
+// en: Unless force is set, it returns an error when there are any
+func checkWorktrees(runner commandRunner, project *Project, force bool) error {
+	// ja: path のないプロジェクトはカレントディレクトリを指すので、関係のないチェックアウトを調べないようにする
+	// en: A project without a path means the current directory, so do not look at an unrelated checkout
+	if project.Path == "" || containsString(project.SkipGitFeatures, gitFeatureWorktrees) {
+		return nil
+	}
+	dir, err := resolveProjectDir(project)
+	if err != nil || !dirExists(dir) {
+		return nil
+	}
+
+	skip := []string{gitFeatureSubmodules, gitFeatureLFS}
+	features := detectGitFeatures(runner, dir, skip)
+	if len(features.Worktrees) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, i18n.T("delete.worktreesWarning")+"\n", project.Name)
+	for _, worktree := range features.Worktrees {
+		fmt.Fprintf(os.Stderr, "  %s\n", worktree)
+	}
+	if !force {
+		return fmt.Errorf(i18n.T("delete.worktreesRefused"), project.Name)
+	}
+	return nil
+}
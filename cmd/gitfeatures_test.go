@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectGitFeatures(t *testing.T) {
+	const repoDir = "/work/app"
+
+	tests := []struct {
+		name      string
+		skip      []string
+		responses map[string]string
+		expected  gitFeatures
+	}{
+		{
+			name:     "not a repository",
+			expected: gitFeatures{},
+		},
+		{
+			name: "plain clone",
+			responses: map[string]string{
+				"submodule status --recursive":   "",
+				"rev-parse --show-toplevel":      repoDir,
+				"worktree list --porcelain":      "worktree " + repoDir + "\nHEAD abc\nbranch refs/heads/main\n",
+				"ls-files -- :(attr:filter=lfs)": "",
+			},
+			expected: gitFeatures{},
+		},
+		{
+			name: "submodules, worktrees and lfs",
+			responses: map[string]string{
+				"submodule status --recursive":   " 1111111 vendor/lib (v1.0)\n-2222222 vendor/tools\n",
+				"rev-parse --show-toplevel":      repoDir,
+				"worktree list --porcelain":      "worktree /work/main\nHEAD abc\n\nworktree " + repoDir + "\nHEAD def\n",
+				"ls-files -- :(attr:filter=lfs)": "assets/logo.psd",
+				"lfs ls-files":                   "0123456789 * assets/logo.psd\nabcdef0123 - assets/video.mp4\n",
+			},
+			expected: gitFeatures{
+				Submodules:    []string{"vendor/lib", "vendor/tools"},
+				Uninitialized: true,
+				Worktrees:     []string{"/work/main"},
+				LFS:           true,
+				LFSInstalled:  true,
+				LFSMissing:    1,
+			},
+		},
+		{
+			name: "lfs without git-lfs",
+			responses: map[string]string{
+				"ls-files -- :(attr:filter=lfs)": "assets/logo.psd",
+			},
+			expected: gitFeatures{LFS: true},
+		},
+		{
+			name: "skipped features",
+			skip: []string{gitFeatureSubmodules, gitFeatureWorktrees, gitFeatureLFS},
+			responses: map[string]string{
+				"submodule status --recursive":   " 1111111 vendor/lib (v1.0)",
+				"rev-parse --show-toplevel":      repoDir,
+				"worktree list --porcelain":      "worktree /work/main\n",
+				"ls-files -- :(attr:filter=lfs)": "assets/logo.psd",
+			},
+			expected: gitFeatures{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := detectGitFeatures(&fakeGitRunner{responses: tt.responses}, repoDir, tt.skip)
+			if !reflect.DeepEqual(features, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, features)
+			}
+		})
+	}
+}
+
+func TestSyncGitFeatures(t *testing.T) {
+	const repoDir = "/work/app"
+	initialized := " 1111111 vendor/lib (v1.0)"
+
+	tests := []struct {
+		name      string
+		checkout  bool
+		skip      []string
+		submodule string
+		expected  []string
+	}{
+		{name: "nothing to do", submodule: initialized},
+		{name: "uninitialized submodule", submodule: "-1111111 vendor/lib", expected: []string{"submodule update --init --recursive", "lfs pull"}},
+		{name: "checked out another commit", checkout: true, submodule: initialized, expected: []string{"submodule update --init --recursive", "lfs pull"}},
+		{name: "skipped", checkout: true, skip: []string{gitFeatureSubmodules, gitFeatureLFS}, submodule: initialized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lfsFiles := "0123456789 * assets/logo.psd"
+			if tt.expected != nil {
+				lfsFiles += "\nabcdef0123 - assets/video.mp4"
+			}
+			runner := &fakeGitRunner{responses: map[string]string{
+				"submodule status --recursive":        tt.submodule,
+				"submodule update --init --recursive": "",
+				"ls-files -- :(attr:filter=lfs)":      "assets/logo.psd",
+				"lfs ls-files":                        lfsFiles,
+				"lfs pull":                            "",
+			}}
+
+			if _, err := captureStdout(t, func() error {
+				syncGitFeatures(runner, &Project{Name: "app", SkipGitFeatures: tt.skip}, repoDir, tt.checkout)
+				return nil
+			}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var actions []string
+			for _, call := range runner.calls {
+				if call == "submodule update --init --recursive" || call == "lfs pull" {
+					actions = append(actions, call)
+				}
+			}
+			if !reflect.DeepEqual(actions, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actions)
+			}
+		})
+	}
+}
+
+func TestCheckWorktrees(t *testing.T) {
+	repoDir := t.TempDir()
+	responses := map[string]string{
+		"rev-parse --show-toplevel": repoDir,
+		"worktree list --porcelain": "worktree " + repoDir + "\nHEAD abc\n\nworktree /work/feature\nHEAD def\n",
+	}
+
+	tests := []struct {
+		name      string
+		project   Project
+		force     bool
+		expectErr bool
+	}{
+		{
+			name:      "refused without force",
+			project:   Project{Name: "app", Path: repoDir},
+			expectErr: true,
+		},
+		{
+			name:    "warned only with force",
+			project: Project{Name: "app", Path: repoDir},
+			force:   true,
+		},
+		{
+			name:    "worktrees skipped",
+			project: Project{Name: "app", Path: repoDir, SkipGitFeatures: []string{gitFeatureWorktrees}},
+		},
+		{
+			name:    "no path",
+			project: Project{Name: "app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWorktrees(&fakeGitRunner{responses: responses}, &tt.project, tt.force)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("checkWorktrees() error = %v, expectErr %v", err, tt.expectErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: 同じ名前のプロジェクトが既にある場合の扱い（--on-conflict）
+// en: How to handle a project whose name is already taken (--on-conflict)
+const (
+	// ja: conflictAsk はプロジェクトごとに尋ねます
+	// en: conflictAsk asks for each project
+	conflictAsk = "ask"
+	// ja: conflictMerge は既存の設定を残し、アーカイブだけを既存のプロジェクトに加えます
+	// en: conflictMerge keeps the existing config and only adds the archives to the existing project
+	conflictMerge = "merge"
+	// ja: conflictRename は空いている名前（name-2 など）で新しいプロジェクトとして登録します
+	// en: conflictRename registers it as a new project under a free name (name-2, ...)
+	conflictRename = "rename"
+	// ja: conflictReplace は設定をバンドルのもので置き換え、アーカイブを加えます
+	// en: conflictReplace replaces the config with the one in the bundle and adds the archives
+	conflictReplace = "replace"
+	// ja: conflictSkip はそのプロジェクトを取り込みません
+	// en: conflictSkip does not import that project
+	conflictSkip = "skip"
+)
+
+// ja: importAdd は新しいプロジェクトとして登録したことを表します（他は conflict* と同じ値）
+// en: importAdd means the project was registered as a new one (the other actions share the conflict* values)
+const importAdd = "add"
+
+var (
+	importOnConflict    string
+	importName          string
+	importKeepPath      bool
+	importAllowCommands bool
+)
+
+// ja: importCmd は import コマンドを表します
+// en: importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: i18n.T("import.short"),
+	Long:  i18n.T("import.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runImport(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importOnConflict, "on-conflict", conflictAsk, i18n.T("import.flag.onConflict"))
+	importCmd.Flags().StringVar(&importName, "name", "", i18n.T("import.flag.name"))
+	importCmd.Flags().BoolVar(&importKeepPath, "keep-path", false, i18n.T("import.flag.keepPath"))
+	importCmd.Flags().BoolVar(&importAllowCommands, "allow-commands", false, i18n.T("import.flag.allowCommands"))
+}
+
+// ja: importResult は取り込んだ内容です（--dry-run では計画として表示します）
+// en: importResult describes what was imported (shown as the plan with --dry-run)
+type importResult struct {
+	Bundle   string            `json:"bundle" yaml:"bundle"`
+	Projects []importedProject `json:"projects" yaml:"projects"`
+}
+
+// ja: importedProject は取り込んだプロジェクトです。Source はバンドル内での名前です
+// en: importedProject is an imported project; Source is its name in the bundle
+type importedProject struct {
+	Name     string            `json:"name" yaml:"name"`
+	Source   string            `json:"source" yaml:"source"`
+	Action   string            `json:"action" yaml:"action"`
+	Archives []importedArchive `json:"archives" yaml:"archives"`
+	// ja: Path はバンドルに記録されていた送り手のマシンでのチェックアウト先で、PathKept は設定に残したかを表します
+	// en: Path is the checkout directory on the sender's machine recorded in the bundle; PathKept tells whether it went into the config
+	Path     string `json:"path,omitempty" yaml:"path,omitempty"`
+	PathKept bool   `json:"path_kept,omitempty" yaml:"path_kept,omitempty"`
+	// ja: CommandDumps はコマンドを実行するダンプで、CommandsKept は設定に残したかを表します
+	// en: CommandDumps are the dumps that run commands; CommandsKept tells whether they went into the config
+	CommandDumps []Dump `json:"command_dumps,omitempty" yaml:"command_dumps,omitempty"`
+	CommandsKept bool   `json:"commands_kept,omitempty" yaml:"commands_kept,omitempty"`
+
+	project Project
+	backups map[string]toske.BackupRecord
+}
+
+// ja: importedArchive は取り込んだアーカイブです
+// ja: Stored は名前が重なったために別名で保存した場合の名前、Duplicate は既に同じバックアップがあったことを表します
+// en: importedArchive is an imported archive
+// en: Stored is the name it was saved under when its name was taken, and Duplicate means the same backup was already there
+type importedArchive struct {
+	Filename  string `json:"filename" yaml:"filename"`
+	Stored    string `json:"stored,omitempty" yaml:"stored,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty" yaml:"duplicate,omitempty"`
+}
+
+func runImport(bundlePath string) (err error) {
+	switch importOnConflict {
+	case conflictAsk, conflictMerge, conflictRename, conflictReplace, conflictSkip:
+	default:
+		return fmt.Errorf(i18n.T("import.invalidOnConflict"), importOnConflict)
+	}
+
+	journal := startOperation("import", "")
+	defer func() { journal.finish(err) }()
+	journal.note(bundlePath)
+
+	bundle, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf(i18n.T("import.readError"), err)
+	}
+	defer bundle.Close()
+
+	tr := tar.NewReader(bundle)
+	manifest, err := readBundleManifest(tr)
+	if err != nil {
+		return err
+	}
+	if importName != "" && len(manifest.Projects) != 1 {
+		return errors.New(i18n.T("import.nameNeedsSingleProject"))
+	}
+
+	store := newConfigStore()
+	config, err := store.LoadStored()
+	if err != nil {
+		return err
+	}
+
+	opts := toskeOptions()
+	result, err := planImport(manifest, config, bufio.NewReader(os.Stdin), opts)
+	if err != nil {
+		return err
+	}
+	result.Bundle = bundlePath
+	if len(result.Projects) == 1 {
+		journal.entry.Project = result.Projects[0].Name
+	}
+
+	if dryRun {
+		return showPlan(result, func() { printImportResult(result) })
+	}
+
+	// ja: 先に設定ファイルを更新し、その後でアーカイブをバックアップディレクトリに取り込む
+	// en: Update the config file first, then bring the archives into the backup directories
+	if result.changesConfig() {
+		err := store.Edit(func(file *configfile.File) error {
+			for _, imported := range result.Projects {
+				switch imported.Action {
+				case importAdd, conflictRename:
+					if err := file.AppendProject(imported.project); err != nil {
+						return err
+					}
+				case conflictReplace:
+					if err := file.ReplaceProject(imported.Name, imported.project); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		journal.touch(store.Path(), fileModified)
+	}
+
+	if err := importArchives(tr, &result, opts, journal); err != nil {
+		return err
+	}
+
+	if isStructuredOutput() {
+		return writeResult(result)
+	}
+	if outputVerbosity() == verbosityQuiet {
+		return nil
+	}
+
+	printImportResult(result)
+	fmt.Println()
+	fmt.Printf(i18n.T("import.success")+"\n", bundlePath)
+	return nil
+}
+
+// ja: changesConfig は設定ファイルにプロジェクトを追加または置き換えるかを返します
+// en: changesConfig reports whether any project is added to or replaced in the config file
+func (r importResult) changesConfig() bool {
+	for _, imported := range r.Projects {
+		switch imported.Action {
+		case importAdd, conflictRename, conflictReplace:
+			return true
+		}
+	}
+	return false
+}
+
+// ja: planImport はバンドル内の各プロジェクトをどう取り込むかを決めます
+// ja: 名前が重なった場合は --on-conflict に従い、ask の場合は reader から選択を読みます
+// en: planImport decides how each project in the bundle is imported
+// en: Name conflicts follow --on-conflict; with ask, the choice is read from reader
+func planImport(manifest bundleManifest, config *Config, reader *bufio.Reader, opts toske.Options) (importResult, error) {
+	result := importResult{Projects: []importedProject{}}
+
+	taken := make(map[string]bool, len(config.Projects))
+	for _, project := range config.Projects {
+		taken[project.Name] = true
+	}
+
+	for _, bundled := range manifest.Projects {
+		project := bundled.Project
+		imported := importedProject{Source: project.Name, Action: importAdd, Archives: []importedArchive{}}
+		if importName != "" {
+			project.Name = importName
+		}
+
+		// ja: 名前はバックアップディレクトリになるため、BackupDir を呼ぶ前にバンドルの名前を確かめる
+		// en: The name becomes a backup directory, so check the bundle's name before anything calls BackupDir
+		if err := checkProjectName(project.Name); err != nil {
+			return result, err
+		}
+
+		if taken[project.Name] {
+			action := importOnConflict
+			if action == conflictAsk {
+				var err error
+				if action, err = promptImportConflict(reader, project.Name); err != nil {
+					return result, err
+				}
+			}
+			imported.Action = action
+			if action == conflictRename {
+				project.Name = freeProjectName(project.Name, taken)
+			}
+		}
+
+		// ja: 設定に加えるプロジェクトからは、送り手のマシンのパスとコマンドを実行するダンプを外す
+		// en: Leave the sender's machine path and the dumps that run commands out of a project going into the config
+		if imported.Action != conflictMerge && imported.Action != conflictSkip {
+			screenImportedProject(&project, &imported)
+		}
+
+		// ja: 設定に加えるプロジェクトは既存のプロジェクトと同じ規則で検証
+		// en: Projects going into the config are validated with the same rules as existing ones
+		if imported.Action == importAdd || imported.Action == conflictRename {
+			if err := validateProject(&project, len(config.Projects), taken); err != nil {
+				return result, err
+			}
+		}
+		if imported.Action == conflictReplace {
+			others := make(map[string]bool, len(taken))
+			for name := range taken {
+				others[name] = name != project.Name
+			}
+			if err := validateProject(&project, findProjectIndex(config.Projects, project.Name), others); err != nil {
+				return result, err
+			}
+		}
+		taken[project.Name] = true
+
+		imported.Name = project.Name
+		imported.project = project
+		imported.backups = make(map[string]toske.BackupRecord, len(bundled.Backups))
+
+		if imported.Action != conflictSkip {
+			backupDir, err := opts.BackupDir(project.Name)
+			if err != nil {
+				return result, err
+			}
+			metadata, err := toske.LoadMetadata(backupDir)
+			if err != nil {
+				return result, err
+			}
+			for _, record := range bundled.Backups {
+				imported.backups[record.Filename] = record
+				imported.Archives = append(imported.Archives, importedArchive{
+					Filename:  record.Filename,
+					Duplicate: toske.HasRecord(metadata.Backups, record),
+				})
+			}
+		}
+
+		result.Projects = append(result.Projects, imported)
+	}
+
+	return result, nil
+}
+
+// ja: screenImportedProject は path と command のダンプを imported に記録し、
+// ja: --keep-path や --allow-commands が指定されていなければ project から外します
+// ja: .toske.yml と同じく、バンドルを受け取っただけで任意のコマンドが実行されないようにするためです
+// en: screenImportedProject records path and the command dumps in imported, and takes them out of project
+// en: unless --keep-path or --allow-commands is given
+// en: As with .toske.yml, receiving a bundle must not be enough to get arbitrary commands run
+func screenImportedProject(project *Project, imported *importedProject) {
+	imported.Path = project.Path
+	imported.PathKept = importKeepPath && project.Path != ""
+	if !importKeepPath {
+		project.Path = ""
+	}
+
+	var dumps []Dump
+	for _, dump := range project.Dumps {
+		if dump.Provider == toske.DumpProviderCommand {
+			imported.CommandDumps = append(imported.CommandDumps, dump)
+			if !importAllowCommands {
+				continue
+			}
+		}
+		dumps = append(dumps, dump)
+	}
+	imported.CommandsKept = importAllowCommands && len(imported.CommandDumps) > 0
+	project.Dumps = dumps
+}
+
+// ja: importArchives はバンドルの残りのエントリを読み、取り込むプロジェクトのアーカイブを保存します
+// en: importArchives reads the rest of the bundle and stores the archives of the projects being imported
+func importArchives(tr *tar.Reader, result *importResult, opts toske.Options, journal *operation) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf(i18n.T("import.readError"), err)
+		}
+
+		source, filename, ok := splitBundleArchivePath(header.Name)
+		if !ok {
+			continue
+		}
+		imported, archive := findImportedArchive(result, source, filename)
+		if archive == nil || archive.Duplicate {
+			continue
+		}
+
+		stored, added, err := toske.AddArchive(imported.Name, tr, imported.backups[filename], opts)
+		if err != nil {
+			return fmt.Errorf(i18n.T("import.archiveError"), filename, err)
+		}
+		if !added {
+			archive.Duplicate = true
+			continue
+		}
+		if stored.Filename != filename {
+			archive.Stored = stored.Filename
+		}
+
+		backupDir, err := opts.BackupDir(imported.Name)
+		if err != nil {
+			return err
+		}
+		journal.touchArchive(filepath.Join(backupDir, stored.Filename), fileCreated, "")
+	}
+}
+
+// ja: findImportedArchive はバンドル内のプロジェクト名とファイル名から取り込み対象のアーカイブを探します
+// en: findImportedArchive looks up the archive to import by its project name and filename in the bundle
+func findImportedArchive(result *importResult, source, filename string) (*importedProject, *importedArchive) {
+	for i := range result.Projects {
+		imported := &result.Projects[i]
+		if imported.Source != source || imported.Action == conflictSkip {
+			continue
+		}
+		for j := range imported.Archives {
+			if imported.Archives[j].Filename == filename {
+				return imported, &imported.Archives[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ja: freeProjectName は taken に含まれない name-2、name-3 ... のうち最初のものを返します
+// en: freeProjectName returns the first of name-2, name-3, ... that is not in taken
+func freeProjectName(name string, taken map[string]bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// ja: promptImportConflict は同じ名前のプロジェクトがある場合の扱いを選ばせます
+// en: promptImportConflict asks how to handle a project whose name is already taken
+func promptImportConflict(reader *bufio.Reader, name string) (string, error) {
+	for {
+		msgf(i18n.T("import.conflictPrompt"), name)
+
+		response, err := reader.ReadString('\n')
+		if err != nil && response == "" {
+			return "", fmt.Errorf(i18n.T("import.readInputError"), err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "", "m", conflictMerge:
+			return conflictMerge, nil
+		case "r", conflictRename:
+			return conflictRename, nil
+		case "p", conflictReplace:
+			return conflictReplace, nil
+		case "s", conflictSkip:
+			return conflictSkip, nil
+		}
+	}
+}
+
+// ja: printImportResult は各プロジェクトの扱いと取り込むアーカイブを表示します
+// en: printImportResult prints how each project is handled and the archives brought in
+func printImportResult(result importResult) {
+	msgf(i18n.T("import.bundle")+"\n", result.Bundle)
+	for _, imported := range result.Projects {
+		name := imported.Name
+		if imported.Source != imported.Name {
+			name = fmt.Sprintf("%s -> %s", imported.Source, imported.Name)
+		}
+		msgf(i18n.T("import.project")+"\n", name, i18n.T("import.action."+imported.Action))
+		switch {
+		case imported.PathKept:
+			msgf(i18n.T("import.pathKept")+"\n", imported.Path)
+		case imported.Path != "":
+			msgf(i18n.T("import.pathDropped")+"\n", imported.Path)
+		}
+		for _, dump := range imported.CommandDumps {
+			key := "import.commandDumpDropped"
+			if imported.CommandsKept {
+				key = "import.commandDumpKept"
+			}
+			msgf(i18n.T(key)+"\n", dump.Name, dump.Command)
+			if dump.RestoreCommand != "" {
+				msgf(i18n.T("import.restoreCommand")+"\n", dump.RestoreCommand)
+			}
+		}
+		for _, archive := range imported.Archives {
+			switch {
+			case archive.Duplicate:
+				msgf(i18n.T("import.archiveDuplicate")+"\n", archive.Filename)
+			case archive.Stored != "":
+				msgf(i18n.T("import.archiveRenamed")+"\n", archive.Filename, archive.Stored)
+			default:
+				msgf("    %s\n", archive.Filename)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var importArchiveProjectName string
+
+// ja: importArchiveCmd は import-archive コマンドを表します
+// en: importArchiveCmd represents the import-archive command
+var importArchiveCmd = &cobra.Command{
+	Use:   "import-archive <file>...",
+	Short: i18n.T("importArchive.short"),
+	Long:  i18n.T("importArchive.long"),
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runImportArchive(args); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importArchiveCmd)
+	importArchiveCmd.Flags().StringVarP(&importArchiveProjectName, "project", "p", "", i18n.T("importArchive.flag.project"))
+	importArchiveCmd.MarkFlagRequired("project")
+}
+
+// ja: adoptedArchive は取り込んだ（--dry-run では取り込む予定の）アーカイブです
+// en: adoptedArchive is an archive that was adopted (or would be, with --dry-run)
+type adoptedArchive struct {
+	Source    string             `json:"source" yaml:"source"`
+	Record    toske.BackupRecord `json:"backup" yaml:"backup"`
+	Duplicate bool               `json:"duplicate,omitempty" yaml:"duplicate,omitempty"`
+}
+
+func runImportArchive(paths []string) (err error) {
+	if importArchiveProjectName == "" {
+		return fmt.Errorf("%s", i18n.T("importArchive.noProjectFlag"))
+	}
+
+	journal := startOperation("import-archive", importArchiveProjectName)
+	defer func() { journal.finish(err) }()
+
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+	project, err := store.FindProject(config, importArchiveProjectName)
+	if err != nil {
+		return err
+	}
+
+	target := toske.Project{Name: project.Name, BackupPaths: project.BackupPaths}
+	opts := toskeOptions()
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return err
+	}
+
+	adopted := []adoptedArchive{}
+	for _, path := range paths {
+		archive := adoptedArchive{Source: path}
+		if dryRun {
+			archive.Record, _, err = toske.InspectArchive(path, project.BackupPaths)
+			if err != nil {
+				return err
+			}
+			metadata, err := toske.LoadMetadata(backupDir)
+			if err != nil {
+				return err
+			}
+			archive.Duplicate = toske.HasRecord(metadata.Backups, archive.Record)
+		} else {
+			var added bool
+			archive.Record, added, err = toske.ImportArchive(target, path, opts)
+			if err != nil {
+				return err
+			}
+			archive.Duplicate = !added
+			if added {
+				journal.touchArchive(filepath.Join(backupDir, archive.Record.Filename), fileCreated, "")
+			}
+		}
+		archive.Record.Files = nonNil(archive.Record.Files)
+		adopted = append(adopted, archive)
+	}
+
+	if dryRun {
+		return showPlan(adopted, func() { printAdoptedArchives(adopted) })
+	}
+	if isStructuredOutput() {
+		return writeResult(adopted)
+	}
+	if outputVerbosity() == verbosityQuiet {
+		return nil
+	}
+	printAdoptedArchives(adopted)
+	return nil
+}
+
+// ja: printAdoptedArchives は取り込んだアーカイブと記録した内容を表示します
+// en: printAdoptedArchives prints the adopted archives and what was recorded for them
+func printAdoptedArchives(adopted []adoptedArchive) {
+	for _, archive := range adopted {
+		if archive.Duplicate {
+			msgf(i18n.T("importArchive.duplicate")+"\n", archive.Source)
+			continue
+		}
+		msgf(i18n.T("importArchive.adopted")+"\n", archive.Source, archive.Record.Filename, archive.Record.Timestamp.Format("2006-01-02 15:04:05"))
+		msgf(i18n.T("importArchive.contents")+"\n", len(archive.Record.Files), len(archive.Record.Dumps))
+	}
+}
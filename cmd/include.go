@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: localProjectFileName はリポジトリ内に置くプロジェクト設定ファイルの名前です
+// en: localProjectFileName is the name of the project file kept inside a repository
+const localProjectFileName = ".toske.yml"
+
+// ja: localProject はリポジトリ内の .toske.yml で宣言できる項目です
+// ja: 名前・リポジトリ・ブランチ・パスと、コマンドを実行するダンプは中央の設定でのみ指定できます
+// ja: （リポジトリを取得しただけで任意のコマンドが実行されないようにするため）
+// en: localProject holds the fields a repository's .toske.yml may declare
+// en: Name, repo, branch, path and dumps, which run commands, can only be set in the central config
+// en: (so that checking out a repository is not enough to get arbitrary commands run)
+type localProject struct {
+	BackupPaths     []string `yaml:"backup_paths"`
+	BackupRetention int      `yaml:"backup_retention"`
+	IncludeGitState bool     `yaml:"include_git_state"`
+	Exclude         []string `yaml:"exclude"`
+}
+
+// ja: resolveConfig はバージョンを確認した上で、include で指定されたファイルのプロジェクトを追加し、
+// ja: path を持つ各プロジェクトにリポジトリ内の .toske.yml をマージします
+// ja: 各フィールドの出所は Project.Origins に記録します
+// en: resolveConfig checks the version, then appends the projects of the files listed in include and
+// en: merges the repository's .toske.yml into every project that has a path
+// en: The source of each field is recorded in Project.Origins
+func resolveConfig(configPath string, config *Config) error {
+	if err := checkConfigVersion(config.Version); err != nil {
+		return err
+	}
+
+	for i := range config.Projects {
+		recordOrigins(&config.Projects[i], configPath)
+	}
+
+	files, err := includeFiles(configPath, config.Include)
+	if err != nil {
+		return err
+	}
+
+	// ja: include は入れ子にしません（読み込んだファイルの include は無視します）
+	// en: Includes do not nest (the include key of an included file is ignored)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf(i18n.T("include.readError"), file, err)
+		}
+
+		var included Config
+		if err := yaml.Unmarshal(data, &included); err != nil {
+			return fmt.Errorf(i18n.T("include.parseError"), file, err)
+		}
+		if err := checkConfigVersion(included.Version); err != nil {
+			return fmt.Errorf(i18n.T("include.parseError"), file, err)
+		}
+
+		for _, project := range included.Projects {
+			recordOrigins(&project, file)
+			config.Projects = append(config.Projects, project)
+		}
+	}
+
+	for i := range config.Projects {
+		if err := mergeLocalProject(&config.Projects[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ja: includeFiles は include のパターンに一致するファイルを返します
+// ja: 相対パターンは設定ファイルのディレクトリを基準にし、設定ファイル自身と重複は除きます
+// ja: 重複はシンボリックリンクを解決した実体で判定します
+// en: includeFiles returns the files matching the include patterns
+// en: Relative patterns are resolved against the config file's directory; the config itself and duplicates are skipped
+// en: Duplicates are detected on the real file, with symlinks resolved
+func includeFiles(configPath string, patterns []string) ([]string, error) {
+	configAbs, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{canonicalPath(configAbs): true}
+
+	var files []string
+	for _, pattern := range patterns {
+		expanded, err := expandHome(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(expanded) {
+			expanded = filepath.Join(filepath.Dir(configAbs), expanded)
+		}
+
+		matches, err := filepath.Glob(expanded)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("include.badPattern"), pattern, err)
+		}
+		for _, match := range matches {
+			if canonical := canonicalPath(match); !seen[canonical] {
+				seen[canonical] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// ja: canonicalPath はシンボリックリンクを解決した絶対パスを返します（解決できない場合は絶対パスのまま）
+// en: canonicalPath returns the absolute path with symlinks resolved (or just the absolute path when they cannot be)
+func canonicalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// ja: mergeLocalProject はプロジェクトのディレクトリにある .toske.yml をマージします
+// ja: リストは和集合、スカラーは中央の設定が未指定の場合のみ採用し、プロジェクトのディレクトリの外を指すパスは拒否します
+// en: mergeLocalProject merges the .toske.yml found in the project's directory
+// en: Lists are merged, scalars only fill in values the central config leaves unset, and paths pointing outside the project directory are rejected
+func mergeLocalProject(project *Project) error {
+	// ja: path が未指定のプロジェクトはカレントディレクトリを使うため対象外
+	// en: Projects without a path use the current directory, so they are skipped
+	if project.Path == "" {
+		return nil
+	}
+
+	dir, err := resolveProjectDir(project)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, localProjectFileName)
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf(i18n.T("include.readError"), file, err)
+	}
+
+	var local localProject
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&local); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf(i18n.T("include.localParseError"), file, err)
+	}
+
+	// ja: .toske.yml のパスはプロジェクトのディレクトリの外を指せない
+	// en: Paths in .toske.yml cannot point outside the project directory
+	for _, path := range local.BackupPaths {
+		if !insideProjectDir(path) {
+			return fmt.Errorf(i18n.T("include.localPathOutside"), file, "backup_paths", path)
+		}
+	}
+	for _, pattern := range local.Exclude {
+		if !insideProjectDir(pattern) {
+			return fmt.Errorf(i18n.T("include.localPathOutside"), file, "exclude", pattern)
+		}
+	}
+
+	if len(local.BackupPaths) > 0 {
+		project.BackupPaths = appendUnique(project.BackupPaths, local.BackupPaths...)
+		project.addOrigin("backup_paths", file)
+	}
+	if local.BackupRetention != 0 && project.BackupRetention == 0 {
+		project.BackupRetention = local.BackupRetention
+		project.addOrigin("backup_retention", file)
+	}
+	if local.IncludeGitState && !project.IncludeGitState {
+		project.IncludeGitState = true
+		project.addOrigin("include_git_state", file)
+	}
+	if len(local.Exclude) > 0 {
+		project.Exclude = appendUnique(project.Exclude, local.Exclude...)
+		project.addOrigin("exclude", file)
+	}
+
+	return nil
+}
+
+// ja: insideProjectDir は path がプロジェクトのディレクトリからの相対パスで、その外に出ないかを返します
+// en: insideProjectDir reports whether path is relative to the project directory and stays inside it
+func insideProjectDir(path string) bool {
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "~") {
+		return false
+	}
+	cleaned := filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	return cleaned != ".." && !strings.HasPrefix(cleaned, "../")
+}
+
+// ja: recordOrigins は値を持つすべてのフィールドの出所を source として記録します
+// en: recordOrigins records source as the origin of every field that holds a value
+func recordOrigins(project *Project, source string) {
+	for _, key := range fieldKeys(project) {
+		field, err := lookupField(project, key)
+		if err != nil || field.isEmpty() {
+			continue
+		}
+		project.addOrigin(key, source)
+	}
+}
+
+// ja: addOrigin はフィールドの出所を追加します
+// en: addOrigin adds a source to the origins of a field
+func (p *Project) addOrigin(key, source string) {
+	if p.Origins == nil {
+		p.Origins = make(map[string][]string)
+	}
+	if !containsString(p.Origins[key], source) {
+		p.Origins[key] = append(p.Origins[key], source)
+	}
+}
+
+// ja: origin はフィールドの出所を表示用に返します（出所が不明な場合は空文字列）
+// en: origin returns the origins of a field for display (empty when unknown)
+func (p *Project) origin(key string) string {
+	sources := make([]string, 0, len(p.Origins[key]))
+	for _, source := range p.Origins[key] {
+		sources = append(sources, collapseHome(source))
+	}
+	return strings.Join(sources, ", ")
+}
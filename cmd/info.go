@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var (
+	infoProjectName string
+	infoJSON        bool
+)
+
+// ja: projectInfo は info コマンドで表示するプロジェクトの詳細情報です
+// en: projectInfo holds the detailed project information shown by the info command
+type projectInfo struct {
+	Project     Project        `json:"project" yaml:"project"`
+	Checkout    checkoutStatus `json:"checkout" yaml:"checkout"`
+	Backups     backupSummary  `json:"backups" yaml:"backups"`
+	BackupPaths []pathStatus   `json:"backup_paths" yaml:"backup_paths"`
+}
+
+// ja: backupSummary はバックアップディレクトリの概要です
+// en: backupSummary summarizes a backup directory
+type backupSummary struct {
+	Dir          string     `json:"dir" yaml:"dir"`
+	Exists       bool       `json:"exists" yaml:"exists"`
+	Count        int        `json:"count" yaml:"count"`
+	TotalSize    int64      `json:"total_size" yaml:"total_size"`
+	LatestFile   string     `json:"latest_file,omitempty" yaml:"latest_file,omitempty"`
+	LatestTime   *time.Time `json:"latest_time,omitempty" yaml:"latest_time,omitempty"`
+	LatestAgeSec int64      `json:"latest_age_seconds,omitempty" yaml:"latest_age_seconds,omitempty"`
+}
+
+// ja: pathStatus は backup_paths の各エントリの現在の状態です
+// en: pathStatus is the current state of a backup_paths entry
+type pathStatus struct {
+	Path   string `json:"path" yaml:"path"`
+	Exists bool   `json:"exists" yaml:"exists"`
+	Size   int64  `json:"size" yaml:"size"`
+}
+
+// ja: infoCmd は info コマンドを表します
+// en: infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: i18n.T("info.short"),
+	Long:  i18n.T("info.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runInfo(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().StringVarP(&infoProjectName, "project", "p", "", i18n.T("info.flag.project"))
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, i18n.T("info.flag.json"))
+}
+
+func runInfo() error {
+	// ja: プロジェクト名が指定されているかチェック
+	// en: Check if project name is specified
+	if infoProjectName == "" {
+		return fmt.Errorf("%s", i18n.T("info.noProjectFlag"))
+	}
+
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	// ja: 指定されたプロジェクトを検索
+	// en: Find the specified project
+	project, err := store.FindProject(config, infoProjectName)
+	if err != nil {
+		return err
+	}
+
+	info, err := collectProjectInfo(project, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// ja: --json は --output json の短縮形
+	// en: --json is shorthand for --output json
+	if infoJSON || isStructuredOutput() {
+		return writeResult(info)
+	}
+
+	printProjectInfo(info)
+	return nil
+}
+
+// ja: collectProjectInfo はプロジェクトの設定・チェックアウト・バックアップの状態を集めます
+// en: collectProjectInfo gathers the config, checkout and backup state of a project
+func collectProjectInfo(project *Project, now time.Time) (*projectInfo, error) {
+	projectDir, err := resolveProjectDir(project)
+	if err != nil {
+		return nil, err
+	}
+
+	backupDir, err := getBackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	backups, err := summarizeBackups(backupDir, now)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := newVCS(project, gitRunner)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &projectInfo{
+		Project:  *project,
+		Checkout: v.inspect(projectDir),
+		Backups:  backups,
+	}
+
+	for _, backupPath := range project.BackupPaths {
+		status := pathStatus{Path: backupPath}
+		if size, err := pathSize(filepath.Join(projectDir, backupPath)); err == nil {
+			status.Exists = true
+			status.Size = size
+		}
+		info.BackupPaths = append(info.BackupPaths, status)
+	}
+
+	return info, nil
+}
+
+// ja: summarizeBackups はバックアップディレクトリとメタデータから概要を作成します
+// en: summarizeBackups builds a summary from a backup directory and its metadata
+func summarizeBackups(backupDir string, now time.Time) (backupSummary, error) {
+	summary := backupSummary{Dir: backupDir}
+
+	if !dirExists(backupDir) {
+		return summary, nil
+	}
+	summary.Exists = true
+
+	size, err := pathSize(backupDir)
+	if err != nil {
+		return summary, err
+	}
+	summary.TotalSize = size
+
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		return summary, fmt.Errorf(i18n.T("info.metadataError"), err)
+	}
+
+	summary.Count = len(metadata.Backups)
+	if summary.Count > 0 {
+		// ja: メタデータは新しい順に並んでいる
+		// en: Metadata is sorted newest first
+		latest := metadata.Backups[0]
+		summary.LatestFile = latest.Filename
+		summary.LatestTime = &latest.Timestamp
+		summary.LatestAgeSec = int64(now.Sub(latest.Timestamp).Seconds())
+	}
+
+	return summary, nil
+}
+
+// ja: printProjectInfo はプロジェクト情報を人間向けに表示します
+// en: printProjectInfo prints project information for humans
+func printProjectInfo(info *projectInfo) {
+	project := info.Project
+
+	fmt.Printf(i18n.T("info.header")+"\n", project.Name)
+	if project.VCS != "" {
+		fmt.Printf("  %s: %s\n", i18n.T("list.vcs"), project.VCS)
+	}
+	if project.VCS != vcsNone {
+		fmt.Printf("  %s: %s\n", i18n.T("list.repo"), project.Repo)
+		fmt.Printf("  %s: %s\n", i18n.T("list.branch"), project.Branch)
+	}
+	if project.Path != "" {
+		fmt.Printf("  %s: %s\n", i18n.T("info.path"), project.Path)
+	} else {
+		fmt.Printf("  %s: %s\n", i18n.T("info.path"), i18n.T("info.currentDir"))
+	}
+	if project.BackupRetention > 0 {
+		fmt.Printf("  %s: %d\n", i18n.T("list.retention"), project.BackupRetention)
+	}
+	if project.IncludeGitState {
+		fmt.Printf("  %s: %t\n", i18n.T("list.includeGitState"), project.IncludeGitState)
+	}
+	if project.ArchiveMode != "" {
+		fmt.Printf("  %s: %s\n", i18n.T("list.archiveMode"), project.ArchiveMode)
+	}
+	if len(project.Exclude) > 0 {
+		fmt.Printf("  %s: %s\n", i18n.T("list.exclude"), strings.Join(project.Exclude, ", "))
+	}
+	for _, dump := range project.Dumps {
+		fmt.Printf("  %s: %s (%s)\n", i18n.T("info.dump"), dump.Name, dump.Provider)
+	}
+	fmt.Println()
+
+	// ja: チェックアウトの状態
+	// en: Checkout state
+	checkout := info.Checkout
+	fmt.Println(i18n.T("info.checkoutHeader"))
+	fmt.Printf("  %s: %s\n", i18n.T("info.directory"), checkout.Path)
+	switch {
+	case !checkout.Present:
+		fmt.Printf("  %s: %s\n", i18n.T("info.status"), i18n.T("info.checkoutAbsent"))
+	case info.Project.VCS == vcsNone:
+		fmt.Printf("  %s: %s\n", i18n.T("info.status"), i18n.T("info.checkoutUnversioned"))
+	case !checkout.IsRepo:
+		fmt.Printf("  %s: %s\n", i18n.T("info.status"), i18n.T("info.checkoutNotRepo"))
+	default:
+		fmt.Printf("  %s: %s\n", i18n.T("info.status"), i18n.T("info.checkoutPresent"))
+		fmt.Printf("  %s: %s\n", i18n.T("info.currentBranch"), checkout.Branch)
+		if checkout.Dirty {
+			fmt.Printf("  %s: %s\n", i18n.T("info.workingTree"), i18n.T("info.dirty"))
+		} else {
+			fmt.Printf("  %s: %s\n", i18n.T("info.workingTree"), i18n.T("info.clean"))
+		}
+		if checkout.HasUpstream {
+			fmt.Printf("  %s: "+i18n.T("info.aheadBehind")+"\n", i18n.T("info.upstream"), checkout.Ahead, checkout.Behind)
+		} else {
+			fmt.Printf("  %s: %s\n", i18n.T("info.upstream"), i18n.T("info.noUpstream"))
+		}
+	}
+	fmt.Println()
+
+	// ja: バックアップの状態
+	// en: Backup state
+	backups := info.Backups
+	fmt.Println(i18n.T("info.backupsHeader"))
+	fmt.Printf("  %s: %s\n", i18n.T("info.directory"), backups.Dir)
+	fmt.Printf("  %s: %d\n", i18n.T("info.backupCount"), backups.Count)
+	fmt.Printf("  %s: %s\n", i18n.T("info.totalSize"), formatBytes(backups.TotalSize))
+	if backups.LatestTime != nil {
+		fmt.Printf("  %s: %s (%s)\n", i18n.T("info.latestBackup"),
+			backups.LatestTime.Format("2006-01-02 15:04:05"),
+			formatAge(time.Duration(backups.LatestAgeSec)*time.Second))
+	} else {
+		fmt.Printf("  %s: %s\n", i18n.T("info.latestBackup"), i18n.T("info.never"))
+	}
+
+	// ja: バックアップ対象パスの現在の状態
+	// en: Current state of backup paths
+	if len(info.BackupPaths) > 0 {
+		fmt.Println()
+		fmt.Println(i18n.T("info.backupPathsHeader"))
+		for _, status := range info.BackupPaths {
+			if status.Exists {
+				fmt.Printf("  ✓ %s (%s)\n", status.Path, formatBytes(status.Size))
+			} else {
+				fmt.Printf("  ✗ %s (%s)\n", status.Path, i18n.T("info.missing"))
+			}
+		}
+	}
+}
+
+// ja: formatBytes はバイト数を人間が読みやすい形式に変換します
+// en: formatBytes converts a byte count to a human-readable string
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ja: formatAge は経過時間を「3 日前」のような形式に変換します
+// en: formatAge converts an elapsed duration to a form like "3 days ago"
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return i18n.T("age.justNow")
+	case age < time.Hour:
+		return fmt.Sprintf(i18n.T("age.minutes"), int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf(i18n.T("age.hours"), int(age.Hours()))
+	default:
+		return fmt.Sprintf(i18n.T("age.days"), int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupInfoTest prepares a project checkout with one existing and one missing backup path,
+// plus a single backup in the temporary HOME.
+func setupInfoTest(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	checkoutDir := filepath.Join(tempDir, "src", "info-project")
+	if err := os.MkdirAll(checkoutDir, 0755); err != nil {
+		t.Fatalf("Failed to create checkout directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(checkoutDir, ".env"), []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := createTestBackup(tempDir, "info-project", []testFile{{name: ".env", content: "TEST=value"}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+
+	configData := `version: 1.0.0
+projects:
+  - name: info-project
+    repo: git@github.com:user/info.git
+    branch: main
+    path: ` + filepath.ToSlash(checkoutDir) + `
+    backup_paths:
+      - .env
+      - db.sqlite3
+    backup_retention: 3
+`
+	t.Cleanup(setupTestConfig(t, configData))
+
+	originalRunner := gitRunner
+	gitRunner = &fakeGitRunner{responses: map[string]string{
+		"rev-parse --is-inside-work-tree":                  "true",
+		"rev-parse --abbrev-ref HEAD":                      "main",
+		"status --porcelain":                               " M README.md",
+		"rev-list --left-right --count @{upstream}...HEAD": "0\t1",
+	}}
+	t.Cleanup(func() { gitRunner = originalRunner })
+
+	return checkoutDir
+}
+
+func TestRunInfo(t *testing.T) {
+	checkoutDir := setupInfoTest(t)
+
+	originalProjectName := infoProjectName
+	infoProjectName = "info-project"
+	defer func() { infoProjectName = originalProjectName }()
+
+	output, err := captureStdout(t, runInfo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedStrings := []string{
+		"info-project",
+		"git@github.com:user/info.git",
+		checkoutDir,
+		"dirty",
+		"1 ahead, 0 behind",
+		"✓ .env",
+		"✗ db.sqlite3",
+		"just now",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunInfoJSON(t *testing.T) {
+	setupInfoTest(t)
+
+	originalProjectName := infoProjectName
+	originalJSON := infoJSON
+	infoProjectName = "info-project"
+	infoJSON = true
+	defer func() {
+		infoProjectName = originalProjectName
+		infoJSON = originalJSON
+	}()
+
+	output, err := captureStdout(t, runInfo)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var info projectInfo
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+
+	if info.Project.Name != "info-project" {
+		t.Errorf("Expected project name 'info-project', got %q", info.Project.Name)
+	}
+	if !info.Checkout.Present || !info.Checkout.Dirty || info.Checkout.Ahead != 1 {
+		t.Errorf("Unexpected checkout state: %+v", info.Checkout)
+	}
+	if info.Backups.Count != 1 || info.Backups.LatestTime == nil || info.Backups.TotalSize == 0 {
+		t.Errorf("Unexpected backup summary: %+v", info.Backups)
+	}
+	if len(info.BackupPaths) != 2 || !info.BackupPaths[0].Exists || info.BackupPaths[1].Exists {
+		t.Errorf("Unexpected backup path states: %+v", info.BackupPaths)
+	}
+}
+
+func TestCollectProjectInfoWithoutVCS(t *testing.T) {
+	checkoutDir := setupInfoTest(t)
+	runner := gitRunner.(*fakeGitRunner)
+
+	project := &Project{Name: "info-project", VCS: vcsNone, Path: checkoutDir, BackupPaths: []string{".env"}}
+	info, err := collectProjectInfo(project, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("Expected git not to run for vcs: none, got %v", runner.calls)
+	}
+	if !info.Checkout.Present || info.Checkout.IsRepo || info.Checkout.Dirty {
+		t.Errorf("Unexpected checkout state: %+v", info.Checkout)
+	}
+
+	output, err := captureStdout(t, func() error {
+		printProjectInfo(info)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "not under version control") {
+		t.Errorf("Expected the checkout to be shown as unversioned, got:\n%s", output)
+	}
+}
+
+func TestRunInfoErrors(t *testing.T) {
+	setupInfoTest(t)
+
+	tests := []struct {
+		name         string
+		projectName  string
+		errorMessage string
+	}{
+		{name: "missing project flag", projectName: "", errorMessage: "Project name is required"},
+		{name: "project not found", projectName: "nonexistent", errorMessage: "not found in configuration file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalProjectName := infoProjectName
+			infoProjectName = tt.projectName
+			defer func() { infoProjectName = originalProjectName }()
+
+			err := runInfo()
+			if err == nil || !strings.Contains(err.Error(), tt.errorMessage) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errorMessage, err)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{2 * 1024 * 1024 * 1024, "2.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.size); got != tt.expected {
+			t.Errorf("formatBytes(%d) = %q, expected %q", tt.size, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5 minutes ago"},
+		{3 * time.Hour, "3 hours ago"},
+		{50 * time.Hour, "2 days ago"},
+	}
+
+	for _, tt := range tests {
+		if got := formatAge(tt.age); got != tt.expected {
+			t.Errorf("formatAge(%v) = %q, expected %q", tt.age, got, tt.expected)
+		}
+	}
+}
@@ -29,7 +29,10 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 }
 
-func runInit() error {
+func runInit() (err error) {
+	journal := startOperation("init", "")
+	defer func() { journal.finish(err) }()
+
 	// ja: 設定ファイルパスを決定（優先順位: --config フラグ > TOSKE_CONFIG 環境変数 > デフォルトパス）
 	// en: Determine config file path (priority: --config flag > TOSKE_CONFIG env var > default path)
 	configPath := cfgFile
@@ -37,6 +40,20 @@ func runInit() error {
 		configPath = getDefaultConfigPath()
 	}
 
+	// ja: 既存のファイルをテンプレートで置き換える差分を表示する
+	// en: Show the diff of replacing any existing file with the template
+	if dryRun {
+		existing, err := os.ReadFile(configPath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf(i18n.T("config.readError"), err)
+		}
+		return showConfigChange(&configChange{
+			Command: "init",
+			Path:    configPath,
+			Diff:    diffLines(existing, []byte(getConfigTemplate())),
+		})
+	}
+
 	// ja: 設定ファイルが既に存在するかチェック
 	// en: Check if config file already exists
 	if _, err := os.Stat(configPath); err == nil {
@@ -53,6 +70,7 @@ func runInit() error {
 
 		response = strings.TrimSpace(strings.ToLower(response))
 		if response != "y" && response != "yes" {
+			journal.cancel()
 			fmt.Println(i18n.T("init.cancelled"))
 			return nil
 		}
@@ -71,6 +89,7 @@ func runInit() error {
 	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf(i18n.T("init.writeFileError"), err)
 	}
+	journal.touch(configPath, fileCreated)
 
 	fmt.Printf(i18n.T("init.success")+"\n", configPath)
 	fmt.Println(i18n.T("init.nextSteps"))
@@ -87,7 +106,7 @@ func runInit() error {
 // ja: getConfigTemplate はデフォルトの設定テンプレートを返します
 // en: getConfigTemplate returns the default configuration template
 func getConfigTemplate() string {
-	return `version: 1.0.0
+	return `version: ` + latestConfigVersion() + `
 projects:
   - name: sample-project
     repo: git@github.com:user/sample-project.git
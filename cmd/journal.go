@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ja: 操作の結果
+// en: Operation results
+const (
+	resultSuccess     = "success"
+	resultFailed      = "failed"
+	resultCancelled   = "cancelled"
+	resultInterrupted = "interrupted"
+)
+
+// ja: 操作がファイルに対して行ったこと
+// en: What an operation did to a file
+const (
+	fileCreated  = "created"
+	fileWritten  = "written"
+	fileModified = "modified"
+	fileRemoved  = "removed"
+)
+
+// ja: journalFile は操作で触れたファイルです（削除されたファイル以外はハッシュ付き）
+// ja: アーカイブは読み直すと大きなファイルで時間がかかるため、書き込みながら計算したハッシュとサイズ・更新時刻を記録します
+// en: journalFile is a file touched by an operation (with a hash unless it was removed)
+// en: Archives can be large enough that reading them back is slow, so they are recorded with the hash computed while writing them, their size and modification time
+type journalFile struct {
+	Path    string     `json:"path" yaml:"path"`
+	Action  string     `json:"action" yaml:"action"`
+	SHA256  string     `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	Size    int64      `json:"size,omitempty" yaml:"size,omitempty"`
+	ModTime *time.Time `json:"mod_time,omitempty" yaml:"mod_time,omitempty"`
+}
+
+// ja: journalEntry はジャーナルの 1 行（1 回の変更操作）です
+// en: journalEntry is one line of the journal (one mutating operation)
+type journalEntry struct {
+	Time       time.Time     `json:"time" yaml:"time"`
+	Command    string        `json:"command" yaml:"command"`
+	Project    string        `json:"project,omitempty" yaml:"project,omitempty"`
+	Archive    string        `json:"archive,omitempty" yaml:"archive,omitempty"`
+	Files      []journalFile `json:"files,omitempty" yaml:"files,omitempty"`
+	Notes      []string      `json:"notes,omitempty" yaml:"notes,omitempty"`
+	DurationMS int64         `json:"duration_ms" yaml:"duration_ms"`
+	Result     string        `json:"result" yaml:"result"`
+	Error      string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ja: operation は実行中の変更操作で、終了時にジャーナルに記録されます
+// ja: 使い方: op := startOperation(...); defer func() { op.finish(err) }()
+// en: operation is a mutating operation in progress that is recorded in the journal once finished
+// en: Usage: op := startOperation(...); defer func() { op.finish(err) }()
+type operation struct {
+	entry   journalEntry
+	started time.Time
+	result  string
+	// ja: dryRun の場合、何も変更していないのでジャーナルには書き込みません
+	// en: Nothing is changed in a dry run, so it is not written to the journal
+	dryRun bool
+}
+
+// ja: startOperation は変更操作の記録を開始します
+// en: startOperation starts recording a mutating operation
+func startOperation(command, project string) *operation {
+	now := time.Now()
+	return &operation{
+		entry:   journalEntry{Time: now, Command: command, Project: project},
+		started: now,
+		dryRun:  dryRun,
+	}
+}
+
+// ja: touch は操作で触れたファイルを記録します（削除以外は現在の内容のハッシュも記録）
+// en: touch records a file touched by the operation (with the hash of its current content unless removed)
+func (o *operation) touch(path, action string) {
+	file := journalFile{Path: path, Action: action}
+	if action != fileRemoved {
+		hash, err := fileSHA256(path)
+		if err != nil {
+			slog.Debug("could not hash journal file", "path", path, "error", err)
+		}
+		file.SHA256 = hash
+	}
+	o.entry.Files = append(o.entry.Files, file)
+}
+
+// ja: touchArchive は操作で作成したアーカイブを、内容を読み直さずに sum（書き込みながら計算した SHA-256）とサイズ・更新時刻で記録します
+// ja: sum が分からない場合は空にします
+// en: touchArchive records an archive created by the operation by sum (the SHA-256 computed while writing it), its size and modification time, without reading it back
+// en: Leave sum empty when it is not known
+func (o *operation) touchArchive(path, action, sum string) {
+	file := journalFile{Path: path, Action: action, SHA256: sum}
+	if info, err := os.Stat(path); err == nil {
+		modTime := info.ModTime()
+		file.Size, file.ModTime = info.Size(), &modTime
+	} else {
+		slog.Debug("could not stat journal archive", "path", path, "error", err)
+	}
+	o.entry.Files = append(o.entry.Files, file)
+}
+
+// ja: note は操作についての補足を記録します
+// en: note records a remark about the operation
+func (o *operation) note(text string) {
+	o.entry.Notes = append(o.entry.Notes, text)
+}
+
+// ja: cancel はユーザーが確認で取り消したことを記録します
+// en: cancel records that the user declined the confirmation
+func (o *operation) cancel() {
+	o.result = resultCancelled
+}
+
+// ja: interrupt は Ctrl-C などで中断されたことを記録します
+// en: interrupt records that the operation was interrupted (e.g. with Ctrl-C)
+func (o *operation) interrupt() {
+	o.result = resultInterrupted
+}
+
+// ja: finish は操作の結果をジャーナルに書き込みます
+// ja: ジャーナルに書けなくても操作自体は失敗させず、警告をログに出すだけにします
+// en: finish writes the outcome of the operation to the journal
+// en: Failing to write the journal does not fail the operation; it is only logged as a warning
+func (o *operation) finish(err error) {
+	if o.dryRun {
+		return
+	}
+	o.entry.DurationMS = time.Since(o.started).Milliseconds()
+	switch {
+	case o.result != "":
+		o.entry.Result = o.result
+	case err != nil:
+		o.entry.Result = resultFailed
+	default:
+		o.entry.Result = resultSuccess
+	}
+	if err != nil {
+		o.entry.Error = err.Error()
+	}
+
+	path, pathErr := journalPath()
+	if pathErr == nil {
+		pathErr = appendJournal(path, o.entry)
+	}
+	if pathErr != nil {
+		slog.Warn("failed to write journal", "error", pathErr)
+		return
+	}
+	slog.Debug("journal entry written", "path", path, "command", o.entry.Command, "result", o.entry.Result)
+}
+
+// ja: journalPath はジャーナルのパス（$XDG_STATE_HOME/toske/journal.jsonl）を返します
+// ja: XDG_STATE_HOME が未設定の場合は ~/.local/state を使用します
+// en: journalPath returns the path of the journal ($XDG_STATE_HOME/toske/journal.jsonl)
+// en: ~/.local/state is used when XDG_STATE_HOME is not set
+func journalPath() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(stateDir, "toske", "journal.jsonl"), nil
+}
+
+// ja: appendJournal はエントリを JSON の 1 行としてジャーナルに追記します
+// en: appendJournal appends the entry to the journal as a single JSON line
+func appendJournal(path string, entry journalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ja: readJournal はジャーナルの全エントリを古い順に読み込みます（存在しない場合は空）
+// ja: 壊れた行は読み飛ばします
+// en: readJournal reads every journal entry, oldest first (empty when there is no journal)
+// en: Malformed lines are skipped
+func readJournal(path string) ([]journalEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []journalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Debug("skipping malformed journal line", "line", lineNo, "error", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ja: fileSHA256 はファイルの SHA-256 を 16 進数で返します
+// en: fileSHA256 returns the hex-encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
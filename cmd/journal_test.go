@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain keeps the operations run by the tests out of the real journal.
+func TestMain(m *testing.M) {
+	stateDir, err := os.MkdirTemp("", "toske-state-*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_STATE_HOME", stateDir)
+
+	code := m.Run()
+	os.RemoveAll(stateDir)
+	os.Exit(code)
+}
+
+// setupJournal points the journal at a fresh temp dir and returns its path.
+func setupJournal(t *testing.T) string {
+	t.Helper()
+	stateDir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateDir)
+	return filepath.Join(stateDir, "toske", "journal.jsonl")
+}
+
+func TestOperationJournal(t *testing.T) {
+	path := setupJournal(t)
+
+	touched := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(touched, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	op := startOperation("set", "myapp")
+	op.touch(touched, fileModified)
+	op.touch("/gone/backup_1.tar.gz", fileRemoved)
+	op.finish(nil)
+
+	op = startOperation("restore", "myapp")
+	op.interrupt()
+	op.finish(errors.New("stopped"))
+
+	startOperation("delete", "other").finish(errors.New("boom"))
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatalf("readJournal failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Command != "set" || first.Project != "myapp" || first.Result != resultSuccess {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	// ja: "hello" の SHA-256
+	// en: SHA-256 of "hello"
+	wantHash := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if len(first.Files) != 2 || first.Files[0].SHA256 != wantHash || first.Files[1].SHA256 != "" {
+		t.Errorf("Expected the modified file to be hashed and the removed one not, got %+v", first.Files)
+	}
+	if entries[1].Result != resultInterrupted || entries[1].Error != "stopped" {
+		t.Errorf("Expected an interrupted entry with its error, got %+v", entries[1])
+	}
+	if entries[2].Result != resultFailed {
+		t.Errorf("Expected a failed entry, got %+v", entries[2])
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat journal: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected journal mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestReadJournalSkipsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	content := `{"command":"backup","result":"success"}` + "\nnot json\n" + `{"command":"restore","result":"failed"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatalf("readJournal failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Command != "restore" {
+		t.Errorf("Expected the two valid entries, got %+v", entries)
+	}
+
+	entries, err = readJournal(filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries and no error for a missing journal, got %v, %v", entries, err)
+	}
+}
+
+func TestBackupAndRestoreAreJournaled(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	path := setupJournal(t)
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: journal-test
+    repo: git@github.com:user/test.git
+    branch: main
+    backup_paths:
+      - .env
+`)()
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	if err := os.WriteFile(".env", []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create .env: %v", err)
+	}
+
+	originalProjectName, originalRestoreName, originalForce := projectName, restoreProjectName, forceRestore
+	defer func() {
+		projectName, restoreProjectName, forceRestore = originalProjectName, originalRestoreName, originalForce
+	}()
+	projectName, restoreProjectName, forceRestore = "journal-test", "journal-test", true
+
+	if err := runBackup(); err != nil {
+		t.Fatalf("runBackup failed: %v", err)
+	}
+	if err := runRestore(); err != nil {
+		t.Fatalf("runRestore failed: %v", err)
+	}
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatalf("readJournal failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+
+	backup, restore := entries[0], entries[1]
+	if backup.Command != "backup" || backup.Archive == "" || len(backup.Files) != 1 || backup.Files[0].Action != fileCreated {
+		t.Fatalf("Unexpected backup entry: %+v", backup)
+	}
+	// ja: アーカイブは書き込みながら計算したハッシュとサイズ・更新時刻で記録される
+	// en: The archive is recorded by the hash computed while writing it, its size and modification time
+	if archive := backup.Files[0]; archive.Size == 0 || archive.ModTime == nil {
+		t.Errorf("Expected the archive to be recorded by size and modification time, got %+v", archive)
+	}
+	if sum, err := fileSHA256(backup.Archive); err != nil || backup.Files[0].SHA256 != sum {
+		t.Errorf("Expected the archive to be recorded with its hash %s, got %+v (%v)", sum, backup.Files[0], err)
+	}
+	if restore.Command != "restore" || restore.Archive != backup.Archive {
+		t.Errorf("Unexpected restore entry: %+v", restore)
+	}
+	if len(restore.Files) != 1 || filepath.Base(restore.Files[0].Path) != ".env" || restore.Files[0].SHA256 == "" {
+		t.Errorf("Expected the restored .env to be recorded with its hash, got %+v", restore.Files)
+	}
+}
+
+func TestRunLog(t *testing.T) {
+	path := setupJournal(t)
+	now := time.Now()
+	for _, entry := range []journalEntry{
+		{Time: now.Add(-72 * time.Hour), Command: "backup", Project: "alpha", Result: resultSuccess},
+		{Time: now.Add(-time.Hour), Command: "restore", Project: "alpha", Result: resultFailed, Error: "boom"},
+		{Time: now.Add(-time.Minute), Command: "backup", Project: "beta", Result: resultSuccess},
+	} {
+		if err := appendJournal(path, entry); err != nil {
+			t.Fatalf("appendJournal failed: %v", err)
+		}
+	}
+
+	originalProject, originalSince := logProjectName, logSince
+	defer func() { logProjectName, logSince = originalProject, originalSince }()
+
+	t.Run("table", func(t *testing.T) {
+		logProjectName, logSince = "alpha", ""
+		output, err := captureStdout(t, runLog)
+		if err != nil {
+			t.Fatalf("runLog failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected a header and 2 rows, got:\n%s", output)
+		}
+		if !strings.Contains(lines[1], "restore") || !strings.Contains(lines[1], "boom") {
+			t.Errorf("Expected the newest entry first, got %q", lines[1])
+		}
+		if strings.Contains(output, "beta") {
+			t.Errorf("Expected other projects to be filtered out, got:\n%s", output)
+		}
+	})
+
+	t.Run("since", func(t *testing.T) {
+		setOutputFormat(t, outputJSON)
+		logProjectName, logSince = "", "2h"
+		output, err := captureStdout(t, runLog)
+		if err != nil {
+			t.Fatalf("runLog failed: %v", err)
+		}
+		var entries []journalEntry
+		if err := json.Unmarshal([]byte(output), &entries); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+		}
+		if len(entries) != 2 || entries[0].Project != "beta" {
+			t.Errorf("Expected the two recent entries, newest first, got %+v", entries)
+		}
+	})
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value       string
+		want        time.Time
+		expectError bool
+	}{
+		{value: "36h", want: now.Add(-36 * time.Hour)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2025-06-01T00:00:00Z", want: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2025-06-01", want: time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)},
+		{value: "last week", expectError: true},
+		{value: "-3d", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSince(tt.value, now)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigureLogging(t *testing.T) {
+	original := logLevel
+	defer func() {
+		logLevel = original
+		configureLogging()
+	}()
+
+	for _, level := range []string{"debug", "INFO", "warn", "error"} {
+		logLevel = level
+		if err := configureLogging(); err != nil {
+			t.Errorf("Expected level %q to be accepted, got %v", level, err)
+		}
+	}
+
+	logLevel = "loud"
+	if err := configureLogging(); err == nil {
+		t.Error("Expected an invalid level to be rejected")
+	}
+}
@@ -5,10 +5,21 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/yk-lab/toske/i18n"
 )
 
+// ja: listResult は list コマンドの構造化出力です
+// en: listResult is the structured output of the list command
+// ja: Origins は --origin 指定時のみ、プロジェクト名ごとに各フィールドの出所を保持します
+// en: Origins holds, per project name, the source of each field (only with --origin)
+type listResult struct {
+	Projects []Project                      `json:"projects" yaml:"projects"`
+	Total    int                            `json:"total" yaml:"total"`
+	Origins  map[string]map[string][]string `json:"origins,omitempty" yaml:"origins,omitempty"`
+}
+
+var listOrigin bool
+
 // ja: listCmd は list コマンドを表します
 // en: listCmd represents the list command
 var listCmd = &cobra.Command{
@@ -25,36 +36,29 @@ var listCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVar(&listOrigin, "origin", false, i18n.T("list.flag.origin"))
 }
 
 func runList() error {
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
-	}
-
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("list.noConfig"), configPath)
-	}
-
 	// ja: 設定ファイルを読み込む
 	// en: Load configuration file
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return fmt.Errorf(i18n.T("list.readError"), err)
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
 	}
 
-	// ja: 設定を構造体にアンマーシャル
-	// en: Unmarshal config into struct
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return fmt.Errorf(i18n.T("list.parseError"), err)
+	// ja: 構造化出力の場合は結果のみを書き出す
+	// en: For structured output, write only the result
+	if isStructuredOutput() {
+		result := listResult{Projects: nonNil(config.Projects), Total: len(config.Projects)}
+		if listOrigin {
+			result.Origins = make(map[string]map[string][]string, len(config.Projects))
+			for _, project := range config.Projects {
+				result.Origins[project.Name] = project.Origins
+			}
+		}
+		return writeResult(result)
 	}
 
 	// ja: プロジェクトが存在しない場合
@@ -69,17 +73,42 @@ func runList() error {
 	fmt.Println(i18n.T("list.header"))
 	fmt.Println()
 	for _, project := range config.Projects {
-		fmt.Printf("  • %s\n", project.Name)
-		fmt.Printf("    %s: %s\n", i18n.T("list.repo"), project.Repo)
-		fmt.Printf("    %s: %s\n", i18n.T("list.branch"), project.Branch)
+		fmt.Printf("  • %s%s\n", project.Name, originSuffix(&project, "name"))
+		if project.VCS != "" {
+			fmt.Printf("    %s: %s%s\n", i18n.T("list.vcs"), project.VCS, originSuffix(&project, "vcs"))
+		}
+		// ja: vcs が none のプロジェクトにはリポジトリとブランチがない
+		// en: Projects with vcs none have no repository or branch
+		if project.VCS != vcsNone {
+			fmt.Printf("    %s: %s%s\n", i18n.T("list.repo"), project.Repo, originSuffix(&project, "repo"))
+			fmt.Printf("    %s: %s%s\n", i18n.T("list.branch"), project.Branch, originSuffix(&project, "branch"))
+		}
 		if len(project.BackupPaths) > 0 {
-			fmt.Printf("    %s:\n", i18n.T("list.backupPaths"))
+			fmt.Printf("    %s:%s\n", i18n.T("list.backupPaths"), originSuffix(&project, "backup_paths"))
 			for _, path := range project.BackupPaths {
 				fmt.Printf("      - %s\n", path)
 			}
 		}
 		if project.BackupRetention > 0 {
-			fmt.Printf("    %s: %d\n", i18n.T("list.retention"), project.BackupRetention)
+			fmt.Printf("    %s: %d%s\n", i18n.T("list.retention"), project.BackupRetention, originSuffix(&project, "backup_retention"))
+		}
+		if project.IncludeGitState {
+			fmt.Printf("    %s: %t%s\n", i18n.T("list.includeGitState"), project.IncludeGitState, originSuffix(&project, "include_git_state"))
+		}
+		if project.ArchiveMode != "" {
+			fmt.Printf("    %s: %s%s\n", i18n.T("list.archiveMode"), project.ArchiveMode, originSuffix(&project, "archive_mode"))
+		}
+		if len(project.Exclude) > 0 {
+			fmt.Printf("    %s:%s\n", i18n.T("list.exclude"), originSuffix(&project, "exclude"))
+			for _, pattern := range project.Exclude {
+				fmt.Printf("      - %s\n", pattern)
+			}
+		}
+		if listOrigin && len(project.Dumps) > 0 {
+			fmt.Printf("    %s:%s\n", i18n.T("list.dumps"), originSuffix(&project, "dumps"))
+			for _, dump := range project.Dumps {
+				fmt.Printf("      - %s (%s)\n", dump.Name, dump.Provider)
+			}
 		}
 		fmt.Println()
 	}
@@ -88,3 +117,16 @@ func runList() error {
 
 	return nil
 }
+
+// ja: originSuffix は --origin 指定時にフィールドの出所を表示用の接尾辞として返します
+// en: originSuffix returns the origin of a field as a display suffix when --origin is given
+func originSuffix(project *Project, key string) string {
+	if !listOrigin {
+		return ""
+	}
+	origin := project.origin(key)
+	if origin == "" {
+		return ""
+	}
+	return "  ← " + origin
+}
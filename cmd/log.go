@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+)
+
+var (
+	logProjectName string
+	logSince       string
+)
+
+// ja: logCmd は log コマンドを表します
+// en: logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: i18n.T("log.short"),
+	Long:  i18n.T("log.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runLog(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logCmd)
+	logCmd.Flags().StringVarP(&logProjectName, "project", "p", "", i18n.T("log.flag.project"))
+	logCmd.Flags().StringVar(&logSince, "since", "", i18n.T("log.flag.since"))
+}
+
+func runLog() error {
+	var since time.Time
+	if logSince != "" {
+		var err error
+		if since, err = parseSince(logSince, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	entries, err := readJournal(path)
+	if err != nil {
+		return fmt.Errorf(i18n.T("log.readError"), err)
+	}
+
+	// ja: 絞り込んで新しい順に並べる
+	// en: Filter, newest first
+	var matched []journalEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if logProjectName != "" && entry.Project != logProjectName {
+			continue
+		}
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+
+	if isStructuredOutput() {
+		return writeResult(nonNil(matched))
+	}
+
+	if len(matched) == 0 {
+		fmt.Println(i18n.T("log.empty"))
+		return nil
+	}
+	printJournalTable(matched)
+	return nil
+}
+
+// ja: parseSince は --since の値を時刻に変換します
+// ja: 期間（36h、7d）、日付（2006-01-02）、RFC 3339 形式の時刻を受け付けます
+// en: parseSince converts the value of --since to a point in time
+// en: It accepts a duration (36h, 7d), a date (2006-01-02) or an RFC 3339 timestamp
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf(i18n.T("log.invalidSince"), value)
+}
+
+// ja: printJournalTable はジャーナルのエントリを表形式で表示します
+// en: printJournalTable prints journal entries as a table
+func printJournalTable(entries []journalEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, i18n.T("log.tableHeader"))
+
+	for _, entry := range entries {
+		project := entry.Project
+		if project == "" {
+			project = "-"
+		}
+
+		detail := entry.Error
+		if detail == "" && entry.Archive != "" {
+			detail = entry.Archive
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Command,
+			project,
+			i18n.T("log.result."+entry.Result),
+			(time.Duration(entry.DurationMS) * time.Millisecond).String(),
+			len(entry.Files),
+			detail)
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: logLevel は --log-level フラグで指定された、標準エラー出力に出すログのレベルです
+// en: logLevel is the level of the logs written to stderr, selected with the --log-level flag
+var logLevel = "warn"
+
+// ja: configureLogging は --log-level に合わせて既定の slog ロガーを設定します
+// en: configureLogging sets up the default slog logger for --log-level
+func configureLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return fmt.Errorf(i18n.T("logging.invalidLevel"), logLevel)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+	return nil
+}
+
+// ja: loggingRunner は実行する外部コマンドをデバッグログに出力します
+// ja: command や mysql のダンプでは引数にパスワードなどが含まれうるため、プログラム名と引数の数だけを記録します
+// en: loggingRunner writes the external commands it runs to the debug log
+// en: Arguments of command or mysql dumps may hold passwords and the like, so only the program name and the number of arguments are logged
+type loggingRunner struct {
+	runner commandRunner
+}
+
+func (r loggingRunner) Run(c toske.Command) error {
+	slog.Debug("running command", "name", c.Name, "args", len(c.Args), "dir", c.Dir)
+	err := r.runner.Run(c)
+	if err != nil {
+		slog.Debug("command failed", "name", c.Name, "error", err)
+	}
+	return err
+}
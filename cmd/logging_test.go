@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggingRunnerRedactsArguments(t *testing.T) {
+	var logs bytes.Buffer
+	original := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(original)
+
+	runner := &fakeRunner{}
+	err := loggingRunner{runner: runner}.Run(runCommand{Name: "mysqldump", Args: []string{"-uroot", "-phunter2", "app"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(runner.calls) != 1 || len(runner.calls[0].Args) != 3 {
+		t.Fatalf("Expected the command to be passed through unchanged, got %+v", runner.calls)
+	}
+
+	out := logs.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("Expected the password not to be logged, got %q", out)
+	}
+	if !strings.Contains(out, "name=mysqldump") || !strings.Contains(out, "args=3") {
+		t.Errorf("Expected the program name and argument count to be logged, got %q", out)
+	}
+}
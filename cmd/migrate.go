@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: baseConfigVersion は移行が 1 つも登録されていない場合の設定ファイルのバージョンです
+// en: baseConfigVersion is the config version when no migration is registered
+const baseConfigVersion = "1.0.0"
+
+// ja: projectKeysVersion は projectKeyDefaults のキーが加わった設定ファイルのバージョンです
+// en: projectKeysVersion is the config version that added the keys of projectKeyDefaults
+const projectKeysVersion = "1.1.0"
+
+// ja: configMigration は設定ファイルをあるバージョンから次のバージョンへ移行します
+// ja: Upgrade は configfile の編集で必要な行だけを書き換えます（それ以外の行とコメントはそのまま残ります）
+// ja: version は移行の後に To へ書き換えられます
+// en: configMigration upgrades a config file from one version to the next
+// en: Upgrade rewrites only the lines it needs through configfile edits (every other line and comment is kept)
+// en: version is set to To after the migration
+type configMigration struct {
+	From    string
+	To      string
+	Upgrade func(file *configfile.File) error
+}
+
+// ja: configMigrations は登録済みの移行です
+// ja: スキーマを変更する場合は、ここに From/To と Upgrade を追加してください
+// en: configMigrations lists the registered migrations
+// en: When changing the schema, add its From/To and Upgrade here
+var configMigrations = []configMigration{
+	// ja: 1.1.0 で include_git_state、skip_git_features、vcs、archive_mode、exclude が加わりました
+	// ja: バージョンを確認するようになった後の、これらを知らないバイナリは、黙って無視せずにファイルを拒否します
+	// ja: バージョンを確認する前のバイナリ（1.0.0 の最初のリリースなど）は、これまでどおりこれらのキーを無視します
+	// en: 1.1.0 adds include_git_state, skip_git_features, vcs, archive_mode and exclude
+	// en: Binaries that check the version but predate these keys refuse the file rather than ignore them
+	// en: Binaries from before the version check (such as the first 1.0.0 release) still ignore them, as they always have
+	{From: "1.0.0", To: projectKeysVersion, Upgrade: dropDefaultProjectKeys},
+}
+
+// ja: projectKeyDefaults は 1.1.0 で加わったキーの既定値です（明示しても意味がありません）
+// en: projectKeyDefaults are the defaults of the keys added in 1.1.0 (spelling them out changes nothing)
+var projectKeyDefaults = map[string]any{
+	"vcs":               vcsGit,
+	"archive_mode":      toske.ArchiveModePaths,
+	"include_git_state": false,
+	"skip_git_features": []any{},
+	"exclude":           []any{},
+}
+
+// ja: dropDefaultProjectKeys は 1.0.0 のまま書かれた新しいキーのうち、既定値と同じものを削除します
+// en: dropDefaultProjectKeys removes the new keys written under 1.0.0 that merely restate their defaults
+func dropDefaultProjectKeys(file *configfile.File) error {
+	var config struct {
+		Projects []map[string]any `yaml:"projects"`
+	}
+	if err := yaml.Unmarshal(file.Bytes(), &config); err != nil {
+		return err
+	}
+
+	for _, project := range config.Projects {
+		name, ok := project["name"].(string)
+		if !ok || name == "" {
+			continue
+		}
+		for _, key := range sortedKeys(projectKeyDefaults) {
+			value, ok := project[key]
+			if !ok || !isDefaultValue(value, projectKeyDefaults[key]) {
+				continue
+			}
+			if err := file.DeleteProjectField(name, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ja: bumpConfigVersion は keys のうち projectKeysVersion で加わったキーを書き込む場合に、
+// ja: それより古い version を宣言しているファイルの version を projectKeysVersion に上げます
+// ja: version を宣言していないファイル（include したファイルなど）はそのままにします
+// en: bumpConfigVersion raises the version of a file declaring an older one to projectKeysVersion
+// en: when keys include any that projectKeysVersion added
+// en: Files that declare no version (such as included files) are left as they are
+func bumpConfigVersion(file *configfile.File, keys []string) error {
+	added := false
+	for _, key := range keys {
+		if _, ok := projectKeyDefaults[key]; ok {
+			added = true
+			break
+		}
+	}
+	if !added {
+		return nil
+	}
+
+	var config struct {
+		Version string `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(file.Bytes(), &config); err != nil {
+		return err
+	}
+	if config.Version == "" || compareVersions(config.Version, projectKeysVersion) >= 0 {
+		return nil
+	}
+	return file.SetField("version", projectKeysVersion)
+}
+
+// ja: writtenProjectKeys は project のうち値を持つ（設定ファイルに書き込まれる）キーを返します
+// en: writtenProjectKeys returns the keys of project that hold a value (and so are written to the config file)
+func writtenProjectKeys(project *Project) []string {
+	var keys []string
+	for _, key := range fieldKeys(project) {
+		if field, err := lookupField(project, key); err == nil && !field.isEmpty() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ja: isDefaultValue は YAML から読み込んだ値が既定値と同じかを返します（null と空のリストは既定値とみなします）
+// en: isDefaultValue reports whether a value read from YAML equals the default (null and empty lists count as defaults)
+func isDefaultValue(value, defaultValue any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []any:
+		return len(v) == 0
+	default:
+		return value == defaultValue
+	}
+}
+
+// ja: sortedKeys はマップのキーを並べて返します
+// en: sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ja: migrateResult は config migrate コマンドの構造化出力です
+// en: migrateResult is the structured output of the config migrate command
+type migrateResult struct {
+	ConfigPath string `json:"config_path" yaml:"config_path"`
+	From       string `json:"from" yaml:"from"`
+	To         string `json:"to" yaml:"to"`
+	Migrated   bool   `json:"migrated" yaml:"migrated"`
+	BackupPath string `json:"backup_path,omitempty" yaml:"backup_path,omitempty"`
+}
+
+// ja: configMigrateCmd は config migrate コマンドを表します
+// en: configMigrateCmd represents the config migrate command
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: i18n.T("migrate.short"),
+	Long:  i18n.T("migrate.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runConfigMigrate(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configMigrateCmd)
+}
+
+func runConfigMigrate() (err error) {
+	store := newConfigStore()
+	if err := store.ensureExists(); err != nil {
+		return err
+	}
+	configPath := store.Path()
+
+	file, err := configfile.Load(configPath)
+	if err != nil {
+		return fmt.Errorf(i18n.T("migrate.readError"), err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(file.Bytes(), &doc); err != nil {
+		return fmt.Errorf(i18n.T("migrate.parseError"), err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf(i18n.T("migrate.parseError"), configfile.ErrNotMapping)
+	}
+
+	versionNode := mappingValue(doc.Content[0], "version")
+	if versionNode == nil || versionNode.Value == "" {
+		return fmt.Errorf("%s", i18n.T("migrate.noVersion"))
+	}
+	from := versionNode.Value
+	if err := checkConfigVersion(from); err != nil {
+		return err
+	}
+
+	result := migrateResult{ConfigPath: configPath, From: from, To: from}
+
+	steps, err := pendingMigrations(from)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		if isStructuredOutput() {
+			return writeResult(result)
+		}
+		fmt.Printf(i18n.T("migrate.upToDate")+"\n", configPath, from)
+		return nil
+	}
+
+	// ja: 移行は行単位の編集で行い、触れない行はバイト単位でそのまま残す
+	// en: Migrations are line-level edits, so the lines they do not touch stay byte-for-byte the same
+	original := append([]byte(nil), file.Bytes()...)
+	for _, step := range steps {
+		if err := step.Upgrade(file); err != nil {
+			return fmt.Errorf(i18n.T("migrate.stepError"), step.From, step.To, err)
+		}
+		if err := file.SetField("version", step.To); err != nil {
+			return fmt.Errorf(i18n.T("migrate.stepError"), step.From, step.To, err)
+		}
+		result.To = step.To
+	}
+
+	if dryRun {
+		return showConfigChange(&configChange{
+			Command: "config migrate",
+			Path:    configPath,
+			Diff:    diffLines(original, file.Bytes()),
+		})
+	}
+
+	// ja: 実際に書き換える場合だけジャーナルに記録する
+	// en: Only record in the journal when the file is actually rewritten
+	journal := startOperation("config migrate", "")
+	defer func() { journal.finish(err) }()
+
+	// ja: 書き換える前に元のファイルを同じディレクトリにバックアップ
+	// en: Back up the original file next to it before rewriting
+	result.BackupPath = fmt.Sprintf("%s.%s.%s.bak", configPath, from, time.Now().Format("20060102_150405"))
+	if err := configfile.WriteAtomic(result.BackupPath, original, file.Mode()); err != nil {
+		return fmt.Errorf(i18n.T("migrate.backupError"), err)
+	}
+	if err := file.Save(); err != nil {
+		return fmt.Errorf(i18n.T("migrate.writeError"), err)
+	}
+	result.Migrated = true
+	journal.touch(result.BackupPath, fileCreated)
+	journal.touch(configPath, fileModified)
+	journal.note(fmt.Sprintf("%s -> %s", result.From, result.To))
+
+	if isStructuredOutput() {
+		return writeResult(result)
+	}
+	fmt.Printf(i18n.T("migrate.backedUp")+"\n", result.BackupPath)
+	fmt.Printf(i18n.T("migrate.success")+"\n", configPath, result.From, result.To)
+	return nil
+}
+
+// ja: latestConfigVersion はこのバイナリが理解できる最新の設定ファイルのバージョンを返します
+// en: latestConfigVersion returns the newest config version this binary understands
+func latestConfigVersion() string {
+	latest := baseConfigVersion
+	for _, migration := range configMigrations {
+		if compareVersions(migration.To, latest) > 0 {
+			latest = migration.To
+		}
+	}
+	return latest
+}
+
+// ja: checkConfigVersion はバイナリより新しいバージョンの設定ファイルを拒否します
+// ja: 知らないキーが黙って無視されるのを防ぐためです
+// en: checkConfigVersion rejects config files newer than this binary
+// en: This keeps unknown keys from being silently dropped
+func checkConfigVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	if _, err := parseVersion(version); err != nil {
+		return fmt.Errorf(i18n.T("migrate.invalidVersion"), version)
+	}
+	if latest := latestConfigVersion(); compareVersions(version, latest) > 0 {
+		return fmt.Errorf(i18n.T("migrate.tooNew"), version, latest)
+	}
+	return nil
+}
+
+// ja: pendingMigrations は version から最新バージョンまでに適用する移行を順に返します
+// en: pendingMigrations returns, in order, the migrations to apply from version to the latest
+func pendingMigrations(version string) ([]configMigration, error) {
+	migrations := append([]configMigration(nil), configMigrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return compareVersions(migrations[i].From, migrations[j].From) < 0
+	})
+
+	var steps []configMigration
+	current := version
+	for _, migration := range migrations {
+		if compareVersions(migration.To, current) <= 0 {
+			continue
+		}
+		// ja: 途中のバージョンが抜けている場合は安全に移行できない
+		// en: A gap in the chain means the file cannot be migrated safely
+		if compareVersions(migration.From, current) > 0 {
+			return nil, fmt.Errorf(i18n.T("migrate.noPath"), current, migration.From)
+		}
+		steps = append(steps, migration)
+		current = migration.To
+	}
+	return steps, nil
+}
+
+// ja: parseVersion は "1"、"1.2"、"1.2.3" 形式のバージョンを数値に分解します
+// en: parseVersion splits a "1", "1.2" or "1.2.3" style version into numbers
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(fields) > len(parts) {
+		return parts, fmt.Errorf("invalid version %q", version)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version %q", version)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// ja: compareVersions は a < b なら負、a == b なら 0、a > b なら正の値を返します
+// ja: 解釈できないバージョンは 0.0.0 として扱います
+// en: compareVersions returns a negative number if a < b, zero if equal and a positive number if a > b
+// en: Versions that cannot be parsed are treated as 0.0.0
+func compareVersions(a, b string) int {
+	va, _ := parseVersion(a)
+	vb, _ := parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] - vb[i]
+		}
+	}
+	return 0
+}
+
+// ja: mappingValue はマッピングノードからキーに対応する値のノードを返します
+// en: mappingValue returns the value node for a key in a mapping node
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
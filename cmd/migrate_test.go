@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yk-lab/toske/configfile"
+	"gopkg.in/yaml.v3"
+)
+
+// withMigrations replaces the registered migrations for the duration of a test.
+func withMigrations(t *testing.T, migrations []configMigration) {
+	t.Helper()
+	original := configMigrations
+	configMigrations = migrations
+	t.Cleanup(func() { configMigrations = original })
+}
+
+// configTemplateAt returns the init template declaring the given version.
+func configTemplateAt(version string) string {
+	return strings.Replace(getConfigTemplate(), "version: "+latestConfigVersion(), "version: "+version, 1)
+}
+
+// renameRetention is a sample migration renaming backup_retention to keep in every project.
+var renameRetention = configMigration{
+	From: "1.0.0",
+	To:   "1.1.0",
+	Upgrade: func(file *configfile.File) error {
+		var config struct {
+			Projects []map[string]any `yaml:"projects"`
+		}
+		if err := yaml.Unmarshal(file.Bytes(), &config); err != nil {
+			return err
+		}
+		for _, project := range config.Projects {
+			retention, ok := project["backup_retention"]
+			if !ok {
+				continue
+			}
+			name := project["name"].(string)
+			if err := file.DeleteProjectField(name, "backup_retention"); err != nil {
+				return err
+			}
+			if err := file.SetProjectField(name, "keep", retention); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1.0.0", b: "1.0.0", expected: 0},
+		{a: "1.0", b: "1.0.0", expected: 0},
+		{a: "v1", b: "1.0.0", expected: 0},
+		{a: "1.0.1", b: "1.0.0", expected: 1},
+		{a: "1.2.0", b: "1.10.0", expected: -1},
+		{a: "2.0.0", b: "1.9.9", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			got := compareVersions(tt.a, tt.b)
+			if (got > 0) != (tt.expected > 0) || (got < 0) != (tt.expected < 0) {
+				t.Errorf("compareVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckConfigVersion(t *testing.T) {
+	withMigrations(t, []configMigration{renameRetention})
+
+	tests := []struct {
+		version     string
+		expectedErr string
+	}{
+		{version: "1.0.0"},
+		{version: "1.1.0"},
+		{version: ""},
+		{version: "1.2.0", expectedErr: "only understands up to 1.1.0"},
+		{version: "one", expectedErr: "Invalid configuration version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			err := checkConfigVersion(tt.version)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	second := configMigration{From: "1.1.0", To: "2.0.0", Upgrade: func(*configfile.File) error { return nil }}
+	withMigrations(t, []configMigration{second, renameRetention})
+
+	steps, err := pendingMigrations("1.0.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(steps) != 2 || steps[0].To != "1.1.0" || steps[1].To != "2.0.0" {
+		t.Errorf("Expected both migrations in order, got %+v", steps)
+	}
+
+	if steps, _ := pendingMigrations("1.1.0"); len(steps) != 1 {
+		t.Errorf("Expected one pending migration from 1.1.0, got %d", len(steps))
+	}
+
+	withMigrations(t, []configMigration{second})
+	if _, err := pendingMigrations("1.0.0"); err == nil {
+		t.Error("Expected an error for a gap in the migration chain")
+	}
+}
+
+func TestRunConfigMigrate(t *testing.T) {
+	withMigrations(t, []configMigration{renameRetention})
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, configTemplateAt("1.0.0")))
+	configPath := cfgFile
+
+	output, err := captureStdout(t, runConfigMigrate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "from 1.0.0 to 1.1.0") {
+		t.Errorf("Expected a success message, got:\n%s", output)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	content := string(data)
+	for _, expected := range []string{"version: 1.1.0\n", "    keep: 3\n", "# en: Add more projects as needed:"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected migrated config to contain %q, got:\n%s", expected, content)
+		}
+	}
+
+	// ja: 元のファイルがバックアップされていること
+	// en: The original file must have been backed up
+	backups, _ := filepath.Glob(configPath + ".1.0.0.*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup, got %v", backups)
+	}
+	backup, _ := os.ReadFile(backups[0])
+	if string(backup) != configTemplateAt("1.0.0") {
+		t.Errorf("Expected the backup to hold the original file, got:\n%s", backup)
+	}
+
+	// ja: 2 回目は何もしない
+	// en: A second run does nothing
+	output, err = captureStdout(t, runConfigMigrate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "already at the current version (1.1.0)") {
+		t.Errorf("Expected an up-to-date message, got:\n%s", output)
+	}
+}
+
+func TestNewerConfigVersionIsRejected(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, configTemplateAt("9.0.0")))
+	before, _ := os.ReadFile(cfgFile)
+
+	for name, run := range map[string]func() error{"list": runList, "migrate": runConfigMigrate} {
+		t.Run(name, func(t *testing.T) {
+			_, err := captureStdout(t, run)
+			if err == nil || !strings.Contains(err.Error(), "only understands up to") {
+				t.Errorf("Expected a newer-version error, got: %v", err)
+			}
+		})
+	}
+
+	after, _ := os.ReadFile(cfgFile)
+	if string(before) != string(after) {
+		t.Error("Config must not change")
+	}
+}
+
+func TestCheckConfigFileSuggestsMigration(t *testing.T) {
+	withMigrations(t, []configMigration{renameRetention})
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, configTemplateAt("1.0.0")))
+
+	_, findings := checkConfigFile(newConfigStore())
+	if len(findings) != 2 || findings[0].Severity != severityWarning || !strings.Contains(findings[0].Suggestion, "toske config migrate") {
+		t.Errorf("Expected a migration warning before the OK finding, got %+v", findings)
+	}
+}
+
+func TestRunConfigMigrateKeepsCommentsAndOrder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(setupTestConfig(t, `# team config
+version: 1.0.0 # schema
+projects:
+  # the main app
+  - name: app
+    branch: main # stable
+    repo: git@github.com:user/app.git
+    vcs: git
+    backup_paths:
+      - .env # secrets
+    archive_mode: paths
+    include_git_state: false
+    backup_retention: 3
+  - name: site
+    repo: git@github.com:user/site.git
+    branch: main
+    archive_mode: full
+    exclude: []
+    skip_git_features:
+      - lfs
+
+# trailing notes
+`))
+
+	if _, err := captureStdout(t, runConfigMigrate); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// ja: 既定値を書いただけのキーと version の値以外は、コメントも順序もそのまま
+	// en: Apart from the keys restating defaults and the version value, comments and order stay as they were
+	expected := `# team config
+version: 1.1.0 # schema
+projects:
+  # the main app
+  - name: app
+    branch: main # stable
+    repo: git@github.com:user/app.git
+    backup_paths:
+      - .env # secrets
+    backup_retention: 3
+  - name: site
+    repo: git@github.com:user/site.git
+    branch: main
+    archive_mode: full
+    skip_git_features:
+      - lfs
+
+# trailing notes
+`
+	data, _ := os.ReadFile(cfgFile)
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestNewProjectKeysRaiseVersion(t *testing.T) {
+	t.Run("set", func(t *testing.T) {
+		configPath := setupSetTest(t)
+		if err := os.WriteFile(configPath, []byte(configTemplateAt("1.0.0")), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		setProjectName = "sample-project"
+
+		// ja: 1.0.0 からあるキーでは version はそのまま
+		// en: Keys that 1.0.0 already had leave the version alone
+		if _, err := captureStdout(t, func() error { return runSet([]string{"branch", "develop"}) }); err != nil {
+			t.Fatalf("runSet failed: %v", err)
+		}
+		if version := loadVersionFromFile(t, configPath); version != "1.0.0" {
+			t.Errorf("Expected version 1.0.0 to be kept, got %s", version)
+		}
+
+		if _, err := captureStdout(t, func() error { return runSet([]string{"archive_mode", "full"}) }); err != nil {
+			t.Fatalf("runSet failed: %v", err)
+		}
+		if version := loadVersionFromFile(t, configPath); version != projectKeysVersion {
+			t.Errorf("Expected version %s after writing archive_mode, got %s", projectKeysVersion, version)
+		}
+	})
+
+	t.Run("add", func(t *testing.T) {
+		checkoutDir, configPath := setupAddTest(t)
+		if err := os.WriteFile(configPath, []byte(configTemplateAt("1.0.0")), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		addNonInteractive = true
+		addNoSuggest = true
+		addExclude = []string{"node_modules"}
+		addArchiveMode = "full"
+
+		if _, err := captureStdout(t, func() error { return runAdd(checkoutDir) }); err != nil {
+			t.Fatalf("runAdd failed: %v", err)
+		}
+		if version := loadVersionFromFile(t, configPath); version != projectKeysVersion {
+			t.Errorf("Expected version %s after adding a project with archive_mode, got %s", projectKeysVersion, version)
+		}
+	})
+}
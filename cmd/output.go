@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: 出力形式
+// en: Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// ja: outputFormat は --output フラグで指定された出力形式です
+// en: outputFormat is the output format selected with the --output flag
+var outputFormat = outputTable
+
+// ja: 出力の詳しさ（--quiet / --verbose）
+// en: Output verbosity levels (--quiet / --verbose)
+type verbosity int
+
+const (
+	verbosityQuiet verbosity = iota
+	verbosityNormal
+	verbosityVerbose
+)
+
+// ja: quietOutput と verboseOutput は --quiet と --verbose フラグの値です
+// en: quietOutput and verboseOutput hold the values of the --quiet and --verbose flags
+var (
+	quietOutput   bool
+	verboseOutput bool
+)
+
+// ja: outputVerbosity はフラグから出力の詳しさを返します
+// en: outputVerbosity returns the output verbosity selected by the flags
+func outputVerbosity() verbosity {
+	switch {
+	case quietOutput:
+		return verbosityQuiet
+	case verboseOutput:
+		return verbosityVerbose
+	default:
+		return verbosityNormal
+	}
+}
+
+// ja: validateVerbosity は --quiet と --verbose が同時に指定されていないかを確認します
+// en: validateVerbosity checks that --quiet and --verbose are not used together
+func validateVerbosity() error {
+	if quietOutput && verboseOutput {
+		return fmt.Errorf("%s", i18n.T("output.quietVerbose"))
+	}
+	return nil
+}
+
+// ja: validateOutputFormat は出力形式が対応しているものかを確認します
+// en: validateOutputFormat checks that the output format is supported
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	default:
+		return fmt.Errorf(i18n.T("output.invalidFormat"), outputFormat)
+	}
+}
+
+// ja: isStructuredOutput は JSON または YAML で出力するかを返します
+// en: isStructuredOutput reports whether output is JSON or YAML
+func isStructuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// ja: messageWriter は人間向けメッセージの出力先を返します
+// ja: 構造化出力時は標準出力を結果専用にするため、メッセージは標準エラー出力に送ります
+// en: messageWriter returns where human-readable messages go
+// en: With structured output, stdout is reserved for the result, so messages go to stderr
+func messageWriter() io.Writer {
+	if isStructuredOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// ja: msgf は人間向けメッセージを書式付きで出力します
+// en: msgf prints a formatted human-readable message
+func msgf(format string, a ...any) {
+	fmt.Fprintf(messageWriter(), format, a...)
+}
+
+// ja: msgln は人間向けメッセージを改行付きで出力します
+// en: msgln prints a human-readable message followed by a newline
+func msgln(a ...any) {
+	fmt.Fprintln(messageWriter(), a...)
+}
+
+// ja: writeResult は結果を選択された構造化形式で標準出力に書き出します
+// en: writeResult writes the result to stdout in the selected structured format
+func writeResult(result any) error {
+	switch outputFormat {
+	case outputYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+}
+
+// ja: nonNil は nil スライスを空スライスに置き換えます（JSON で null ではなく [] を出力するため）
+// en: nonNil replaces a nil slice with an empty one (so JSON shows [] rather than null)
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
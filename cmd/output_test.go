@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yk-lab/toske/toske"
+	"gopkg.in/yaml.v3"
+)
+
+// setOutputFormat switches the global output format for the duration of the test.
+func setOutputFormat(t *testing.T, format string) {
+	t.Helper()
+	original := outputFormat
+	outputFormat = format
+	t.Cleanup(func() { outputFormat = original })
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	tests := []struct {
+		format      string
+		expectError bool
+	}{
+		{format: "table"},
+		{format: "json"},
+		{format: "yaml"},
+		{format: "xml", expectError: true},
+		{format: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			setOutputFormat(t, tt.format)
+			err := validateOutputFormat()
+			if tt.expectError && err == nil {
+				t.Error("Expected error but got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunListStructuredOutput(t *testing.T) {
+	configData := `version: 1.0.0
+projects:
+  - name: project-a
+    repo: git@github.com:user/a.git
+    branch: main
+    backup_paths:
+      - .env
+  - name: project-b
+    repo: git@github.com:user/b.git
+    branch: develop
+`
+	defer setupTestConfig(t, configData)()
+
+	t.Run("json", func(t *testing.T) {
+		setOutputFormat(t, outputJSON)
+
+		output, err := captureStdout(t, runList)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result listResult
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+		}
+		if result.Total != 2 || result.Projects[0].Name != "project-a" || result.Projects[1].Branch != "develop" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+		if !strings.Contains(output, `"backup_paths"`) {
+			t.Errorf("Expected snake_case keys in JSON output, got:\n%s", output)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		setOutputFormat(t, outputYAML)
+
+		output, err := captureStdout(t, runList)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var result listResult
+		if err := yaml.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Failed to parse YAML output: %v\n%s", err, output)
+		}
+		if result.Total != 2 || result.Projects[0].BackupPaths[0] != ".env" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+func TestRunValidateStructuredOutput(t *testing.T) {
+	configData := `version: 1.0.0
+projects:
+  - name: project-a
+    repo: git@github.com:user/a.git
+  - name: project-b
+    branch: main
+`
+	defer setupTestConfig(t, configData)()
+	setOutputFormat(t, outputJSON)
+
+	output, err := captureStdout(t, runValidate)
+	if err == nil {
+		t.Fatal("Expected validation error but got nil")
+	}
+
+	var result validateResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+
+	if result.Valid {
+		t.Error("Expected valid to be false")
+	}
+	if len(result.Errors) != 2 {
+		t.Fatalf("Expected 2 errors (one per project), got %d: %v", len(result.Errors), result.Errors)
+	}
+	if !strings.Contains(result.Errors[0], "project-a") || !strings.Contains(result.Errors[1], "project-b") {
+		t.Errorf("Unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestRunBackupStructuredOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(filepath.Join(workDir, "config"), 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	if err := os.WriteFile(filepath.Join(workDir, ".env"), []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "config", "app.conf"), []byte("config"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	configData := `version: 1.0.0
+projects:
+  - name: json-backup
+    repo: git@github.com:user/test.git
+    branch: main
+    path: ` + filepath.ToSlash(workDir) + `
+    backup_paths:
+      - .env
+      - config/
+      - missing.db
+`
+	defer setupTestConfig(t, configData)()
+	setOutputFormat(t, outputJSON)
+
+	originalProjectName := projectName
+	projectName = "json-backup"
+	defer func() { projectName = originalProjectName }()
+
+	output, err := captureStdout(t, runBackup)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	var result toske.BackupResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected stdout to contain only the JSON result: %v\n%s", err, output)
+	}
+
+	if result.Project != "json-backup" || result.Size == 0 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if _, err := os.Stat(result.Archive); err != nil {
+		t.Errorf("Expected archive %s to exist: %v", result.Archive, err)
+	}
+	if len(result.Files) != 2 || result.Files[0].Path != ".env" || result.Files[0].Size != int64(len("TEST=value")) {
+		t.Errorf("Unexpected files: %+v", result.Files)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "missing.db" {
+		t.Errorf("Unexpected skipped files: %v", result.Skipped)
+	}
+}
+
+func TestRunRestoreStructuredOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	if err := createTestBackup(tempDir, "json-restore", []testFile{{name: ".env", content: "TEST=value"}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+
+	// ja: パストラバーサルを含むエントリを最新のアーカイブに追記する
+	// en: Rewrite the archive with an extra path traversal entry
+	backupDir := filepath.Join(tempDir, ".config", "toske", "backups", "json-restore")
+	metadata, err := toske.LoadMetadata(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	archivePath := filepath.Join(backupDir, metadata.Backups[0].Filename)
+	writeArchiveWithTraversal(t, archivePath)
+
+	configData := `version: 1.0.0
+projects:
+  - name: json-restore
+    repo: git@github.com:user/test.git
+    branch: main
+    path: ` + filepath.ToSlash(workDir) + `
+`
+	defer setupTestConfig(t, configData)()
+	setOutputFormat(t, outputJSON)
+
+	originalRestoreProjectName := restoreProjectName
+	originalForce := forceRestore
+	originalIndex := backupIndex
+	restoreProjectName = "json-restore"
+	forceRestore = true
+	backupIndex = 1
+	defer func() {
+		restoreProjectName = originalRestoreProjectName
+		forceRestore = originalForce
+		backupIndex = originalIndex
+	}()
+
+	output, err := captureStdout(t, runRestore)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	var result toske.RestoreResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Expected stdout to contain only the JSON result: %v\n%s", err, output)
+	}
+
+	if len(result.Files) != 1 || result.Files[0] != ".env" {
+		t.Errorf("Unexpected restored files: %v", result.Files)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Reason != toske.SkipOutsideDir {
+		t.Errorf("Expected one file skipped as %s, got: %+v", toske.SkipOutsideDir, result.Skipped)
+	}
+}
+
+// writeArchiveWithTraversal overwrites archivePath with a safe file and a path traversal entry.
+func writeArchiveWithTraversal(t *testing.T, archivePath string) {
+	t.Helper()
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer archiveFile.Close()
+
+	gzipWriter := gzip.NewWriter(archiveFile)
+	defer gzipWriter.Close()
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	for name, content := range map[string]string{".env": "TEST=value", "../evil.txt": "evil"} {
+		header := &tar.Header{Name: name, Size: int64(len(content)), Mode: 0644}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write content: %v", err)
+		}
+	}
+}
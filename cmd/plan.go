@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: dryRun は --dry-run フラグの値です。true の場合、変更系のコマンドは計画を表示するだけで何も変更しません
+// en: dryRun holds the --dry-run flag; when true, mutating commands only show their plan and change nothing
+var dryRun bool
+
+// ja: dryRunAnnotation は --dry-run に対応していない変更系コマンドに付ける注釈のキーです
+// en: dryRunAnnotation is the annotation key set on mutating commands that do not support --dry-run
+const (
+	dryRunAnnotation  = "toske/dry-run"
+	dryRunUnsupported = "unsupported"
+)
+
+// ja: configDiffContext は設定ファイルの差分で変更箇所の前後に表示する行数です
+// en: configDiffContext is the number of unchanged lines shown around a change in a config diff
+const configDiffContext = 2
+
+// ja: configChange は設定ファイルへの変更の計画です
+// en: configChange is a planned change to the config file
+type configChange struct {
+	Command string   `json:"command" yaml:"command"`
+	Project string   `json:"project,omitempty" yaml:"project,omitempty"`
+	Path    string   `json:"path" yaml:"path"`
+	Diff    []string `json:"diff" yaml:"diff"`
+}
+
+// ja: checkDryRun は --dry-run に対応していない変更系コマンドで --dry-run が指定されていないかを確認します
+// ja: 黙って実行してしまわないよう、エラーにします
+// en: checkDryRun rejects --dry-run on mutating commands that do not support it
+// en: It is an error so that the command is never run for real by mistake
+func checkDryRun(cmd *cobra.Command) error {
+	if dryRun && cmd.Annotations[dryRunAnnotation] == dryRunUnsupported {
+		return fmt.Errorf(i18n.T("dryRun.unsupported"), cmd.CommandPath())
+	}
+	return nil
+}
+
+// ja: planConfigEdit は command が設定ファイルに加える変更の計画を作ります
+// en: planConfigEdit builds the plan of the change command would make to the config file
+func planConfigEdit(store *configStore, command, project string, edit func(file *configfile.File) error) (*configChange, error) {
+	change, err := store.PlanEdit(edit)
+	if err != nil {
+		return nil, err
+	}
+	change.Command = command
+	change.Project = project
+	return change, nil
+}
+
+// ja: showPlan は --dry-run の計画を表示します（構造化出力ではそのまま書き出します）
+// en: showPlan displays a --dry-run plan (written as is with structured output)
+func showPlan(plan any, print func()) error {
+	if isStructuredOutput() {
+		return writeResult(plan)
+	}
+	print()
+	fmt.Println()
+	fmt.Println(i18n.T("dryRun.notice"))
+	return nil
+}
+
+// ja: showBackupPlan はバックアップの計画を表示します
+// en: showBackupPlan displays a backup plan
+// ja: removeTree が true の場合は、バックアップの後にディレクトリを削除することも表示します
+// en: When removeTree is true, it also shows that the directory will be removed after the backup
+func showBackupPlan(plan *toske.BackupPlan, removeTree bool) error {
+	plan.Files = nonNil(plan.Files)
+	plan.Missing = nonNil(plan.Missing)
+	plan.Dumps = nonNil(plan.Dumps)
+	plan.Prune = nonNil(plan.Prune)
+	return showPlan(plan, func() { printBackupPlan(plan, removeTree) })
+}
+
+// ja: printBackupPlan はバックアップの計画を人間向けに表示します
+// en: printBackupPlan prints a backup plan for humans
+func printBackupPlan(plan *toske.BackupPlan, removeTree bool) {
+	msgf(i18n.T("plan.backupTitle")+"\n", plan.Project)
+	msgf(i18n.T("plan.backupDir")+"\n", plan.BackupDir)
+	if plan.ArchiveMode == toske.ArchiveModeFull {
+		msgln(i18n.T("plan.fullArchive"))
+	}
+	msgf(i18n.T("plan.files")+"\n", len(plan.Files), formatBytes(plan.Size))
+	for _, file := range plan.Files {
+		msgf(i18n.T("plan.file")+"\n", file.Path, formatBytes(file.Size))
+	}
+	for _, missing := range plan.Missing {
+		msgf(i18n.T("backup.fileNotFound")+"\n", missing)
+	}
+	for _, unsupported := range plan.Unsupported {
+		msgf(i18n.T("plan.unsupported")+"\n", unsupported)
+	}
+	for _, dump := range plan.Dumps {
+		msgf(i18n.T("plan.dump")+"\n", dump)
+	}
+	if plan.GitState {
+		msgln(i18n.T("plan.gitState"))
+	}
+	if len(plan.Prune) > 0 {
+		msgln(i18n.T("plan.prune"))
+		for _, name := range plan.Prune {
+			msgf(i18n.T("plan.pruneItem")+"\n", name)
+		}
+	}
+	if removeTree {
+		msgln(i18n.T("plan.removeTree"))
+	}
+}
+
+// ja: showRestorePlan は復元の計画を表示します
+// en: showRestorePlan displays a restore plan
+func showRestorePlan(plan *toske.RestorePlan) error {
+	plan.Files = nonNil(plan.Files)
+	plan.Skipped = nonNil(plan.Skipped)
+	plan.Dumps = nonNil(plan.Dumps)
+	return showPlan(plan, func() {
+		msgf(i18n.T("restore.selectingBackup")+"\n", plan.Record.Filename, plan.Record.Timestamp.Format("2006-01-02 15:04:05"))
+		printRestorePlan(plan, true)
+	})
+}
+
+// ja: restoreClonePlan はチェックアウトが存在しないため、復元の前にリポジトリをクローンする計画です
+// ja: 復元するファイルはクローンするまで分からないため、クローンだけを表します
+// en: restoreClonePlan is the plan to clone the repository before restoring, as the checkout does not exist
+// en: The files to restore are unknown until the clone is made, so it only describes the clone
+type restoreClonePlan struct {
+	Project string `json:"project" yaml:"project"`
+	Repo    string `json:"repo" yaml:"repo"`
+	Dir     string `json:"dir" yaml:"dir"`
+}
+
+// ja: printRestorePlan は復元されるファイルの数と一覧を表示します（確認プロンプトの前にも使います）
+// ja: all が false の場合、上書きされるファイルとスキップされるファイルだけを一覧にします
+// en: printRestorePlan prints the number and list of files a restore would write (also used before the confirmation prompt)
+// en: When all is false, only the files that would be overwritten or skipped are listed
+func printRestorePlan(plan *toske.RestorePlan, all bool) {
+	if plan.CreateDir {
+		msgln(i18n.T("plan.restoreCreateDir"))
+	}
+	msgf(i18n.T("plan.restoreSummary")+"\n", len(plan.Files), plan.Overwrites())
+	for _, file := range plan.Files {
+		switch {
+		case file.Overwrite:
+			msgf(i18n.T("plan.restoreOverwrite")+"\n", file.Path, formatBytes(file.Size))
+		case all:
+			msgf(i18n.T("plan.restoreNew")+"\n", file.Path, formatBytes(file.Size))
+		}
+	}
+	for _, skipped := range plan.Skipped {
+		msgf(i18n.T("plan.restoreSkipped")+"\n", skipped.Path, skipped.Reason)
+	}
+	for _, dump := range plan.Dumps {
+		msgf(i18n.T("plan.restoreDump")+"\n", dump)
+	}
+	if work := plan.GitWork; work != nil {
+		for _, branch := range work.Branches {
+			msgf(i18n.T("plan.restoreBranch")+"\n", branch)
+		}
+		if work.Stashes > 0 {
+			msgf(i18n.T("plan.restoreStashes")+"\n", work.Stashes)
+		}
+		if work.Patch {
+			msgln(i18n.T("plan.restorePatch"))
+		}
+	}
+}
+
+// ja: showConfigChange は設定ファイルへの変更の計画を表示します
+// en: showConfigChange displays a planned change to the config file
+func showConfigChange(change *configChange) error {
+	change.Diff = nonNil(change.Diff)
+	return showPlan(change, func() { printConfigChange(change) })
+}
+
+// ja: printConfigChange は設定ファイルへの変更を差分として表示します
+// en: printConfigChange prints a change to the config file as a diff
+func printConfigChange(change *configChange) {
+	msgf(i18n.T("plan.configTitle")+"\n", change.Path)
+	if len(change.Diff) == 0 {
+		msgln(i18n.T("plan.noChanges"))
+		return
+	}
+	for _, line := range change.Diff {
+		msgf("  %s\n", line)
+	}
+}
+
+// ja: diffLines は before と after の差分を unified diff 形式の行で返します（変更がなければ nil）
+// ja: configfile は 1 か所を差し替えるだけなので、共通の先頭と末尾を除いた 1 つの塊として表します
+// en: diffLines returns the difference between before and after as unified diff lines (nil when equal)
+// en: configfile only splices one place, so the change is shown as one hunk between the common head and tail
+func diffLines(before, after []byte) []string {
+	a, b := splitLines(before), splitLines(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(a) && prefix == len(b) {
+		return nil
+	}
+
+	start := max(prefix-configDiffContext, 0)
+	trailing := min(suffix, configDiffContext)
+	endA := len(a) - suffix + trailing
+	endB := len(b) - suffix + trailing
+
+	lines := []string{fmt.Sprintf("@@ -%d,%d +%d,%d @@", start+1, endA-start, start+1, endB-start)}
+	for _, line := range a[start:prefix] {
+		lines = append(lines, " "+line)
+	}
+	for _, line := range a[prefix : len(a)-suffix] {
+		lines = append(lines, "-"+line)
+	}
+	for _, line := range b[prefix : len(b)-suffix] {
+		lines = append(lines, "+"+line)
+	}
+	for _, line := range a[len(a)-suffix : endA] {
+		lines = append(lines, " "+line)
+	}
+	return lines
+}
+
+// ja: splitLines は内容を行に分割します（末尾の改行は区切りとして扱います）
+// en: splitLines splits contents into lines (a trailing newline ends the last line)
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/toske"
+)
+
+// setDryRun turns on --dry-run for the duration of the test.
+func setDryRun(t *testing.T) {
+	t.Helper()
+	original := dryRun
+	dryRun = true
+	t.Cleanup(func() { dryRun = original })
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			want:   nil,
+		},
+		{
+			name:   "changed line with context",
+			before: "1\n2\n3\n4\n5\n6\n7\n",
+			after:  "1\n2\n3\nfour\n5\n6\n7\n",
+			want:   []string{"@@ -2,5 +2,5 @@", " 2", " 3", "-4", "+four", " 5", " 6"},
+		},
+		{
+			name:   "removed lines at the end",
+			before: "a\nb\nc\n",
+			after:  "a\n",
+			want:   []string{"@@ -1,3 +1,1 @@", " a", "-b", "-c"},
+		},
+		{
+			name:   "new file",
+			before: "",
+			after:  "a\nb\n",
+			want:   []string{"@@ -1,0 +1,2 @@", "+a", "+b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines([]byte(tt.before), []byte(tt.after))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, ".env"), []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create .env: %v", err)
+	}
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	journalFile := setupJournal(t)
+	setDryRun(t)
+	setOutputFormat(t, outputJSON)
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: dry-run-test
+    repo: git@github.com:user/test.git
+    branch: main
+    path: `+workDir+`
+    backup_paths:
+      - .env
+`)()
+
+	originalProjectName := projectName
+	defer func() { projectName = originalProjectName }()
+	projectName = "dry-run-test"
+
+	output, err := captureStdout(t, runBackup)
+	if err != nil {
+		t.Fatalf("runBackup failed: %v", err)
+	}
+
+	var plan toske.BackupPlan
+	if err := json.Unmarshal([]byte(output), &plan); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+	if len(plan.Files) != 1 || plan.Files[0].Path != ".env" || plan.Size != 10 {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+
+	if _, err := os.Stat(plan.BackupDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup directory to be created, got %v", err)
+	}
+	if _, err := os.Stat(journalFile); !os.IsNotExist(err) {
+		t.Error("Expected a dry run not to be journaled")
+	}
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	setDryRun(t)
+	config := `version: 1.0.0
+projects:
+  - name: keep
+    repo: git@github.com:user/keep.git
+  - name: drop
+    repo: git@github.com:user/drop.git
+`
+	defer setupTestConfig(t, config)()
+
+	originalName := deleteProjectName
+	defer func() { deleteProjectName = originalName }()
+	deleteProjectName = "drop"
+
+	output, err := captureStdout(t, runDelete)
+	if err != nil {
+		t.Fatalf("runDelete failed: %v", err)
+	}
+	for _, want := range []string{"-  - name: drop", "-    repo: git@github.com:user/drop.git"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected the diff to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	content, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(content) != config {
+		t.Errorf("Expected the config to be left untouched, got:\n%s", content)
+	}
+}
+
+func TestCheckDryRun(t *testing.T) {
+	setDryRun(t)
+
+	if err := checkDryRun(editCmd); err == nil {
+		t.Error("Expected --dry-run to be rejected for edit")
+	}
+	for _, cmd := range []*cobra.Command{backupCmd, restoreCmd, deleteCmd, setCmd} {
+		if err := checkDryRun(cmd); err != nil {
+			t.Errorf("Expected --dry-run to be accepted for %s, got %v", cmd.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: 進捗表示の間隔と進捗バーの幅
+// en: Progress reporting intervals and the width of the progress bar
+const (
+	progressRedrawInterval = 100 * time.Millisecond
+	progressLogInterval    = 5 * time.Second
+	progressBarWidth       = 24
+)
+
+// ja: clearLine は端末の現在の行を消去するエスケープシーケンスです
+// en: clearLine is the escape sequence that clears the current terminal line
+const clearLine = "\r\033[K"
+
+// ja: progressReporter は toske パッケージの進捗イベントを表示します
+// ja: 端末では進捗バーを標準エラー出力に描き直し、それ以外では一定間隔で進捗を 1 行ずつ出力します
+// en: progressReporter displays the progress events of the toske package
+// en: On a terminal it redraws a progress bar on stderr, otherwise it prints a progress line at intervals
+type progressReporter struct {
+	bar   io.Writer
+	tty   bool
+	level verbosity
+	now   func() time.Time
+
+	started  time.Time
+	last     time.Time
+	done     int64
+	total    int64
+	barShown bool
+}
+
+// ja: newProgressReporter は現在の出力設定に合わせた progressReporter を作成します
+// en: newProgressReporter creates a progressReporter for the current output settings
+func newProgressReporter() *progressReporter {
+	return &progressReporter{
+		bar:   os.Stderr,
+		tty:   isTerminal(os.Stderr),
+		level: outputVerbosity(),
+		now:   time.Now,
+	}
+}
+
+// ja: isTerminal は f が端末かを返します（TERM=dumb の場合は端末として扱いません）
+// en: isTerminal reports whether f is a terminal (TERM=dumb is not treated as one)
+func isTerminal(f *os.File) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ja: report は進捗イベントを 1 件表示します（toske.Options.Progress に渡します）
+// ja: 警告は --quiet でも標準エラー出力に表示し、ファイルごとの行は --verbose の場合だけ表示します
+// en: report displays a single progress event (it is passed as toske.Options.Progress)
+// en: Warnings go to stderr even with --quiet, and per-file lines are only shown with --verbose
+func (p *progressReporter) report(event toske.Event) {
+	if event.Kind != toske.EventBytes {
+		slog.Debug("progress", "event", event.Kind, "path", event.Path, "size", event.Size, "error", event.Err)
+	}
+
+	switch event.Kind {
+	case toske.EventBytes:
+		p.updateBytes(event.Size, event.Total)
+		return
+	case toske.EventPruneFailed:
+		p.warnf(i18n.T("backup.pruneError"), event.Err)
+		return
+	case toske.EventFileSkipped:
+		// ja: 安全のためのスキップは結果に含まれるので、書き込みの失敗だけを警告する
+		// en: Skips made for safety are part of the result, so only warn about write failures
+		switch event.Reason {
+		case toske.SkipCreateFailed:
+			p.warnf(i18n.T("restore.fileCreateWarning"), event.Path, event.Err)
+		case toske.SkipCopyFailed:
+			p.warnf(i18n.T("restore.fileCopyWarning"), event.Path, event.Err)
+		case toske.SkipChecksumMismatch:
+			p.warnf(i18n.T("restore.fileChecksumWarning"), event.Path)
+		}
+		return
+	case toske.EventChmodFailed:
+		p.warnf(i18n.T("restore.fileChmodWarning"), event.Path, event.Err)
+		return
+	case toske.EventDumpSkipped:
+		p.warnf(i18n.T("dump.notConfiguredWarning"), event.Path)
+		return
+	case toske.EventBranchSkipped:
+		p.warnf(i18n.T("restore.branchSkippedWarning"), event.Path)
+		return
+	case toske.EventFileUnsupported:
+		p.warnf(i18n.T("backup.fileUnsupportedWarning"), event.Path)
+		return
+	}
+
+	if p.level == verbosityQuiet {
+		return
+	}
+
+	switch event.Kind {
+	case toske.EventArchiveStarted:
+		p.printf(i18n.T("backup.creatingDir"), filepath.Dir(event.Path))
+		p.printf(i18n.T("backup.creatingArchive"), filepath.Base(event.Path))
+	case toske.EventFileMissing:
+		p.printf(i18n.T("backup.fileNotFound"), event.Path)
+	case toske.EventDumpStarted:
+		p.printf(i18n.T("dump.dumping"), event.Path, event.Provider)
+	case toske.EventMetadataUpdating:
+		p.printf("%s", i18n.T("backup.updatingMetadata"))
+	case toske.EventPruneStarted:
+		p.printf(i18n.T("backup.pruningOldBackups"), event.Count)
+	case toske.EventDumpRestoring:
+		p.printf(i18n.T("dump.restoring"), event.Path, event.Provider)
+	case toske.EventArchiveVerifying:
+		p.printf("%s", i18n.T("backup.verifyingArchive"))
+	case toske.EventGitStateSaving:
+		p.printf("%s", i18n.T("backup.savingGitState"))
+	case toske.EventGitStateRestoring:
+		p.printf("%s", i18n.T("restore.restoringGitState"))
+	case toske.EventFileAdded:
+		if p.level == verbosityVerbose {
+			p.printf(i18n.T("backup.addingFile"), event.Path)
+		}
+	case toske.EventFileExtracting:
+		if p.level == verbosityVerbose {
+			p.printf(i18n.T("restore.extractingFile"), event.Path)
+		}
+	}
+}
+
+// ja: finish は進捗バーを最新の状態で描き、改行して確定させます
+// ja: 操作が終わったら、結果を表示する前に呼び出してください
+// en: finish draws the progress bar one last time and ends its line
+// en: Call it once the operation is over, before printing the result
+func (p *progressReporter) finish() {
+	if !p.barShown {
+		return
+	}
+	fmt.Fprintf(p.bar, "%s%s\n", clearLine, p.line(p.now()))
+	p.barShown = false
+}
+
+// ja: updateBytes は処理済みのバイト数を更新し、必要なら進捗を表示します
+// en: updateBytes updates the bytes processed and displays the progress when due
+func (p *progressReporter) updateBytes(done, total int64) {
+	if p.level == verbosityQuiet {
+		return
+	}
+
+	now := p.now()
+	if p.started.IsZero() {
+		p.started = now
+		p.last = now
+	}
+	p.done, p.total = done, total
+
+	if p.tty {
+		// ja: 描き直しは間引くが、完了時は必ず描く
+		// en: Throttle the redraws, but always draw once complete
+		if p.barShown && now.Sub(p.last) < progressRedrawInterval && done < total {
+			return
+		}
+		p.last = now
+		fmt.Fprintf(p.bar, "%s%s", clearLine, p.line(now))
+		p.barShown = true
+		return
+	}
+
+	if now.Sub(p.last) < progressLogInterval {
+		return
+	}
+	p.last = now
+	msgln(p.line(now))
+}
+
+// ja: line は現在の進捗を表す 1 行（端末では進捗バー付き）を返します
+// en: line returns a single line describing the current progress (with a bar on a terminal)
+func (p *progressReporter) line(now time.Time) string {
+	var rate float64
+	if elapsed := now.Sub(p.started).Seconds(); elapsed > 0 {
+		rate = float64(p.done) / elapsed
+	}
+	throughput := formatBytes(int64(rate))
+
+	if p.total <= 0 {
+		return fmt.Sprintf(i18n.T("progress.lineUnknownTotal"), formatBytes(p.done), throughput)
+	}
+
+	done := min(p.done, p.total)
+	percent := int(done * 100 / p.total)
+	eta := "--"
+	if rate > 0 {
+		eta = time.Duration(float64(p.total-done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+	line := fmt.Sprintf(i18n.T("progress.line"), formatBytes(done), formatBytes(p.total), percent, throughput, eta)
+
+	if !p.tty {
+		return line
+	}
+	filled := progressBarWidth * percent / 100
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "] " + line
+}
+
+// ja: printf は進捗バーを消してから情報メッセージを 1 行表示します
+// en: printf clears the progress bar, then prints an informational message line
+func (p *progressReporter) printf(format string, a ...any) {
+	p.clearBar()
+	msgf(format+"\n", a...)
+}
+
+// ja: warnf は進捗バーを消してから警告を標準エラー出力に 1 行表示します
+// en: warnf clears the progress bar, then prints a warning line on stderr
+func (p *progressReporter) warnf(format string, a ...any) {
+	p.clearBar()
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+// ja: clearBar は表示中の進捗バーを消します（次の進捗イベントで描き直されます）
+// en: clearBar erases the progress bar on screen (it is redrawn on the next progress event)
+func (p *progressReporter) clearBar() {
+	if p.barShown {
+		fmt.Fprint(p.bar, clearLine)
+		p.barShown = false
+	}
+}
+
+// ja: statusf は --quiet でない場合に情報メッセージを出力します
+// en: statusf prints an informational message unless --quiet is set
+func statusf(format string, a ...any) {
+	if outputVerbosity() != verbosityQuiet {
+		msgf(format, a...)
+	}
+}
+
+// ja: statusln は --quiet でない場合に情報メッセージを改行付きで出力します
+// en: statusln prints an informational message followed by a newline unless --quiet is set
+func statusln(a ...any) {
+	if outputVerbosity() != verbosityQuiet {
+		msgln(a...)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+// fakeClock returns a now function that advances by step on every call.
+func fakeClock(step time.Duration) func() time.Time {
+	current := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		current = current.Add(step)
+		return current
+	}
+}
+
+func TestProgressReporterBar(t *testing.T) {
+	var bar bytes.Buffer
+	p := &progressReporter{bar: &bar, tty: true, level: verbosityNormal, now: fakeClock(time.Second)}
+
+	output, _ := captureStdout(t, func() error {
+		p.report(toske.Event{Kind: toske.EventBytes, Size: 0, Total: 4 << 20})
+		p.report(toske.Event{Kind: toske.EventBytes, Size: 2 << 20, Total: 4 << 20})
+		p.report(toske.Event{Kind: toske.EventDumpStarted, Path: "app.sql", Provider: "postgres"})
+		p.report(toske.Event{Kind: toske.EventBytes, Size: 4 << 20, Total: 4 << 20})
+		p.finish()
+		return nil
+	})
+
+	drawn := bar.String()
+	for _, expected := range []string{
+		"[============            ] 2.0 MiB / 4.0 MiB (50%)  2.0 MiB/s  ETA 1s",
+		"[========================] 4.0 MiB / 4.0 MiB (100%)",
+	} {
+		if !strings.Contains(drawn, expected) {
+			t.Errorf("Expected progress bar to contain %q, got %q", expected, drawn)
+		}
+	}
+	if !strings.HasSuffix(drawn, "\n") {
+		t.Error("Expected finish to end the progress bar line")
+	}
+	if strings.Count(drawn, clearLine) < 4 {
+		t.Errorf("Expected the bar to be cleared before the dump message, got %q", drawn)
+	}
+	if !strings.Contains(output, "app.sql") {
+		t.Errorf("Expected the dump message on stdout, got %q", output)
+	}
+}
+
+func TestProgressReporterPlainLines(t *testing.T) {
+	var bar bytes.Buffer
+	p := &progressReporter{bar: &bar, tty: false, level: verbosityNormal, now: fakeClock(3 * time.Second)}
+
+	output, _ := captureStdout(t, func() error {
+		for i := int64(0); i <= 4; i++ {
+			p.report(toske.Event{Kind: toske.EventBytes, Size: i << 20, Total: 4 << 20})
+		}
+		p.finish()
+		return nil
+	})
+
+	if bar.Len() != 0 {
+		t.Errorf("Expected no progress bar without a terminal, got %q", bar.String())
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a progress line every 5 seconds, got %q", output)
+	}
+	if !strings.HasPrefix(lines[0], "2.0 MiB / 4.0 MiB (50%)") {
+		t.Errorf("Unexpected progress line %q", lines[0])
+	}
+}
+
+func TestProgressReporterLevels(t *testing.T) {
+	events := []toske.Event{
+		{Kind: toske.EventArchiveStarted, Path: "/backups/demo/backup_1.tar.gz"},
+		{Kind: toske.EventFileAdded, Path: ".env", Size: 10},
+		{Kind: toske.EventBytes, Size: 10, Total: 10},
+	}
+
+	tests := []struct {
+		name        string
+		level       verbosity
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "quiet",
+			level:       verbosityQuiet,
+			notContains: []string{"backup_1.tar.gz", ".env"},
+		},
+		{
+			name:        "normal",
+			level:       verbosityNormal,
+			contains:    []string{"backup_1.tar.gz"},
+			notContains: []string{"+ .env"},
+		},
+		{
+			name:     "verbose",
+			level:    verbosityVerbose,
+			contains: []string{"backup_1.tar.gz", "+ .env"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bar bytes.Buffer
+			p := &progressReporter{bar: &bar, tty: true, level: tt.level, now: fakeClock(time.Second)}
+
+			output, _ := captureStdout(t, func() error {
+				for _, event := range events {
+					p.report(event)
+				}
+				p.finish()
+				return nil
+			})
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, got %q", expected, output)
+				}
+			}
+			for _, unexpected := range tt.notContains {
+				if strings.Contains(output, unexpected) {
+					t.Errorf("Expected output not to contain %q, got %q", unexpected, output)
+				}
+			}
+			if tt.level == verbosityQuiet && bar.Len() != 0 {
+				t.Errorf("Expected no progress bar with --quiet, got %q", bar.String())
+			}
+		})
+	}
+}
+
+func TestValidateVerbosity(t *testing.T) {
+	originalQuiet, originalVerbose := quietOutput, verboseOutput
+	defer func() { quietOutput, verboseOutput = originalQuiet, originalVerbose }()
+
+	quietOutput, verboseOutput = true, true
+	if err := validateVerbosity(); err == nil {
+		t.Error("Expected --quiet with --verbose to be rejected")
+	}
+
+	quietOutput, verboseOutput = false, true
+	if err := validateVerbosity(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if outputVerbosity() != verbosityVerbose {
+		t.Errorf("Expected verbose output, got %v", outputVerbosity())
+	}
+}
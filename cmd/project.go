@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: resolveProjectDir はプロジェクトのローカルディレクトリを返します
+// ja: path が未指定の場合はカレントディレクトリを使用します（従来の動作）
+// en: resolveProjectDir returns the local directory of a project
+// en: Falls back to the current directory when path is not set (the original behaviour)
+func resolveProjectDir(project *Project) (string, error) {
+	if project.Path == "" {
+		return os.Getwd()
+	}
+
+	path, err := expandHome(project.Path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(path)
+}
+
+// ja: backupTarget は設定のプロジェクトを toske パッケージが扱う形に変換します
+// en: backupTarget converts a configured project into the form the toske package works with
+func backupTarget(project *Project) (toske.Project, error) {
+	projectDir, err := resolveProjectDir(project)
+	if err != nil {
+		return toske.Project{}, err
+	}
+
+	return toske.Project{
+		Name:        project.Name,
+		Dir:         projectDir,
+		BackupPaths: project.BackupPaths,
+		Retention:   project.BackupRetention,
+		Dumps:       project.Dumps,
+		Repo:        project.Repo,
+		Branch:      project.Branch,
+
+		IncludeGitState: project.IncludeGitState,
+		ArchiveMode:     project.ArchiveMode,
+		Exclude:         project.Exclude,
+	}, nil
+}
+
+// ja: expandHome はパス先頭の ~ をホームディレクトリに展開します
+// en: expandHome expands a leading ~ in the path to the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
+// ja: dirExists は path がディレクトリとして存在するかを返します
+// en: dirExists reports whether path exists and is a directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ja: pathSize はファイルのサイズ、またはディレクトリ配下のファイルの合計サイズを返します
+// en: pathSize returns the size of a file, or the total size of the files under a directory
+func pathSize(path string) (int64, error) {
+	var total int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ja: looksLikeSecret はファイル名が秘密情報を含みそうかを判定します（ヒューリスティック）
+// en: looksLikeSecret reports whether a file name suggests it holds secrets (heuristic)
+func looksLikeSecret(path string) bool {
+	name := strings.ToLower(filepath.Base(filepath.FromSlash(path)))
+
+	// ja: サンプルやテンプレートはコミットされる前提のものなので除外
+	// en: Samples and templates are meant to be committed, so skip them
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+
+	if name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env") {
+		return true
+	}
+
+	switch name {
+	case ".npmrc", ".pypirc", ".netrc", ".pgpass", "master.key", "credentials", "credentials.json":
+		return true
+	}
+
+	switch filepath.Ext(name) {
+	case ".pem", ".key", ".p12", ".pfx", ".jks", ".keystore", ".tfvars":
+		return true
+	}
+
+	for _, prefix := range []string{"id_rsa", "id_ecdsa", "id_ed25519", "secret", "service-account"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ja: looksLikeDatabase はファイル名がローカルデータベースらしいかを判定します（ヒューリスティック）
+// en: looksLikeDatabase reports whether a file name suggests a local database (heuristic)
+func looksLikeDatabase(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sqlite", ".sqlite3", ".db", ".db3", ".mdb", ".accdb":
+		return true
+	}
+	return false
+}
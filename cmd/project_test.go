@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveProjectDir(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "no path uses the current directory", path: "", expected: wd},
+		{name: "absolute path", path: filepath.Join(tempDir, "src", "app"), expected: filepath.Join(tempDir, "src", "app")},
+		{name: "home directory", path: "~/src/app", expected: filepath.Join(tempDir, "src", "app")},
+		{name: "relative path", path: "src/app", expected: filepath.Join(wd, "src", "app")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveProjectDir(&Project{Name: "app", Path: tt.path})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBackupAndRestoreUseProjectPath(t *testing.T) {
+	tempDir := t.TempDir()
+	projectDir := filepath.Join(tempDir, "src", "path-test")
+	workDir := filepath.Join(tempDir, "work")
+	for _, dir := range []string{projectDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ".env"), []byte("TEST=project"), 0644); err != nil {
+		t.Fatalf("Failed to create .env: %v", err)
+	}
+	// ja: カレントディレクトリの同名ファイルは使われない
+	// en: A file with the same name in the current directory must not be used
+	if err := os.WriteFile(filepath.Join(workDir, ".env"), []byte("TEST=work"), 0644); err != nil {
+		t.Fatalf("Failed to create .env: %v", err)
+	}
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: path-test
+    repo: git@github.com:user/test.git
+    branch: main
+    path: `+projectDir+`
+    backup_paths:
+      - .env
+`)()
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	originalProjectName, originalRestoreName, originalForce := projectName, restoreProjectName, forceRestore
+	projectName, restoreProjectName, forceRestore = "path-test", "path-test", true
+	defer func() {
+		projectName, restoreProjectName, forceRestore = originalProjectName, originalRestoreName, originalForce
+	}()
+
+	if err := runBackup(); err != nil {
+		t.Fatalf("runBackup failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ".env"), []byte("TEST=changed"), 0644); err != nil {
+		t.Fatalf("Failed to change .env: %v", err)
+	}
+	if err := runRestore(); err != nil {
+		t.Fatalf("runRestore failed: %v", err)
+	}
+
+	if content, _ := os.ReadFile(filepath.Join(projectDir, ".env")); string(content) != "TEST=project" {
+		t.Errorf("Expected the project's .env to be backed up and restored, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(workDir, ".env")); string(content) != "TEST=work" {
+		t.Errorf("Expected the current directory to be left alone, got %q", content)
+	}
+}
+
+func TestBackupRejectsMissingProjectPath(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: missing-path
+    repo: git@github.com:user/test.git
+    branch: main
+    path: `+filepath.Join(tempDir, "missing")+`
+    backup_paths:
+      - .env
+`)()
+
+	originalProjectName := projectName
+	projectName = "missing-path"
+	defer func() { projectName = originalProjectName }()
+
+	if err := runBackup(); err == nil {
+		t.Error("Expected an error for a missing project directory")
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var reindexProjectName string
+
+// ja: reindexCmd は reindex コマンドを表します
+// en: reindexCmd represents the reindex command
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: i18n.T("reindex.short"),
+	Long:  i18n.T("reindex.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runReindex(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reindexCmd)
+	reindexCmd.Flags().StringVarP(&reindexProjectName, "project", "p", "", i18n.T("reindex.flag.project"))
+	reindexCmd.MarkFlagRequired("project")
+}
+
+func runReindex() (err error) {
+	if reindexProjectName == "" {
+		return fmt.Errorf("%s", i18n.T("reindex.noProjectFlag"))
+	}
+
+	journal := startOperation("reindex", reindexProjectName)
+	defer func() { journal.finish(err) }()
+
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+	project, err := store.FindProject(config, reindexProjectName)
+	if err != nil {
+		return err
+	}
+
+	// ja: backup_paths はアーカイブのエントリを記録に対応付けるためだけに使うので、ディレクトリの解決は不要
+	// en: backup_paths are only used to map archive entries to records, so the project directory is not resolved
+	target := toske.Project{Name: project.Name, BackupPaths: project.BackupPaths}
+	opts := toskeOptions()
+
+	var result *toske.ReindexResult
+	if dryRun {
+		result, err = toske.PlanReindex(target, opts)
+	} else {
+		result, err = toske.Reindex(target, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Backups = nonNil(result.Backups)
+	result.Added = nonNil(result.Added)
+	result.Dropped = nonNil(result.Dropped)
+	result.Unreadable = nonNil(result.Unreadable)
+
+	if dryRun {
+		return showPlan(result, func() { printReindexResult(result) })
+	}
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return err
+	}
+	journal.touch(filepath.Join(backupDir, "backups.yaml"), fileModified)
+
+	if isStructuredOutput() {
+		return writeResult(result)
+	}
+	printReindexResult(result)
+	return nil
+}
+
+// ja: printReindexResult は作り直したメタデータの概要を表示します
+// ja: 読み込めなかったアーカイブは --quiet でも標準エラー出力に警告します
+// en: printReindexResult prints a summary of the rebuilt metadata
+// en: Unreadable archives are warned about on stderr even with --quiet
+func printReindexResult(result *toske.ReindexResult) {
+	for _, unreadable := range result.Unreadable {
+		fmt.Fprintf(os.Stderr, i18n.T("reindex.unreadable")+"\n", unreadable.Filename, unreadable.Error)
+	}
+	if outputVerbosity() == verbosityQuiet {
+		return
+	}
+
+	msgf(i18n.T("reindex.summary")+"\n", result.Project, len(result.Backups), len(result.Added), len(result.Dropped))
+	for _, name := range result.Added {
+		msgf(i18n.T("reindex.added")+"\n", name)
+	}
+	for _, name := range result.Dropped {
+		msgf(i18n.T("reindex.dropped")+"\n", name)
+	}
+}
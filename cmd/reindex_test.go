@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const reindexTestConfig = `version: 1.0.0
+projects:
+  - name: myapp
+    repo: git@github.com:user/myapp.git
+    branch: main
+    backup_paths:
+      - .env
+`
+
+func TestReindexRebuildsMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	defer setupTestConfig(t, reindexTestConfig)()
+
+	for _, content := range []string{"OLD=1", "NEW=1"} {
+		if err := createTestBackup(tempDir, "myapp", []testFile{{name: ".env", content: content}}); err != nil {
+			t.Fatalf("Failed to create test backup: %v", err)
+		}
+	}
+	backupDir := filepath.Join(tempDir, ".config", "toske", "backups", "myapp")
+	if err := os.Remove(filepath.Join(backupDir, "backups.yaml")); err != nil {
+		t.Fatalf("Failed to remove metadata: %v", err)
+	}
+
+	original := reindexProjectName
+	t.Cleanup(func() { reindexProjectName = original })
+	reindexProjectName = "myapp"
+
+	t.Run("dry run leaves the metadata alone", func(t *testing.T) {
+		setDryRun(t)
+		output, err := captureStdout(t, runReindex)
+		if err != nil {
+			t.Fatalf("runReindex failed: %v", err)
+		}
+		if !strings.Contains(output, "2 backup(s)") {
+			t.Errorf("Expected the plan to list both archives, got:\n%s", output)
+		}
+		if _, err := os.Stat(filepath.Join(backupDir, "backups.yaml")); !os.IsNotExist(err) {
+			t.Errorf("Expected no metadata to be written, got %v", err)
+		}
+	})
+
+	if _, err := captureStdout(t, runReindex); err != nil {
+		t.Fatalf("runReindex failed: %v", err)
+	}
+	if got := listBackupFiles(t, "myapp"); len(got) != 2 || !got[0].Timestamp.After(got[1].Timestamp) {
+		t.Errorf("Expected both backups to be indexed newest first, got %+v", got)
+	}
+}
+
+func TestImportArchive(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	defer setupTestConfig(t, reindexTestConfig)()
+
+	original := importArchiveProjectName
+	t.Cleanup(func() { importArchiveProjectName = original })
+	importArchiveProjectName = "myapp"
+
+	source := filepath.Join(tempDir, "old-env.tar")
+	file, err := os.Create(source)
+	if err != nil {
+		t.Fatalf("Failed to create tar: %v", err)
+	}
+	tw := tar.NewWriter(file)
+	if err := tw.WriteHeader(&tar.Header{Name: ".env", Mode: 0644, Size: 5}); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	tw.Write([]byte("OLD=1"))
+	tw.Close()
+	file.Close()
+
+	run := func() error { return runImportArchive([]string{source}) }
+	if _, err := captureStdout(t, run); err != nil {
+		t.Fatalf("runImportArchive failed: %v", err)
+	}
+
+	records := listBackupFiles(t, "myapp")
+	if len(records) != 1 || !strings.HasPrefix(records[0].Filename, "backup_") || !strings.HasSuffix(records[0].Filename, ".tar.gz") {
+		t.Fatalf("Expected the tar to be adopted as a backup_*.tar.gz archive, got %+v", records)
+	}
+	if len(records[0].Files) != 1 || records[0].Files[0] != ".env" {
+		t.Errorf("Expected .env to be recorded, got %v", records[0].Files)
+	}
+
+	output, err := captureStdout(t, run)
+	if err != nil {
+		t.Fatalf("runImportArchive failed: %v", err)
+	}
+	if got := listBackupFiles(t, "myapp"); len(got) != 1 {
+		t.Errorf("Expected the second import to be skipped, got %+v", got)
+	}
+	if !strings.Contains(output, "already") {
+		t.Errorf("Expected the duplicate to be reported, got:\n%s", output)
+	}
+}
@@ -7,9 +7,8 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/yk-lab/toske/configfile"
 	"github.com/yk-lab/toske/i18n"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -38,42 +37,52 @@ func init() {
 	removeCmd.MarkFlagRequired("project")
 }
 
-func runRemove() error {
+func runRemove() (err error) {
 	// ja: プロジェクト名の前後の空白を削除
 	// en: Trim leading and trailing whitespace from project name
 	removeProjectName = strings.TrimSpace(removeProjectName)
 
+	journal := startOperation("remove", removeProjectName)
+	defer func() { journal.finish(err) }()
+
 	// ja: プロジェクト名が空でないかチェック (Cobra の MarkFlagRequired のバックアップ)
 	// en: Check project name is not empty (backup for Cobra's MarkFlagRequired)
 	if removeProjectName == "" {
 		return fmt.Errorf("%s", i18n.T("remove.noProjectFlag"))
 	}
 
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
+	// ja: プロジェクトを定義しているファイル（include したファイルの場合もある）を読み込み、指定されたプロジェクトを検索
+	// en: Load the file defining the project (which may be an included file) and find the specified project
+	store, err := newConfigStore().projectSource(removeProjectName)
+	if err != nil {
+		return err
+	}
+	config, err := store.LoadStored()
+	if err != nil {
+		return err
+	}
+	project, err := store.FindProject(config, removeProjectName)
+	if err != nil {
+		return err
 	}
 
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("remove.noConfig"), configPath)
+	// ja: 設定から外す前に、チェックアウトに残る作業を警告し、--force がなければ断る（vcs が不明でも設定からは外せるようにする）
+	// en: Warn about work left in the checkout before taking it out of the config, refusing without --force (an unknown vcs must not block this)
+	if v, err := newVCS(project, gitRunner); err == nil {
+		if err := v.checkDelete(project, removeForce); err != nil {
+			return err
+		}
 	}
 
-	// ja: 設定ファイルを読み込む
-	// en: Load configuration file
-	config, err := loadRemoveConfig(configPath)
+	change, err := planConfigEdit(store, "remove", removeProjectName, func(file *configfile.File) error { return file.RemoveProject(removeProjectName) })
 	if err != nil {
 		return err
 	}
-
-	// ja: 指定されたプロジェクトを検索
-	// en: Find the specified project
-	projectIndex := findRemoveProjectIndex(config.Projects, removeProjectName)
-	if projectIndex == -1 {
-		return fmt.Errorf(i18n.T("remove.projectNotFound"), removeProjectName)
+	if dryRun {
+		return showConfigChange(change)
+	}
+	if !removeForce {
+		printConfigChange(change)
 	}
 
 	// ja: 削除確認
@@ -83,54 +92,23 @@ func runRemove() error {
 		return err
 	}
 	if !confirmed {
+		journal.cancel()
 		fmt.Println(i18n.T("remove.cancelled"))
 		return nil
 	}
 
-	// ja: プロジェクトを削除
-	// en: Remove the project
-	config.Projects = append(config.Projects[:projectIndex], config.Projects[projectIndex+1:]...)
-
-	// ja: 設定ファイルを保存
-	// en: Save configuration file
-	if err := saveRemoveConfig(configPath, &config); err != nil {
+	// ja: プロジェクトを削除して設定ファイルを保存
+	// en: Remove the project and save the configuration file
+	if err := removeProjectFromConfig(store.Path(), removeProjectName); err != nil {
 		return err
 	}
+	journal.touch(store.Path(), fileModified)
 
 	fmt.Printf(i18n.T("remove.success")+"\n", removeProjectName)
 
 	return nil
 }
 
-// ja: loadRemoveConfig は設定ファイルを読み込みます
-// en: loadRemoveConfig loads the configuration file
-func loadRemoveConfig(configPath string) (Config, error) {
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return Config{}, fmt.Errorf(i18n.T("remove.readError"), err)
-	}
-
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return Config{}, fmt.Errorf(i18n.T("remove.parseError"), err)
-	}
-
-	return config, nil
-}
-
-// ja: findRemoveProjectIndex はプロジェクト名からインデックスを検索します
-// en: findRemoveProjectIndex finds the index of a project by name
-func findRemoveProjectIndex(projects []Project, name string) int {
-	for i := range projects {
-		if projects[i].Name == name {
-			return i
-		}
-	}
-	return -1
-}
-
 // ja: confirmRemoval は削除の確認を行います
 // en: confirmRemoval confirms the removal
 func confirmRemoval(projectName string, force bool) (bool, error) {
@@ -159,17 +137,11 @@ func confirmRemoval(projectName string, force bool) (bool, error) {
 	return response == "y" || response == "yes", nil
 }
 
-// ja: saveRemoveConfig は設定ファイルを保存します
-// en: saveRemoveConfig saves the configuration file
-func saveRemoveConfig(configPath string, config *Config) error {
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf(i18n.T("remove.marshalError"), err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0600); err != nil {
-		return fmt.Errorf(i18n.T("remove.writeError"), err)
-	}
-
-	return nil
+// ja: removeProjectFromConfig は設定ファイルからプロジェクトを削除して保存します
+// ja: 該当するプロジェクトの行のみを取り除くため、コメントや他のプロジェクトの書式、パーミッションは維持されます
+// en: removeProjectFromConfig removes the project from the configuration file and saves it
+// en: Only the project's lines are taken out, so comments, the formatting of other projects and the file mode are kept
+func removeProjectFromConfig(configPath, projectName string) error {
+	store := &configStore{path: configPath}
+	return store.Edit(func(file *configfile.File) error { return file.RemoveProject(projectName) })
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -563,62 +564,6 @@ projects:
 	}
 }
 
-// TestFindRemoveProjectIndex tests the findRemoveProjectIndex helper function
-func TestFindRemoveProjectIndex(t *testing.T) {
-	projects := []Project{
-		{Name: "first"},
-		{Name: "second"},
-		{Name: "third"},
-	}
-
-	tests := []struct {
-		name     string
-		projects []Project
-		search   string
-		expected int
-	}{
-		{
-			name:     "find first project",
-			projects: projects,
-			search:   "first",
-			expected: 0,
-		},
-		{
-			name:     "find middle project",
-			projects: projects,
-			search:   "second",
-			expected: 1,
-		},
-		{
-			name:     "find last project",
-			projects: projects,
-			search:   "third",
-			expected: 2,
-		},
-		{
-			name:     "project not found",
-			projects: projects,
-			search:   "nonexistent",
-			expected: -1,
-		},
-		{
-			name:     "empty projects list",
-			projects: []Project{},
-			search:   "any",
-			expected: -1,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := findRemoveProjectIndex(tt.projects, tt.search)
-			if result != tt.expected {
-				t.Errorf("Expected index %d, got %d", tt.expected, result)
-			}
-		})
-	}
-}
-
 // TestConfirmRemoval tests the confirmRemoval function
 func TestConfirmRemoval(t *testing.T) {
 	tests := []struct {
@@ -697,37 +642,52 @@ func TestConfirmRemoval(t *testing.T) {
 	}
 }
 
-// TestSaveRemoveConfigPermissions tests that config file is saved with 0600 permissions
-func TestSaveRemoveConfigPermissions(t *testing.T) {
+// TestRemoveProjectFromConfigKeepsFile tests that only the project's lines are removed and the file mode is kept
+func TestRemoveProjectFromConfigKeepsFile(t *testing.T) {
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yml")
 
-	config := Config{
-		Version: "1.0.0",
-		Projects: []Project{
-			{
-				Name:   "test",
-				Repo:   "git@github.com:user/test.git",
-				Branch: "main",
-			},
-		},
+	configData := `# ja: 設定ファイル
+# en: Configuration file
+version: 1.0.0
+projects:
+  - name: keep
+    repo: git@github.com:user/keep.git # comment
+    branch: main
+    custom_key: value
+  - name: test
+    repo: git@github.com:user/test.git
+    branch: main
+`
+	if err := os.WriteFile(configPath, []byte(configData), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if err := os.Chmod(configPath, 0644); err != nil {
+		t.Fatalf("Failed to change file mode: %v", err)
 	}
 
-	err := saveRemoveConfig(configPath, &config)
-	if err != nil {
+	if err := removeProjectFromConfig(configPath, "test"); err != nil {
 		t.Fatalf("Failed to save config: %v", err)
 	}
 
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	expected := configData[:strings.Index(configData, "  - name: test")]
+	if string(data) != expected {
+		t.Errorf("Expected only the project to be removed.\nExpected:\n%s\nGot:\n%s", expected, data)
+	}
+
 	info, err := os.Stat(configPath)
 	if err != nil {
 		t.Fatalf("Failed to stat config file: %v", err)
 	}
 
-	// Check file permissions
-	mode := info.Mode().Perm()
-	expected := os.FileMode(0600)
-	if mode != expected {
-		t.Errorf("Expected file permissions %o, got %o", expected, mode)
+	// Check file permissions are kept
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0644 {
+		t.Errorf("Expected file permissions to stay 644, got %o", info.Mode().Perm())
 	}
 }
 
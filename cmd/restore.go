@@ -1,25 +1,25 @@
 package cmd
 
 import (
-	"archive/tar"
 	"bufio"
-	"compress/gzip"
+	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/yk-lab/toske/i18n"
-	"gopkg.in/yaml.v3"
+	"github.com/yk-lab/toske/toske"
 )
 
 var (
 	restoreProjectName string
 	backupIndex        int
 	forceRestore       bool
+	skipDumps          bool
+	restoreCheckout    bool
+	skipGitState       bool
 )
 
 // ja: restoreCmd は restore コマンドを表します
@@ -41,309 +41,260 @@ func init() {
 	restoreCmd.Flags().StringVarP(&restoreProjectName, "project", "p", "", i18n.T("restore.flag.project"))
 	restoreCmd.Flags().IntVarP(&backupIndex, "backup", "b", 1, i18n.T("restore.flag.backup"))
 	restoreCmd.Flags().BoolVarP(&forceRestore, "force", "f", false, i18n.T("restore.flag.force"))
+	restoreCmd.Flags().BoolVar(&skipDumps, "skip-dumps", false, i18n.T("restore.flag.skipDumps"))
+	restoreCmd.Flags().BoolVar(&restoreCheckout, "checkout", false, i18n.T("restore.flag.checkout"))
+	restoreCmd.Flags().BoolVar(&skipGitState, "skip-git-state", false, i18n.T("restore.flag.skipGitState"))
 }
 
-func runRestore() error {
+func runRestore() (err error) {
 	// ja: プロジェクト名が指定されているかチェック
 	// en: Check if project name is specified
 	if restoreProjectName == "" {
 		return fmt.Errorf("%s", i18n.T("restore.noProjectFlag"))
 	}
 
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
-	}
-
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("restore.noConfig"), configPath)
-	}
+	journal := startOperation("restore", restoreProjectName)
+	defer func() { journal.finish(err) }()
 
 	// ja: 設定ファイルを読み込む
 	// en: Load configuration file
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return fmt.Errorf(i18n.T("restore.readError"), err)
-	}
-
-	// ja: 設定を構造体にアンマーシャル
-	// en: Unmarshal config into struct
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return fmt.Errorf(i18n.T("restore.parseError"), err)
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
 	}
 
 	// ja: 指定されたプロジェクトを検索
 	// en: Find the specified project
-	var project *Project
-	for i := range config.Projects {
-		if config.Projects[i].Name == restoreProjectName {
-			project = &config.Projects[i]
-			break
-		}
+	project, err := store.FindProject(config, restoreProjectName)
+	if err != nil {
+		return err
 	}
 
-	if project == nil {
-		return fmt.Errorf(i18n.T("restore.projectNotFound"), restoreProjectName)
+	target, err := backupTarget(project)
+	if err != nil {
+		return err
 	}
-
-	// ja: バックアップディレクトリを取得
-	// en: Get backup directory
-	homeDir, err := os.UserHomeDir()
+	v, err := newVCS(project, gitRunner)
 	if err != nil {
 		return err
 	}
 
-	backupDir := filepath.Join(homeDir, ".config", "toske", "backups", project.Name)
-
-	// ja: バックアップディレクトリが存在するかチェック
-	// en: Check if backup directory exists
-	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("restore.noBackupDir"), project.Name)
+	// ja: 復元するバックアップを選択し（1-indexed）、書き込まれるファイルを調べる
+	// en: Select the backup to restore (1-indexed) and work out the files it would write
+	restoreOpts := toske.RestoreOptions{
+		Options:      toskeOptions(),
+		Backup:       backupIndex,
+		SkipDumps:    skipDumps,
+		SkipGitState: skipGitState,
 	}
-
-	// ja: メタデータファイルを読み込む
-	// en: Load metadata file
-	metadataPath := filepath.Join(backupDir, "backups.yaml")
-	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("restore.noMetadata"), project.Name)
+	plan, err := toske.PlanRestore(target, restoreOpts)
+
+	// ja: チェックアウトが存在しなければ、先にリポジトリをクローンしてから復元するファイルを調べ直す
+	// ja: クローンは確認の後で行い、確認はクローンと復元を合わせて 1 度だけにする
+	// en: When the checkout is missing, clone the repository first and then work out the files again
+	// en: The clone only happens once confirmed, and that one confirmation covers both the clone and the restore
+	confirmed := forceRestore
+	var notFound *toske.ProjectDirNotFoundError
+	if errors.As(err, &notFound) {
+		printClone := func() { msgf(i18n.T("plan.restoreClone")+"\n", project.Repo, target.Dir) }
+		if dryRun {
+			return showPlan(restoreClonePlan{Project: project.Name, Repo: project.Repo, Dir: target.Dir}, printClone)
+		}
+		if !confirmed {
+			printClone()
+			if confirmed, err = confirmRestore(); err != nil {
+				return err
+			}
+			if !confirmed {
+				journal.cancel()
+				msgln(i18n.T("restore.cancelled"))
+				return nil
+			}
+		}
+		statusf(i18n.T("restore.cloning")+"\n", project.Repo, target.Dir)
+		if err := v.clone(project, target.Dir); err != nil {
+			return fmt.Errorf(i18n.T("restore.cloneError"), project.Repo, err)
+		}
+		journal.touch(target.Dir, fileCreated)
+		plan, err = toske.PlanRestore(target, restoreOpts)
 	}
-
-	data, err := os.ReadFile(metadataPath)
 	if err != nil {
-		return fmt.Errorf(i18n.T("restore.readMetadataError"), err)
+		return err
 	}
 
-	var metadata BackupMetadata
-	if err := yaml.Unmarshal(data, &metadata); err != nil {
-		return fmt.Errorf(i18n.T("restore.parseMetadataError"), err)
+	// ja: 別のプロジェクトのアーカイブ（取り込み時に名前を変えた場合など）は警告だけにとどめる
+	// en: An archive made for another project (e.g. renamed on import) only gets a warning
+	if plan.Manifest != nil && plan.Manifest.Project != target.Name {
+		fmt.Fprintf(os.Stderr, i18n.T("restore.manifestProjectMismatch")+"\n", plan.Record.Filename, plan.Manifest.Project, target.Name)
 	}
 
-	// ja: バックアップが存在するかチェック
-	// en: Check if backups exist
-	if len(metadata.Backups) == 0 {
-		return fmt.Errorf(i18n.T("restore.noBackups"), project.Name)
+	// ja: バックアップ時のコミットと現在のチェックアウトを比べ、ずれていれば警告する
+	// en: Compare the checkout with the commit the backup was taken at, and warn when they differ
+	drift := v.compare(target.Dir, plan.Record.Git)
+	if restoreCheckout {
+		if plan.Record.Git == nil {
+			return fmt.Errorf(i18n.T("restore.noRecordedCommit"), plan.Record.Filename)
+		}
+		if drift != nil && drift.Missing {
+			return fmt.Errorf(i18n.T("restore.checkoutMissing"), shortCommit(drift.Recorded.Commit))
+		}
 	}
-
-	// ja: バックアップインデックスが有効かチェック
-	// en: Check if backup index is valid
-	if backupIndex < 1 || backupIndex > len(metadata.Backups) {
-		return fmt.Errorf(i18n.T("restore.invalidBackupIndex"), backupIndex, len(metadata.Backups))
+	warnCheckoutDrift(drift, restoreCheckout)
+	checkout := restoreCheckout && drift != nil
+	if checkout && (dryRun || !confirmed) {
+		msgf(i18n.T("plan.restoreCheckout")+"\n", shortCommit(drift.Recorded.Commit))
 	}
 
-	// ja: 復元するバックアップを選択（1-indexed）
-	// en: Select backup to restore (1-indexed)
-	selectedBackup := metadata.Backups[backupIndex-1]
-	archivePath := filepath.Join(backupDir, selectedBackup.Filename)
+	// ja: 復元の後に VCS が行う処理（サブモジュールや LFS など）も先に表示する
+	// en: Also show what the VCS will do after restoring (submodules, LFS and so on)
+	if dryRun || !confirmed {
+		v.planRestore(project, target.Dir, checkout)
+	}
 
-	// ja: アーカイブファイルが存在するかチェック
-	// en: Check if archive file exists
-	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("restore.backupNotFound"), selectedBackup.Filename)
+	if dryRun {
+		return showRestorePlan(plan)
 	}
 
 	// ja: 選択したバックアップ情報を表示
 	// en: Display selected backup information
-	fmt.Printf(i18n.T("restore.selectingBackup")+"\n", selectedBackup.Filename, selectedBackup.Timestamp.Format("2006-01-02 15:04:05"))
-
-	// ja: 確認プロンプト（--force フラグが指定されていない場合）
-	// en: Confirmation prompt (if --force flag is not specified)
-	if !forceRestore {
-		fmt.Println(i18n.T("restore.confirmOverwrite"))
-		fmt.Print(i18n.T("restore.confirmPrompt"))
-
-		reader := bufio.NewReader(os.Stdin)
-		response, err := reader.ReadString('\n')
-		if err != nil {
-			return fmt.Errorf(i18n.T("restore.readInputError"), err)
+	statusf(i18n.T("restore.selectingBackup")+"\n", plan.Record.Filename, plan.Record.Timestamp.Format("2006-01-02 15:04:05"))
+
+	// ja: 確認プロンプト（--force フラグが指定されておらず、クローンの前にも確認していない場合）
+	// ja: 上書きされるファイルを先に一覧にして、何が変わるかを確認できるようにする
+	// en: Confirmation prompt (unless --force was given or it was already confirmed before cloning)
+	// en: The files that would be overwritten are listed first so the user knows what will change
+	if !confirmed {
+		printRestorePlan(plan, false)
+		msgln(i18n.T("restore.confirmOverwrite"))
+		if confirmed, err = confirmRestore(); err != nil {
+			return err
 		}
-
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "y" && response != "yes" {
-			fmt.Println(i18n.T("restore.cancelled"))
+		if !confirmed {
+			journal.cancel()
+			msgln(i18n.T("restore.cancelled"))
 			return nil
 		}
 	}
 
+	if checkout {
+		statusf(i18n.T("restore.checkingOut")+"\n", shortCommit(drift.Recorded.Commit))
+		if err := v.checkout(target.Dir, drift.Recorded); err != nil {
+			return fmt.Errorf(i18n.T("restore.checkoutError"), err)
+		}
+		journal.note(fmt.Sprintf("checked out %s", drift.Recorded.Commit))
+	}
+
 	// ja: ファイルを復元
 	// en: Restore files
-	fmt.Println(i18n.T("restore.restoringFiles"))
-
-	fileCount, err := extractBackupArchive(archivePath)
+	statusln(i18n.T("restore.restoringFiles"))
+
+	// ja: 確認プロンプトの後から Ctrl-C を捕まえる（プロンプト中は通常どおり終了できる）
+	// en: Catch Ctrl-C only after the confirmation prompt (it exits as usual while prompting)
+	ctx, stop := interruptContext()
+	defer stop()
+
+	progress := newProgressReporter()
+	restoreOpts.Progress = progress.report
+
+	result, err := toske.Restore(ctx, target, restoreOpts)
+	progress.finish()
+	if result != nil {
+		// ja: 中断や失敗の場合も、書き込んだファイルは記録に残す
+		// en: Record the files written even when interrupted or failed
+		journal.entry.Archive = result.Archive
+		for _, file := range result.Files {
+			journal.touch(filepath.Join(target.Dir, filepath.FromSlash(file)), fileWritten)
+		}
+		if result.Dumps > 0 {
+			journal.note(fmt.Sprintf("restored %d database dump(s)", result.Dumps))
+		}
+		if work := result.GitWork; work != nil {
+			journal.note(fmt.Sprintf("restored %d branch(es) and %d stash(es)", len(work.Branches), work.Stashes))
+		}
+	}
 	if err != nil {
-		return fmt.Errorf(i18n.T("restore.extractError"), err)
+		if ctx.Err() != nil && result != nil {
+			journal.interrupt()
+			reportInterruptedRestore(result)
+			return fmt.Errorf(i18n.T("restore.interrupted"), len(result.Files))
+		}
+		return err
 	}
 
-	fmt.Println()
-	fmt.Println(i18n.T("restore.success"))
-	fmt.Printf(i18n.T("restore.restoredFiles")+"\n", fileCount)
-
-	return nil
-}
+	v.afterRestore(project, target.Dir, checkout)
 
-// ja: extractBackupArchive はバックアップアーカイブを展開します
-// en: extractBackupArchive extracts a backup archive
-func extractBackupArchive(archivePath string) (int, error) {
-	// ja: アーカイブファイルを開く
-	// en: Open archive file
-	archiveFile, err := os.Open(archivePath)
-	if err != nil {
-		return 0, fmt.Errorf(i18n.T("restore.openArchiveError"), err)
+	if isStructuredOutput() {
+		result.Files = nonNil(result.Files)
+		result.Skipped = nonNil(result.Skipped)
+		return writeResult(result)
 	}
-	defer archiveFile.Close()
 
-	// ja: gzip リーダーを作成
-	// en: Create gzip reader
-	gzipReader, err := gzip.NewReader(archiveFile)
-	if err != nil {
-		return 0, err
+	if outputVerbosity() == verbosityQuiet {
+		return nil
 	}
-	defer gzipReader.Close()
-
-	// ja: tar リーダーを作成
-	// en: Create tar reader
-	tarReader := tar.NewReader(gzipReader)
 
-	// ja: カレントディレクトリを取得
-	// en: Get current directory
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return 0, err
+	fmt.Println()
+	fmt.Println(i18n.T("restore.success"))
+	fmt.Printf(i18n.T("restore.restoredFiles")+"\n", len(result.Files))
+	if result.Dumps > 0 {
+		fmt.Printf(i18n.T("restore.restoredDumps")+"\n", result.Dumps)
 	}
-
-	fileCount := 0
-
-	// ja: アーカイブ内の各ファイルを処理
-	// en: Process each file in the archive
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return 0, err
-		}
-
-		// ja: ディレクトリエントリはスキップ（ファイル作成時に自動的に作成される）
-		// en: Skip directory entries (they will be created automatically when creating files)
-		if header.Typeflag == tar.TypeDir {
-			continue
-		}
-
-		// ja: セキュリティチェック：絶対パスとパストラバーサル攻撃を防ぐ
-		// en: Security check: prevent absolute paths and path traversal attacks
-		if filepath.IsAbs(header.Name) {
-			continue
-		}
-
-		// ja: ファイルパスを決定
-		// en: Determine file path
-		targetPath := filepath.Join(currentDir, header.Name)
-
-		// ja: 相対パスでの追加セキュリティチェック
-		// en: Additional security check with relative path
-		relPath, err := filepath.Rel(currentDir, targetPath)
-		if err != nil || strings.HasPrefix(relPath, "..") {
-			continue
-		}
-
-		fmt.Printf(i18n.T("restore.extractingFile")+"\n", header.Name)
-
-		targetDir := filepath.Dir(targetPath)
-
-		// ja: シンボリックリンク攻撃を防ぐため、ディレクトリパスを事前に検証
-		// en: Validate directory path before creation to prevent symlink attacks
-		if err := validatePathNoSymlinks(currentDir, targetDir); err != nil {
-			continue
-		}
-
-		// ja: ディレクトリを作成
-		// en: Create directory
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			// ja: ディレクトリ作成エラーの場合、このファイルをスキップして次へ
-			// en: Skip this file if directory creation fails and continue with next
-			continue
-		}
-
-		// ja: ファイルパス全体を再検証（MkdirAll後の安全性確認）
-		// en: Re-validate full file path after directory creation for additional safety
-		if err := validatePathNoSymlinks(currentDir, targetPath); err != nil {
-			continue
+	if work := result.GitWork; work != nil {
+		fmt.Printf(i18n.T("restore.restoredGitState")+"\n", len(work.Branches), work.Stashes)
+		if work.Patch {
+			fmt.Println(i18n.T("restore.restoredPatch"))
 		}
+	}
 
-		// ja: ファイルを作成
-		// en: Create file
-		outFile, err := os.Create(targetPath)
-		if err != nil {
-			// ja: ファイル作成エラー - 警告を表示して次のファイルへ
-			// en: File creation error - log warning and continue with next file
-			fmt.Fprintf(os.Stderr, i18n.T("restore.fileCreateWarning")+"\n", header.Name, err)
-			continue
-		}
+	return nil
+}
 
-		// ja: ファイル内容をコピー
-		// en: Copy file contents
-		if _, err := io.Copy(outFile, tarReader); err != nil {
-			outFile.Close()
-			// ja: 部分的なファイルを削除
-			// en: Remove partial file
-			os.Remove(targetPath)
-			// ja: コピーエラー - 警告を表示して次のファイルへ
-			// en: Copy error - log warning and continue with next file
-			fmt.Fprintf(os.Stderr, i18n.T("restore.fileCopyWarning")+"\n", header.Name, err)
-			continue
-		}
-		outFile.Close()
-
-		// ja: ファイルのパーミッションを設定
-		// en: Set file permissions
-		if err := os.Chmod(targetPath, os.FileMode(header.Mode)); err != nil {
-			// ja: パーミッション設定エラー - 警告を表示するが、ファイルは保持
-			// en: Chmod error - log warning but keep the file
-			fmt.Fprintf(os.Stderr, i18n.T("restore.fileChmodWarning")+"\n", header.Name, err)
-			// ja: パーミッション設定に失敗してもファイルはカウント
-			// en: Count file even if chmod failed
-		}
+// ja: confirmRestore は復元を続けるかを尋ね、y または yes と答えた場合に true を返します
+// en: confirmRestore asks whether to go on with the restore and returns true for y or yes
+func confirmRestore() (bool, error) {
+	msgf("%s", i18n.T("restore.confirmPrompt"))
 
-		fileCount++
+	reader := bufio.NewReader(os.Stdin)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf(i18n.T("restore.readInputError"), err)
 	}
 
-	return fileCount, nil
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes", nil
 }
 
-// ja: validatePathNoSymlinks はパスにシンボリックリンクが含まれていないことを検証します
-// en: validatePathNoSymlinks validates that the path contains no symlinks
-func validatePathNoSymlinks(baseDir, targetPath string) error {
-	// ja: シンボリックリンクを解決して実際のパスを取得
-	// en: Resolve symlinks to get the actual path
-	resolvedPath, err := filepath.EvalSymlinks(targetPath)
-	if err != nil {
-		// ja: パスが存在しない場合、親ディレクトリまで確認
-		// en: If path doesn't exist, check parent directory
-		parentDir := filepath.Dir(targetPath)
-		if parentDir == targetPath {
-			return nil // Root directory reached
-		}
-		return validatePathNoSymlinks(baseDir, parentDir)
+// ja: warnCheckoutDrift はバックアップ時のコミットからのずれを標準エラー出力に警告します
+// ja: checkout が false の場合は、--checkout でそのコミットに戻せることも案内します
+// en: warnCheckoutDrift warns on stderr about how far the checkout is from the commit the backup was taken at
+// en: When checkout is false, it also points out that --checkout can go back to that commit
+func warnCheckoutDrift(drift *checkoutDrift, checkout bool) {
+	if drift == nil {
+		return
 	}
-
-	// ja: 解決されたパスが base ディレクトリ内にあることを確認
-	// en: Ensure resolved path is within base directory
-	resolvedBase, err := filepath.EvalSymlinks(baseDir)
-	if err != nil {
-		return err
+	commit := shortCommit(drift.Recorded.Commit)
+	if drift.Missing {
+		fmt.Fprintf(os.Stderr, i18n.T("restore.commitMissing")+"\n", commit)
+	} else {
+		fmt.Fprintf(os.Stderr, i18n.T("restore.commitDrift")+"\n", commit, drift.Ahead, drift.Behind)
 	}
-
-	relPath, err := filepath.Rel(resolvedBase, resolvedPath)
-	if err != nil || strings.HasPrefix(relPath, "..") {
-		return fmt.Errorf("%s", i18n.T("restore.symlinkOutsideDir"))
+	if drift.Recorded.Dirty {
+		fmt.Fprintln(os.Stderr, i18n.T("restore.commitDirty"))
 	}
+	if !checkout && !drift.Missing {
+		fmt.Fprintf(os.Stderr, i18n.T("restore.checkoutHint")+"\n", commit)
+	}
+}
 
-	return nil
+// ja: reportInterruptedRestore は中断されるまでに書き込んだファイルを標準エラー出力に表示します
+// en: reportInterruptedRestore prints the files written before the restore was interrupted to stderr
+func reportInterruptedRestore(result *toske.RestoreResult) {
+	if len(result.Files) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("restore.writtenBeforeInterrupt"))
+	for _, file := range result.Files {
+		fmt.Fprintf(os.Stderr, "  %s\n", file)
+	}
 }
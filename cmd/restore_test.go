@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/yk-lab/toske/toske"
 	"gopkg.in/yaml.v3"
 )
 
@@ -392,263 +393,115 @@ projects:
 	})
 }
 
-func TestExtractBackupArchive(t *testing.T) {
-	// Setup temporary directory
-	tempDir := t.TempDir()
-	workDir := filepath.Join(tempDir, "work")
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		t.Fatalf("Failed to create work directory: %v", err)
-	}
-
-	// Create a test archive
-	archivePath := filepath.Join(tempDir, "test.tar.gz")
-	testFiles := []testFile{
-		{name: ".env", content: "TEST=value"},
-		{name: "config/app.conf", content: "config content"},
-	}
-
-	if err := createTestArchive(archivePath, testFiles); err != nil {
-		t.Fatalf("Failed to create test archive: %v", err)
-	}
-
-	// Change to work directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
-	if err := os.Chdir(workDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-
-	// Extract archive
-	fileCount, err := extractBackupArchive(archivePath)
-	if err != nil {
-		t.Fatalf("Failed to extract archive: %v", err)
-	}
+// Helper types and functions
 
-	if fileCount != len(testFiles) {
-		t.Errorf("Expected %d files to be extracted, got %d", len(testFiles), fileCount)
-	}
+type testFile struct {
+	name    string
+	content string
+}
 
-	// Verify extracted files
-	for _, tf := range testFiles {
-		filePath := filepath.Join(workDir, tf.name)
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			t.Errorf("Failed to read extracted file %s: %v", tf.name, err)
-			continue
-		}
+// createTestBackup creates a test backup in the specified temp directory
+// cloningRunner answers git like fakeGitRunner and creates the directory a clone is made into.
+type cloningRunner struct {
+	fakeGitRunner
+}
 
-		if string(data) != tf.content {
-			t.Errorf("File %s content mismatch. Expected: %s, Got: %s", tf.name, tf.content, string(data))
-		}
+func (c *cloningRunner) Run(cmd runCommand) error {
+	if len(cmd.Args) > 0 && cmd.Args[0] == "clone" {
+		c.calls = append(c.calls, strings.Join(cmd.Args, " "))
+		return os.MkdirAll(cmd.Args[len(cmd.Args)-1], 0755)
 	}
+	return c.fakeGitRunner.Run(cmd)
 }
 
-func TestExtractBackupArchivePathTraversal(t *testing.T) {
-	// Setup temporary directory
+func TestRestoreClonesMissingCheckout(t *testing.T) {
 	tempDir := t.TempDir()
-	workDir := filepath.Join(tempDir, "work")
-	siblingDir := filepath.Join(tempDir, "sibling")
-
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		t.Fatalf("Failed to create work directory: %v", err)
-	}
-	if err := os.MkdirAll(siblingDir, 0755); err != nil {
-		t.Fatalf("Failed to create sibling directory: %v", err)
-	}
+	checkoutDir := filepath.Join(tempDir, "src", "clone-test")
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	setupJournal(t)
 
-	// Create an archive with path traversal attempts
-	archivePath := filepath.Join(tempDir, "malicious.tar.gz")
-	archiveFile, err := os.Create(archivePath)
-	if err != nil {
-		t.Fatalf("Failed to create archive file: %v", err)
+	defer setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: clone-test
+    repo: git@github.com:user/test.git
+    branch: main
+    path: `+checkoutDir+`
+    backup_paths:
+      - .env
+`)()
+	if err := createTestBackup(tempDir, "clone-test", []testFile{{name: ".env", content: "TEST=value"}}); err != nil {
+		t.Fatalf("Failed to setup test backup: %v", err)
 	}
-	defer archiveFile.Close()
 
-	gzipWriter := gzip.NewWriter(archiveFile)
-	defer gzipWriter.Close()
+	originalRunner := gitRunner
+	runner := &cloningRunner{fakeGitRunner{responses: map[string]string{}}}
+	gitRunner = runner
+	t.Cleanup(func() { gitRunner = originalRunner })
 
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+	originalProjectName, originalForce := restoreProjectName, forceRestore
+	restoreProjectName, forceRestore = "clone-test", true
+	defer func() { restoreProjectName, forceRestore = originalProjectName, originalForce }()
 
-	// Attempt various path traversal attacks
-	maliciousFiles := []struct {
-		name    string
-		content string
-	}{
-		{name: "../escape.txt", content: "escaped"},
-		{name: "../../escape2.txt", content: "escaped2"},
-		{name: "../sibling/attack.txt", content: "attacked"},
-		{name: filepath.Join(tempDir, "absolute_attack.txt"), content: "absolute path attack"},
-	}
-
-	for _, mf := range maliciousFiles {
-		header := &tar.Header{
-			Name:    mf.name,
-			Size:    int64(len(mf.content)),
-			Mode:    0644,
-			ModTime: time.Now(),
+	t.Run("dry run only plans the clone", func(t *testing.T) {
+		setDryRun(t)
+		output, err := captureStdout(t, runRestore)
+		if err != nil {
+			t.Fatalf("runRestore failed: %v", err)
 		}
-		if err := tarWriter.WriteHeader(header); err != nil {
-			t.Fatalf("Failed to write tar header: %v", err)
+		if !strings.Contains(output, "will be cloned into "+checkoutDir) {
+			t.Errorf("Expected the clone in the plan, got:\n%s", output)
 		}
-		if _, err := tarWriter.Write([]byte(mf.content)); err != nil {
-			t.Fatalf("Failed to write tar content: %v", err)
+		if len(runner.calls) != 0 || dirExists(checkoutDir) {
+			t.Errorf("Expected nothing to be cloned on a dry run, got calls %q", runner.calls)
 		}
-	}
-
-	tarWriter.Close()
-	gzipWriter.Close()
-	archiveFile.Close()
-
-	// Change to work directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
-	if err := os.Chdir(workDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-
-	// Extract archive - should skip all malicious files
-	fileCount, err := extractBackupArchive(archivePath)
-	if err != nil {
-		t.Fatalf("Failed to extract archive: %v", err)
-	}
-
-	// Should extract 0 files (all were malicious)
-	if fileCount != 0 {
-		t.Errorf("Expected 0 files to be extracted (all malicious), got %d", fileCount)
-	}
-
-	// Verify no files escaped the work directory
-	escapedFiles := []string{
-		filepath.Join(tempDir, "escape.txt"),
-		filepath.Join(tempDir, "escape2.txt"),
-		filepath.Join(siblingDir, "attack.txt"),
-		filepath.Join(tempDir, "absolute_attack.txt"),
-	}
+	})
 
-	for _, escapedFile := range escapedFiles {
-		data, err := os.ReadFile(escapedFile)
-		if err == nil {
-			t.Errorf("Security vulnerability: file escaped to %s with content: %s", escapedFile, string(data))
+	t.Run("restore asks before cloning", func(t *testing.T) {
+		forceRestore = false
+		defer func() { forceRestore = true }()
+		stdin := filepath.Join(t.TempDir(), "stdin")
+		if err := os.WriteFile(stdin, []byte("n\n"), 0644); err != nil {
+			t.Fatalf("Failed to write stdin: %v", err)
 		}
-	}
-
-	// Verify work directory is still empty
-	entries, err := os.ReadDir(workDir)
-	if err != nil {
-		t.Fatalf("Failed to read work directory: %v", err)
-	}
-	if len(entries) > 0 {
-		t.Errorf("Expected work directory to be empty, but found %d entries", len(entries))
-	}
-}
-
-func TestExtractBackupArchiveSymlinkAttack(t *testing.T) {
-	// Setup temporary directory
-	tempDir := t.TempDir()
-	workDir := filepath.Join(tempDir, "work")
-	attackTarget := filepath.Join(tempDir, "attack_target")
-
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		t.Fatalf("Failed to create work directory: %v", err)
-	}
-	if err := os.MkdirAll(attackTarget, 0755); err != nil {
-		t.Fatalf("Failed to create attack target directory: %v", err)
-	}
-
-	// Create a symlink in work directory pointing to attack target
-	symlinkPath := filepath.Join(workDir, "backup")
-	if err := os.Symlink(attackTarget, symlinkPath); err != nil {
-		t.Fatalf("Failed to create symlink: %v", err)
-	}
-
-	// Create an archive that tries to write through the symlink
-	archivePath := filepath.Join(tempDir, "symlink_attack.tar.gz")
-	archiveFile, err := os.Create(archivePath)
-	if err != nil {
-		t.Fatalf("Failed to create archive file: %v", err)
-	}
-	defer archiveFile.Close()
-
-	gzipWriter := gzip.NewWriter(archiveFile)
-	defer gzipWriter.Close()
-
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
-
-	// Try to write a file through the symlink
-	maliciousContent := "SYMLINK ATTACK SUCCESS"
-	header := &tar.Header{
-		Name:    "backup/passwd",
-		Size:    int64(len(maliciousContent)),
-		Mode:    0644,
-		ModTime: time.Now(),
-	}
-	if err := tarWriter.WriteHeader(header); err != nil {
-		t.Fatalf("Failed to write tar header: %v", err)
-	}
-	if _, err := tarWriter.Write([]byte(maliciousContent)); err != nil {
-		t.Fatalf("Failed to write tar content: %v", err)
-	}
-
-	tarWriter.Close()
-	gzipWriter.Close()
-	archiveFile.Close()
-
-	// Change to work directory
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get working directory: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
-	if err := os.Chdir(workDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-
-	// Extract archive - should skip the file due to symlink
-	fileCount, err := extractBackupArchive(archivePath)
-	if err != nil {
-		t.Fatalf("Failed to extract archive: %v", err)
-	}
-
-	// Should extract 0 files (symlink should be detected)
-	if fileCount != 0 {
-		t.Errorf("Expected 0 files to be extracted (symlink detected), got %d", fileCount)
-	}
-
-	// Verify the attack target was not modified
-	attackFile := filepath.Join(attackTarget, "passwd")
-	if data, err := os.ReadFile(attackFile); err == nil {
-		t.Errorf("Security vulnerability: symlink was followed, file created with content: %s", string(data))
-	}
-
-	// Verify symlink still exists and wasn't replaced
-	linkInfo, err := os.Lstat(symlinkPath)
-	if err != nil {
-		t.Fatalf("Symlink was removed or modified: %v", err)
-	}
-	if linkInfo.Mode()&os.ModeSymlink == 0 {
-		t.Error("Symlink was replaced with a regular file or directory")
-	}
-}
+		input, err := os.Open(stdin)
+		if err != nil {
+			t.Fatalf("Failed to open stdin: %v", err)
+		}
+		oldStdin := os.Stdin
+		os.Stdin = input
+		defer func() {
+			os.Stdin = oldStdin
+			input.Close()
+		}()
 
-// Helper types and functions
+		output, err := captureStdout(t, runRestore)
+		if err != nil {
+			t.Fatalf("runRestore failed: %v", err)
+		}
+		if !strings.Contains(output, "will be cloned into "+checkoutDir) || !strings.Contains(output, "Restore cancelled.") {
+			t.Errorf("Expected the clone to be shown before the prompt, got:\n%s", output)
+		}
+		if len(runner.calls) != 0 || dirExists(checkoutDir) {
+			t.Errorf("Expected nothing to be cloned once declined, got calls %q", runner.calls)
+		}
+	})
 
-type testFile struct {
-	name    string
-	content string
+	t.Run("restore clones first", func(t *testing.T) {
+		if err := runRestore(); err != nil {
+			t.Fatalf("runRestore failed: %v", err)
+		}
+		want := "clone --quiet --branch main --recurse-submodules git@github.com:user/test.git " + checkoutDir
+		if len(runner.calls) == 0 || runner.calls[0] != want {
+			t.Errorf("Expected %q first, got %q", want, runner.calls)
+		}
+		content, err := os.ReadFile(filepath.Join(checkoutDir, ".env"))
+		if err != nil || string(content) != "TEST=value" {
+			t.Errorf("Expected .env to be restored into the clone, got %q (%v)", content, err)
+		}
+	})
 }
 
-// createTestBackup creates a test backup in the specified temp directory
 func createTestBackup(tempDir, projectName string, files []testFile) error {
 	backupDir := filepath.Join(tempDir, ".config", "toske", "backups", projectName)
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
@@ -666,7 +519,7 @@ func createTestBackup(tempDir, projectName string, files []testFile) error {
 
 	// Read existing metadata if it exists
 	metadataPath := filepath.Join(backupDir, "backups.yaml")
-	var metadata BackupMetadata
+	var metadata toske.BackupMetadata
 	if data, err := os.ReadFile(metadataPath); err == nil {
 		if err := yaml.Unmarshal(data, &metadata); err != nil {
 			return err
@@ -682,14 +535,14 @@ func createTestBackup(tempDir, projectName string, files []testFile) error {
 		fileNames = append(fileNames, f.name)
 	}
 
-	newBackup := BackupRecord{
+	newBackup := toske.BackupRecord{
 		Filename:  archiveFilename,
 		Timestamp: timestamp,
 		Files:     fileNames,
 	}
 
 	// Prepend new backup (newest first)
-	metadata.Backups = append([]BackupRecord{newBackup}, metadata.Backups...)
+	metadata.Backups = append([]toske.BackupRecord{newBackup}, metadata.Backups...)
 
 	// Write updated metadata
 	data, err := yaml.Marshal(&metadata)
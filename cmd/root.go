@@ -1,12 +1,10 @@
 package cmd
 
 import (
-	"fmt"
 	"log"
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"github.com/yk-lab/toske/i18n"
 	"github.com/yk-lab/toske/utils"
@@ -20,6 +18,18 @@ var rootCmd = &cobra.Command{
 	Use:   "toske",
 	Short: i18n.T("root.short"),
 	Long:  getHeroMessage(),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateOutputFormat(); err != nil {
+			return err
+		}
+		if err := validateVerbosity(); err != nil {
+			return err
+		}
+		if err := checkDryRun(cmd); err != nil {
+			return err
+		}
+		return configureLogging()
+	},
 	// ja: アクションが関連付けられている場合は、以下の行のコメントを解除してください
 	// en: Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -47,8 +57,6 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// ja: デフォルトの help コマンドを無効にして、カスタム help コマンドを使用
 	// en: Disable the default help command and use our custom help command
 	rootCmd.SetHelpCommand(&cobra.Command{
@@ -63,43 +71,14 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/toske/config.yml)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, i18n.T("output.flag"))
+	rootCmd.PersistentFlags().BoolVarP(&quietOutput, "quiet", "q", false, i18n.T("output.flag.quiet"))
+	rootCmd.PersistentFlags().BoolVarP(&verboseOutput, "verbose", "v", false, i18n.T("output.flag.verbose"))
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, i18n.T("dryRun.flag"))
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logLevel, i18n.T("logging.flag"))
 
 	// ja: Cobra はローカルフラグもサポートしており、これはこのアクションが直接呼び出された場合にのみ実行されます。
 	// en: Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
-
-// ja: initConfig は、設定ファイルと設定されている場合は環境変数を読み込みます。
-// en: initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// ja: フラグから設定ファイルを使用する
-		// en: Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// ja: デフォルトの設定ファイルパスを使用する（TOSKE_CONFIG 環境変数やフォールバックを処理）
-		// en: Use default config path (handles TOSKE_CONFIG env var and fallback)
-		viper.SetConfigFile(getDefaultConfigPath())
-	}
-
-	// ja: 環境変数を自動的に読み込む
-	// en: read in environment variables that match
-	viper.AutomaticEnv()
-
-	// ja: 設定ファイルが見つかった場合は読み込む
-	// en: If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		configFile := viper.ConfigFileUsed()
-		fmt.Fprintln(os.Stderr, "Using config file:", configFile)
-
-		// ja: レガシーパスを使用している場合は移行を促す警告を表示
-		// en: Show migration warning if using legacy path
-		if isLegacyConfigPath(configFile) {
-			fmt.Fprintln(os.Stderr, "")
-			fmt.Fprintln(os.Stderr, i18n.T("config.legacyWarning"))
-			fmt.Fprintln(os.Stderr, i18n.T("config.legacyWarningDetail"))
-			fmt.Fprintln(os.Stderr, "")
-		}
-	}
-}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/configfile"
+	"github.com/yk-lab/toske/i18n"
+)
+
+var setProjectName string
+
+// ja: setCmd は set コマンドを表します
+// en: setCmd represents the set command
+var setCmd = &cobra.Command{
+	Use:   "set <key> <value> | <key>[+-]=<value>",
+	Short: i18n.T("set.short"),
+	Long:  i18n.T("set.long"),
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSet(args); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	setCmd.Flags().StringVarP(&setProjectName, "project", "p", "", i18n.T("set.flag.project"))
+}
+
+func runSet(args []string) (err error) {
+	key, op, value, err := parseFieldAssignment(args)
+	if err != nil {
+		return err
+	}
+
+	journal := startOperation("set", setProjectName)
+	defer func() { journal.finish(err) }()
+	journal.note(strings.Join(args, " "))
+
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	store := newConfigStore()
+	config, err := store.LoadStored()
+	if err != nil {
+		return err
+	}
+
+	var field configField
+	var edit func(file *configfile.File) error
+	if setProjectName == "" {
+		// ja: トップレベルのキーを変更し、include を含めた設定全体を検証
+		// en: Change a top-level key and validate the whole config, includes and all
+		if field, err = lookupField(config, key); err != nil {
+			return err
+		}
+		if err := field.apply(op, value); err != nil {
+			return err
+		}
+		if err := resolveConfig(store.Path(), config); err != nil {
+			return err
+		}
+		if err := validateConfig(config); err != nil {
+			return err
+		}
+		edit = func(file *configfile.File) error { return file.SetField(key, field.Value.Interface()) }
+	} else {
+		// ja: プロジェクトのキーを、プロジェクトを定義しているファイル（include したファイルの場合もある）で変更し、
+		// ja: validateProject と同じ規則で検証する。名前の重複は include を含めた設定全体で確かめる
+		// en: Change a project key in the file defining the project (which may be an included file) and validate it
+		// en: with the validateProject rules. Duplicate names are checked across the whole config, includes and all
+		if err := resolveConfig(store.Path(), config); err != nil {
+			return err
+		}
+		otherNames := make(map[string]bool, len(config.Projects))
+		for _, other := range config.Projects {
+			if other.Name != setProjectName {
+				otherNames[other.Name] = true
+			}
+		}
+
+		if store, err = store.projectSource(setProjectName); err != nil {
+			return err
+		}
+		if config, err = store.LoadStored(); err != nil {
+			return err
+		}
+		project, err := store.FindProject(config, setProjectName)
+		if err != nil {
+			return err
+		}
+
+		updated := *project
+		if field, err = lookupField(&updated, key); err != nil {
+			return err
+		}
+		if err := field.apply(op, value); err != nil {
+			return err
+		}
+
+		if err := validateProject(&updated, findProjectIndex(config.Projects, setProjectName), otherNames); err != nil {
+			return err
+		}
+
+		// ja: 省略可能なキーを空にした場合はキーごと取り除く
+		// ja: 1.1.0 で加わったキーを書き込む場合は、version も上げる
+		// en: Emptying an optional key removes the key altogether
+		// en: When writing a key added in 1.1.0, the version is raised too
+		edit = func(file *configfile.File) error {
+			if field.OmitEmpty && field.isEmpty() {
+				return file.DeleteProjectField(setProjectName, key)
+			}
+			if err := bumpConfigVersion(file, []string{key}); err != nil {
+				return err
+			}
+			return file.SetProjectField(setProjectName, key, field.Value.Interface())
+		}
+	}
+
+	if dryRun {
+		change, err := planConfigEdit(store, "set", setProjectName, edit)
+		if err != nil {
+			return err
+		}
+		return showConfigChange(change)
+	}
+
+	if err := store.Edit(edit); err != nil {
+		return err
+	}
+	journal.touch(store.Path(), fileModified)
+
+	display := fmt.Sprint(field.Value.Interface())
+	if values, ok := field.Value.Interface().([]string); ok {
+		display = "[" + strings.Join(values, ", ") + "]"
+	}
+	if setProjectName != "" {
+		fmt.Printf(i18n.T("set.successProject")+"\n", setProjectName, key, display)
+	} else {
+		fmt.Printf(i18n.T("set.success")+"\n", key, display)
+	}
+	return nil
+}
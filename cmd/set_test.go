@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupSetTest writes a config with the init template and resets the project flags.
+func setupSetTest(t *testing.T) string {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	t.Cleanup(setupTestConfig(t, getConfigTemplate()))
+
+	originalSet, originalGet := setProjectName, getProjectName
+	t.Cleanup(func() { setProjectName, getProjectName = originalSet, originalGet })
+	setProjectName, getProjectName = "", ""
+
+	return cfgFile
+}
+
+func TestParseFieldAssignment(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		key     string
+		op      string
+		value   string
+		wantErr bool
+	}{
+		{name: "two arguments", args: []string{"branch", "develop"}, key: "branch", op: fieldAssign, value: "develop"},
+		{name: "assign", args: []string{"branch=develop"}, key: "branch", op: fieldAssign, value: "develop"},
+		{name: "assign empty", args: []string{"path="}, key: "path", op: fieldAssign, value: ""},
+		{name: "append", args: []string{"backup_paths+=storage/"}, key: "backup_paths", op: fieldAppend, value: "storage/"},
+		{name: "remove", args: []string{"backup_paths-=.env"}, key: "backup_paths", op: fieldRemove, value: ".env"},
+		{name: "value with equals sign", args: []string{"repo=https://example.com/?a=b"}, key: "repo", op: fieldAssign, value: "https://example.com/?a=b"},
+		{name: "value with operators", args: []string{"repo=git@host:a-=b+=c"}, key: "repo", op: fieldAssign, value: "git@host:a-=b+=c"},
+		{name: "append value with operators", args: []string{"backup_paths+=a-=b"}, key: "backup_paths", op: fieldAppend, value: "a-=b"},
+		{name: "no operator", args: []string{"branch"}, wantErr: true},
+		{name: "no key", args: []string{"=develop"}, wantErr: true},
+		{name: "no key before append", args: []string{"+=storage/"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, op, value, err := parseFieldAssignment(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if key != tt.key || op != tt.op || value != tt.value {
+				t.Errorf("Expected (%q, %q, %q), got (%q, %q, %q)", tt.key, tt.op, tt.value, key, op, value)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected []string
+	}{
+		{raw: "a, b,,c", expected: []string{"a", "b", "c"}},
+		{raw: `data/a\,b.csv,c`, expected: []string{"data/a,b.csv", "c"}},
+		{raw: `dir\\,c`, expected: []string{`dir\`, "c"}},
+		{raw: `C:\data\db`, expected: []string{`C:\data\db`}},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.raw); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("splitList(%q): expected %q, got %q", tt.raw, tt.expected, got)
+		}
+	}
+}
+
+func TestRunSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		args    []string
+		check   func(t *testing.T, config Config)
+	}{
+		{
+			name:    "change branch",
+			project: "sample-project",
+			args:    []string{"branch", "develop"},
+			check: func(t *testing.T, config Config) {
+				if config.Projects[0].Branch != "develop" {
+					t.Errorf("Expected branch develop, got %q", config.Projects[0].Branch)
+				}
+			},
+		},
+		{
+			name:    "append backup path",
+			project: "sample-project",
+			args:    []string{"backup_paths+=storage/,.env"},
+			check: func(t *testing.T, config Config) {
+				expected := []string{".env", "db.sqlite3", "config/", "storage/"}
+				if !reflect.DeepEqual(config.Projects[0].BackupPaths, expected) {
+					t.Errorf("Expected %v, got %v", expected, config.Projects[0].BackupPaths)
+				}
+			},
+		},
+		{
+			name:    "remove backup path",
+			project: "sample-project",
+			args:    []string{"backup_paths-=db.sqlite3"},
+			check: func(t *testing.T, config Config) {
+				expected := []string{".env", "config/"}
+				if !reflect.DeepEqual(config.Projects[0].BackupPaths, expected) {
+					t.Errorf("Expected %v, got %v", expected, config.Projects[0].BackupPaths)
+				}
+			},
+		},
+		{
+			name:    "change retention",
+			project: "sample-project",
+			args:    []string{"backup_retention=7"},
+			check: func(t *testing.T, config Config) {
+				if config.Projects[0].BackupRetention != 7 {
+					t.Errorf("Expected retention 7, got %d", config.Projects[0].BackupRetention)
+				}
+			},
+		},
+		{
+			name:    "enable git state",
+			project: "sample-project",
+			args:    []string{"include_git_state=true"},
+			check: func(t *testing.T, config Config) {
+				if !config.Projects[0].IncludeGitState {
+					t.Error("Expected include_git_state to be enabled")
+				}
+			},
+		},
+		{
+			name: "change version",
+			args: []string{"version", "0.9.0"},
+			check: func(t *testing.T, config Config) {
+				if config.Version != "0.9.0" {
+					t.Errorf("Expected version 0.9.0, got %q", config.Version)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupSetTest(t)
+			setProjectName = tt.project
+
+			if _, err := captureStdout(t, func() error { return runSet(tt.args) }); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			tt.check(t, Config{Version: loadVersionFromFile(t, configPath), Projects: loadProjectsFromFile(t, configPath)})
+
+			// ja: テンプレート末尾のコメントはそのまま残ること
+			// en: The comments at the end of the template must survive
+			data, _ := os.ReadFile(configPath)
+			if !strings.Contains(string(data), "# en: Add more projects as needed:") {
+				t.Errorf("Expected comments to be kept, got:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestRunSetRemovesEmptyOptionalField(t *testing.T) {
+	configPath := setupSetTest(t)
+	setProjectName = "sample-project"
+
+	if _, err := captureStdout(t, func() error { return runSet([]string{"backup_retention="}) }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if strings.Contains(string(data), "\n    backup_retention") {
+		t.Errorf("Expected backup_retention to be removed, got:\n%s", data)
+	}
+}
+
+func TestRunSetErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     string
+		args        []string
+		expectedErr string
+	}{
+		{name: "unknown key", project: "sample-project", args: []string{"colour", "red"}, expectedErr: "Unknown key 'colour'"},
+		{name: "unknown project", project: "missing", args: []string{"branch", "main"}, expectedErr: "not found"},
+		{name: "invalid number", project: "sample-project", args: []string{"backup_retention", "many"}, expectedErr: "must be a number"},
+		{name: "invalid bool", project: "sample-project", args: []string{"include_git_state", "sometimes"}, expectedErr: "must be true or false"},
+		{name: "append to scalar", project: "sample-project", args: []string{"branch+=x"}, expectedErr: "is not a list"},
+		{name: "remove missing item", project: "sample-project", args: []string{"backup_paths-=nope"}, expectedErr: "'nope' is not in backup_paths"},
+		{name: "validation failure", project: "sample-project", args: []string{"repo="}, expectedErr: "repo"},
+		{name: "negative retention", project: "sample-project", args: []string{"backup_retention=-1"}, expectedErr: "retention"},
+		{name: "unsupported key", args: []string{"projects", "x"}, expectedErr: "cannot be changed"},
+		{name: "newer version", args: []string{"version", "9.0.0"}, expectedErr: "only understands up to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := setupSetTest(t)
+			setProjectName = tt.project
+
+			before, _ := os.ReadFile(configPath)
+			_, err := captureStdout(t, func() error { return runSet(tt.args) })
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedErr, err)
+			}
+			after, _ := os.ReadFile(configPath)
+			if string(before) != string(after) {
+				t.Error("Config must not change when the update fails")
+			}
+		})
+	}
+}
+
+func TestRunGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		key      string
+		expected string
+	}{
+		{name: "version", key: "version", expected: latestConfigVersion() + "\n"},
+		{name: "project scalar", project: "sample-project", key: "branch", expected: "main\n"},
+		{name: "project list", project: "sample-project", key: "backup_paths", expected: ".env\ndb.sqlite3\nconfig/\n"},
+		{name: "project number", project: "sample-project", key: "backup_retention", expected: "3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupSetTest(t)
+			getProjectName = tt.project
+
+			output, err := captureStdout(t, func() error { return runGet(tt.key) })
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if output != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output)
+			}
+		})
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		setupSetTest(t)
+		getProjectName = "sample-project"
+
+		_, err := captureStdout(t, func() error { return runGet("colour") })
+		if err == nil || !strings.Contains(err.Error(), "backup_paths") {
+			t.Errorf("Expected error listing the available keys, got: %v", err)
+		}
+	})
+}
+
+// loadVersionFromFile returns the version of a config file.
+func loadVersionFromFile(t *testing.T, configPath string) string {
+	t.Helper()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if version, found := strings.CutPrefix(line, "version: "); found {
+			return version
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: interruptExitCode は 2 回目の Ctrl-C で強制終了する際の終了コードです（128 + SIGINT）
+// en: interruptExitCode is the exit code when a second Ctrl-C forces an exit (128 + SIGINT)
+const interruptExitCode = 130
+
+// ja: exitProcess は強制終了に使う関数です（テストでは差し替え可能）
+// en: exitProcess is the function used to force an exit (can be swapped in tests)
+var exitProcess = os.Exit
+
+// ja: interruptContext は Ctrl-C（SIGINT）または SIGTERM でキャンセルされるコンテキストを返します
+// ja: 1 回目はキャンセルして後片付けを待ち、2 回目は待たずに終了します
+// ja: 返される stop は処理が終わったら必ず呼び出してください
+// en: interruptContext returns a context that is cancelled by Ctrl-C (SIGINT) or SIGTERM
+// en: The first signal cancels it and waits for the cleanup, a second one exits right away
+// en: Always call the returned stop once the work is done
+func interruptContext() (context.Context, func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	ctx, stop := watchInterrupts(signals)
+	return ctx, func() {
+		signal.Stop(signals)
+		stop()
+	}
+}
+
+// ja: watchInterrupts は signals に届いたシグナルでコンテキストをキャンセルします
+// en: watchInterrupts cancels the context on the signals received from signals
+func watchInterrupts(signals <-chan os.Signal) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(os.Stderr, i18n.T("signal.interrupted"))
+		cancel()
+
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, i18n.T("signal.forceExit"))
+			exitProcess(interruptExitCode)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		close(done)
+		cancel()
+	}
+}
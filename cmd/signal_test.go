@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatchInterrupts(t *testing.T) {
+	exited := make(chan int, 1)
+	originalExit := exitProcess
+	exitProcess = func(code int) { exited <- code }
+	defer func() { exitProcess = originalExit }()
+
+	signals := make(chan os.Signal, 2)
+	ctx, stop := watchInterrupts(signals)
+	defer stop()
+
+	// ja: 1 回目はキャンセルのみ
+	// en: The first signal only cancels
+	signals <- os.Interrupt
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the context to be cancelled by the first signal")
+	}
+	select {
+	case code := <-exited:
+		t.Fatalf("Did not expect an exit after the first signal, got code %d", code)
+	default:
+	}
+
+	// ja: 2 回目は強制終了
+	// en: The second signal forces an exit
+	signals <- os.Interrupt
+	select {
+	case code := <-exited:
+		if code != interruptExitCode {
+			t.Errorf("Expected exit code %d, got %d", interruptExitCode, code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the second signal to force an exit")
+	}
+}
+
+func TestWatchInterruptsStop(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	ctx, stop := watchInterrupts(signals)
+	stop()
+
+	if ctx.Err() == nil {
+		t.Error("Expected stop to cancel the context")
+	}
+}
@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+var (
+	statusSort         string
+	statusFilter       string
+	statusWarningsOnly bool
+)
+
+// ja: status コマンドの並び順
+// en: Sort orders for the status command
+const (
+	statusSortName       = "name"
+	statusSortLastBackup = "last-backup"
+	statusSortSize       = "size"
+)
+
+// ja: チェックアウトの状態
+// en: Checkout states
+const (
+	checkoutStatePresent = "present"
+	checkoutStateAbsent  = "absent"
+	checkoutStateUnknown = "unknown"
+)
+
+// ja: status コマンドの警告コード
+// en: Warning codes for the status command
+const (
+	warningNeverBackedUp      = "never_backed_up"
+	warningChangedSinceBackup = "changed_since_backup"
+	warningMissingPaths       = "missing_backup_paths"
+	warningStatusFailed       = "status_failed"
+)
+
+// ja: projectStatus は status コマンドで表示する 1 プロジェクト分の行です
+// en: projectStatus is one project's row in the status command
+type projectStatus struct {
+	Name         string     `json:"name" yaml:"name"`
+	Checkout     string     `json:"checkout" yaml:"checkout"`
+	LastBackup   *time.Time `json:"last_backup,omitempty" yaml:"last_backup,omitempty"`
+	LastAgeSec   int64      `json:"last_backup_age_seconds,omitempty" yaml:"last_backup_age_seconds,omitempty"`
+	BackupCount  int        `json:"backup_count" yaml:"backup_count"`
+	Retention    int        `json:"backup_retention,omitempty" yaml:"backup_retention,omitempty"`
+	DiskUsage    int64      `json:"disk_usage" yaml:"disk_usage"`
+	Warnings     []string   `json:"warnings" yaml:"warnings"`
+	ChangedFiles []string   `json:"changed_files,omitempty" yaml:"changed_files,omitempty"`
+	// ja: Error は状態を集められなかった理由です（status_failed の警告と一緒に設定します）
+	// en: Error is why the status could not be gathered (set along with the status_failed warning)
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ja: statusResult は status コマンドの構造化出力です
+// en: statusResult is the structured output of the status command
+type statusResult struct {
+	Projects []projectStatus `json:"projects" yaml:"projects"`
+	Total    int             `json:"total" yaml:"total"`
+}
+
+// ja: statusCmd は status コマンドを表します
+// en: statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: i18n.T("status.short"),
+	Long:  i18n.T("status.long"),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runStatus(); err != nil {
+			fmt.Fprintf(os.Stderr, i18n.T("common.error")+"\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+	statusCmd.Flags().StringVar(&statusSort, "sort", statusSortName, i18n.T("status.flag.sort"))
+	statusCmd.Flags().StringVar(&statusFilter, "filter", "", i18n.T("status.flag.filter"))
+	statusCmd.Flags().BoolVar(&statusWarningsOnly, "warnings-only", false, i18n.T("status.flag.warningsOnly"))
+}
+
+func runStatus() error {
+	switch statusSort {
+	case statusSortName, statusSortLastBackup, statusSortSize:
+	default:
+		return fmt.Errorf(i18n.T("status.invalidSort"), statusSort)
+	}
+
+	// ja: 設定ファイルを読み込む
+	// en: Load configuration file
+	store := newConfigStore()
+	config, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	statuses := []projectStatus{}
+	for i := range config.Projects {
+		project := &config.Projects[i]
+		if !matchProjectFilter(project.Name, statusFilter) {
+			continue
+		}
+
+		// ja: 1 件の失敗（壊れた backups.yaml など）で他のプロジェクトを隠さないよう、警告として残して続ける
+		// en: Keep going with a warning, so that one failure (a broken backups.yaml and so on) does not hide the other projects
+		status, err := collectProjectStatus(project, now)
+		if err != nil {
+			status.Error = err.Error()
+			status.Warnings = append(status.Warnings, warningStatusFailed)
+			if status.Checkout == "" {
+				status.Checkout = checkoutStateUnknown
+			}
+		}
+		if statusWarningsOnly && len(status.Warnings) == 0 {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+
+	sortProjectStatuses(statuses, statusSort)
+
+	if isStructuredOutput() {
+		return writeResult(statusResult{Projects: statuses, Total: len(statuses)})
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println(i18n.T("status.noProjects"))
+		return nil
+	}
+
+	printStatusTable(statuses)
+	return nil
+}
+
+// ja: matchProjectFilter はプロジェクト名がフィルターに一致するかを返します
+// ja: ワイルドカードを含む場合はグロブ、それ以外は部分一致で比較します
+// en: matchProjectFilter reports whether a project name matches the filter
+// en: Filters containing wildcards are globs; anything else is a substring match
+func matchProjectFilter(name, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if strings.ContainsAny(filter, "*?[") {
+		matched, err := filepath.Match(filter, name)
+		return err == nil && matched
+	}
+	return strings.Contains(name, filter)
+}
+
+// ja: collectProjectStatus はプロジェクト 1 件分の状態を集めます
+// en: collectProjectStatus gathers the status of a single project
+func collectProjectStatus(project *Project, now time.Time) (projectStatus, error) {
+	status := projectStatus{
+		Name:      project.Name,
+		Retention: project.BackupRetention,
+		Warnings:  []string{},
+	}
+
+	backupDir, err := getBackupDir(project.Name)
+	if err != nil {
+		return status, err
+	}
+
+	backups, err := summarizeBackups(backupDir, now)
+	if err != nil {
+		return status, err
+	}
+	status.BackupCount = backups.Count
+	status.DiskUsage = backups.TotalSize
+	status.LastBackup = backups.LatestTime
+	status.LastAgeSec = backups.LatestAgeSec
+
+	if backups.Count == 0 {
+		status.Warnings = append(status.Warnings, warningNeverBackedUp)
+	}
+
+	// ja: path が未設定のプロジェクトはカレントディレクトリ依存のため、チェックアウトを判定しない
+	// en: Projects without a path depend on the current directory, so their checkout is not inspected
+	if project.Path == "" {
+		status.Checkout = checkoutStateUnknown
+		return status, nil
+	}
+
+	projectDir, err := resolveProjectDir(project)
+	if err != nil {
+		return status, err
+	}
+	if !dirExists(projectDir) {
+		status.Checkout = checkoutStateAbsent
+		return status, nil
+	}
+	status.Checkout = checkoutStatePresent
+
+	// ja: archive_mode full ではディレクトリ全体を、exclude を適用してアーカイブと同じように調べる
+	// en: With archive_mode full, look at the whole directory with exclude applied, just as the archive does
+	full := project.ArchiveMode == toske.ArchiveModeFull
+	missing := false
+	for _, backupPath := range project.BackupPaths {
+		if _, err := os.Stat(filepath.Join(projectDir, backupPath)); os.IsNotExist(err) {
+			missing = true
+		}
+	}
+	if missing && !full {
+		status.Warnings = append(status.Warnings, warningMissingPaths)
+	}
+
+	if backups.LatestTime != nil {
+		var changed []string
+		if full {
+			changed, err = changedInTree(projectDir, project.Exclude, *backups.LatestTime)
+		} else {
+			changed, err = changedSince(projectDir, project.BackupPaths, *backups.LatestTime)
+		}
+		if err != nil {
+			return status, err
+		}
+		if len(changed) > 0 {
+			status.ChangedFiles = changed
+			status.Warnings = append(status.Warnings, warningChangedSinceBackup)
+		}
+	}
+
+	return status, nil
+}
+
+// ja: changedSince は backup_paths 内で指定時刻より後に更新されたファイルを返します
+// en: changedSince returns the files under backup_paths modified after the given time
+func changedSince(projectDir string, backupPaths []string, since time.Time) ([]string, error) {
+	var changed []string
+	for _, backupPath := range backupPaths {
+		root := filepath.Join(projectDir, backupPath)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().After(since) {
+				relPath, err := filepath.Rel(projectDir, path)
+				if err != nil {
+					return err
+				}
+				changed = append(changed, filepath.ToSlash(relPath))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// ja: changedInTree は archive_mode full でアーカイブされるファイルのうち、指定時刻より後に更新されたものを返します
+// en: changedInTree returns the files archive_mode full would archive that were modified after the given time
+func changedInTree(projectDir string, exclude []string, since time.Time) ([]string, error) {
+	var changed []string
+	err := toske.WalkTree(projectDir, exclude, func(name string, info os.FileInfo) error {
+		if info.ModTime().After(since) {
+			changed = append(changed, name)
+		}
+		return nil
+	})
+	return changed, err
+}
+
+// ja: sortProjectStatuses は指定された順序で並べ替えます
+// ja: 最終バックアップ順は古いもの（未バックアップを含む）が先頭になります
+// en: sortProjectStatuses sorts by the given order
+// en: Last-backup order puts the stalest first, including projects never backed up
+func sortProjectStatuses(statuses []projectStatus, order string) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		a, b := statuses[i], statuses[j]
+		switch order {
+		case statusSortLastBackup:
+			if a.LastBackup == nil || b.LastBackup == nil {
+				if a.LastBackup == nil && b.LastBackup != nil {
+					return true
+				}
+				if a.LastBackup != nil && b.LastBackup == nil {
+					return false
+				}
+				return a.Name < b.Name
+			}
+			if !a.LastBackup.Equal(*b.LastBackup) {
+				return a.LastBackup.Before(*b.LastBackup)
+			}
+		case statusSortSize:
+			if a.DiskUsage != b.DiskUsage {
+				return a.DiskUsage > b.DiskUsage
+			}
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ja: printStatusTable は状態を表形式で表示します
+// en: printStatusTable prints the statuses as a table
+func printStatusTable(statuses []projectStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, i18n.T("status.tableHeader"))
+
+	for _, status := range statuses {
+		lastBackup := "-"
+		if status.LastBackup != nil {
+			lastBackup = formatAge(time.Duration(status.LastAgeSec) * time.Second)
+		}
+
+		count := fmt.Sprintf("%d", status.BackupCount)
+		if status.Retention > 0 {
+			count = fmt.Sprintf("%d/%d", status.BackupCount, status.Retention)
+		}
+
+		warnings := make([]string, 0, len(status.Warnings))
+		for _, code := range status.Warnings {
+			warning := i18n.T("status.warning." + code)
+			if code == warningStatusFailed {
+				warning += ": " + status.Error
+			}
+			warnings = append(warnings, warning)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			status.Name,
+			i18n.T("status.checkout."+status.Checkout),
+			lastBackup,
+			count,
+			formatBytes(status.DiskUsage),
+			strings.Join(warnings, ", "))
+	}
+
+	w.Flush()
+}
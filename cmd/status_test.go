@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupStatusTest prepares three projects: one backed up with a file changed afterwards,
+// one never backed up whose checkout is gone, and one without a path.
+func setupStatusTest(t *testing.T) {
+	t.Helper()
+	tempDir := t.TempDir()
+
+	// ja: テスト環境を分離するため、HOMEを一時ディレクトリに設定
+	// en: Isolate test environment by setting HOME to temp directory
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+
+	checkoutDir := filepath.Join(tempDir, "src", "alpha")
+	if err := os.MkdirAll(checkoutDir, 0755); err != nil {
+		t.Fatalf("Failed to create checkout directory: %v", err)
+	}
+	envPath := filepath.Join(checkoutDir, ".env")
+	if err := os.WriteFile(envPath, []byte("TEST=value"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := createTestBackup(tempDir, "alpha", []testFile{{name: ".env", content: "TEST=value"}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+	if err := createTestBackup(tempDir, "gamma", []testFile{{name: "data.db", content: strings.Repeat("x", 4096)}}); err != nil {
+		t.Fatalf("Failed to create test backup: %v", err)
+	}
+
+	// ja: バックアップ後にファイルが変更されたことにする
+	// en: Pretend the file was modified after the backup
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(envPath, future, future); err != nil {
+		t.Fatalf("Failed to change file times: %v", err)
+	}
+
+	configData := `version: 1.0.0
+projects:
+  - name: gamma
+    repo: git@github.com:user/gamma.git
+    branch: main
+    backup_retention: 5
+  - name: alpha
+    repo: git@github.com:user/alpha.git
+    branch: main
+    path: ` + filepath.ToSlash(checkoutDir) + `
+    backup_paths:
+      - .env
+  - name: beta
+    repo: git@github.com:user/beta.git
+    branch: main
+    path: ` + filepath.ToSlash(filepath.Join(tempDir, "src", "beta")) + `
+`
+	t.Cleanup(setupTestConfig(t, configData))
+
+	originalSort, originalFilter, originalWarningsOnly := statusSort, statusFilter, statusWarningsOnly
+	t.Cleanup(func() {
+		statusSort, statusFilter, statusWarningsOnly = originalSort, originalFilter, originalWarningsOnly
+	})
+	statusSort, statusFilter, statusWarningsOnly = statusSortName, "", false
+}
+
+func TestRunStatus(t *testing.T) {
+	setupStatusTest(t)
+
+	output, err := captureStdout(t, runStatus)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header and 3 rows, got:\n%s", output)
+	}
+
+	expectedRows := []struct {
+		prefix   string
+		contains []string
+	}{
+		{prefix: "alpha", contains: []string{"present", "just now", "files changed since last backup"}},
+		{prefix: "beta", contains: []string{"absent", "never backed up"}},
+		{prefix: "gamma", contains: []string{"1/5", " B"}},
+	}
+	for i, row := range expectedRows {
+		line := lines[i+1]
+		if !strings.HasPrefix(line, row.prefix) {
+			t.Errorf("Expected row %d to start with %q, got: %s", i+1, row.prefix, line)
+		}
+		for _, expected := range row.contains {
+			if !strings.Contains(line, expected) {
+				t.Errorf("Expected row %q to contain %q, got: %s", row.prefix, expected, line)
+			}
+		}
+	}
+}
+
+func TestRunStatusSortAndFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		sort         string
+		filter       string
+		warningsOnly bool
+		expected     []string
+		expectError  bool
+	}{
+		{name: "sort by name", sort: statusSortName, expected: []string{"alpha", "beta", "gamma"}},
+		{name: "sort by size", sort: statusSortSize, expected: []string{"gamma", "alpha", "beta"}},
+		{name: "sort by last backup puts never backed up first", sort: statusSortLastBackup, expected: []string{"beta", "alpha", "gamma"}},
+		{name: "substring filter", sort: statusSortName, filter: "mm", expected: []string{"gamma"}},
+		{name: "glob filter", sort: statusSortName, filter: "*a", expected: []string{"alpha", "beta", "gamma"}},
+		{name: "warnings only", sort: statusSortName, warningsOnly: true, expected: []string{"alpha", "beta"}},
+		{name: "invalid sort", sort: "age", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupStatusTest(t)
+			setOutputFormat(t, outputJSON)
+			statusSort, statusFilter, statusWarningsOnly = tt.sort, tt.filter, tt.warningsOnly
+
+			output, err := captureStdout(t, runStatus)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var result statusResult
+			if err := json.Unmarshal([]byte(output), &result); err != nil {
+				t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+			}
+
+			var names []string
+			for _, status := range result.Projects {
+				names = append(names, status.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	projectDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectDir, "config"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{".env", "config/old.yml", "config/new.yml"} {
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	since := time.Now().Add(-time.Hour)
+	past := since.Add(-time.Hour)
+	for _, name := range []string{".env", "config/old.yml"} {
+		if err := os.Chtimes(filepath.Join(projectDir, name), past, past); err != nil {
+			t.Fatalf("Failed to change file times: %v", err)
+		}
+	}
+
+	changed, err := changedSince(projectDir, []string{".env", "config/", "missing.db"}, since)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"config/new.yml"}) {
+		t.Errorf("Expected only config/new.yml to be reported, got %v", changed)
+	}
+}
+
+func TestRunStatusKeepsGoingPastBrokenProject(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("USERPROFILE", tempDir) // Windows support
+	setOutputFormat(t, outputJSON)
+
+	for _, name := range []string{"alpha", "gamma"} {
+		if err := createTestBackup(tempDir, name, []testFile{{name: ".env", content: "TEST=value"}}); err != nil {
+			t.Fatalf("Failed to create test backup: %v", err)
+		}
+	}
+	brokenDir := filepath.Join(tempDir, ".config", "toske", "backups", "broken")
+	if err := os.MkdirAll(brokenDir, 0755); err != nil {
+		t.Fatalf("Failed to create backup directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(brokenDir, "backups.yaml"), []byte("backups: [unclosed"), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+
+	t.Cleanup(setupTestConfig(t, `version: 1.0.0
+projects:
+  - name: alpha
+    repo: git@github.com:user/alpha.git
+    branch: main
+  - name: broken
+    repo: git@github.com:user/broken.git
+    branch: main
+  - name: gamma
+    repo: git@github.com:user/gamma.git
+    branch: main
+`))
+	originalSort, originalFilter, originalWarningsOnly := statusSort, statusFilter, statusWarningsOnly
+	t.Cleanup(func() {
+		statusSort, statusFilter, statusWarningsOnly = originalSort, originalFilter, originalWarningsOnly
+	})
+	statusSort, statusFilter, statusWarningsOnly = statusSortName, "", false
+
+	output, err := captureStdout(t, runStatus)
+	if err != nil {
+		t.Fatalf("Expected the broken project not to fail the command, got %v", err)
+	}
+	var result statusResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, output)
+	}
+	if result.Total != 3 {
+		t.Fatalf("Expected all 3 projects, got %+v", result.Projects)
+	}
+	for _, status := range result.Projects {
+		failed := reflect.DeepEqual(status.Warnings, []string{warningStatusFailed}) && status.Error != ""
+		if status.Name == "broken" && !failed {
+			t.Errorf("Expected broken to carry a status_failed warning, got %+v", status)
+		}
+		if status.Name != "broken" && (status.BackupCount != 1 || len(status.Warnings) != 0) {
+			t.Errorf("Expected %s to be reported normally, got %+v", status.Name, status)
+		}
+	}
+}
+
+func TestChangedInTree(t *testing.T) {
+	projectDir := t.TempDir()
+	for _, name := range []string{"main.go", "dist/app", "node_modules/x/index.js", "old.txt"} {
+		path := filepath.Join(projectDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	since := time.Now().Add(-time.Hour)
+	past := since.Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(projectDir, "old.txt"), past, past); err != nil {
+		t.Fatalf("Failed to change file times: %v", err)
+	}
+
+	changed, err := changedInTree(projectDir, []string{"dist", "node_modules"}, since)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"main.go"}) {
+		t.Errorf("Expected only main.go to be reported, got %v", changed)
+	}
+}
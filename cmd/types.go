@@ -1,18 +1,50 @@
 package cmd
 
+import "github.com/yk-lab/toske/toske"
+
 // ja: Config は設定ファイルの構造を表します
+// ja: Include は追加で読み込むプロジェクトファイルの glob パターンです（設定ファイルからの相対パス）
 // en: Config represents the structure of the configuration file
+// en: Include lists glob patterns of extra project files to load (relative to the config file)
 type Config struct {
-	Version  string    `mapstructure:"version" yaml:"version"`
-	Projects []Project `mapstructure:"projects" yaml:"projects"`
+	Version  string    `mapstructure:"version" yaml:"version" json:"version"`
+	Include  []string  `mapstructure:"include" yaml:"include,omitempty" json:"include,omitempty"`
+	Projects []Project `mapstructure:"projects" yaml:"projects" json:"projects"`
 }
 
 // ja: Project はプロジェクト設定を表します
+// ja: Path はローカルのチェックアウト先で、未指定の場合はカレントディレクトリを使用します
 // en: Project represents a project configuration
+// en: Path is the local checkout directory; the current directory is used when it is empty
+// ja: VCS はチェックアウトを管理するバージョン管理システムです（未指定の場合は git、none の場合は repo と branch は不要）
+// en: VCS is the version control system of the checkout (git when empty; repo and branch are not needed for none)
 type Project struct {
-	Name            string   `mapstructure:"name" yaml:"name"`
-	Repo            string   `mapstructure:"repo" yaml:"repo"`
-	Branch          string   `mapstructure:"branch" yaml:"branch"`
-	BackupPaths     []string `mapstructure:"backup_paths" yaml:"backup_paths,omitempty"`
-	BackupRetention int      `mapstructure:"backup_retention" yaml:"backup_retention,omitempty"`
+	Name            string   `mapstructure:"name" yaml:"name" json:"name"`
+	VCS             string   `mapstructure:"vcs" yaml:"vcs,omitempty" json:"vcs,omitempty"`
+	Repo            string   `mapstructure:"repo" yaml:"repo" json:"repo"`
+	Branch          string   `mapstructure:"branch" yaml:"branch" json:"branch"`
+	Path            string   `mapstructure:"path" yaml:"path,omitempty" json:"path,omitempty"`
+	BackupPaths     []string `mapstructure:"backup_paths" yaml:"backup_paths,omitempty" json:"backup_paths,omitempty"`
+	BackupRetention int      `mapstructure:"backup_retention" yaml:"backup_retention,omitempty" json:"backup_retention,omitempty"`
+	Dumps           []Dump   `mapstructure:"dumps" yaml:"dumps,omitempty" json:"dumps,omitempty"`
+	// ja: IncludeGitState が true の場合、プッシュしていないブランチ・stash・コミットしていない変更もバックアップします
+	// en: When IncludeGitState is true, unpushed branches, stashes and uncommitted changes are backed up too
+	IncludeGitState bool `mapstructure:"include_git_state" yaml:"include_git_state,omitempty" json:"include_git_state,omitempty"`
+	// ja: SkipGitFeatures は toske に扱わせない git の機能（submodules、worktrees、lfs）です
+	// en: SkipGitFeatures lists the git features toske should leave alone (submodules, worktrees, lfs)
+	SkipGitFeatures []string `mapstructure:"skip_git_features" yaml:"skip_git_features,omitempty" json:"skip_git_features,omitempty"`
+	// ja: ArchiveMode は何をアーカイブするかです（未指定または paths は backup_paths、full はディレクトリ全体）
+	// ja: Exclude は full の場合にアーカイブしないパスのパターンです（ビルドの出力など）
+	// en: ArchiveMode is what gets archived (the backup_paths when empty or paths, the whole directory with full)
+	// en: Exclude holds the patterns of paths left out with full (build outputs and so on)
+	ArchiveMode string   `mapstructure:"archive_mode" yaml:"archive_mode,omitempty" json:"archive_mode,omitempty"`
+	Exclude     []string `mapstructure:"exclude" yaml:"exclude,omitempty" json:"exclude,omitempty"`
+
+	// ja: Origins はフィールドごとの出所（設定ファイル、include したファイル、.toske.yml）です
+	// en: Origins maps each field to the files it came from (the config, an included file or .toske.yml)
+	Origins map[string][]string `mapstructure:"-" yaml:"-" json:"-"`
 }
+
+// ja: Dump はバックアップ時に取得するデータベースダンプの設定を表します（toske パッケージと共通）
+// en: Dump represents a database dump taken during backup (shared with the toske package)
+type Dump = toske.Dump
@@ -3,12 +3,23 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
 )
 
+// ja: validateResult は validate コマンドの構造化出力です
+// en: validateResult is the structured output of the validate command
+type validateResult struct {
+	ConfigPath   string   `json:"config_path" yaml:"config_path"`
+	Valid        bool     `json:"valid" yaml:"valid"`
+	ProjectCount int      `json:"project_count" yaml:"project_count"`
+	Errors       []string `json:"errors" yaml:"errors"`
+}
+
 // ja: validateCmd は validate コマンドを表します
 // en: validateCmd represents the validate command
 var validateCmd = &cobra.Command{
@@ -28,75 +39,117 @@ func init() {
 }
 
 func runValidate() error {
-	// ja: 設定ファイルパスを決定
-	// en: Determine config file path
-	configPath := cfgFile
-	if configPath == "" {
-		configPath = getDefaultConfigPath()
-	}
-
-	// ja: 設定ファイルが存在するかチェック
-	// en: Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return fmt.Errorf(i18n.T("validate.noConfig"), configPath)
-	}
-
-	fmt.Printf(i18n.T("validate.checking")+"\n", configPath)
+	store := newConfigStore()
+	msgf(i18n.T("validate.checking")+"\n", store.Path())
 
 	// ja: 設定ファイルを読み込む
 	// en: Load configuration file
-	v := viper.New()
-	v.SetConfigFile(configPath)
-
-	if err := v.ReadInConfig(); err != nil {
-		return fmt.Errorf(i18n.T("validate.readError"), err)
-	}
-
-	// ja: 設定を構造体にアンマーシャル
-	// en: Unmarshal config into struct
-	var config Config
-	if err := v.Unmarshal(&config); err != nil {
-		return fmt.Errorf(i18n.T("validate.parseError"), err)
+	config, err := store.Load()
+	if err != nil {
+		return err
 	}
 
 	// ja: 設定を検証
 	// en: Validate configuration
-	if err := validateConfig(&config); err != nil {
-		return err
+	errs := validateConfigErrors(config)
+
+	// ja: 構造化出力の場合はすべてのエラーを結果として書き出す
+	// en: For structured output, write every error as part of the result
+	if isStructuredOutput() {
+		result := validateResult{
+			ConfigPath:   store.Path(),
+			Valid:        len(errs) == 0,
+			ProjectCount: len(config.Projects),
+			Errors:       []string{},
+		}
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		if err := writeResult(result); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
 	}
 
-	fmt.Println(i18n.T("validate.success"))
-	fmt.Printf(i18n.T("validate.projectCount")+"\n", len(config.Projects))
+	msgln(i18n.T("validate.success"))
+	msgf(i18n.T("validate.projectCount")+"\n", len(config.Projects))
 
 	return nil
 }
 
-// ja: validateConfig は設定ファイルの内容を検証します
-// en: validateConfig validates the contents of the configuration
+// ja: validateConfig は設定ファイルの内容を検証し、最初のエラーを返します
+// en: validateConfig validates the contents of the configuration and returns the first error
 func validateConfig(config *Config) error {
+	if errs := validateConfigErrors(config); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ja: validateConfigErrors は設定ファイルの内容を検証し、見つかったすべてのエラーを返します
+// ja: 各プロジェクトについては最初のエラーのみを報告します
+// en: validateConfigErrors validates the contents of the configuration and returns every error found
+// en: Only the first error of each project is reported
+func validateConfigErrors(config *Config) []error {
+	var errs []error
+
 	// ja: バージョンの検証
 	// en: Validate version
 	if config.Version == "" {
-		return fmt.Errorf("%s", i18n.T("validate.error.noVersion"))
+		errs = append(errs, fmt.Errorf("%s", i18n.T("validate.error.noVersion")))
+	} else if err := checkConfigVersion(config.Version); err != nil {
+		errs = append(errs, err)
 	}
 
 	// ja: プロジェクトの検証
 	// en: Validate projects
 	if len(config.Projects) == 0 {
-		return fmt.Errorf("%s", i18n.T("validate.error.noProjects"))
+		errs = append(errs, fmt.Errorf("%s", i18n.T("validate.error.noProjects")))
 	}
 
 	// ja: 各プロジェクトを検証
 	// en: Validate each project
 	projectNames := make(map[string]bool)
+	projectSources := make(map[string]string)
 	for i, project := range config.Projects {
+		// ja: include や .toske.yml をマージした設定では、重複した名前の定義元を両方示す
+		// en: For configs merged from includes, name both files that define a duplicate name
+		if source, seen := projectSources[project.Name]; seen && source != "" {
+			errs = append(errs, fmt.Errorf(i18n.T("validate.error.duplicateNameSources"), project.Name, source, project.origin("name")))
+			continue
+		}
 		if err := validateProject(&project, i, projectNames); err != nil {
-			return err
+			errs = append(errs, err)
+		}
+		if project.Name != "" {
+			projectNames[project.Name] = true
+			projectSources[project.Name] = project.origin("name")
 		}
-		projectNames[project.Name] = true
 	}
 
-	return nil
+	errs = append(errs, envOverrideCollisionErrors(config.Projects)...)
+
+	return errs
+}
+
+// ja: envOverrideCollisionErrors は、上書き用の環境変数名が同じになってしまう別々のプロジェクト（my-app と my_app など）を報告します
+// ja: どちらのプロジェクトを上書きするのか決められないため、設定エラーとして扱います
+// en: envOverrideCollisionErrors reports distinct projects whose override environment variables come out the same (my-app and my_app, say)
+// en: There is no telling which project such a variable overrides, so it is a configuration error
+func envOverrideCollisionErrors(projects []Project) []error {
+	var errs []error
+	reported := make(map[[2]string]bool)
+	for _, collision := range findEnvOverrideCollisions(projects) {
+		if reported[collision.Projects] {
+			continue
+		}
+		reported[collision.Projects] = true
+		errs = append(errs, fmt.Errorf(i18n.T("validate.error.envOverrideCollision"), collision.Projects[0], collision.Projects[1], collision.Variable))
+	}
+	return errs
 }
 
 // ja: validateProject は個々のプロジェクト設定を検証します
@@ -109,6 +162,9 @@ func validateProject(project *Project, index int, projectNames map[string]bool)
 	if project.Name == "" {
 		return fmt.Errorf(i18n.T("validate.error.projectNoName"), projectNum)
 	}
+	if err := checkProjectName(project.Name); err != nil {
+		return err
+	}
 
 	// ja: 重複した名前のチェック
 	// en: Check for duplicate names
@@ -116,16 +172,26 @@ func validateProject(project *Project, index int, projectNames map[string]bool)
 		return fmt.Errorf(i18n.T("validate.error.duplicateName"), project.Name)
 	}
 
-	// ja: リポジトリURLの検証
-	// en: Validate repository URL
-	if project.Repo == "" {
-		return fmt.Errorf(i18n.T("validate.error.projectNoRepo"), project.Name)
+	// ja: リポジトリやブランチなど、VCS ごとに必要なフィールドの検証
+	// en: Validate the fields each VCS needs, such as the repository and branch
+	v, err := newVCS(project, gitRunner)
+	if err != nil {
+		return err
+	}
+	if err := v.validate(project); err != nil {
+		return err
 	}
 
-	// ja: ブランチ名の検証
-	// en: Validate branch name
-	if project.Branch == "" {
-		return fmt.Errorf(i18n.T("validate.error.projectNoBranch"), project.Name)
+	// ja: archive_mode の検証（full ではディレクトリ全体に .git も含まれるため、include_git_state は不要）
+	// en: Validate archive_mode (full already archives .git along with everything else, so include_git_state is redundant)
+	switch project.ArchiveMode {
+	case "", toske.ArchiveModePaths:
+	case toske.ArchiveModeFull:
+		if project.IncludeGitState {
+			return fmt.Errorf(i18n.T("validate.error.gitStateWithFullArchive"), project.Name)
+		}
+	default:
+		return fmt.Errorf(i18n.T("validate.error.unknownArchiveMode"), project.Name, project.ArchiveMode, strings.Join([]string{toske.ArchiveModePaths, toske.ArchiveModeFull}, ", "))
 	}
 
 	// ja: backup_retention の検証（0以上である必要がある）
@@ -134,5 +200,61 @@ func validateProject(project *Project, index int, projectNames map[string]bool)
 		return fmt.Errorf(i18n.T("validate.error.invalidRetention"), project.Name, project.BackupRetention)
 	}
 
+	// ja: データベースダンプ設定の検証
+	// en: Validate database dump configuration
+	dumpNames := make(map[string]bool)
+	for i, dump := range project.Dumps {
+		if err := validateDump(project.Name, &dump, i, dumpNames); err != nil {
+			return err
+		}
+		dumpNames[dump.Name] = true
+	}
+
+	return nil
+}
+
+// ja: checkProjectName はプロジェクト名がバックアップディレクトリの名前として使えるかを確かめます
+// ja: 名前はバックアップのルートの下にそのまま結合されるため、パス区切りを含まない 1 つの要素に限ります
+// en: checkProjectName checks that a project name can be used as the name of its backup directory
+// en: The name is joined onto the backup root as is, so it must be a single path element without separators
+func checkProjectName(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) || name != filepath.Base(name) {
+		return fmt.Errorf(i18n.T("validate.error.invalidName"), name)
+	}
+	return nil
+}
+
+// ja: validateDump は個々のダンプ設定を検証します
+// en: validateDump validates an individual dump configuration
+func validateDump(projectName string, dump *Dump, index int, dumpNames map[string]bool) error {
+	// ja: 出力名はアーカイブ内のファイル名になるため、パス区切りを含めない
+	// en: The output name becomes a file name inside the archive, so it must not contain path separators
+	if dump.Name == "" {
+		return fmt.Errorf(i18n.T("validate.error.dumpNoName"), projectName, index+1)
+	}
+	if strings.ContainsAny(dump.Name, `/\`) || dump.Name == "." || dump.Name == ".." {
+		return fmt.Errorf(i18n.T("validate.error.dumpInvalidName"), projectName, dump.Name)
+	}
+	if dumpNames[dump.Name] {
+		return fmt.Errorf(i18n.T("validate.error.dumpDuplicateName"), projectName, dump.Name)
+	}
+
+	switch dump.Provider {
+	case toske.DumpProviderPostgres, toske.DumpProviderMySQL:
+		if dump.Database == "" {
+			return fmt.Errorf(i18n.T("validate.error.dumpNoDatabase"), projectName, dump.Name)
+		}
+	case toske.DumpProviderCommand:
+		if dump.Command == "" {
+			return fmt.Errorf(i18n.T("validate.error.dumpNoCommand"), projectName, dump.Name)
+		}
+	default:
+		return fmt.Errorf(i18n.T("validate.error.dumpUnknownProvider"), projectName, dump.Name, dump.Provider)
+	}
+
+	if dump.Port < 0 {
+		return fmt.Errorf(i18n.T("validate.error.dumpInvalidPort"), projectName, dump.Name, dump.Port)
+	}
+
 	return nil
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -161,6 +162,28 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestValidateConfigEnvOverrideCollision(t *testing.T) {
+	config := &Config{
+		Version: "1.0.0",
+		Projects: []Project{
+			{Name: "my-app", Repo: "git@github.com:user/my-app.git", Branch: "main"},
+			{Name: "my_app", Repo: "git@github.com:user/my_app.git", Branch: "main"},
+			{Name: "My.App", Repo: "git@github.com:user/MyApp.git", Branch: "main"},
+			{Name: "other", Repo: "git@github.com:user/other.git", Branch: "main"},
+		},
+	}
+
+	errs := validateConfigErrors(config)
+	if len(errs) != 2 {
+		t.Fatalf("Expected one error per colliding project, got %v", errs)
+	}
+	for _, err := range errs {
+		if !strings.Contains(err.Error(), "'my-app'") || !strings.Contains(err.Error(), "TOSKE_PROJECT_MY_APP_") {
+			t.Errorf("Expected the error to name both projects and the variable, got: %v", err)
+		}
+	}
+}
+
 func TestValidateProject(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -205,6 +228,39 @@ func TestValidateProject(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "project name traversing out of the backup root",
+			project: &Project{
+				Name:   "../../.ssh",
+				Repo:   "git@github.com:user/repo.git",
+				Branch: "main",
+			},
+			index:        0,
+			projectNames: map[string]bool{},
+			expectError:  true,
+		},
+		{
+			name: "project name with a backslash",
+			project: &Project{
+				Name:   `team\app`,
+				Repo:   "git@github.com:user/repo.git",
+				Branch: "main",
+			},
+			index:        0,
+			projectNames: map[string]bool{},
+			expectError:  true,
+		},
+		{
+			name: "project name of a dot",
+			project: &Project{
+				Name:   ".",
+				Repo:   "git@github.com:user/repo.git",
+				Branch: "main",
+			},
+			index:        0,
+			projectNames: map[string]bool{},
+			expectError:  true,
+		},
 		{
 			name: "project without repo",
 			project: &Project{
@@ -237,6 +293,89 @@ func TestValidateProject(t *testing.T) {
 			projectNames: make(map[string]bool),
 			expectError:  true,
 		},
+		{
+			name: "no vcs without repo and branch (valid)",
+			project: &Project{
+				Name: "test-project",
+				VCS:  vcsNone,
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  false,
+		},
+		{
+			name: "no vcs with git state",
+			project: &Project{
+				Name:            "test-project",
+				VCS:             vcsNone,
+				IncludeGitState: true,
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  true,
+		},
+		{
+			name: "unknown vcs",
+			project: &Project{
+				Name:   "test-project",
+				VCS:    "hg",
+				Repo:   "https://example.com/repo",
+				Branch: "default",
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  true,
+		},
+		{
+			name: "unknown git feature",
+			project: &Project{
+				Name:            "test-project",
+				Repo:            "git@github.com:user/repo.git",
+				Branch:          "main",
+				SkipGitFeatures: []string{"lfs", "hooks"},
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  true,
+		},
+		{
+			name: "full archive mode (valid)",
+			project: &Project{
+				Name:        "test-project",
+				Repo:        "git@github.com:user/repo.git",
+				Branch:      "main",
+				ArchiveMode: "full",
+				Exclude:     []string{"node_modules", "dist/"},
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  false,
+		},
+		{
+			name: "full archive mode with git state",
+			project: &Project{
+				Name:            "test-project",
+				Repo:            "git@github.com:user/repo.git",
+				Branch:          "main",
+				ArchiveMode:     "full",
+				IncludeGitState: true,
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  true,
+		},
+		{
+			name: "unknown archive mode",
+			project: &Project{
+				Name:        "test-project",
+				Repo:        "git@github.com:user/repo.git",
+				Branch:      "main",
+				ArchiveMode: "everything",
+			},
+			index:        0,
+			projectNames: make(map[string]bool),
+			expectError:  true,
+		},
 		{
 			name: "zero backup retention (valid)",
 			project: &Project{
@@ -358,3 +497,83 @@ func TestRunValidateNoConfig(t *testing.T) {
 		t.Errorf("Expected error for non-existent config file but got nil")
 	}
 }
+
+func TestValidateDump(t *testing.T) {
+	tests := []struct {
+		name         string
+		dumps        []Dump
+		expectError  bool
+		errorMessage string
+	}{
+		{
+			name: "valid dumps",
+			dumps: []Dump{
+				{Name: "app.sql", Provider: "postgres", Database: "app"},
+				{Name: "legacy.sql", Provider: "mysql", Database: "legacy", Port: 3306},
+				{Name: "redis.rdb", Provider: "command", Command: "redis-cli --rdb -"},
+			},
+		},
+		{
+			name:         "missing name",
+			dumps:        []Dump{{Provider: "postgres", Database: "app"}},
+			expectError:  true,
+			errorMessage: "missing the 'name' field",
+		},
+		{
+			name:         "name with path separator",
+			dumps:        []Dump{{Name: "../app.sql", Provider: "postgres", Database: "app"}},
+			expectError:  true,
+			errorMessage: "invalid dump name",
+		},
+		{
+			name: "duplicate name",
+			dumps: []Dump{
+				{Name: "app.sql", Provider: "postgres", Database: "app"},
+				{Name: "app.sql", Provider: "mysql", Database: "app"},
+			},
+			expectError:  true,
+			errorMessage: "duplicate dump name",
+		},
+		{
+			name:         "missing database",
+			dumps:        []Dump{{Name: "app.sql", Provider: "mysql"}},
+			expectError:  true,
+			errorMessage: "missing the 'database' field",
+		},
+		{
+			name:         "missing command",
+			dumps:        []Dump{{Name: "data", Provider: "command"}},
+			expectError:  true,
+			errorMessage: "missing the 'command' field",
+		},
+		{
+			name:         "unknown provider",
+			dumps:        []Dump{{Name: "data", Provider: "oracle"}},
+			expectError:  true,
+			errorMessage: "unknown provider 'oracle'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := Project{
+				Name:   "test-project",
+				Repo:   "git@github.com:user/test.git",
+				Branch: "main",
+				Dumps:  tt.dumps,
+			}
+
+			err := validateProject(&project, 0, map[string]bool{})
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				} else if !strings.Contains(err.Error(), tt.errorMessage) {
+					t.Errorf("Expected error message to contain '%s', got: %v", tt.errorMessage, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+	"github.com/yk-lab/toske/toske"
+)
+
+// ja: プロジェクトのバージョン管理システムの種類
+// en: Version control systems a project can use
+const (
+	vcsGit  = "git"
+	vcsNone = "none"
+)
+
+// ja: vcsNames は vcs に指定できる名前です（空の場合は git）
+// en: vcsNames are the names accepted in vcs (git when empty)
+var vcsNames = []string{vcsGit, vcsNone}
+
+// ja: vcs はプロジェクトのチェックアウトを管理するバージョン管理システムです
+// ja: 各コマンドは git を前提とせず、必要なことをこのインターフェースに問い合わせます
+// en: vcs is the version control system managing a project's checkout
+// en: Commands ask it for what they need instead of assuming git
+type vcs interface {
+	// ja: validate はこの VCS が必要とするプロジェクトのフィールドを検証します
+	// en: validate checks the project fields this VCS requires
+	validate(project *Project) error
+	// ja: inspect は dir のチェックアウトの状態を調べます（分かった範囲だけを返します）
+	// en: inspect inspects the checkout at dir (returning only what could be determined)
+	inspect(dir string) checkoutStatus
+	// ja: detect は add で使うリポジトリとブランチを dir から推測します（分からなければ空）
+	// en: detect guesses the repository and branch for add from dir (empty when unknown)
+	detect(dir string) (repo, branch string)
+	// ja: suggestBackupPaths は add でバックアップ対象として提案するファイルを返します
+	// en: suggestBackupPaths returns the files add suggests as backup paths
+	suggestBackupPaths(dir string) []string
+	// ja: captureState はバックアップに記録するチェックアウトの状態を返します（記録するものがなければ nil）
+	// en: captureState returns the state of the checkout to record in a backup (nil when there is nothing to record)
+	captureState(dir string) *toske.GitState
+	// ja: warnBackup はバックアップで扱いきれないものを警告します
+	// en: warnBackup warns about what a backup cannot deal with
+	warnBackup(project *Project, dir string)
+	// ja: compare はチェックアウトを記録された状態と比べます（ずれがなければ nil）
+	// en: compare compares the checkout with the recorded state (nil when they match)
+	compare(dir string, recorded *toske.GitState) *checkoutDrift
+	// ja: clone は存在しない dir にプロジェクトのリポジトリをクローンします
+	// en: clone clones the project's repository into dir, which does not exist
+	clone(project *Project, dir string) error
+	// ja: checkout は記録された状態をチェックアウトします
+	// en: checkout checks out the recorded state
+	checkout(dir string, recorded toske.GitState) error
+	// ja: planRestore は復元の後に行う処理を表示し、afterRestore はそれを行います
+	// en: planRestore prints what will be done after restoring, and afterRestore does it
+	planRestore(project *Project, dir string, checkout bool)
+	afterRestore(project *Project, dir string, checkout bool)
+	// ja: checkDelete はプロジェクトを設定から外す前やディレクトリを削除する前に、失われうるものを警告します
+	// ja: force でなければ、失われうるものがある場合にエラーを返します
+	// en: checkDelete warns about what could be lost before a project is taken out of the config or its directory removed
+	// en: Unless force is set, it returns an error when there is anything that could be lost
+	checkDelete(project *Project, force bool) error
+}
+
+// ja: newVCS はプロジェクトの vcs に対応する実装を返します
+// en: newVCS returns the implementation for the project's vcs
+func newVCS(project *Project, runner commandRunner) (vcs, error) {
+	switch project.VCS {
+	case "", vcsGit:
+		return &gitVCS{runner: runner}, nil
+	case vcsNone:
+		return noneVCS{}, nil
+	default:
+		return nil, fmt.Errorf(i18n.T("validate.error.unknownVCS"), project.Name, project.VCS, strings.Join(vcsNames, ", "))
+	}
+}
+
+// ja: detectVCS は dir を管理している VCS の名前を返します（git のリポジトリでなければ none）
+// en: detectVCS returns the name of the VCS managing dir (none unless it is a git repository)
+func detectVCS(runner commandRunner, dir string) string {
+	if (&gitVCS{runner: runner}).inspect(dir).IsRepo {
+		return vcsGit
+	}
+	return vcsNone
+}
+
+// ja: gitVCS は git のチェックアウトを扱います
+// en: gitVCS deals with git checkouts
+type gitVCS struct {
+	runner commandRunner
+}
+
+func (g *gitVCS) validate(project *Project) error {
+	// ja: リポジトリURLの検証
+	// en: Validate repository URL
+	if project.Repo == "" {
+		return fmt.Errorf(i18n.T("validate.error.projectNoRepo"), project.Name)
+	}
+
+	// ja: ブランチ名の検証
+	// en: Validate branch name
+	if project.Branch == "" {
+		return fmt.Errorf(i18n.T("validate.error.projectNoBranch"), project.Name)
+	}
+
+	// ja: skip_git_features の検証（既知の機能の名前だけを受け付ける）
+	// en: Validate skip_git_features (only the names of known features are accepted)
+	for _, feature := range project.SkipGitFeatures {
+		if !containsString(gitFeatureNames, feature) {
+			return fmt.Errorf(i18n.T("validate.error.unknownGitFeature"), project.Name, feature, strings.Join(gitFeatureNames, ", "))
+		}
+	}
+	return nil
+}
+
+func (g *gitVCS) inspect(dir string) checkoutStatus {
+	return inspectCheckout(g.runner, dir)
+}
+
+func (g *gitVCS) detect(dir string) (repo, branch string) {
+	if out, err := gitOutput(g.runner, dir, "remote", "get-url", "origin"); err == nil {
+		repo = out
+	}
+	if out, err := gitOutput(g.runner, dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && out != "HEAD" {
+		branch = out
+	}
+	return repo, branch
+}
+
+func (g *gitVCS) suggestBackupPaths(dir string) []string {
+	return suggestIgnoredFiles(g.runner, dir)
+}
+
+func (g *gitVCS) captureState(dir string) *toske.GitState {
+	return captureGitState(g.runner, dir)
+}
+
+func (g *gitVCS) warnBackup(project *Project, dir string) {
+	warnGitFeatures(project, detectGitFeatures(g.runner, dir, project.SkipGitFeatures))
+}
+
+func (g *gitVCS) compare(dir string, recorded *toske.GitState) *checkoutDrift {
+	return compareCheckout(g.runner, dir, recorded)
+}
+
+func (g *gitVCS) clone(project *Project, dir string) error {
+	return cloneRepository(g.runner, project, dir)
+}
+
+func (g *gitVCS) checkout(dir string, recorded toske.GitState) error {
+	return checkoutRecorded(g.runner, dir, recorded)
+}
+
+func (g *gitVCS) planRestore(project *Project, dir string, checkout bool) {
+	printGitFeaturePlan(detectGitFeatures(g.runner, dir, project.SkipGitFeatures), checkout)
+}
+
+func (g *gitVCS) afterRestore(project *Project, dir string, checkout bool) {
+	syncGitFeatures(g.runner, project, dir, checkout)
+}
+
+func (g *gitVCS) checkDelete(project *Project, force bool) error {
+	return checkWorktrees(g.runner, project, force)
+}
+
+// ja: noneVCS はバージョン管理されていないディレクトリを扱います（backup_paths をアーカイブするだけです）
+// en: noneVCS deals with directories that are not under version control (it only archives the backup_paths)
+type noneVCS struct{}
+
+// ja: repo と branch は不要ですが、git の作業を保存する include_git_state は使えません
+// en: repo and branch are not needed, but include_git_state cannot be used as it saves git work
+func (noneVCS) validate(project *Project) error {
+	if project.IncludeGitState {
+		return fmt.Errorf(i18n.T("validate.error.gitStateWithoutGit"), project.Name, project.VCS)
+	}
+	return nil
+}
+
+func (noneVCS) inspect(dir string) checkoutStatus {
+	return checkoutStatus{Path: dir, Present: dirExists(dir)}
+}
+
+func (noneVCS) detect(string) (string, string) { return "", "" }
+
+// ja: 無視されているファイルを知る手段がないため、提案はしません
+// en: There is no way to tell which files are ignored, so nothing is suggested
+func (noneVCS) suggestBackupPaths(string) []string { return nil }
+
+func (noneVCS) captureState(string) *toske.GitState { return nil }
+
+func (noneVCS) warnBackup(*Project, string) {}
+
+func (noneVCS) compare(string, *toske.GitState) *checkoutDrift { return nil }
+
+func (noneVCS) clone(*Project, string) error {
+	return fmt.Errorf(i18n.T("vcs.cloneUnsupported"), vcsNone)
+}
+
+func (noneVCS) checkout(string, toske.GitState) error {
+	return fmt.Errorf(i18n.T("vcs.checkoutUnsupported"), vcsNone)
+}
+
+func (noneVCS) planRestore(*Project, string, bool) {}
+
+func (noneVCS) afterRestore(*Project, string, bool) {}
+
+func (noneVCS) checkDelete(*Project, bool) error { return nil }
@@ -0,0 +1,580 @@
+// Package configfile edits toske configuration files in place.
+//
+// ja: 設定ファイルを構造体から再生成するのではなく、yaml.Node の行情報を使って
+// ja: 変更箇所のテキストだけを差し替えます。そのためコメント、キーの順序、未知のキー、
+// ja: 変更していないプロジェクトの書式はバイト単位でそのまま残ります。
+// en: Rather than regenerating the file from a struct, edits use the line positions of
+// en: the yaml.Node tree to splice only the affected text. Comments, key order, unknown
+// en: keys and the formatting of untouched projects therefore survive byte for byte.
+package configfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ja: defaultMode は新しく作成する設定ファイルのパーミッションです
+// en: defaultMode is the permission of newly created config files
+const defaultMode os.FileMode = 0600
+
+var (
+	// ErrProjectNotFound is returned when no project has the given name.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrFlowStyle is returned when the node to edit is written in flow style ([...] or {...}).
+	ErrFlowStyle = errors.New("cannot edit YAML written in flow style; please use block style")
+	// ErrNotMapping is returned when the document or a project is not a YAML mapping.
+	ErrNotMapping = errors.New("not a YAML mapping")
+)
+
+// ja: File は編集中の設定ファイルです
+// en: File is a config file being edited
+type File struct {
+	path string
+	data []byte
+	mode os.FileMode
+}
+
+// ja: Load は設定ファイルを読み込みます
+// ja: ファイルが存在しない場合は空のファイルとして扱い、保存時に作成します
+// en: Load reads a config file
+// en: A missing file is treated as empty and created on Save
+func Load(path string) (*File, error) {
+	f := &File{path: path, mode: defaultMode}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.mode = info.Mode().Perm()
+
+	if f.data, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ja: Path は設定ファイルのパスを返します
+// en: Path returns the path of the config file
+func (f *File) Path() string {
+	return f.path
+}
+
+// ja: Bytes は現在の内容を返します
+// en: Bytes returns the current contents
+func (f *File) Bytes() []byte {
+	return f.data
+}
+
+// ja: Mode は設定ファイルのパーミッションを返します
+// en: Mode returns the permissions of the config file
+func (f *File) Mode() os.FileMode {
+	return f.mode
+}
+
+// ja: Save は内容を一時ファイルに書き出してから置き換えます（元のパーミッションを維持）
+// en: Save writes the contents to a temp file and renames it over the original (keeping its permissions)
+func (f *File) Save() error {
+	return WriteAtomic(f.path, f.data, f.mode)
+}
+
+// ja: WriteAtomic は同じディレクトリの一時ファイルを経由してファイルを置き換えます
+// ja: 途中で失敗しても元のファイルは壊れません
+// en: WriteAtomic replaces a file via a temp file in the same directory
+// en: The original file is left intact if anything fails midway
+// ja: path がシンボリックリンク（dotfiles で管理された設定など）の場合は、リンクを置き換えずにリンク先を書き換えます
+// en: When path is a symlink (a config kept in a dotfiles repo, say), the file it points to is replaced rather than the link
+func WriteAtomic(path string, data []byte, mode os.FileMode) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// ja: SetField はトップレベルのキーの値を設定します（キーがなければ末尾に追加）
+// en: SetField sets the value of a top-level key (appending the key when missing)
+func (f *File) SetField(key string, value any) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	root := documentRoot(doc)
+	if root == nil {
+		rendered, err := render(map[string]any{key: value}, "")
+		if err != nil {
+			return err
+		}
+		f.data = append(f.ensureTrailingNewline(), rendered...)
+		return nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return ErrNotMapping
+	}
+
+	return f.setInMapping(root, key, value)
+}
+
+// ja: AppendProject はプロジェクトを projects の末尾に追加します
+// en: AppendProject appends a project to the end of projects
+func (f *File) AppendProject(project any) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	entry, err := render([]any{project}, "")
+	if err != nil {
+		return err
+	}
+
+	lines := f.lines()
+	root := documentRoot(doc)
+	if root != nil && root.Kind != yaml.MappingNode {
+		return ErrNotMapping
+	}
+
+	key, value := findKey(root, "projects")
+	switch {
+	case key == nil:
+		// ja: projects キーがない場合はファイル末尾に追加
+		// en: No projects key, so append one at the end of the file
+		f.data = append(f.ensureTrailingNewline(), ("projects:\n" + indentLines(entry, "  "))...)
+		return nil
+
+	case value.Kind == yaml.SequenceNode && len(value.Content) > 0:
+		if value.Style&yaml.FlowStyle != 0 {
+			return ErrFlowStyle
+		}
+		// ja: 既存の要素と同じインデントで、最後の要素の直後に挿入
+		// en: Insert right after the last item, using the same indentation as existing items
+		last := lastLine(value)
+		f.setLines(splice(lines, last, last, indentLines(entry, itemIndent(lines, value.Content[0]))))
+		return nil
+
+	default:
+		// ja: 空（null や []）の場合は projects: の行をブロック形式に書き換える
+		// en: When empty (null or []), rewrite the projects: line into block style
+		indent := strings.Repeat(" ", key.Column-1)
+		f.setLines(splice(lines, key.Line-1, key.Line, indent+"projects:\n"+indentLines(entry, indent+"  ")))
+		return nil
+	}
+}
+
+// ja: RemoveProject は名前が一致するプロジェクトを projects から削除します
+// en: RemoveProject removes the project with the given name from projects
+func (f *File) RemoveProject(name string) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	key, projects, index, err := findProject(doc, name)
+	if err != nil {
+		return err
+	}
+
+	lines := f.lines()
+
+	// ja: 最後の 1 件を削除する場合は、空のリストとして残す
+	// en: When removing the only project, leave an empty list behind
+	if len(projects.Content) == 1 {
+		indent := strings.Repeat(" ", key.Column-1)
+		f.setLines(splice(lines, key.Line-1, lastLine(projects), indent+"projects: []\n"))
+		return nil
+	}
+
+	start, end := itemBounds(lines, projects, index)
+	f.setLines(splice(lines, start, end, ""))
+	return nil
+}
+
+// ja: ReplaceProject は名前が一致するプロジェクトを丸ごと置き換えます
+// en: ReplaceProject replaces the whole project with the given name
+func (f *File) ReplaceProject(name string, project any) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	_, projects, index, err := findProject(doc, name)
+	if err != nil {
+		return err
+	}
+
+	entry, err := render([]any{project}, "")
+	if err != nil {
+		return err
+	}
+
+	lines := f.lines()
+	start, end := itemBounds(lines, projects, index)
+	f.setLines(splice(lines, start, end, indentLines(entry, itemIndent(lines, projects.Content[index]))))
+	return nil
+}
+
+// ja: SetProjectField はプロジェクトの 1 つのキーの値を設定します（キーがなければ末尾に追加）
+// en: SetProjectField sets a single key of a project (appending the key when missing)
+func (f *File) SetProjectField(name, key string, value any) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	_, projects, index, err := findProject(doc, name)
+	if err != nil {
+		return err
+	}
+
+	return f.setInMapping(projects.Content[index], key, value)
+}
+
+// ja: DeleteProjectField はプロジェクトからキーを削除します（存在しない場合は何もしません）
+// en: DeleteProjectField removes a key from a project (doing nothing when it is absent)
+func (f *File) DeleteProjectField(name, key string) error {
+	doc, err := f.parse()
+	if err != nil {
+		return err
+	}
+
+	_, projects, index, err := findProject(doc, name)
+	if err != nil {
+		return err
+	}
+
+	mapping := projects.Content[index]
+	if mapping.Style&yaml.FlowStyle != 0 {
+		return ErrFlowStyle
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		keyNode := mapping.Content[i]
+		lines := f.lines()
+		start, end := fieldBounds(lines, mapping, i)
+
+		// ja: 要素の先頭キー（"- " の行）を削除する場合は、次のキーに "- " を引き継ぐ
+		// en: When removing the first key of an item (the "- " line), hand the "- " over to the next key
+		prefix := lines[keyNode.Line-1][:keyNode.Column-1]
+		if strings.TrimSpace(prefix) != "" && i+2 < len(mapping.Content) {
+			nextLine := mapping.Content[i+2].Line - 1
+			next := lines[nextLine]
+			lines[nextLine] = prefix + next[min(len(next), keyNode.Column-1):]
+			// ja: "- " の行より前に残る次のキーのコメントは、要素の前に置く
+			// en: The next key's comment, left before the "- " line, goes in front of the item
+			indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " "))]
+			for j := end; j < nextLine; j++ {
+				lines[j] = indent + strings.TrimLeft(lines[j], " ")
+			}
+		}
+
+		f.setLines(splice(lines, start, end, ""))
+		return nil
+	}
+	return nil
+}
+
+// ja: setInMapping はマッピング内のキーの値を書き換える、またはキーを追加します
+// en: setInMapping rewrites the value of a key in a mapping, or adds the key
+func (f *File) setInMapping(mapping *yaml.Node, key string, value any) error {
+	if mapping.Kind != yaml.MappingNode {
+		return ErrNotMapping
+	}
+	if mapping.Style&yaml.FlowStyle != 0 {
+		return ErrFlowStyle
+	}
+
+	lines := f.lines()
+	indent := strings.Repeat(" ", mapping.Column-1)
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode := mapping.Content[i]
+		if keyNode.Value != key {
+			continue
+		}
+
+		// ja: キーの前の部分（インデントや "- "）と、行末のコメントはそのまま残す
+		// en: Keep whatever precedes the key (indentation or "- ") and the comment at the end of the line
+		rendered, err := render(map[string]any{key: value}, indent)
+		if err != nil {
+			return err
+		}
+		start := keyNode.Line - 1
+		prefix := lines[start][:keyNode.Column-1]
+		rendered = prefix + strings.TrimPrefix(rendered, indent)
+		if comment := lineComment(keyNode, mapping.Content[i+1]); comment != "" {
+			first, rest, _ := strings.Cut(rendered, "\n")
+			rendered = first + " " + comment + "\n" + rest
+		}
+
+		f.setLines(splice(lines, start, valueEnd(lines, mapping, i), rendered))
+		return nil
+	}
+
+	rendered, err := render(map[string]any{key: value}, indent)
+	if err != nil {
+		return err
+	}
+	last := lastLine(mapping)
+	f.setLines(splice(lines, last, last, rendered))
+	return nil
+}
+
+// ja: parse は現在の内容を yaml.Node として解析します
+// en: parse parses the current contents into a yaml.Node
+func (f *File) parse() (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(f.data, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ja: lines は現在の内容を改行付きの行に分割します（最終行には必ず改行を付けます）
+// en: lines splits the current contents into lines with their newlines (the last line always gets one)
+func (f *File) lines() []string {
+	lines := strings.SplitAfter(string(f.ensureTrailingNewline()), "\n")
+	return lines[:len(lines)-1]
+}
+
+func (f *File) setLines(lines []string) {
+	f.data = []byte(strings.Join(lines, ""))
+}
+
+func (f *File) ensureTrailingNewline() []byte {
+	if len(f.data) > 0 && !bytes.HasSuffix(f.data, []byte("\n")) {
+		return append(f.data, '\n')
+	}
+	return f.data
+}
+
+// ja: documentRoot はドキュメントの最上位ノードを返します（空のドキュメントでは nil）
+// en: documentRoot returns the top-level node of the document (nil for an empty document)
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// ja: findKey はマッピングからキーとその値のノードを探します
+// en: findKey looks up a key and its value node in a mapping
+func findKey(mapping *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// ja: findProject は projects のキー、リスト、名前が一致する要素のインデックスを返します
+// en: findProject returns the projects key, the list and the index of the item with the given name
+func findProject(doc *yaml.Node, name string) (*yaml.Node, *yaml.Node, int, error) {
+	key, projects := findKey(documentRoot(doc), "projects")
+	if projects == nil || projects.Kind != yaml.SequenceNode {
+		return nil, nil, 0, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+
+	for i, item := range projects.Content {
+		if _, value := findKey(item, "name"); value != nil && value.Value == name {
+			if projects.Style&yaml.FlowStyle != 0 || item.Style&yaml.FlowStyle != 0 {
+				return nil, nil, 0, ErrFlowStyle
+			}
+			return key, projects, i, nil
+		}
+	}
+	return nil, nil, 0, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+}
+
+// ja: itemBounds はリストの要素が占める行の範囲 [start, end) を返します（0 始まり）
+// ja: 要素の直前にある、その要素に付いたコメント行も含めます
+// en: itemBounds returns the range of lines [start, end) occupied by a list item (0-based)
+// en: Comment lines attached directly above the item are included
+func itemBounds(lines []string, list *yaml.Node, index int) (int, int) {
+	item := list.Content[index]
+	start := item.Line - 1
+	if headLines := commentLines(item.HeadComment); headLines > 0 && start-headLines >= 0 {
+		start -= headLines
+	}
+
+	end := lastLine(item)
+	if index+1 < len(list.Content) {
+		// ja: 次の要素のコメントは次の要素に属する
+		// en: The next item's comment belongs to the next item
+		next := list.Content[index+1]
+		end = next.Line - 1 - commentLines(next.HeadComment)
+	}
+	return start, end
+}
+
+// ja: fieldBounds はマッピングの i 番目のキーとその値が占める行の範囲 [start, end) を返します（0 始まり）
+// ja: キーの前のコメントを含み、次のキーの前のコメントは含みません
+// en: fieldBounds returns the range of lines [start, end) occupied by the i-th key of a mapping and its value (0-based)
+// en: The comment before the key is included; the one before the next key is not
+func fieldBounds(lines []string, mapping *yaml.Node, i int) (int, int) {
+	keyNode := mapping.Content[i]
+	start := keyNode.Line - 1
+	if headLines := commentLines(keyNode.HeadComment); headLines > 0 && start-headLines >= 0 {
+		start -= headLines
+	}
+
+	end := lastLine(mapping.Content[i+1])
+	if i+2 < len(mapping.Content) {
+		next := mapping.Content[i+2]
+		end = next.Line - 1 - commentLines(next.HeadComment)
+	}
+	return start, end
+}
+
+// ja: valueEnd はマッピングの i 番目のキーの値が占める行の終わり（0 始まりで排他的）を返します
+// ja: 複数行にわたるプレーンやクォートのスカラーはノードから終わりの行が分からないため、次のキーの行
+// ja: （最後のキーではキーより深くインデントされた行の終わり）から求め、後ろの空行とコメント行は含めません
+// en: valueEnd returns the end (0-based, exclusive) of the lines occupied by the value of the i-th key of a mapping
+// en: A plain or quoted scalar spanning several lines does not tell where it ends, so the end is taken from the
+// en: line of the next key (or, for the last key, the end of the lines indented deeper than the key),
+// en: leaving out the blank and comment lines after the value
+func valueEnd(lines []string, mapping *yaml.Node, i int) int {
+	keyNode, value := mapping.Content[i], mapping.Content[i+1]
+	last := lastLine(value)
+
+	end := last
+	if i+2 < len(mapping.Content) {
+		end = mapping.Content[i+2].Line - 1
+	} else {
+		for end < len(lines) {
+			line := strings.TrimRight(lines[end], "\r\n")
+			if trimmed := strings.TrimLeft(line, " "); trimmed != "" && len(line)-len(trimmed) < keyNode.Column {
+				break
+			}
+			end++
+		}
+	}
+	for end > last {
+		if trimmed := strings.TrimSpace(lines[end-1]); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+// ja: lineComment はキーの行末にあるコメントを返します（スカラーの値ならその値に、そうでなければキーに付いています）
+// en: lineComment returns the comment at the end of the key's line (attached to the value when it is a scalar, otherwise to the key)
+func lineComment(keyNode, value *yaml.Node) string {
+	if value.Kind == yaml.ScalarNode && value.LineComment != "" && value.Line == keyNode.Line {
+		return value.LineComment
+	}
+	return keyNode.LineComment
+}
+
+// ja: itemIndent は "- " より前のインデントを返します
+// en: itemIndent returns the indentation before "- "
+func itemIndent(lines []string, item *yaml.Node) string {
+	line := lines[item.Line-1]
+	if dash := strings.Index(line, "-"); dash >= 0 && dash < item.Column {
+		return line[:dash]
+	}
+	return strings.Repeat(" ", max(item.Column-3, 0))
+}
+
+// ja: lastLine はノードとその子孫が占める最後の行番号（1 始まり）を返します
+// en: lastLine returns the last line (1-based) occupied by a node and its descendants
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	if node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		last += strings.Count(strings.TrimRight(node.Value, "\n"), "\n") + 1
+	}
+	for _, child := range node.Content {
+		if line := lastLine(child); line > last {
+			last = line
+		}
+	}
+	return last
+}
+
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+	return strings.Count(comment, "\n") + 1
+}
+
+// ja: splice は lines[start:end] を text に置き換えます
+// en: splice replaces lines[start:end] with text
+func splice(lines []string, start, end int, text string) []string {
+	result := make([]string, 0, len(lines)+1)
+	result = append(result, lines[:start]...)
+	if text != "" {
+		result = append(result, text)
+	}
+	return append(result, lines[end:]...)
+}
+
+// ja: render は値を 2 スペースインデントの YAML に変換し、各行にインデントを付けます
+// en: render encodes a value as YAML with 2-space indentation and indents every line
+func render(value any, indent string) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return indentLines(buf.String(), indent), nil
+}
+
+// ja: indentLines は空行以外の各行の先頭にインデントを付けます
+// en: indentLines prefixes every non-empty line with the indent
+func indentLines(text, indent string) string {
+	if indent == "" {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
+}
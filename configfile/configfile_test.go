@@ -0,0 +1,505 @@
+package configfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// testProject mirrors the fields of cmd.Project that matter for rendering.
+type testProject struct {
+	Name        string   `yaml:"name"`
+	Repo        string   `yaml:"repo"`
+	Branch      string   `yaml:"branch"`
+	BackupPaths []string `yaml:"backup_paths,omitempty"`
+}
+
+const sampleConfig = `# toske configuration
+version: 1.0.0 # schema version
+projects:
+  # ja: 最初のプロジェクト
+  # en: The first project
+  - name: first
+    repo: git@github.com:user/first.git
+    branch: main
+    custom_key: kept
+    backup_paths:
+      - .env
+
+  - name: second
+    repo: git@github.com:user/second.git   # aligned comment
+    branch: develop
+  - name: third
+    repo: git@github.com:user/third.git
+    branch: main
+    notes: |
+      line one
+      line two
+
+# ja: 必要に応じてプロジェクトを追加してください:
+# en: Add more projects as needed:
+#  - name: another-project
+unknown_top_level: true
+`
+
+// writeTempConfig writes content to a config file and returns its path.
+func writeTempConfig(t *testing.T, content string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	return path
+}
+
+func load(t *testing.T, content string) *File {
+	t.Helper()
+	f, err := Load(writeTempConfig(t, content, 0644))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return f
+}
+
+func TestRemoveProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		project  string
+		expected string
+	}{
+		{
+			name:    "first project with its comment",
+			input:   sampleConfig,
+			project: "first",
+			expected: `# toske configuration
+version: 1.0.0 # schema version
+projects:
+  - name: second
+    repo: git@github.com:user/second.git   # aligned comment
+    branch: develop
+  - name: third
+    repo: git@github.com:user/third.git
+    branch: main
+    notes: |
+      line one
+      line two
+
+# ja: 必要に応じてプロジェクトを追加してください:
+# en: Add more projects as needed:
+#  - name: another-project
+unknown_top_level: true
+`,
+		},
+		{
+			name:    "middle project",
+			input:   sampleConfig,
+			project: "second",
+			expected: `# toske configuration
+version: 1.0.0 # schema version
+projects:
+  # ja: 最初のプロジェクト
+  # en: The first project
+  - name: first
+    repo: git@github.com:user/first.git
+    branch: main
+    custom_key: kept
+    backup_paths:
+      - .env
+
+  - name: third
+    repo: git@github.com:user/third.git
+    branch: main
+    notes: |
+      line one
+      line two
+
+# ja: 必要に応じてプロジェクトを追加してください:
+# en: Add more projects as needed:
+#  - name: another-project
+unknown_top_level: true
+`,
+		},
+		{
+			name:    "last project with block scalar",
+			input:   sampleConfig,
+			project: "third",
+			expected: `# toske configuration
+version: 1.0.0 # schema version
+projects:
+  # ja: 最初のプロジェクト
+  # en: The first project
+  - name: first
+    repo: git@github.com:user/first.git
+    branch: main
+    custom_key: kept
+    backup_paths:
+      - .env
+
+  - name: second
+    repo: git@github.com:user/second.git   # aligned comment
+    branch: develop
+
+# ja: 必要に応じてプロジェクトを追加してください:
+# en: Add more projects as needed:
+#  - name: another-project
+unknown_top_level: true
+`,
+		},
+		{
+			name:     "only project",
+			input:    "version: 1.0.0\nprojects:\n  - name: only\n    repo: r\n# footer\n",
+			project:  "only",
+			expected: "version: 1.0.0\nprojects: []\n# footer\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := load(t, tt.input)
+			if err := f.RemoveProject(tt.project); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(f.Bytes()); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRemoveProjectNotFound(t *testing.T) {
+	f := load(t, sampleConfig)
+	if err := f.RemoveProject("missing"); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got: %v", err)
+	}
+	if string(f.Bytes()) != sampleConfig {
+		t.Error("Contents must not change when the project is not found")
+	}
+}
+
+func TestAppendProject(t *testing.T) {
+	project := testProject{Name: "b", Repo: "git@github.com:user/b.git", Branch: "main", BackupPaths: []string{".env"}}
+	entry := func(indent string) string {
+		return indent + "- name: b\n" +
+			indent + "  repo: git@github.com:user/b.git\n" +
+			indent + "  branch: main\n" +
+			indent + "  backup_paths:\n" +
+			indent + "    - .env\n"
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "empty flow list",
+			input:    "version: 1.0.0 # keep me\nprojects: []\n",
+			expected: "version: 1.0.0 # keep me\nprojects:\n" + entry("  "),
+		},
+		{
+			name:     "null projects",
+			input:    "version: 1.0.0\nprojects:\n# trailing comment\n",
+			expected: "version: 1.0.0\nprojects:\n" + entry("  ") + "# trailing comment\n",
+		},
+		{
+			name:     "no projects key",
+			input:    "version: 1.0.0",
+			expected: "version: 1.0.0\nprojects:\n" + entry("  "),
+		},
+		{
+			name:     "unindented items followed by another key",
+			input:    "projects:\n- name: a # first\n  repo: r\n  branch: main\n\nversion: 1.0.0\n",
+			expected: "projects:\n- name: a # first\n  repo: r\n  branch: main\n" + entry("") + "\nversion: 1.0.0\n",
+		},
+		{
+			name:     "block scalar in last item",
+			input:    "version: 1.0.0\nprojects:\n    - name: a\n      note: |\n        line one\n        line two\n",
+			expected: "version: 1.0.0\nprojects:\n    - name: a\n      note: |\n        line one\n        line two\n" + entry("    "),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := load(t, tt.input)
+			if err := f.AppendProject(project); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(f.Bytes()); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("flow style list", func(t *testing.T) {
+		f := load(t, "projects: [{name: a}]\n")
+		if err := f.AppendProject(project); !errors.Is(err, ErrFlowStyle) {
+			t.Errorf("Expected ErrFlowStyle, got: %v", err)
+		}
+	})
+}
+
+func TestReplaceProject(t *testing.T) {
+	input := "projects:\n  - name: a\n    repo: old # comment\n  # about b\n  - name: b\n    repo: r\n"
+	f := load(t, input)
+
+	if err := f.ReplaceProject("a", testProject{Name: "a", Repo: "new", Branch: "main"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "projects:\n  - name: a\n    repo: new\n    branch: main\n  # about b\n  - name: b\n    repo: r\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestSetProjectField(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		value    any
+		expected string
+	}{
+		{
+			name:     "replace scalar",
+			key:      "branch",
+			value:    "develop",
+			expected: "projects:\n  - name: a # first\n    branch: develop # keep?\n    backup_paths:\n      - .env\n  - name: b\n",
+		},
+		{
+			name:     "replace list",
+			key:      "backup_paths",
+			value:    []string{".env", "storage/"},
+			expected: "projects:\n  - name: a # first\n    branch: main # keep?\n    backup_paths:\n      - .env\n      - storage/\n  - name: b\n",
+		},
+		{
+			name:     "replace first key keeps the dash",
+			key:      "name",
+			value:    "renamed",
+			expected: "projects:\n  - name: renamed # first\n    branch: main # keep?\n    backup_paths:\n      - .env\n  - name: b\n",
+		},
+		{
+			name:     "add missing key",
+			key:      "backup_retention",
+			value:    3,
+			expected: "projects:\n  - name: a # first\n    branch: main # keep?\n    backup_paths:\n      - .env\n    backup_retention: 3\n  - name: b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := load(t, "projects:\n  - name: a # first\n    branch: main # keep?\n    backup_paths:\n      - .env\n  - name: b\n")
+			if err := f.SetProjectField("a", tt.key, tt.value); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(f.Bytes()); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetProjectFieldKeepsLineComment(t *testing.T) {
+	f := load(t, "projects:\n  - name: a\n    branch: main # the default branch\n    path: ~/src/a\n")
+	if err := f.SetProjectField("a", "branch", "develop"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "projects:\n  - name: a\n    branch: develop # the default branch\n    path: ~/src/a\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestSetProjectFieldReplacesMultiLineScalar(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "plain scalar followed by a key",
+			input:    "projects:\n  - name: a\n    path: ~/src/a\n      /nested\n\n    branch: main\n  - name: b\n",
+			expected: "projects:\n  - name: a\n    path: ~/src/b\n\n    branch: main\n  - name: b\n",
+		},
+		{
+			name:     "quoted scalar as the last key",
+			input:    "projects:\n  - name: a\n    path: \"~/src/a\n      /nested\" # where\n  # next\n  - name: b\n",
+			expected: "projects:\n  - name: a\n    path: ~/src/b # where\n  # next\n  - name: b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := load(t, tt.input)
+			if err := f.SetProjectField("a", "path", "~/src/b"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := string(f.Bytes()); got != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeleteProjectField(t *testing.T) {
+	input := "projects:\n  - name: a\n    branch: main\n    backup_paths:\n      - .env\n    backup_retention: 3\n"
+
+	f := load(t, input)
+	if err := f.DeleteProjectField("a", "backup_paths"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "projects:\n  - name: a\n    branch: main\n    backup_retention: 3\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// ja: 先頭のキーを削除すると "- " が次のキーに引き継がれる
+	// en: Removing the first key hands the "- " over to the next key
+	f = load(t, "projects:\n  - repo: r\n    name: a\n")
+	if err := f.DeleteProjectField("a", "repo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := string(f.Bytes()); got != "projects:\n  - name: a\n" {
+		t.Errorf("Unexpected result:\n%s", got)
+	}
+
+	// ja: キーのコメントはキーと一緒に消え、次のキーのコメントは残る（値が複数行のリストでも）
+	// en: A key's comment goes with the key and the next key's comment stays (with a multi-line list value too)
+	f = load(t, "projects:\n  - name: a\n    # paths to keep\n    backup_paths:\n      - .env\n\n      - db/\n    # keep three\n    backup_retention: 3\n  - name: b\n")
+	if err := f.DeleteProjectField("a", "backup_paths"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = "projects:\n  - name: a\n    # keep three\n    backup_retention: 3\n  - name: b\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	f = load(t, "projects:\n  - repo: r\n    # the name\n    name: a\n")
+	if err := f.DeleteProjectField("a", "repo"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := string(f.Bytes()); got != "projects:\n  # the name\n  - name: a\n" {
+		t.Errorf("Unexpected result:\n%s", got)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	f := load(t, "# header\nversion: 1.0.0 # old\nprojects: []\n")
+	if err := f.SetField("version", "2.0.0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := f.SetField("include", []string{"projects.d/*.yml"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "# header\nversion: 2.0.0 # old\nprojects: []\ninclude:\n  - projects.d/*.yml\n"
+	if got := string(f.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestSaveKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not meaningful on Windows")
+	}
+
+	path := writeTempConfig(t, sampleConfig, 0640)
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := f.RemoveProject("second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 to be kept, got %o", info.Mode().Perm())
+	}
+
+	// ja: 一時ファイルが残っていないこと
+	// en: No temp files may be left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the config file, found %d entries", len(entries))
+	}
+
+	// ja: 新しいファイルは 0600 で作成される
+	// en: New files are created with 0600
+	newPath := filepath.Join(t.TempDir(), "new.yml")
+	f, err = Load(newPath)
+	if err != nil {
+		t.Fatalf("Failed to load missing config: %v", err)
+	}
+	if err := f.SetField("version", "1.0.0"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	info, err = os.Stat(newPath)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 for a new file, got %o", info.Mode().Perm())
+	}
+}
+
+func TestSaveThroughSymlink(t *testing.T) {
+	dotfiles := t.TempDir()
+	target := filepath.Join(dotfiles, "config.yml")
+	if err := os.WriteFile(target, []byte(sampleConfig), 0640); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.Symlink(target, path); err != nil {
+		t.Skipf("Symlinks are not available: %v", err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := f.RemoveProject("second"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	// ja: リンクは残り、リンク先のファイルが書き換わる
+	// en: The link is kept and the file it points to is rewritten
+	if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Expected %s to stay a symlink, got %v", path, err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(data) != string(f.Bytes()) {
+		t.Errorf("Expected the link target to hold the saved config, got:\n%s", data)
+	}
+	entries, err := os.ReadDir(dotfiles)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the config file next to the link target, found %d entries", len(entries))
+	}
+}
@@ -33,12 +33,20 @@ You can override the default path by setting the TOSKE_CONFIG environment variab
 		"version.flag.short": "Print only the version number",
 
 		// Edit command
-		"edit.short":         "Edit the configuration file",
-		"edit.long":          "Open the configuration file in your default editor.\nThe editor is determined by the EDITOR environment variable, or falls back to vi/vim/nano.",
-		"edit.noConfig":      "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"edit.noEditor":      "No suitable editor found. Please set the EDITOR environment variable.",
-		"edit.editorError":   "Failed to open editor: %v",
-		"edit.openingEditor": "Opening configuration file in %s...",
+		"edit.short":          "Edit the configuration file",
+		"edit.long":           "Open the configuration file in your default editor.\nThe editor is determined by the EDITOR environment variable, or falls back to vi/vim/nano.\nChanges are made to a temporary copy and validated when the editor exits; the configuration file\nis only replaced once the result is valid (or you choose to save it anyway).",
+		"edit.noEditor":       "No suitable editor found. Please set the EDITOR environment variable.",
+		"edit.editorError":    "Failed to open editor: %v",
+		"edit.openingEditor":  "Opening configuration file in %s...",
+		"edit.readError":      "Failed to read configuration file: %v",
+		"edit.tempError":      "Failed to create a temporary copy for editing: %v",
+		"edit.writeError":     "Failed to write configuration file: %v",
+		"edit.readInputError": "Failed to read input: %v",
+		"edit.noChanges":      "No changes made.",
+		"edit.invalid":        "✗ The edited configuration has errors:",
+		"edit.prompt":         "What now? (e)dit again, (d)iscard changes, (s)ave anyway [e]: ",
+		"edit.discarded":      "Changes discarded. The configuration file was not modified.",
+		"edit.saved":          "✓ Configuration saved: %s",
 		"init.fileExists":          "Configuration file already exists at: %s",
 		"init.overwritePrompt":     "Do you want to overwrite it? [y/N]: ",
 		"init.cancelled":           "Initialization cancelled.",
@@ -57,131 +65,512 @@ You can override the default path by setting the TOSKE_CONFIG environment variab
 		// Validate command
 		"validate.short":                    "Validate the configuration file",
 		"validate.long":                     "Validate checks the configuration file for syntax errors and ensures all required fields are present.",
-		"validate.noConfig":                 "Configuration file does not exist: %s\nRun 'toske init' to create one.",
 		"validate.checking":                 "Checking configuration file: %s",
-		"validate.readError":                "Failed to read configuration file: %v",
-		"validate.parseError":               "Failed to parse configuration file: %v",
 		"validate.success":                  "✓ Configuration file is valid!",
 		"validate.projectCount":             "  Found %d project(s) configured",
 		"validate.error.noVersion":          "Configuration error: 'version' field is required",
 		"validate.error.noProjects":         "Configuration error: at least one project must be defined",
 		"validate.error.projectNoName":      "Configuration error: project #%d is missing the 'name' field",
 		"validate.error.duplicateName":      "Configuration error: duplicate project name '%s'",
+		"validate.error.invalidName":        "Configuration error: invalid project name '%s' (must not contain path separators or be . or ..)",
+		"validate.error.duplicateNameSources": "Configuration error: duplicate project name '%s' (defined in %s and %s)",
+		"validate.error.envOverrideCollision": "Configuration error: projects '%s' and '%s' would both be overridden by %s; rename one of them",
 		"validate.error.projectNoRepo":      "Configuration error: project '%s' is missing the 'repo' field",
 		"validate.error.projectNoBranch":    "Configuration error: project '%s' is missing the 'branch' field",
 		"validate.error.invalidRetention":   "Configuration error: project '%s' has invalid backup_retention value: %d (must be >= 0)",
+		"validate.error.unknownGitFeature":  "Configuration error: project '%s' has unknown skip_git_features entry '%s' (available: %s)",
+		"validate.error.unknownVCS":         "Configuration error: project '%s' has unknown vcs '%s' (available: %s)",
+		"validate.error.gitStateWithoutGit": "Configuration error: project '%s' sets include_git_state, which needs vcs git (not %s)",
+		"validate.error.unknownArchiveMode": "Configuration error: project '%s' has unknown archive_mode '%s' (available: %s)",
+		"validate.error.gitStateWithFullArchive": "Configuration error: project '%s' sets include_git_state, which is not needed with archive_mode full (.git is archived as a whole)",
+		"validate.error.dumpNoName":         "Configuration error: dump #%[2]d of project '%[1]s' is missing the 'name' field",
+		"validate.error.dumpInvalidName":    "Configuration error: project '%s' has an invalid dump name '%s' (must not contain path separators)",
+		"validate.error.dumpDuplicateName":  "Configuration error: project '%s' has duplicate dump name '%s'",
+		"validate.error.dumpNoDatabase":     "Configuration error: dump '%[2]s' of project '%[1]s' is missing the 'database' field",
+		"validate.error.dumpNoCommand":      "Configuration error: dump '%[2]s' of project '%[1]s' is missing the 'command' field",
+		"validate.error.dumpUnknownProvider": "Configuration error: dump '%[2]s' of project '%[1]s' has unknown provider '%[3]s' (expected postgres, mysql or command)",
+		"validate.error.dumpInvalidPort":    "Configuration error: dump '%[2]s' of project '%[1]s' has invalid port: %[3]d",
 
 		// List command
 		"list.short":       "List all registered projects",
-		"list.long":        "Display a list of all projects registered in the configuration file.",
-		"list.noConfig":    "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"list.readError":   "Failed to read configuration file: %v",
-		"list.parseError":  "Failed to parse configuration file: %v",
+		"list.long":        "Display a list of all projects registered in the configuration file,\nincluding projects from included files and settings from each repository's .toske.yml.\nUse --origin to show which file each field comes from.",
 		"list.noProjects":  "No projects are registered yet.\nRun 'toske edit' to add projects to your configuration.",
 		"list.header":      "Registered Projects:",
 		"list.repo":        "Repository",
+		"list.vcs":         "VCS",
 		"list.branch":      "Branch",
 		"list.backupPaths": "Backup Paths",
 		"list.retention":   "Retention",
+		"list.dumps":       "Dumps",
+		"list.includeGitState": "Include git state",
+		"list.archiveMode":     "Archive mode",
+		"list.exclude":         "Exclude",
 		"list.total":       "\nTotal: %d project(s)",
+		"list.flag.origin": "Show which file each field comes from",
 
 		// Backup command
 		"backup.short":                    "Backup project files",
 		"backup.long":                     "Create a backup archive of files specified in the project configuration.",
-		"backup.noConfig":                 "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"backup.readError":                "Failed to read configuration file: %v",
-		"backup.parseError":               "Failed to parse configuration file: %v",
 		"backup.noProjectFlag":            "Project name is required. Use --project flag to specify the project.",
-		"backup.projectNotFound":          "Project '%s' not found in configuration file.",
-		"backup.noBackupPaths":            "Project '%s' has no backup_paths configured.",
+		"backup.noBackupPaths":            "Project '%s' has no backup_paths configured (and no dumps).",
+		"backup.noProjectDir":             "Project directory does not exist: %s",
 		"backup.creatingBackup":           "Creating backup for project: %s",
 		"backup.creatingDir":              "Creating backup directory: %s",
 		"backup.createDirError":           "Failed to create backup directory: %v",
 		"backup.creatingArchive":          "Creating backup archive: %s",
 		"backup.fileNotFound":             "  ⚠ Skipping: %s (not found)",
 		"backup.addingFile":               "  + %s",
-		"backup.archiveError":             "Failed to create backup archive: %v",
+		"backup.archiveError":             "Failed to create backup archive: %w",
+		"backup.fileChanged":              "%s changed while the backup was being created",
+		"backup.gitStateError":            "failed to save the git state of %s: %w",
+		"backup.savingGitState":           "Saving unpushed branches, stashes and uncommitted changes",
+		"backup.submodulesWarning":        "Warning: %s has submodules (%s); include_git_state does not save changes made inside them",
+		"backup.worktreesWarning":         "Warning: %s has other worktrees (%s); include_git_state does not save their uncommitted changes",
 		"backup.updatingMetadata":         "Updating metadata file",
 		"backup.metadataError":            "Failed to update metadata: %v",
 		"backup.pruningOldBackups":        "Cleaning up old backups (keeping %d)",
 		"backup.pruneError":               "Warning: Failed to prune old backups: %v",
+		"backup.interrupted":              "Backup interrupted; the unfinished archive was removed.",
 		"backup.success":                  "✓ Backup completed successfully!",
 		"backup.backupLocation":           "  Backup location: %s",
+		"backup.gitStateSaved":            "  Saved git state: %d unpushed branch(es), %d stash(es), %d untracked file(s)",
+		"backup.gitPatchSaved":            "  Saved uncommitted changes to tracked files",
+		"backup.verifyingArchive":         "Verifying archive",
+		"backup.verifyNoManifest":         "the archive has no manifest to verify it against",
+		"backup.verifyMissing":            "the archive is missing files listed in its manifest: %s",
+		"backup.archiveVerified":          "  Archive verified against its manifest",
+		"backup.fileUnsupportedWarning":   "  ⚠ Warning: %s is not a regular file, directory or symlink (a socket or similar) and was not archived",
+		"backup.unsupportedSkipped":       "  ⚠ %d file(s) that are not regular files, directories or symlinks were not archived",
+		"backup.removeTreeNeedsFull":      "--remove-tree needs archive_mode full (project '%s')",
+		"backup.removeTreeNeedsPath":      "--remove-tree needs a path for project '%s' (it will not remove the current directory)",
+		"backup.removeTreeRefused":        "refusing to remove %s",
+		"backup.removeTreeHoldsBackups":   "refusing to remove %s as it holds %s",
+		"backup.removeTreeNotVerified":    "the backup of project '%s' was not verified, so its directory is kept",
+		"backup.removeTreeUnsupported":    "refusing to remove %s as %d file(s) in it could not be archived",
+		"backup.removeTreeError":          "failed to remove %s: %w",
+		"backup.confirmRemoveTree":        "Remove %s now that it is archived? This cannot be undone. [y/N]: ",
+		"backup.readInputError":           "Failed to read input: %v",
+		"backup.removeTreeCancelled":      "Kept %s.",
+		"backup.removingTree":             "Removing %s",
+		"backup.treeRemoved":              "  Removed %s",
 		"backup.flag.project":             "Specify the project name to backup",
+		"backup.flag.removeTree":          "Remove the project directory after a verified archive_mode full backup",
+		"backup.flag.force":               "Do not ask for confirmation before --remove-tree removes the directory, even when the checkout has other worktrees",
 
 		// Restore command
 		"restore.short":                    "Restore project files from backup",
 		"restore.long":                     "Restore files from a backup archive. By default, restores from the most recent backup.",
-		"restore.noConfig":                 "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"restore.readError":                "Failed to read configuration file: %v",
-		"restore.parseError":               "Failed to parse configuration file: %v",
 		"restore.noProjectFlag":            "Project name is required. Use --project flag to specify the project.",
-		"restore.projectNotFound":          "Project '%s' not found in configuration file.",
 		"restore.noBackupDir":              "No backup directory found for project '%s'.",
-		"restore.noMetadata":               "No backup metadata found for project '%s'.",
+		"restore.noMetadata":               "No backup metadata found for project '%s'. If its archives are still there, run 'toske reindex -p %[1]s' to rebuild it.",
 		"restore.readMetadataError":        "Failed to read backup metadata: %v",
-		"restore.parseMetadataError":       "Failed to parse backup metadata: %v",
 		"restore.noBackups":                "No backups found for project '%s'.",
 		"restore.backupNotFound":           "Backup file '%s' not found.",
 		"restore.invalidBackupIndex":       "Invalid backup index: %d (available: 1-%d)",
 		"restore.selectingBackup":          "Using backup: %s (created: %s)",
 		"restore.restoringFiles":           "Restoring files from backup...",
 		"restore.openArchiveError":         "Failed to open backup archive: %v",
-		"restore.extractError":             "Failed to extract backup: %v",
+		"restore.extractError":             "Failed to extract backup: %w",
+		"restore.createProjectDirError":    "Failed to create project directory %s: %w",
 		"restore.extractingFile":           "  ← %s",
 		"restore.createDirError":           "Failed to create directory: %v",
 		"restore.writeFileError":           "Failed to write file: %v",
 		"restore.success":                  "✓ Restore completed successfully!",
 		"restore.restoredFiles":            "  Restored %d file(s)",
+		"restore.restoredDumps":            "  Restored %d database dump(s)",
+		"restore.restoredGitState":         "  Restored %d branch(es) and %d stash(es)",
+		"restore.restoredPatch":            "  Re-applied uncommitted changes",
 		"restore.flag.project":             "Specify the project name to restore",
 		"restore.flag.backup":              "Specify the backup index to restore (1 = latest, 2 = second latest, etc.)",
 		"restore.flag.force":               "Overwrite existing files without confirmation",
+		"restore.flag.skipDumps":           "Restore files only and do not load database dumps",
+		"restore.flag.checkout":            "Check out the commit the backup was taken at before restoring",
+		"restore.flag.skipGitState":        "Do not put back the branches, stashes and uncommitted changes saved with include_git_state",
 		"restore.confirmOverwrite":         "\n⚠️  Warning: This will overwrite existing files in the current directory.",
 		"restore.confirmPrompt":            "Do you want to continue? [y/N]: ",
 		"restore.cancelled":                "Restore cancelled.",
 		"restore.readInputError":           "Failed to read input: %v",
+		"restore.interrupted":              "Restore interrupted after writing %d file(s).",
+		"restore.writtenBeforeInterrupt":   "Files written before the interruption:",
 		"restore.symlinkOutsideDir":        "symlink points outside restore directory",
 		"restore.fileCreateWarning":        "  ⚠ Warning: Failed to create file %s: %v",
 		"restore.fileCopyWarning":          "  ⚠ Warning: Failed to copy file %s: %v",
 		"restore.fileChmodWarning":         "  ⚠ Warning: Failed to set permissions for %s: %v",
+		"restore.fileChecksumWarning":      "  ⚠ Warning: %s does not match the checksum in the archive manifest, skipped",
+		"restore.manifestProjectMismatch":  "Warning: %[1]s was created for project %[2]s, not %[3]s",
+		"restore.commitDrift":              "Warning: the backup was taken at commit %[1]s; the checkout is %[2]d commit(s) ahead and %[3]d behind it",
+		"restore.commitMissing":            "Warning: the backup was taken at commit %s, which is not in the local repository (try git fetch)",
+		"restore.commitDirty":              "  The working tree had uncommitted changes when the backup was taken",
+		"restore.checkoutHint":             "  Run with --checkout to check out %s before restoring",
+		"restore.checkingOut":              "Checking out %s...",
+		"restore.checkoutError":            "failed to check out the recorded commit: %w",
+		"restore.cloning":                  "Cloning %s into %s...",
+		"restore.cloneError":               "failed to clone %s: %w",
+		"restore.noRecordedCommit":         "%s has no recorded commit to check out",
+		"restore.checkoutMissing":          "commit %s is not in the local repository; fetch it first (git fetch)",
+		"restore.updatingSubmodules":       "Updating submodules...",
+		"restore.submodulesError":          "Warning: failed to update submodules: %v",
+		"restore.fetchingLFS":              "Fetching %d Git LFS file(s)...",
+		"restore.lfsError":                 "Warning: failed to fetch Git LFS files: %v",
+		"restore.restoringGitState":        "Restoring branches, stashes and uncommitted changes",
+		"restore.branchSkippedWarning":     "  ⚠ Warning: branch %s already exists at a different commit and was left as is",
+		"restore.gitNotRepository":         "%s is not a git repository; clone it first, or restore with --skip-git-state",
+		"restore.gitBundleError":           "failed to restore branches and stashes: %w",
+		"restore.gitPatchError":            "failed to apply the uncommitted changes: %v (the patch is kept in the archive as %s)",
 
 		// Delete command
 		"delete.short":         "Delete a project from configuration",
 		"delete.long":          "Remove a project from the configuration file. This does not delete any backup files.",
-		"delete.noConfig":      "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"delete.readError":     "Failed to read configuration file: %v",
-		"delete.parseError":    "Failed to parse configuration file: %v",
 		"delete.noProjectFlag": "Project name is required. Use --project flag to specify the project.",
-		"delete.projectNotFound": "Project '%s' not found in configuration file.",
 		"delete.confirmPrompt": "Are you sure you want to delete project '%s'? [y/N]: ",
 		"delete.cancelled":     "Deletion cancelled.",
 		"delete.readInputError": "Failed to read input: %v",
-		"delete.marshalError":  "Failed to marshal configuration: %v",
-		"delete.writeError":    "Failed to write configuration file: %v",
 		"delete.success":       "✓ Project '%s' has been successfully deleted from configuration.",
 		"delete.flag.project":  "Specify the project name to delete",
-		"delete.flag.force":    "Skip confirmation prompt (use with caution)",
+		"delete.flag.force":    "Skip confirmation prompt and delete even when the checkout has other worktrees (use with caution)",
+		"delete.worktreesWarning": "Warning: the checkout of %s has other worktrees, which toske does not back up:",
+		"delete.worktreesRefused": "refusing to go on as the checkout of %s has other worktrees; use --force to proceed anyway",
 
 		// Remove command
 		"remove.short":         "Remove a project from configuration",
 		"remove.long":          "Remove a project from the configuration file. This does not delete any backup files.",
-		"remove.noConfig":      "Configuration file does not exist: %s\nRun 'toske init' to create one.",
-		"remove.readError":     "Failed to read configuration file: %v",
-		"remove.parseError":    "Failed to parse configuration file: %v",
 		"remove.noProjectFlag": "Project name is required. Use --project flag to specify the project.",
-		"remove.projectNotFound": "Project '%s' not found in configuration file.",
 		"remove.confirmPrompt": "Are you sure you want to remove project '%s' from configuration? [y/N]: ",
 		"remove.cancelled":     "Removal cancelled.",
 		"remove.readInputError": "Failed to read input: %v",
-		"remove.marshalError":  "Failed to marshal configuration: %v",
-		"remove.writeError":    "Failed to write configuration file: %v",
 		"remove.success":       "✓ Project '%s' has been successfully removed from configuration.",
 		"remove.flag.project":  "Specify the project name to remove",
-		"remove.flag.force":    "Skip confirmation prompt (use with caution)",
+		"remove.flag.force":    "Skip confirmation prompt and remove even when the checkout has other worktrees (use with caution)",
+
+		// Database dumps
+		"dump.dumping":              "  + dump: %s (%s)",
+		"dump.restoring":            "  ← dump: %s (%s)",
+		"dump.unknownProvider":      "unknown dump provider: %s",
+		"dump.noRestoreCommand":     "dump '%s' has no restore_command configured",
+		"dump.dumpError":            "failed to dump '%s': %v",
+		"dump.restoreError":         "failed to restore dump '%s': %v",
+		"dump.notConfiguredWarning": "  ⚠ Warning: Skipping dump '%s' (not configured for this project)",
+
+		// Info command
+		"info.short":             "Show detailed status of a project",
+		"info.long":              "Show the resolved configuration, local checkout state, backups and backup paths of a project.",
+		"info.flag.project":      "Specify the project name to show",
+		"info.flag.json":         "Output in JSON format (same as --output json)",
+		"info.noProjectFlag":     "Project name is required. Use --project flag to specify the project.",
+		"info.metadataError":     "Failed to read backup metadata: %v",
+		"info.header":            "Project: %s",
+		"info.path":              "Path",
+		"info.currentDir":        "(current directory)",
+		"info.dump":              "Dump",
+		"info.checkoutHeader":    "Checkout:",
+		"info.directory":         "Directory",
+		"info.status":            "Status",
+		"info.checkoutPresent":   "present",
+		"info.checkoutAbsent":    "absent",
+		"info.checkoutNotRepo":   "present (not a git repository)",
+		"info.checkoutUnversioned": "present (not under version control)",
+		"info.currentBranch":     "Current branch",
+		"info.workingTree":       "Working tree",
+		"info.clean":             "clean",
+		"info.dirty":             "dirty (uncommitted changes)",
+		"info.upstream":          "Upstream",
+		"info.aheadBehind":       "%d ahead, %d behind",
+		"info.noUpstream":        "no upstream configured",
+		"info.backupsHeader":     "Backups:",
+		"info.backupCount":       "Count",
+		"info.totalSize":         "Total size",
+		"info.latestBackup":      "Latest",
+		"info.never":             "never",
+		"info.backupPathsHeader": "Backup paths (current state):",
+		"info.missing":           "missing",
+
+		// Relative time
+		"age.justNow": "just now",
+		"age.minutes": "%d minutes ago",
+		"age.hours":   "%d hours ago",
+		"age.days":    "%d days ago",
+
+		// Output
+		"output.flag":          "Output format: table, json or yaml",
+		"output.invalidFormat": "Unsupported output format: %s (expected table, json or yaml)",
+		"output.flag.quiet":    "Only print warnings and errors",
+		"output.flag.verbose":  "Also print every file as it is processed",
+		"output.quietVerbose":  "--quiet and --verbose cannot be used together",
+
+		// Progress
+		"progress.line":             "%s / %s (%d%%)  %s/s  ETA %s",
+		"progress.lineUnknownTotal": "%s  %s/s",
+
+		// Status command
+		"status.short":                        "Show backup status of all projects",
+		"status.long":                         "Show a table of all registered projects with checkout state, last backup, backup count, disk usage and warnings.",
+		"status.flag.sort":                    "Sort order (name, last-backup, size)",
+		"status.flag.filter":                  "Only show projects whose name matches (substring or glob)",
+		"status.flag.warningsOnly":            "Only show projects with warnings",
+		"status.invalidSort":                  "Invalid sort order: %s (expected name, last-backup or size)",
+		"status.noProjects":                   "No matching projects.",
+		"status.tableHeader":                  "NAME\tCHECKOUT\tLAST BACKUP\tBACKUPS\tSIZE\tWARNINGS",
+		"status.checkout.present":             "present",
+		"status.checkout.absent":              "absent",
+		"status.checkout.unknown":             "-",
+		"status.warning.never_backed_up":      "never backed up",
+		"status.warning.changed_since_backup": "files changed since last backup",
+		"status.warning.missing_backup_paths": "backup paths missing",
+		"status.warning.status_failed":        "status unavailable",
+
+		// Doctor command
+		"doctor.short":              "Check the environment and configuration for problems",
+		"doctor.long":               "Check git, the configuration file and the backup directories for problems, and suggest how to fix them.\nUse --fix to repair the problems that can be fixed safely.",
+		"doctor.flag.fix":           "Automatically repair problems that can be fixed safely",
+		"doctor.gitOK":              "git is available (version %s)",
+		"doctor.gitMissing":         "git is not available: %v",
+		"doctor.gitMissingFix":      "Install git and make sure it is on your PATH.",
+		"doctor.gitNotNeeded":       "git is not checked: no project uses the git VCS",
+		"doctor.configOK":           "Configuration file is valid: %s (%d project(s))",
+		"doctor.configMissing":      "Configuration file does not exist: %s",
+		"doctor.configMissingFix":   "Run 'toske init' to create one.",
+		"doctor.configInvalidFix":   "Run 'toske edit' to fix the configuration file.",
+		"doctor.configOutdated":     "Configuration file uses schema version %s (current: %s)",
+		"doctor.configOutdatedFix":  "Run 'toske config migrate' to upgrade it.",
+		"doctor.legacyConfig":       "Configuration file is at the legacy path: %s",
+		"doctor.legacyConfigFix":    "Move it to %s",
+		"doctor.noBackupDir":        "No backups have been created yet (%s does not exist)",
+		"doctor.backupDirOK":        "Backup directory is writable: %s",
+		"doctor.notWritable":        "Backup directory is not writable: %s (%v)",
+		"doctor.notWritableFix":     "Check the owner and permissions of %s",
+		"doctor.orphanBackupDir":    "Backups exist for project '%s', which is not in the configuration file",
+		"doctor.orphanBackupDirFix": "Add the project back to the configuration, or remove %s if the backups are no longer needed",
+		"doctor.metadataError":      "Failed to read backup metadata in %s: %v",
+		"doctor.metadataErrorFix":   "Fix or remove %s",
+		"doctor.missingArchives":    "Metadata of '%s' refers to missing archives: %s",
+		"doctor.missingArchivesFix": "Remove the records from backups.yaml",
+		"doctor.exposedArchives":    "Archives of '%s' contain secrets but are readable by other users: %s",
+		"doctor.exposedArchivesFix": "Restrict the archives to the owner (chmod 600)",
+		"doctor.partialArchives":              "Unfinished archives left by an interrupted backup in %s: %s",
+		"doctor.partialArchivesFix":           "Remove the unfinished archives",
+		"doctor.partialArchivesInProgress":    "Unfinished archives in %s were written to recently and may belong to a backup or export still running: %s",
+		"doctor.partialArchivesInProgressFix": "Check that no backup or export is running; --fix removes them once untouched for %s",
+		"doctor.fixed":              "✓ fixed",
+		"doctor.fixFailed":          "✗ failed to fix: %s",
+		"doctor.healthy":            "No problems found.",
+		"doctor.summary":            "%d error(s), %d warning(s)",
+		"doctor.fixHint":            "Run 'toske doctor --fix' to repair %d problem(s) automatically.",
+		"doctor.problemsFound":      "%d problem(s) found",
+
+		// Add command
+		"add.short":                "Register a project from a local checkout",
+		"add.long":                 "Inspect a local checkout (the current directory by default) and add it to the configuration file.\nThe repository URL and branch are read from git (a directory that is not a git repository is added with vcs: none), and git-ignored files that look like secrets or databases are suggested as backup paths.\nEvery field can also be given as a flag; use --non-interactive to skip all prompts.",
+		"add.flag.name":            "Project name (default: directory name)",
+		"add.flag.repo":            "Repository URL (default: URL of the origin remote)",
+		"add.flag.vcs":             "Version control system of the directory (git or none; default: git)",
+		"add.flag.branch":          "Branch (default: current branch)",
+		"add.flag.path":            "Local checkout path to record (default: the directory)",
+		"add.flag.backupPath":      "Path to back up (can be repeated or comma separated)",
+		"add.flag.retention":       "Number of backups to keep (0 keeps all)",
+		"add.flag.dump":            "Database dump to take, as key=value pairs of the dumps keys (e.g. name=app.sql,provider=postgres,database=app; can be repeated)",
+		"add.flag.includeGitState": "Also back up unpushed branches, stashes and uncommitted changes",
+		"add.flag.skipGitFeature":  "Git feature for toske to leave alone (submodules, worktrees or lfs; can be repeated or comma separated)",
+		"add.flag.archiveMode":     "What to archive (paths for the backup paths, full for the whole directory; default: paths)",
+		"add.flag.exclude":         "Pattern of paths left out with --archive-mode full (can be repeated or comma separated)",
+		"add.flag.noSuggest":       "Do not suggest backup paths from git-ignored files",
+		"add.flag.nonInteractive":  "Do not prompt; use flags, detected values and all suggestions",
+		"add.marshalError":         "Failed to marshal project: %v",
+		"add.noDir":                "Directory does not exist: %s",
+		"add.readInputError":       "Failed to read input: %v",
+		"add.invalidNumber":        "Not a number: %s",
+		"add.invalidDump":          "Invalid --dump value (expected key=value pairs separated by commas): %s",
+		"add.prompt.name":          "Project name",
+		"add.prompt.repo":          "Repository URL",
+		"add.prompt.branch":        "Branch",
+		"add.prompt.path":          "Local path",
+		"add.prompt.suggestion":    "Back up %s? [Y/n]: ",
+		"add.prompt.backupPaths":   "Additional backup paths (comma separated)",
+		"add.prompt.retention":     "Backups to keep (0 keeps all)",
+		"add.confirmPrompt":        "Add this project to %s? [Y/n]: ",
+		"add.cancelled":            "Cancelled.",
+		"add.success":              "✓ Project '%s' added to %s",
+
+		// Get / set commands
+		"get.short":             "Print a configuration value",
+		"get.long":              "Print a top-level configuration value (such as version), or a project field with --project.\nLists are printed one item per line.",
+		"get.flag.project":      "Project to read the field from",
+		"set.short":             "Change a configuration value",
+		"set.long":              "Change a top-level configuration value (such as version), or a project field with --project.\nLists accept comma-separated values (write \\, for a comma inside a value), and can be extended or reduced with key+=value and key-=value.\nSetting an optional field to an empty value removes it. Changes are validated before they are saved,\nand the rest of the file (comments, formatting) is left untouched.\n\nExamples:\n  toske set version 1.0.0\n  toske set -p myapp branch develop\n  toske set -p myapp backup_paths+=storage/\n  toske set -p myapp backup_retention=5",
+		"set.flag.project":      "Project to change",
+		"set.success":           "✓ %s = %s",
+		"set.successProject":    "✓ %s.%s = %s",
+		"fields.unknownKey":     "Unknown key '%s' (available: %s)",
+		"fields.invalidArgs":    "Expected '<key> <value>', '<key>=<value>', '<key>+=<value>' or '<key>-=<value>'",
+		"fields.notList":        "'%s' is not a list, so += and -= cannot be used",
+		"fields.notInList":      "'%s' is not in %s",
+		"fields.invalidNumber":  "%s must be a number: %s",
+		"fields.invalidBool":    "%s must be true or false: %s",
+		"fields.unsupportedKey": "'%s' cannot be changed from the command line; use 'toske edit'",
+
+		// Includes
+		"include.badPattern":       "Invalid include pattern '%s': %v",
+		"include.readError":        "Failed to read %s: %v",
+		"include.parseError":       "Failed to parse included file %s: %v",
+		"include.localParseError":  "Failed to parse %s (only backup_paths, backup_retention, include_git_state and exclude are allowed): %v",
+		"include.localPathOutside": "%s: %s entry '%s' must be a relative path inside the project directory",
+
+		// Config command
+		"configCmd.short":        "Manage the configuration file",
+		"configCmd.long":         "Commands that manage the configuration file itself.\n\nProject fields can be overridden without editing the file by setting\nTOSKE_PROJECT_<NAME>_<FIELD>, with the name and field upper-cased and other characters replaced by _.\nLists take comma-separated values. For example:\n  TOSKE_PROJECT_MY_APP_BRANCH=develop toske backup -p my-app",
+		"migrate.short":          "Upgrade the configuration file to the current schema version",
+		"migrate.long":           "Upgrade an older configuration file to the schema version this binary understands.\nThe original file is backed up next to it before it is rewritten.\nFiles written for a newer version of toske are rejected rather than read with unknown keys dropped.",
+		"migrate.readError":      "Failed to read configuration file: %v",
+		"migrate.parseError":     "Failed to parse configuration file: %v",
+		"migrate.noVersion":      "The configuration file has no version, so the migrations to apply cannot be determined",
+		"migrate.invalidVersion": "Invalid configuration version '%s' (expected a form like 1.0.0)",
+		"migrate.tooNew":         "The configuration file is version %s, but this toske only understands up to %s.\nUpgrade toske to use this file.",
+		"migrate.noPath":         "No migration from version %s is registered (the next one starts at %s)",
+		"migrate.stepError":      "Migration from %s to %s failed: %v",
+		"migrate.backupError":    "Failed to back up the configuration file: %v",
+		"migrate.writeError":     "Failed to write configuration file: %v",
+		"migrate.upToDate":       "✓ %s is already at the current version (%s).",
+		"migrate.backedUp":       "Backed up the original file to %s",
+		"migrate.success":        "✓ Migrated %s from %s to %s",
+
+		// Signal
+		"signal.interrupted": "Interrupted; cleaning up... (press Ctrl-C again to quit immediately)",
+		"signal.forceExit":   "Forced to quit.",
+
+		// Log command
+		"log.short":              "Show the journal of past operations",
+		"log.long":               "Show the journal of commands that changed files, backups or the config file, newest first.\nThe journal is kept in $XDG_STATE_HOME/toske/journal.jsonl (~/.local/state/toske/journal.jsonl by default).",
+		"log.flag.project":       "Only show operations on this project",
+		"log.flag.since":         "Only show operations since a duration ago (36h, 7d) or a date (2006-01-02)",
+		"log.invalidSince":       "Invalid --since value: %s (expected a duration like 36h or 7d, or a date like 2006-01-02)",
+		"log.readError":          "Failed to read the journal: %v",
+		"log.empty":              "No operations recorded.",
+		"log.tableHeader":        "TIME\tCOMMAND\tPROJECT\tRESULT\tDURATION\tFILES\tDETAIL",
+		"log.result.success":     "success",
+		"log.result.failed":      "failed",
+		"log.result.cancelled":   "cancelled",
+		"log.result.interrupted": "interrupted",
+		"logging.flag":           "Level of the logs written to stderr: debug, info, warn or error",
+		"logging.invalidLevel":   "Invalid log level: %s (expected debug, info, warn or error)",
+
+		// Dry run / plans
+		"dryRun.flag":           "Show what the command would do without changing anything",
+		"dryRun.notice":         "Dry run: nothing was changed.",
+		"dryRun.unsupported":    "%s does not support --dry-run",
+		"plan.backupTitle":      "Backup plan for project: %s",
+		"plan.backupDir":        "Backup directory: %s",
+		"plan.files":            "Files to archive: %d (%s)",
+		"plan.file":             "  + %s (%s)",
+		"plan.fullArchive":      "Archive mode: full (the whole directory)",
+		"plan.unsupported":      "  ⚠ Skipping: %s (not a regular file, directory or symlink)",
+		"plan.removeTree":       "The project directory will be removed after the archive is verified",
+		"plan.dump":             "  + database dump: %s",
+		"plan.prune":            "Archives to prune:",
+		"plan.pruneItem":        "  - %s",
+		"plan.restoreSummary":   "Files to restore: %d (%d will overwrite existing files)",
+		"plan.restoreOverwrite": "  ! %s (%s, overwrites)",
+		"plan.restoreNew":       "  + %s (%s)",
+		"plan.restoreSkipped":   "  ⚠ Skipping: %s (%s)",
+		"plan.restoreCreateDir": "The project directory does not exist and will be rebuilt from the archive",
+		"plan.restoreDump":      "  + database dump: %s",
+		"plan.gitState":         "  + git state: unpushed branches, stashes and uncommitted changes",
+		"plan.restoreBranch":    "  + branch: %s",
+		"plan.restoreStashes":   "  + stash(es): %d",
+		"plan.restorePatch":     "  + uncommitted changes",
+		"plan.restoreSubmodules": "Submodules will be initialized and updated after restoring",
+		"plan.restoreLFS":       "%d Git LFS file(s) will be fetched after restoring",
+		"plan.restoreCheckout":  "Commit %s will be checked out before restoring",
+		"plan.restoreClone":     "The project directory does not exist: %s will be cloned into %s before restoring",
+		"plan.configTitle":      "Changes to %s:",
+		"plan.noChanges":        "  (no changes)",
+
+		// Export / import commands
+		"export.short":                  "Export projects and their backups to a portable bundle",
+		"export.long":                   "Export a project's config entry and selected backup archives to a single bundle file,\nto hand the project over to someone else or move it to another machine.\nUse --all-projects to export every project at once. The bundle contains secrets such as .env files, so keep it safe.\n\nExamples:\n  toske export -p myapp -o myapp.toske\n  toske export -p myapp --all\n  toske export --all-projects -o laptop.toske",
+		"export.flag.project":           "Project to export",
+		"export.flag.backup":            "Backup to export (1 = latest)",
+		"export.flag.all":               "Export all backups instead of one",
+		"export.flag.allProjects":       "Export all projects",
+		"export.flag.output":            "Bundle file to write (default <project>.toske)",
+		"export.noProject":              "either --project or --all-projects is required",
+		"export.projectAndAll":          "--project and --all-projects cannot be used together",
+		"export.interrupted":            "export interrupted; no bundle was written",
+		"export.writeError":             "failed to write bundle: %w",
+		"export.success":                "✓ Bundle written to %s",
+		"export.bundle":                 "Bundle: %s",
+		"export.project":                "  %s: %d archive(s)",
+		"import.short":                  "Import projects and backups from a bundle",
+		"import.long":                   "Import the projects in a bundle written by 'toske export': register them in the config file\nand add their archives to the backups, skipping backups that are already there.\nWhen a project of the same name exists, --on-conflict decides what happens:\n  merge    keep the existing config and add the archives to it\n  rename   register the project under a free name (name-2, ...)\n  replace  replace the existing config entry and add the archives\n  skip     do not import the project\n  ask      ask for each project (default)\nThe checkout path recorded in the bundle and dumps with provider command, which run commands,\nare left out unless --keep-path or --allow-commands is given; either way they are listed.",
+		"import.flag.onConflict":        "What to do when a project name is taken: ask, merge, rename, replace or skip",
+		"import.flag.name":              "Import the project under this name (single-project bundles only)",
+		"import.flag.keepPath":          "Keep the checkout path recorded in the bundle (by default it is left out, as it belongs to the sender's machine)",
+		"import.flag.allowCommands":     "Keep dumps with provider command, which run their commands at backup and restore (left out by default)",
+		"import.invalidOnConflict":      "invalid --on-conflict value %q (use ask, merge, rename, replace or skip)",
+		"import.readError":              "failed to read bundle: %w",
+		"import.notBundle":              "not a toske bundle (bundle.yaml not found at the start)",
+		"import.unsupportedFormat":      "bundle format %d is newer than this toske supports (%d); please upgrade toske",
+		"import.nameNeedsSingleProject": "--name can only be used with a bundle holding a single project",
+		"import.invalidArchiveName":     "invalid archive name %q",
+		"import.archiveError":           "failed to import %s: %w",
+		"import.readInputError":         "failed to read input: %w",
+		"import.conflictPrompt":         "Project %s already exists. [m]erge backups, [r]ename, re[p]lace, [s]kip? [m]: ",
+		"import.bundle":                 "Bundle: %s",
+		"import.project":                "  %s: %s",
+		"import.action.add":             "new project",
+		"import.action.merge":           "add backups to the existing project",
+		"import.action.rename":          "new project (renamed)",
+		"import.action.replace":         "replace the existing config",
+		"import.action.skip":            "skipped",
+		"import.archiveDuplicate":       "    %s (already present, skipped)",
+		"import.archiveRenamed":         "    %s (stored as %s)",
+		"import.pathKept":               "    path: %s (kept with --keep-path)",
+		"import.pathDropped":            "    path %s is left out (use --keep-path to keep it)",
+		"import.commandDumpKept":        "    dump %s runs: %s (kept with --allow-commands)",
+		"import.commandDumpDropped":     "    dump %s is left out as it runs a command (use --allow-commands to keep it): %s",
+		"import.restoreCommand":         "      restore runs: %s",
+		"import.success":                "✓ Imported %s",
+
+		// Reindex / import-archive commands
+		"reindex.short":               "Rebuild a project's backup metadata from its archives",
+		"reindex.long":                "Scan the project's backup directory, read every tar.gz archive in it and rebuild backups.yaml\nfrom the archive entries and the timestamp in each filename (or its modification time).\nUse it when backups.yaml was lost or archives were copied into the backup directory by hand.\n\nExample:\n  toske reindex -p myapp",
+		"reindex.flag.project":        "Project to reindex",
+		"reindex.noProjectFlag":       "project name is required (use -p or --project)",
+		"reindex.notCompressed":       "not a gzip-compressed archive",
+		"reindex.unreadable":          "⚠ Skipping unreadable archive %s: %s",
+		"reindex.summary":             "Indexed %[2]d backup(s) for %[1]s (%[3]d new, %[4]d dropped)",
+		"reindex.added":               "  + %s",
+		"reindex.dropped":             "  - %s (archive not found)",
+		"importArchive.short":         "Adopt an existing tarball as a backup of a project",
+		"importArchive.long":          "Adopt tar or tar.gz files made elsewhere as backups of a project.\nThe files are copied into the backup directory (plain tar files are compressed) and recorded in backups.yaml,\nso they can be restored with 'toske restore'. Files not named backup_<time>.tar.gz are renamed after their modification time.\n\nExample:\n  toske import-archive -p myapp ~/Downloads/myapp-env.tar.gz",
+		"importArchive.flag.project":  "Project to add the archives to",
+		"importArchive.noProjectFlag": "project name is required (use -p or --project)",
+		"importArchive.readError":     "cannot read %s as a tar archive: %w",
+		"importArchive.adopted":       "✓ %s -> %s (%s)",
+		"importArchive.contents":      "  %d path(s), %d dump(s)",
+		"importArchive.duplicate":     "  %s is already a backup of this project, skipped",
+
+		// Archive manifest
+		"manifest.readError":         "failed to read the archive manifest: %w",
+		"manifest.unsupportedFormat": "the archive manifest has format %d, but this version of toske only supports up to %d; please upgrade toske",
+		"manifest.checksumMismatch":  "%s does not match the checksum in the archive manifest",
+
+		// Git features
+		"gitFeatures.lfsNotInstalled": "Warning: %s uses Git LFS, but git-lfs is not installed; LFS files cannot be checked or fetched",
+
+		// Version control
+		"vcs.checkoutUnsupported": "projects with vcs %s cannot check out a recorded state",
+		"vcs.cloneUnsupported":    "projects with vcs %s cannot be cloned; create the project directory first",
 
 		// Config
 		"config.legacyWarning":       "⚠️  WARNING: You are using a legacy configuration file location.",
 		"config.legacyWarningDetail": "   Please migrate to ~/.config/toske/config.yml by running: mv ~/.toske.yaml ~/.config/toske/config.yml",
+		"config.noConfig":            "Configuration file does not exist: %s\nRun 'toske init' to create one.",
+		"config.readError":           "Failed to read configuration file: %v",
+		"config.parseError":          "Failed to parse configuration file: %v",
+		"config.projectNotFound":     "Project '%s' not found in configuration file.",
+		"config.editError":           "Failed to update configuration: %v",
+		"config.writeError":          "Failed to write configuration file: %v",
+		"config.envError":            "Invalid value in %s: %v",
+		"config.envAmbiguous":        "%s matches more than one project; rename one of them to use it",
 
 		// Common
 		"common.error": "Error: %v",
@@ -216,12 +605,20 @@ TOSKE_CONFIG 環境変数を設定することで、デフォルトパスを上
 		"version.flag.short": "バージョン番号のみを表示",
 
 		// Edit command
-		"edit.short":         "設定ファイルを編集",
-		"edit.long":          "設定ファイルをデフォルトエディタで開きます。\nエディタは EDITOR 環境変数で決定されます。設定されていない場合は vi/vim/nano を使用します。",
-		"edit.noConfig":      "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"edit.noEditor":      "適切なエディタが見つかりません。EDITOR 環境変数を設定してください。",
-		"edit.editorError":   "エディタの起動に失敗しました: %v",
-		"edit.openingEditor": "設定ファイルを %s で開いています...",
+		"edit.short":          "設定ファイルを編集",
+		"edit.long":           "設定ファイルをデフォルトエディタで開きます。\nエディタは EDITOR 環境変数で決定されます。設定されていない場合は vi/vim/nano を使用します。\n変更は一時コピーに対して行われ、エディタの終了時に検証されます。設定ファイルは\n結果が有効な場合 (または強制保存を選んだ場合) にのみ置き換えられます。",
+		"edit.noEditor":       "適切なエディタが見つかりません。EDITOR 環境変数を設定してください。",
+		"edit.editorError":    "エディタの起動に失敗しました: %v",
+		"edit.openingEditor":  "設定ファイルを %s で開いています...",
+		"edit.readError":      "設定ファイルの読み込みに失敗しました: %v",
+		"edit.tempError":      "編集用の一時コピーの作成に失敗しました: %v",
+		"edit.writeError":     "設定ファイルの書き込みに失敗しました: %v",
+		"edit.readInputError": "入力の読み込みに失敗しました: %v",
+		"edit.noChanges":      "変更はありません。",
+		"edit.invalid":        "✗ 編集後の設定にエラーがあります:",
+		"edit.prompt":         "どうしますか? (e) 再編集、(d) 変更を破棄、(s) このまま保存 [e]: ",
+		"edit.discarded":      "変更を破棄しました。設定ファイルは変更されていません。",
+		"edit.saved":          "✓ 設定を保存しました: %s",
 		"init.fileExists":          "設定ファイルは既に存在します: %s",
 		"init.overwritePrompt":     "上書きしますか？ [y/N]: ",
 		"init.cancelled":           "初期化をキャンセルしました。",
@@ -240,131 +637,512 @@ TOSKE_CONFIG 環境変数を設定することで、デフォルトパスを上
 		// Validate command
 		"validate.short":                    "設定ファイルを検証",
 		"validate.long":                     "設定ファイルの構文エラーをチェックし、すべての必須フィールドが存在することを確認します。",
-		"validate.noConfig":                 "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
 		"validate.checking":                 "設定ファイルを確認しています: %s",
-		"validate.readError":                "設定ファイルの読み込みに失敗しました: %v",
-		"validate.parseError":               "設定ファイルのパースに失敗しました: %v",
 		"validate.success":                  "✓ 設定ファイルは正常です！",
 		"validate.projectCount":             "  %d 個のプロジェクトが設定されています",
 		"validate.error.noVersion":          "設定エラー: 'version' フィールドは必須です",
 		"validate.error.noProjects":         "設定エラー: 少なくとも1つのプロジェクトを定義する必要があります",
 		"validate.error.projectNoName":      "設定エラー: プロジェクト #%d に 'name' フィールドがありません",
 		"validate.error.duplicateName":      "設定エラー: プロジェクト名 '%s' が重複しています",
+		"validate.error.invalidName":        "設定エラー: プロジェクト名 '%s' が無効です (パス区切り文字や . 、.. は使用できません)",
+		"validate.error.duplicateNameSources": "設定エラー: プロジェクト名 '%s' が重複しています (%s と %s で定義)",
+		"validate.error.envOverrideCollision": "設定エラー: プロジェクト '%s' と '%s' が同じ環境変数 %s で上書きされてしまいます。どちらかの名前を変更してください",
 		"validate.error.projectNoRepo":      "設定エラー: プロジェクト '%s' に 'repo' フィールドがありません",
 		"validate.error.projectNoBranch":    "設定エラー: プロジェクト '%s' に 'branch' フィールドがありません",
 		"validate.error.invalidRetention":   "設定エラー: プロジェクト '%s' の backup_retention 値が無効です: %d (0以上である必要があります)",
+		"validate.error.unknownGitFeature":  "設定エラー: プロジェクト '%s' の skip_git_features に不明な項目 '%s' があります (利用可能: %s)",
+		"validate.error.unknownVCS":         "設定エラー: プロジェクト '%s' の vcs '%s' は不明です (利用可能: %s)",
+		"validate.error.gitStateWithoutGit": "設定エラー: プロジェクト '%s' の include_git_state には vcs git が必要です (%s では使えません)",
+		"validate.error.unknownArchiveMode": "設定エラー: プロジェクト '%s' の archive_mode '%s' は不明です (利用可能: %s)",
+		"validate.error.gitStateWithFullArchive": "設定エラー: プロジェクト '%s' の include_git_state は archive_mode full では不要です (.git ごとアーカイブされます)",
+		"validate.error.dumpNoName":         "設定エラー: プロジェクト '%[1]s' のダンプ #%[2]d に 'name' フィールドがありません",
+		"validate.error.dumpInvalidName":    "設定エラー: プロジェクト '%s' のダンプ名 '%s' が無効です (パス区切り文字は使用できません)",
+		"validate.error.dumpDuplicateName":  "設定エラー: プロジェクト '%s' のダンプ名 '%s' が重複しています",
+		"validate.error.dumpNoDatabase":     "設定エラー: プロジェクト '%[1]s' のダンプ '%[2]s' に 'database' フィールドがありません",
+		"validate.error.dumpNoCommand":      "設定エラー: プロジェクト '%[1]s' のダンプ '%[2]s' に 'command' フィールドがありません",
+		"validate.error.dumpUnknownProvider": "設定エラー: プロジェクト '%[1]s' のダンプ '%[2]s' のプロバイダー '%[3]s' は不明です (postgres, mysql, command のいずれかを指定してください)",
+		"validate.error.dumpInvalidPort":    "設定エラー: プロジェクト '%[1]s' のダンプ '%[2]s' のポートが無効です: %[3]d",
 
 		// List command
 		"list.short":       "登録済みプロジェクトの一覧を表示",
-		"list.long":        "設定ファイルに登録されているすべてのプロジェクトの一覧を表示します。",
-		"list.noConfig":    "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"list.readError":   "設定ファイルの読み込みに失敗しました: %v",
-		"list.parseError":  "設定ファイルのパースに失敗しました: %v",
+		"list.long":        "設定ファイルに登録されているすべてのプロジェクトの一覧を表示します。\ninclude したファイルのプロジェクトや、各リポジトリの .toske.yml の設定も含まれます。\n--origin を指定すると各フィールドの定義元ファイルを表示します。",
 		"list.noProjects":  "プロジェクトが登録されていません。\n'toske edit' を実行して設定ファイルにプロジェクトを追加してください。",
 		"list.header":      "登録済みプロジェクト:",
 		"list.repo":        "リポジトリ",
+		"list.vcs":         "VCS",
 		"list.branch":      "ブランチ",
 		"list.backupPaths": "バックアップパス",
 		"list.retention":   "保持件数",
+		"list.dumps":       "ダンプ",
+		"list.includeGitState": "git の作業を含める",
+		"list.archiveMode":     "アーカイブの範囲",
+		"list.exclude":         "除外",
 		"list.total":       "\n合計: %d 件",
+		"list.flag.origin": "各フィールドの定義元ファイルを表示",
 
 		// Backup command
 		"backup.short":                    "プロジェクトファイルをバックアップ",
 		"backup.long":                     "プロジェクト設定で指定されたファイルのバックアップアーカイブを作成します。",
-		"backup.noConfig":                 "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"backup.readError":                "設定ファイルの読み込みに失敗しました: %v",
-		"backup.parseError":               "設定ファイルのパースに失敗しました: %v",
 		"backup.noProjectFlag":            "プロジェクト名が必要です。--project フラグを使用してプロジェクトを指定してください。",
-		"backup.projectNotFound":          "プロジェクト '%s' が設定ファイルに見つかりません。",
-		"backup.noBackupPaths":            "プロジェクト '%s' に backup_paths が設定されていません。",
+		"backup.noBackupPaths":            "プロジェクト '%s' に backup_paths も dumps も設定されていません。",
+		"backup.noProjectDir":             "プロジェクトディレクトリが存在しません: %s",
 		"backup.creatingBackup":           "バックアップを作成しています: %s",
 		"backup.creatingDir":              "バックアップディレクトリを作成: %s",
 		"backup.createDirError":           "バックアップディレクトリの作成に失敗しました: %v",
 		"backup.creatingArchive":          "バックアップアーカイブを作成: %s",
 		"backup.fileNotFound":             "  ⚠ スキップ: %s (見つかりません)",
 		"backup.addingFile":               "  + %s",
-		"backup.archiveError":             "バックアップアーカイブの作成に失敗しました: %v",
+		"backup.archiveError":             "バックアップアーカイブの作成に失敗しました: %w",
+		"backup.fileChanged":              "バックアップの作成中に %s が変更されました",
+		"backup.gitStateError":            "%s の git の作業を保存できませんでした: %w",
+		"backup.savingGitState":           "プッシュしていないブランチ・stash・コミットしていない変更を保存しています",
+		"backup.submodulesWarning":        "警告: %s にはサブモジュール (%s) があります。include_git_state はサブモジュール内の変更を保存しません",
+		"backup.worktreesWarning":         "警告: %s には他のワークツリー (%s) があります。include_git_state はそれらのコミットしていない変更を保存しません",
 		"backup.updatingMetadata":         "メタデータファイルを更新",
 		"backup.metadataError":            "メタデータの更新に失敗しました: %v",
 		"backup.pruningOldBackups":        "古いバックアップをクリーンアップ (%d 件保持)",
 		"backup.pruneError":               "警告: 古いバックアップの削除に失敗しました: %v",
+		"backup.interrupted":              "バックアップが中断されました。作成途中のアーカイブは削除しました。",
 		"backup.success":                  "✓ バックアップが正常に完了しました！",
 		"backup.backupLocation":           "  バックアップの場所: %s",
+		"backup.gitStateSaved":            "  保存した git の作業: プッシュしていないブランチ %d 件、stash %d 件、未追跡のファイル %d 件",
+		"backup.gitPatchSaved":            "  追跡中のファイルへのコミットしていない変更を保存しました",
+		"backup.verifyingArchive":         "アーカイブを確認しています",
+		"backup.verifyNoManifest":         "アーカイブに確認に使うマニフェストがありません",
+		"backup.verifyMissing":            "マニフェストにあるファイルがアーカイブにありません: %s",
+		"backup.archiveVerified":          "  アーカイブをマニフェストと照合しました",
+		"backup.fileUnsupportedWarning":   "  ⚠ 警告: %s は通常のファイル・ディレクトリ・シンボリックリンクではない (ソケットなど) ため、アーカイブしませんでした",
+		"backup.unsupportedSkipped":       "  ⚠ 通常のファイル・ディレクトリ・シンボリックリンクでない %d 件はアーカイブしませんでした",
+		"backup.removeTreeNeedsFull":      "--remove-tree には archive_mode full が必要です (プロジェクト '%s')",
+		"backup.removeTreeNeedsPath":      "--remove-tree にはプロジェクト '%s' の path が必要です (カレントディレクトリは削除しません)",
+		"backup.removeTreeRefused":        "%s は削除しません",
+		"backup.removeTreeHoldsBackups":   "%s は %s を含むため削除しません",
+		"backup.removeTreeNotVerified":    "プロジェクト '%s' のバックアップは確認されていないため、ディレクトリは残します",
+		"backup.removeTreeUnsupported":    "%s にはアーカイブできなかったファイルが %d 件あるため削除しません",
+		"backup.removeTreeError":          "%s の削除に失敗しました: %w",
+		"backup.confirmRemoveTree":        "アーカイブが済んだ %s を削除しますか？ 元に戻せません。 [y/N]: ",
+		"backup.readInputError":           "入力の読み取りに失敗しました: %v",
+		"backup.removeTreeCancelled":      "%s を残しました。",
+		"backup.removingTree":             "%s を削除しています",
+		"backup.treeRemoved":              "  %s を削除しました",
 		"backup.flag.project":             "バックアップするプロジェクト名を指定",
+		"backup.flag.removeTree":          "archive_mode full のバックアップを確認した後に、プロジェクトのディレクトリを削除",
+		"backup.flag.force":               "--remove-tree でディレクトリを削除する前に確認しない（チェックアウトに他のワークツリーがあっても削除する）",
 
 		// Restore command
 		"restore.short":                    "バックアップからプロジェクトファイルを復元",
 		"restore.long":                     "バックアップアーカイブからファイルを復元します。デフォルトでは最新のバックアップから復元します。",
-		"restore.noConfig":                 "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"restore.readError":                "設定ファイルの読み込みに失敗しました: %v",
-		"restore.parseError":               "設定ファイルのパースに失敗しました: %v",
 		"restore.noProjectFlag":            "プロジェクト名が必要です。--project フラグを使用してプロジェクトを指定してください。",
-		"restore.projectNotFound":          "プロジェクト '%s' が設定ファイルに見つかりません。",
 		"restore.noBackupDir":              "プロジェクト '%s' のバックアップディレクトリが見つかりません。",
-		"restore.noMetadata":               "プロジェクト '%s' のバックアップメタデータが見つかりません。",
+		"restore.noMetadata":               "プロジェクト '%s' のバックアップメタデータが見つかりません。アーカイブが残っている場合は 'toske reindex -p %[1]s' で作り直せます。",
 		"restore.readMetadataError":        "バックアップメタデータの読み込みに失敗しました: %v",
-		"restore.parseMetadataError":       "バックアップメタデータのパースに失敗しました: %v",
 		"restore.noBackups":                "プロジェクト '%s' のバックアップが見つかりません。",
 		"restore.backupNotFound":           "バックアップファイル '%s' が見つかりません。",
 		"restore.invalidBackupIndex":       "無効なバックアップインデックス: %d (利用可能: 1-%d)",
 		"restore.selectingBackup":          "使用するバックアップ: %s (作成日時: %s)",
 		"restore.restoringFiles":           "バックアップからファイルを復元しています...",
 		"restore.openArchiveError":         "バックアップアーカイブを開くのに失敗しました: %v",
-		"restore.extractError":             "バックアップの展開に失敗しました: %v",
+		"restore.extractError":             "バックアップの展開に失敗しました: %w",
+		"restore.createProjectDirError":    "プロジェクトディレクトリ %s の作成に失敗しました: %w",
 		"restore.extractingFile":           "  ← %s",
 		"restore.createDirError":           "ディレクトリの作成に失敗しました: %v",
 		"restore.writeFileError":           "ファイルの書き込みに失敗しました: %v",
 		"restore.success":                  "✓ 復元が正常に完了しました！",
 		"restore.restoredFiles":            "  %d 個のファイルを復元しました",
+		"restore.restoredDumps":            "  %d 個のデータベースダンプを復元しました",
+		"restore.restoredGitState":         "  ブランチ %d 件と stash %d 件を戻しました",
+		"restore.restoredPatch":            "  コミットしていない変更を適用し直しました",
 		"restore.flag.project":             "復元するプロジェクト名を指定",
 		"restore.flag.backup":              "復元するバックアップのインデックスを指定 (1 = 最新, 2 = 2番目に新しい, など)",
 		"restore.flag.force":               "確認なしで既存のファイルを上書き",
+		"restore.flag.skipDumps":           "ファイルのみ復元し、データベースダンプは読み込まない",
+		"restore.flag.checkout":            "復元の前に、バックアップ時のコミットをチェックアウトする",
+		"restore.flag.skipGitState":        "include_git_state で保存したブランチ・stash・コミットしていない変更を戻さない",
 		"restore.confirmOverwrite":         "\n⚠️  警告: カレントディレクトリの既存ファイルが上書きされます。",
 		"restore.confirmPrompt":            "続行しますか？ [y/N]: ",
 		"restore.cancelled":                "復元をキャンセルしました。",
 		"restore.readInputError":           "入力の読み取りに失敗しました: %v",
+		"restore.interrupted":              "%d 個のファイルを書き込んだところで復元が中断されました。",
+		"restore.writtenBeforeInterrupt":   "中断されるまでに書き込んだファイル:",
 		"restore.symlinkOutsideDir":        "シンボリックリンクが復元ディレクトリ外を指しています",
 		"restore.fileCreateWarning":        "  ⚠ 警告: ファイル %s の作成に失敗しました: %v",
 		"restore.fileCopyWarning":          "  ⚠ 警告: ファイル %s のコピーに失敗しました: %v",
 		"restore.fileChmodWarning":         "  ⚠ 警告: ファイル %s のパーミッション設定に失敗しました: %v",
+		"restore.fileChecksumWarning":      "  ⚠ 警告: %s がアーカイブのマニフェストのチェックサムと一致しないため、スキップしました",
+		"restore.manifestProjectMismatch":  "警告: %[1]s は %[3]s ではなくプロジェクト %[2]s のために作成されたものです",
+		"restore.commitDrift":              "警告: バックアップはコミット %[1]s の時点のものです。現在のチェックアウトはそこから %[2]d コミット進み、%[3]d コミット遅れています",
+		"restore.commitMissing":            "警告: バックアップはコミット %s の時点のものですが、このコミットはローカルのリポジトリにありません（git fetch を試してください）",
+		"restore.commitDirty":              "  バックアップ時の作業ツリーにはコミットされていない変更がありました",
+		"restore.checkoutHint":             "  --checkout を付けて実行すると、復元の前に %s をチェックアウトします",
+		"restore.checkingOut":              "%s をチェックアウトしています...",
+		"restore.checkoutError":            "記録されたコミットのチェックアウトに失敗しました: %w",
+		"restore.cloning":                  "%s を %s にクローンしています...",
+		"restore.cloneError":               "%s のクローンに失敗しました: %w",
+		"restore.noRecordedCommit":         "%s にはチェックアウトするコミットが記録されていません",
+		"restore.checkoutMissing":          "コミット %s がローカルのリポジトリにありません。先に取得してください（git fetch）",
+		"restore.updatingSubmodules":       "サブモジュールを更新しています...",
+		"restore.submodulesError":          "警告: サブモジュールを更新できませんでした: %v",
+		"restore.fetchingLFS":              "Git LFS のファイルを %d 件取得しています...",
+		"restore.lfsError":                 "警告: Git LFS のファイルを取得できませんでした: %v",
+		"restore.restoringGitState":        "ブランチ・stash・コミットしていない変更を戻しています",
+		"restore.branchSkippedWarning":     "  ⚠ 警告: ブランチ %s は別のコミットを指して既に存在するため、そのままにしました",
+		"restore.gitNotRepository":         "%s は git リポジトリではありません。先にクローンするか、--skip-git-state を付けて復元してください",
+		"restore.gitBundleError":           "ブランチと stash を戻せませんでした: %w",
+		"restore.gitPatchError":            "コミットしていない変更を適用できませんでした: %v (パッチはアーカイブ内の %s に残っています)",
 
 		// Delete command
 		"delete.short":         "設定からプロジェクトを削除",
 		"delete.long":          "設定ファイルからプロジェクトを削除します。バックアップファイルは削除されません。",
-		"delete.noConfig":      "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"delete.readError":     "設定ファイルの読み込みに失敗しました: %v",
-		"delete.parseError":    "設定ファイルのパースに失敗しました: %v",
 		"delete.noProjectFlag": "プロジェクト名が必要です。--project フラグを使用してプロジェクトを指定してください。",
-		"delete.projectNotFound": "プロジェクト '%s' が設定ファイルに見つかりません。",
 		"delete.confirmPrompt": "プロジェクト '%s' を本当に削除しますか？ [y/N]: ",
 		"delete.cancelled":     "削除をキャンセルしました。",
 		"delete.readInputError": "入力の読み取りに失敗しました: %v",
-		"delete.marshalError":  "設定のマーシャルに失敗しました: %v",
-		"delete.writeError":    "設定ファイルの書き込みに失敗しました: %v",
 		"delete.success":       "✓ プロジェクト '%s' を設定から正常に削除しました。",
 		"delete.flag.project":  "削除するプロジェクト名を指定",
-		"delete.flag.force":    "確認プロンプトをスキップ（注意して使用してください）",
+		"delete.flag.force":    "確認プロンプトをスキップし、チェックアウトに他のワークツリーがあっても削除（注意して使用してください）",
+		"delete.worktreesWarning": "警告: %s のチェックアウトには他のワークツリーがあり、toske はそれらをバックアップしません:",
+		"delete.worktreesRefused": "%s のチェックアウトには他のワークツリーがあるため中止しました。続ける場合は --force を指定してください",
 
 		// Remove command
 		"remove.short":         "設定からプロジェクトを除外",
 		"remove.long":          "設定ファイルからプロジェクトを除外します。バックアップファイルは削除されません。",
-		"remove.noConfig":      "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
-		"remove.readError":     "設定ファイルの読み込みに失敗しました: %v",
-		"remove.parseError":    "設定ファイルのパースに失敗しました: %v",
 		"remove.noProjectFlag": "プロジェクト名が必要です。--project フラグを使用してプロジェクトを指定してください。",
-		"remove.projectNotFound": "プロジェクト '%s' が設定ファイルに見つかりません。",
 		"remove.confirmPrompt": "プロジェクト '%s' を設定から本当に除外しますか？ [y/N]: ",
 		"remove.cancelled":     "除外をキャンセルしました。",
 		"remove.readInputError": "入力の読み取りに失敗しました: %v",
-		"remove.marshalError":  "設定のマーシャルに失敗しました: %v",
-		"remove.writeError":    "設定ファイルの書き込みに失敗しました: %v",
 		"remove.success":       "✓ プロジェクト '%s' を設定から正常に除外しました。",
 		"remove.flag.project":  "除外するプロジェクト名を指定",
-		"remove.flag.force":    "確認プロンプトをスキップ（注意して使用してください）",
+		"remove.flag.force":    "確認プロンプトをスキップし、チェックアウトに他のワークツリーがあっても削除（注意して使用してください）",
+
+		// Database dumps
+		"dump.dumping":              "  + ダンプ: %s (%s)",
+		"dump.restoring":            "  ← ダンプ: %s (%s)",
+		"dump.unknownProvider":      "不明なダンププロバイダー: %s",
+		"dump.noRestoreCommand":     "ダンプ '%s' に restore_command が設定されていません",
+		"dump.dumpError":            "'%s' のダンプに失敗しました: %v",
+		"dump.restoreError":         "ダンプ '%s' の復元に失敗しました: %v",
+		"dump.notConfiguredWarning": "  ⚠ 警告: ダンプ '%s' をスキップします (このプロジェクトに設定されていません)",
+
+		// Info command
+		"info.short":             "プロジェクトの詳細な状態を表示",
+		"info.long":              "プロジェクトの設定内容、ローカルのチェックアウト状態、バックアップ、バックアップ対象パスの状態を表示します。",
+		"info.flag.project":      "表示するプロジェクト名を指定",
+		"info.flag.json":         "JSON 形式で出力 (--output json と同じ)",
+		"info.noProjectFlag":     "プロジェクト名が必要です。--project フラグを使用してプロジェクトを指定してください。",
+		"info.metadataError":     "バックアップメタデータの読み込みに失敗しました: %v",
+		"info.header":            "プロジェクト: %s",
+		"info.path":              "パス",
+		"info.currentDir":        "(カレントディレクトリ)",
+		"info.dump":              "ダンプ",
+		"info.checkoutHeader":    "チェックアウト:",
+		"info.directory":         "ディレクトリ",
+		"info.status":            "状態",
+		"info.checkoutPresent":   "あり",
+		"info.checkoutAbsent":    "なし",
+		"info.checkoutNotRepo":   "あり (git リポジトリではありません)",
+		"info.checkoutUnversioned": "あり (バージョン管理なし)",
+		"info.currentBranch":     "現在のブランチ",
+		"info.workingTree":       "作業ツリー",
+		"info.clean":             "クリーン",
+		"info.dirty":             "未コミットの変更あり",
+		"info.upstream":          "アップストリーム",
+		"info.aheadBehind":       "%d 件先行, %d 件遅れ",
+		"info.noUpstream":        "アップストリーム未設定",
+		"info.backupsHeader":     "バックアップ:",
+		"info.backupCount":       "件数",
+		"info.totalSize":         "合計サイズ",
+		"info.latestBackup":      "最新",
+		"info.never":             "なし",
+		"info.backupPathsHeader": "バックアップ対象パス (現在の状態):",
+		"info.missing":           "見つかりません",
+
+		// Relative time
+		"age.justNow": "たった今",
+		"age.minutes": "%d 分前",
+		"age.hours":   "%d 時間前",
+		"age.days":    "%d 日前",
+
+		// Output
+		"output.flag":          "出力形式: table, json, yaml",
+		"output.invalidFormat": "未対応の出力形式です: %s (table, json, yaml のいずれかを指定してください)",
+		"output.flag.quiet":    "警告とエラーだけを表示",
+		"output.flag.verbose":  "処理中のファイルを 1 つずつ表示",
+		"output.quietVerbose":  "--quiet と --verbose は同時に指定できません",
+
+		// Progress
+		"progress.line":             "%s / %s (%d%%)  %s/s  残り %s",
+		"progress.lineUnknownTotal": "%s  %s/s",
+
+		// Status command
+		"status.short":                        "全プロジェクトのバックアップ状況を表示",
+		"status.long":                         "登録済みの全プロジェクトについて、チェックアウトの状態、最終バックアップ、バックアップ件数、ディスク使用量、警告を表形式で表示します。",
+		"status.flag.sort":                    "並び順 (name, last-backup, size)",
+		"status.flag.filter":                  "名前が一致するプロジェクトのみ表示 (部分一致またはグロブ)",
+		"status.flag.warningsOnly":            "警告のあるプロジェクトのみ表示",
+		"status.invalidSort":                  "無効な並び順です: %s (name, last-backup, size のいずれかを指定してください)",
+		"status.noProjects":                   "該当するプロジェクトはありません。",
+		"status.tableHeader":                  "名前\tチェックアウト\t最終バックアップ\t件数\tサイズ\t警告",
+		"status.checkout.present":             "あり",
+		"status.checkout.absent":              "なし",
+		"status.checkout.unknown":             "-",
+		"status.warning.never_backed_up":      "未バックアップ",
+		"status.warning.changed_since_backup": "最終バックアップ以降に変更あり",
+		"status.warning.missing_backup_paths": "バックアップ対象パスが見つかりません",
+		"status.warning.status_failed":        "状態を取得できません",
+
+		// Doctor command
+		"doctor.short":              "環境と設定ファイルの問題を診断",
+		"doctor.long":               "git、設定ファイル、バックアップディレクトリに問題がないかを確認し、対処方法を提示します。\n--fix を指定すると、安全に修復できる問題を自動的に修復します。",
+		"doctor.flag.fix":           "安全に修復できる問題を自動的に修復",
+		"doctor.gitOK":              "git が利用可能です (バージョン %s)",
+		"doctor.gitMissing":         "git が利用できません: %v",
+		"doctor.gitMissingFix":      "git をインストールし、PATH に含まれていることを確認してください。",
+		"doctor.gitNotNeeded":       "git は確認しません: git の VCS を使うプロジェクトがありません",
+		"doctor.configOK":           "設定ファイルは有効です: %s (%d 件のプロジェクト)",
+		"doctor.configMissing":      "設定ファイルが存在しません: %s",
+		"doctor.configMissingFix":   "'toske init' を実行して作成してください。",
+		"doctor.configInvalidFix":   "'toske edit' を実行して設定ファイルを修正してください。",
+		"doctor.configOutdated":     "設定ファイルのスキーマバージョンは %s です (現在: %s)",
+		"doctor.configOutdatedFix":  "'toske config migrate' を実行して移行してください。",
+		"doctor.legacyConfig":       "設定ファイルがレガシーパスにあります: %s",
+		"doctor.legacyConfigFix":    "%s に移動してください",
+		"doctor.noBackupDir":        "バックアップはまだ作成されていません (%s が存在しません)",
+		"doctor.backupDirOK":        "バックアップディレクトリに書き込み可能です: %s",
+		"doctor.notWritable":        "バックアップディレクトリに書き込めません: %s (%v)",
+		"doctor.notWritableFix":     "%s の所有者とパーミッションを確認してください",
+		"doctor.orphanBackupDir":    "設定ファイルにないプロジェクト '%s' のバックアップがあります",
+		"doctor.orphanBackupDirFix": "プロジェクトを設定に戻すか、不要であれば %s を削除してください",
+		"doctor.metadataError":      "%s のバックアップメタデータの読み込みに失敗しました: %v",
+		"doctor.metadataErrorFix":   "%s を修正または削除してください",
+		"doctor.missingArchives":    "'%s' のメタデータが存在しないアーカイブを参照しています: %s",
+		"doctor.missingArchivesFix": "backups.yaml から該当する記録を削除してください",
+		"doctor.exposedArchives":    "'%s' のアーカイブは秘密情報を含みますが、他のユーザーから読み取り可能です: %s",
+		"doctor.exposedArchivesFix": "アーカイブを所有者のみに制限してください (chmod 600)",
+		"doctor.partialArchives":              "%s に中断されたバックアップの作成途中のアーカイブが残っています: %s",
+		"doctor.partialArchivesFix":           "作成途中のアーカイブを削除してください",
+		"doctor.partialArchivesInProgress":    "%s の作成途中のアーカイブは最近書き込まれており、実行中のバックアップやエクスポートのものかもしれません: %s",
+		"doctor.partialArchivesInProgressFix": "実行中のバックアップやエクスポートがないか確認してください。%s 更新がなければ --fix で削除します",
+		"doctor.fixed":              "✓ 修復しました",
+		"doctor.fixFailed":          "✗ 修復に失敗しました: %s",
+		"doctor.healthy":            "問題は見つかりませんでした。",
+		"doctor.summary":            "エラー %d 件、警告 %d 件",
+		"doctor.fixHint":            "'toske doctor --fix' を実行すると %d 件の問題を自動的に修復できます。",
+		"doctor.problemsFound":      "%d 件の問題が見つかりました",
+
+		// Add command
+		"add.short":                "ローカルのチェックアウトからプロジェクトを登録",
+		"add.long":                 "ローカルのチェックアウト（デフォルトはカレントディレクトリ）を調べ、設定ファイルに追加します。\nリポジトリ URL とブランチは git から読み取り (git リポジトリでないディレクトリは vcs: none で追加します)、git で無視されているファイルのうち秘密情報やデータベースらしいものをバックアップ対象として提案します。\nすべての項目はフラグでも指定できます。--non-interactive を指定すると確認を行いません。",
+		"add.flag.name":            "プロジェクト名 (デフォルト: ディレクトリ名)",
+		"add.flag.repo":            "リポジトリ URL (デフォルト: origin リモートの URL)",
+		"add.flag.vcs":             "ディレクトリのバージョン管理システム (git または none、デフォルト: git)",
+		"add.flag.branch":          "ブランチ (デフォルト: 現在のブランチ)",
+		"add.flag.path":            "記録するローカルのチェックアウト先 (デフォルト: 対象ディレクトリ)",
+		"add.flag.backupPath":      "バックアップ対象のパス (複数指定またはカンマ区切り)",
+		"add.flag.retention":       "保持するバックアップ数 (0 はすべて保持)",
+		"add.flag.dump":            "取得するデータベースダンプ。dumps のキーを key=value で指定 (例: name=app.sql,provider=postgres,database=app、複数指定可)",
+		"add.flag.includeGitState": "プッシュしていないブランチ・stash・コミットしていない変更もバックアップ",
+		"add.flag.skipGitFeature":  "toske に扱わせない git の機能 (submodules、worktrees、lfs。複数指定またはカンマ区切り)",
+		"add.flag.archiveMode":     "アーカイブする範囲 (paths はバックアップ対象のパス、full はディレクトリ全体、デフォルト: paths)",
+		"add.flag.exclude":         "--archive-mode full でアーカイブしないパスのパターン (複数指定またはカンマ区切り)",
+		"add.flag.noSuggest":       "git で無視されているファイルからバックアップ対象を提案しない",
+		"add.flag.nonInteractive":  "確認を行わず、フラグ・検出値・すべての提案を使用",
+		"add.marshalError":         "プロジェクトのマーシャルに失敗しました: %v",
+		"add.noDir":                "ディレクトリが存在しません: %s",
+		"add.readInputError":       "入力の読み込みに失敗しました: %v",
+		"add.invalidNumber":        "数値ではありません: %s",
+		"add.invalidDump":          "--dump の値が正しくありません (カンマ区切りの key=value で指定してください): %s",
+		"add.prompt.name":          "プロジェクト名",
+		"add.prompt.repo":          "リポジトリ URL",
+		"add.prompt.branch":        "ブランチ",
+		"add.prompt.path":          "ローカルパス",
+		"add.prompt.suggestion":    "%s をバックアップしますか？ [Y/n]: ",
+		"add.prompt.backupPaths":   "追加のバックアップ対象パス (カンマ区切り)",
+		"add.prompt.retention":     "保持するバックアップ数 (0 はすべて保持)",
+		"add.confirmPrompt":        "このプロジェクトを %s に追加しますか？ [Y/n]: ",
+		"add.cancelled":            "キャンセルしました。",
+		"add.success":              "✓ プロジェクト '%s' を %s に追加しました",
+
+		// Get / set commands
+		"get.short":             "設定値を表示",
+		"get.long":              "トップレベルの設定値 (version など)、または --project を指定した場合はプロジェクトのフィールドを表示します。\nリストは 1 行に 1 項目ずつ表示します。",
+		"get.flag.project":      "フィールドを読み取るプロジェクト",
+		"set.short":             "設定値を変更",
+		"set.long":              "トップレベルの設定値 (version など)、または --project を指定した場合はプロジェクトのフィールドを変更します。\nリストにはカンマ区切りで値を指定でき (値の中のカンマは \\, と書きます)、key+=value と key-=value で追加・削除できます。\n省略可能なフィールドに空の値を設定するとフィールドを削除します。変更は保存前に検証され、\nファイルの他の部分 (コメントや書式) はそのまま残ります。\n\n例:\n  toske set version 1.0.0\n  toske set -p myapp branch develop\n  toske set -p myapp backup_paths+=storage/\n  toske set -p myapp backup_retention=5",
+		"set.flag.project":      "変更するプロジェクト",
+		"set.success":           "✓ %s = %s",
+		"set.successProject":    "✓ %s.%s = %s",
+		"fields.unknownKey":     "不明なキー '%s' です (利用可能: %s)",
+		"fields.invalidArgs":    "'<key> <value>'、'<key>=<value>'、'<key>+=<value>'、'<key>-=<value>' のいずれかの形式で指定してください",
+		"fields.notList":        "'%s' はリストではないため、+= と -= は使用できません",
+		"fields.notInList":      "'%s' は %s に含まれていません",
+		"fields.invalidNumber":  "%s には数値を指定してください: %s",
+		"fields.invalidBool":    "%s には true か false を指定してください: %s",
+		"fields.unsupportedKey": "'%s' はコマンドラインから変更できません。'toske edit' を使用してください",
+
+		// Includes
+		"include.badPattern":       "include のパターン '%s' が不正です: %v",
+		"include.readError":        "%s の読み込みに失敗しました: %v",
+		"include.parseError":       "include したファイル %s のパースに失敗しました: %v",
+		"include.localParseError":  "%s のパースに失敗しました (backup_paths, backup_retention, include_git_state, exclude のみ指定できます): %v",
+		"include.localPathOutside": "%s: %s の '%s' はプロジェクトのディレクトリ内の相対パスで指定してください",
+
+		// Config command
+		"configCmd.short":        "設定ファイルを管理",
+		"configCmd.long":         "設定ファイル自体を管理するコマンドです。\n\nTOSKE_PROJECT_<名前>_<フィールド> を設定すると、ファイルを編集せずにプロジェクトのフィールドを上書きできます。\n名前とフィールドは大文字にし、それ以外の文字は _ に置き換えます。リストはカンマ区切りで指定します。例:\n  TOSKE_PROJECT_MY_APP_BRANCH=develop toske backup -p my-app",
+		"migrate.short":          "設定ファイルを現在のスキーマバージョンに移行",
+		"migrate.long":           "古い設定ファイルを、このバイナリが理解できるスキーマバージョンに移行します。\n書き換える前に、元のファイルを同じディレクトリにバックアップします。\n新しいバージョンの toske 向けに書かれたファイルは、未知のキーを無視して読み込むのではなく拒否します。",
+		"migrate.readError":      "設定ファイルの読み込みに失敗しました: %v",
+		"migrate.parseError":     "設定ファイルのパースに失敗しました: %v",
+		"migrate.noVersion":      "設定ファイルにバージョンがないため、適用する移行を判断できません",
+		"migrate.invalidVersion": "設定ファイルのバージョン '%s' が不正です (1.0.0 のような形式で指定してください)",
+		"migrate.tooNew":         "設定ファイルのバージョンは %s ですが、この toske は %s までしか対応していません。\nこのファイルを使用するには toske をアップグレードしてください。",
+		"migrate.noPath":         "バージョン %s からの移行が登録されていません (次の移行は %s から始まります)",
+		"migrate.stepError":      "%s から %s への移行に失敗しました: %v",
+		"migrate.backupError":    "設定ファイルのバックアップに失敗しました: %v",
+		"migrate.writeError":     "設定ファイルの書き込みに失敗しました: %v",
+		"migrate.upToDate":       "✓ %s は既に現在のバージョン (%s) です。",
+		"migrate.backedUp":       "元のファイルを %s にバックアップしました",
+		"migrate.success":        "✓ %s を %s から %s に移行しました",
+
+		// Signal
+		"signal.interrupted": "中断しています。後片付け中です... (すぐに終了するにはもう一度 Ctrl-C を押してください)",
+		"signal.forceExit":   "強制終了しました。",
+
+		// Log command
+		"log.short":              "過去の操作の記録を表示",
+		"log.long":               "ファイル、バックアップ、設定ファイルを変更したコマンドの記録を新しい順に表示します。\n記録は $XDG_STATE_HOME/toske/journal.jsonl（既定では ~/.local/state/toske/journal.jsonl）に保存されます。",
+		"log.flag.project":       "このプロジェクトに対する操作のみ表示",
+		"log.flag.since":         "指定した期間前 (36h, 7d) または日付 (2006-01-02) 以降の操作のみ表示",
+		"log.invalidSince":       "無効な --since の値です: %s (36h や 7d のような期間、または 2006-01-02 のような日付を指定してください)",
+		"log.readError":          "記録の読み込みに失敗しました: %v",
+		"log.empty":              "記録された操作はありません。",
+		"log.tableHeader":        "日時\tコマンド\tプロジェクト\t結果\t所要時間\tファイル\t詳細",
+		"log.result.success":     "成功",
+		"log.result.failed":      "失敗",
+		"log.result.cancelled":   "取り消し",
+		"log.result.interrupted": "中断",
+		"logging.flag":           "標準エラー出力に出すログのレベル: debug, info, warn, error",
+		"logging.invalidLevel":   "無効なログレベルです: %s (debug, info, warn, error のいずれかを指定してください)",
+
+		// Dry run / plans
+		"dryRun.flag":           "何も変更せずにコマンドが行う内容を表示します",
+		"dryRun.notice":         "ドライラン: 何も変更していません。",
+		"dryRun.unsupported":    "%s は --dry-run に対応していません",
+		"plan.backupTitle":      "プロジェクトのバックアップ計画: %s",
+		"plan.backupDir":        "バックアップディレクトリ: %s",
+		"plan.files":            "アーカイブするファイル: %d 件 (%s)",
+		"plan.file":             "  + %s (%s)",
+		"plan.fullArchive":      "アーカイブの範囲: full (ディレクトリ全体)",
+		"plan.unsupported":      "  ⚠ スキップ: %s (通常のファイル・ディレクトリ・シンボリックリンクではありません)",
+		"plan.removeTree":       "アーカイブの確認の後に、プロジェクトのディレクトリを削除します",
+		"plan.dump":             "  + データベースのダンプ: %s",
+		"plan.prune":            "削除するアーカイブ:",
+		"plan.pruneItem":        "  - %s",
+		"plan.restoreSummary":   "復元するファイル: %d 件 (うち既存ファイルの上書き %d 件)",
+		"plan.restoreOverwrite": "  ! %s (%s、上書き)",
+		"plan.restoreNew":       "  + %s (%s)",
+		"plan.restoreSkipped":   "  ⚠ スキップ: %s (%s)",
+		"plan.restoreCreateDir": "プロジェクトのディレクトリが存在しないため、アーカイブから作り直します",
+		"plan.restoreDump":      "  + データベースのダンプ: %s",
+		"plan.gitState":         "  + git の作業: プッシュしていないブランチ・stash・コミットしていない変更",
+		"plan.restoreBranch":    "  + ブランチ: %s",
+		"plan.restoreStashes":   "  + stash: %d 件",
+		"plan.restorePatch":     "  + コミットしていない変更",
+		"plan.restoreSubmodules": "復元の後にサブモジュールを初期化・更新します",
+		"plan.restoreLFS":       "復元の後に Git LFS のファイルを %d 件取得します",
+		"plan.restoreCheckout":  "復元の前にコミット %s をチェックアウトします",
+		"plan.restoreClone":     "プロジェクトのディレクトリが存在しないため、復元の前に %s を %s にクローンします",
+		"plan.configTitle":      "%s への変更:",
+		"plan.noChanges":        "  (変更なし)",
+
+		// Export / import commands
+		"export.short":                  "プロジェクトとバックアップを持ち運べるバンドルに書き出す",
+		"export.long":                   "プロジェクトの設定と選んだバックアップのアーカイブを 1 つのバンドルファイルに書き出します。\nプロジェクトを他の人に渡したり、別のマシンに移したりするときに使います。\n--all-projects で全プロジェクトをまとめて書き出せます。バンドルには .env などの秘密情報が含まれるため、取り扱いに注意してください。\n\n例:\n  toske export -p myapp -o myapp.toske\n  toske export -p myapp --all\n  toske export --all-projects -o laptop.toske",
+		"export.flag.project":           "書き出すプロジェクト",
+		"export.flag.backup":            "書き出すバックアップ (1 = 最新)",
+		"export.flag.all":               "1 つではなく全てのバックアップを書き出す",
+		"export.flag.allProjects":       "全てのプロジェクトを書き出す",
+		"export.flag.output":            "書き出すバンドルファイル (既定は <プロジェクト名>.toske)",
+		"export.noProject":              "--project または --all-projects を指定してください",
+		"export.projectAndAll":          "--project と --all-projects は同時に指定できません",
+		"export.interrupted":            "エクスポートが中断されました。バンドルは書き込まれていません",
+		"export.writeError":             "バンドルの書き込みに失敗しました: %w",
+		"export.success":                "✓ バンドルを書き出しました: %s",
+		"export.bundle":                 "バンドル: %s",
+		"export.project":                "  %s: アーカイブ %d 件",
+		"import.short":                  "バンドルからプロジェクトとバックアップを取り込む",
+		"import.long":                   "'toske export' で書き出したバンドルのプロジェクトを取り込みます。設定ファイルに登録し、\nアーカイブをバックアップに加えます（既にあるバックアップはスキップします）。\n同じ名前のプロジェクトがある場合の扱いは --on-conflict で指定します:\n  merge    既存の設定を残し、アーカイブだけを加える\n  rename   空いている名前 (name-2 など) で登録する\n  replace  既存の設定を置き換え、アーカイブを加える\n  skip     そのプロジェクトを取り込まない\n  ask      プロジェクトごとに尋ねる (既定)\nバンドルに記録されたチェックアウト先と、コマンドを実行する provider command のダンプは、\n--keep-path や --allow-commands を指定しない限り取り込みません (いずれの場合も一覧に表示します)。",
+		"import.flag.onConflict":        "プロジェクト名が重なった場合の扱い: ask、merge、rename、replace、skip",
+		"import.flag.name":              "この名前でプロジェクトを取り込む (プロジェクトが 1 つのバンドルのみ)",
+		"import.flag.keepPath":          "バンドルに記録されたチェックアウト先を残す (送り手のマシンのパスのため、既定では外す)",
+		"import.flag.allowCommands":     "バックアップと復元でコマンドを実行する provider command のダンプを残す (既定では外す)",
+		"import.invalidOnConflict":      "--on-conflict の値 %q が不正です (ask、merge、rename、replace、skip のいずれか)",
+		"import.readError":              "バンドルの読み込みに失敗しました: %w",
+		"import.notBundle":              "toske のバンドルではありません (先頭に bundle.yaml がありません)",
+		"import.unsupportedFormat":      "バンドルの形式 %d はこの toske が対応する形式 (%d) より新しいため読み込めません。toske を更新してください",
+		"import.nameNeedsSingleProject": "--name はプロジェクトが 1 つだけのバンドルでのみ使用できます",
+		"import.invalidArchiveName":     "アーカイブ名 %q が不正です",
+		"import.archiveError":           "%s の取り込みに失敗しました: %w",
+		"import.readInputError":         "入力の読み取りに失敗しました: %w",
+		"import.conflictPrompt":         "プロジェクト %s は既に存在します。[m] バックアップを統合 / [r] 名前を変更 / [p] 置き換え / [s] スキップ [m]: ",
+		"import.bundle":                 "バンドル: %s",
+		"import.project":                "  %s: %s",
+		"import.action.add":             "新しいプロジェクト",
+		"import.action.merge":           "既存のプロジェクトにバックアップを追加",
+		"import.action.rename":          "新しいプロジェクト (名前を変更)",
+		"import.action.replace":         "既存の設定を置き換え",
+		"import.action.skip":            "スキップ",
+		"import.archiveDuplicate":       "    %s (既にあるためスキップ)",
+		"import.archiveRenamed":         "    %s (%s として保存)",
+		"import.pathKept":               "    path: %s (--keep-path により残す)",
+		"import.pathDropped":            "    path %s は取り込みません (残す場合は --keep-path を指定)",
+		"import.commandDumpKept":        "    ダンプ %s は次を実行します: %s (--allow-commands により残す)",
+		"import.commandDumpDropped":     "    ダンプ %s はコマンドを実行するため取り込みません (残す場合は --allow-commands を指定): %s",
+		"import.restoreCommand":         "      復元時に実行: %s",
+		"import.success":                "✓ 取り込みました: %s",
+
+		// Reindex / import-archive commands
+		"reindex.short":               "アーカイブからプロジェクトのバックアップメタデータを作り直す",
+		"reindex.long":                "プロジェクトのバックアップディレクトリにある tar.gz アーカイブを全て読み、\nアーカイブ内のエントリとファイル名のタイムスタンプ（なければ更新日時）から backups.yaml を作り直します。\nbackups.yaml をなくした場合や、アーカイブを手でバックアップディレクトリにコピーした場合に使います。\n\n例:\n  toske reindex -p myapp",
+		"reindex.flag.project":        "メタデータを作り直すプロジェクト",
+		"reindex.noProjectFlag":       "プロジェクト名が必要です (-p または --project を使用してください)",
+		"reindex.notCompressed":       "gzip 圧縮されたアーカイブではありません",
+		"reindex.unreadable":          "⚠ 読み込めないアーカイブをスキップしました %s: %s",
+		"reindex.summary":             "%[1]s のバックアップ %[2]d 件を登録しました (新規 %[3]d 件、削除 %[4]d 件)",
+		"reindex.added":               "  + %s",
+		"reindex.dropped":             "  - %s (アーカイブが見つかりません)",
+		"importArchive.short":         "既存の tar ファイルをプロジェクトのバックアップとして取り込む",
+		"importArchive.long":          "他の場所で作った tar または tar.gz ファイルをプロジェクトのバックアップとして取り込みます。\nファイルはバックアップディレクトリにコピーされ（tar ファイルは圧縮されます）、backups.yaml に記録されるため、\n'toske restore' で復元できます。backup_<日時>.tar.gz 形式でない名前は更新日時に基づいて変更されます。\n\n例:\n  toske import-archive -p myapp ~/Downloads/myapp-env.tar.gz",
+		"importArchive.flag.project":  "アーカイブを加えるプロジェクト",
+		"importArchive.noProjectFlag": "プロジェクト名が必要です (-p または --project を使用してください)",
+		"importArchive.readError":     "%s を tar アーカイブとして読み込めません: %w",
+		"importArchive.adopted":       "✓ %s -> %s (%s)",
+		"importArchive.contents":      "  パス %d 件、ダンプ %d 件",
+		"importArchive.duplicate":     "  %s は既にこのプロジェクトのバックアップにあるためスキップしました",
+
+		// Archive manifest
+		"manifest.readError":         "アーカイブのマニフェストの読み込みに失敗しました: %w",
+		"manifest.unsupportedFormat": "アーカイブのマニフェストの形式 %d は、このバージョンの toske（%d まで対応）では扱えません。toske を更新してください",
+		"manifest.checksumMismatch":  "%s がアーカイブのマニフェストのチェックサムと一致しません",
+
+		// Git features
+		"gitFeatures.lfsNotInstalled": "警告: %s は Git LFS を使っていますが、git-lfs がインストールされていないため、LFS のファイルを確認・取得できません",
+
+		// Version control
+		"vcs.checkoutUnsupported": "vcs が %s のプロジェクトでは、記録された状態をチェックアウトできません",
+		"vcs.cloneUnsupported":    "vcs が %s のプロジェクトはクローンできません。先にプロジェクトのディレクトリを作成してください",
 
 		// Config
 		"config.legacyWarning":       "⚠️  警告: レガシーの設定ファイル位置を使用しています。",
 		"config.legacyWarningDetail": "   次のコマンドで ~/.config/toske/config.yml に移行してください: mv ~/.toske.yaml ~/.config/toske/config.yml",
+		"config.noConfig":            "設定ファイルが存在しません: %s\n'toske init' を実行して作成してください。",
+		"config.readError":           "設定ファイルの読み込みに失敗しました: %v",
+		"config.parseError":          "設定ファイルのパースに失敗しました: %v",
+		"config.projectNotFound":     "プロジェクト '%s' が設定ファイルに見つかりません。",
+		"config.editError":           "設定の更新に失敗しました: %v",
+		"config.writeError":          "設定ファイルの書き込みに失敗しました: %v",
+		"config.envError":            "%s の値が不正です: %v",
+		"config.envAmbiguous":        "%s は複数のプロジェクトに当てはまります。使うにはどちらかのプロジェクト名を変更してください",
 
 		// Common
 		"common.error": "エラー: %v",
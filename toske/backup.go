@@ -0,0 +1,501 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: BackupResult は Backup の結果です
+// en: BackupResult is the outcome of Backup
+type BackupResult struct {
+	Project string `json:"project" yaml:"project"`
+	Archive string `json:"archive" yaml:"archive"`
+	Size    int64  `json:"size" yaml:"size"`
+	// ja: SHA256 はアーカイブ全体の SHA-256 です（書き込みながら計算するため、読み直しは不要です）
+	// en: SHA256 is the SHA-256 of the whole archive (computed while writing it, so it is never read back for this)
+	SHA256 string `json:"sha256" yaml:"sha256"`
+	// ja: Files はアーカイブに追加したファイルです
+	// en: Files are the files added to the archive
+	Files []ArchivedFile `json:"files" yaml:"files"`
+	// ja: Skipped は存在しなかった backup_paths のエントリです
+	// en: Skipped are the backup_paths entries that did not exist
+	Skipped []string `json:"skipped" yaml:"skipped"`
+	Dumps   []string `json:"dumps" yaml:"dumps"`
+	// ja: Pruned は保持件数を超えたため削除したアーカイブです
+	// en: Pruned are the archives removed because they exceeded the retention
+	Pruned []string `json:"pruned" yaml:"pruned"`
+	// ja: GitWork は include_git_state で保存した git の作業です（保存しなかった場合は nil）
+	// en: GitWork is the git work saved for include_git_state (nil when nothing was saved)
+	GitWork *GitWork `json:"git_work,omitempty" yaml:"git_work,omitempty"`
+	// ja: Unsupported は ArchiveModeFull でアーカイブできなかったファイル（ソケットやデバイスなど）です
+	// en: Unsupported are the files ArchiveModeFull could not archive (sockets, devices and so on)
+	Unsupported []string `json:"unsupported,omitempty" yaml:"unsupported,omitempty"`
+	// ja: Verified はアーカイブを読み直して確認したかを、TreeRemoved は RemoveTree でディレクトリを削除したかを表します
+	// en: Verified tells whether the archive was read back and checked, and TreeRemoved whether RemoveTree removed the directory
+	Verified    bool         `json:"verified,omitempty" yaml:"verified,omitempty"`
+	TreeRemoved bool         `json:"tree_removed,omitempty" yaml:"tree_removed,omitempty"`
+	Record      BackupRecord `json:"-" yaml:"-"`
+}
+
+// ja: ArchivedFile はアーカイブに追加されたファイルです
+// en: ArchivedFile is a file that was added to the archive
+type ArchivedFile struct {
+	Path string `json:"path" yaml:"path"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// ja: PartialSuffix は作成中のアーカイブに付く拡張子です
+// en: PartialSuffix is appended to the name of an archive while it is being written
+const PartialSuffix = ".partial"
+
+// ja: archiveContents は createArchive が作成したアーカイブの内容です
+// en: archiveContents describes what createArchive put into the archive
+type archiveContents struct {
+	// ja: Paths はメタデータに記録する backup_paths のエントリです
+	// en: Paths are the backup_paths entries recorded in the metadata
+	Paths   []string
+	Files   []ArchivedFile
+	Skipped []string
+	Dumps   []string
+	GitWork *GitWork
+	// ja: Unsupported は ArchiveModeFull でスキップしたファイルです
+	// en: Unsupported are the files skipped with ArchiveModeFull
+	Unsupported []string
+	// ja: SHA256 は書き込んだアーカイブ全体の SHA-256 です
+	// en: SHA256 is the SHA-256 of the whole archive as written
+	SHA256 string
+}
+
+// ja: Backup はプロジェクトの backup_paths とダンプをアーカイブし、メタデータに記録します
+// ja: Retention が設定されていれば、保持件数を超えた古いバックアップも削除します
+// ja: ctx がキャンセルされた場合、作成途中のアーカイブ（.partial）は削除されます
+// en: Backup archives the backup_paths and dumps of a project and records it in the metadata
+// en: When Retention is set, old backups beyond it are removed as well
+// en: When ctx is cancelled, the archive being written (.partial) is removed
+func Backup(ctx context.Context, project Project, opts Options) (*BackupResult, error) {
+	// ja: 計画を立てて対象を確認し、進捗表示用の合計サイズも得る
+	// en: Plan first to check the targets and to get the total size for progress reporting
+	plan, err := PlanBackup(project, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// ja: バックアップディレクトリを作成
+	// en: Create backup directory
+	backupDir := plan.BackupDir
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return nil, fmt.Errorf(i18n.T("backup.createDirError"), err)
+	}
+
+	// ja: バックアップアーカイブを作成
+	// en: Create backup archive
+	timestamp := time.Now()
+	// ja: マイクロ秒を含めることで、同一秒内の複数実行でもファイル名の衝突を防ぐ
+	// en: Include microseconds to prevent filename collisions when multiple runs occur within the same second
+	archiveFilename := fmt.Sprintf("backup_%s.tar.gz", timestamp.Format("20060102_150405.000000"))
+	archivePath := filepath.Join(backupDir, archiveFilename)
+
+	opts.emit(Event{Kind: EventArchiveStarted, Path: archivePath})
+
+	// ja: 書き込み中は .partial に書き、完成してから名前を変える
+	// ja: 中断やエラーの場合は不完全なアーカイブを残さない
+	// en: Write to a .partial file and rename it only once it is complete
+	// en: On interruption or error, do not leave an incomplete archive behind
+	// ja: ArchiveModeFull ではディレクトリを削除できるよう、名前を変える前にアーカイブを読み直して確認する
+	// en: With ArchiveModeFull, read the archive back and check it before the rename, so the directory can be removed
+	partialPath := archivePath + PartialSuffix
+	contents, err := createArchive(ctx, partialPath, project, timestamp, plan.Size, opts)
+	verified := false
+	if err == nil && project.ArchiveMode == ArchiveModeFull {
+		opts.emit(Event{Kind: EventArchiveVerifying, Path: archivePath})
+		err = verifyArchive(ctx, partialPath)
+		verified = err == nil
+	}
+	if err == nil {
+		err = os.Rename(partialPath, archivePath)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+		return nil, fmt.Errorf(i18n.T("backup.archiveError"), err)
+	}
+
+	// ja: メタデータファイルを更新
+	// en: Update metadata file
+	opts.emit(Event{Kind: EventMetadataUpdating})
+	record := BackupRecord{
+		Filename:  archiveFilename,
+		Timestamp: timestamp,
+		Files:     contents.Paths,
+		Dumps:     contents.Dumps,
+		Git:       project.Git,
+		GitWork:   contents.GitWork,
+
+		ArchiveMode: fullArchiveMode(project),
+	}
+	if err := addRecord(backupDir, project.Name, record); err != nil {
+		return nil, fmt.Errorf(i18n.T("backup.metadataError"), err)
+	}
+
+	result := &BackupResult{
+		Project: project.Name,
+		Archive: archivePath,
+		SHA256:  contents.SHA256,
+		Files:   contents.Files,
+		Skipped: contents.Skipped,
+		Dumps:   contents.Dumps,
+		GitWork: contents.GitWork,
+
+		Unsupported: contents.Unsupported,
+		Verified:    verified,
+		Record:      record,
+	}
+	if info, err := os.Stat(archivePath); err == nil {
+		result.Size = info.Size()
+	}
+
+	// ja: 保持件数に基づいて古いバックアップを削除（失敗してもバックアップ自体は成功）
+	// en: Prune old backups based on the retention (the backup itself succeeded even if this fails)
+	if project.Retention > 0 {
+		opts.emit(Event{Kind: EventPruneStarted, Count: project.Retention})
+		result.Pruned, err = Prune(ctx, project, project.Retention, opts)
+		if err != nil {
+			opts.emit(Event{Kind: EventPruneFailed, Err: err})
+		}
+	}
+
+	return result, nil
+}
+
+// ja: archiveEntry はアーカイブに追加するファイルと、マニフェストに記録するその内容です
+// en: archiveEntry is a file to be added to the archive along with what the manifest records about it
+type archiveEntry struct {
+	fullPath string
+	name     string
+	file     ManifestFile
+}
+
+// ja: createArchive はバックアップアーカイブを作成し、その内容を返します
+// ja: マニフェストを先頭に置くため、書き込む前にファイルのハッシュを計算し、ダンプを一時ファイルに書き出しておきます
+// en: createArchive creates a backup archive and returns what it contains
+// en: The manifest goes first, so files are hashed and dumps are spooled to temp files before anything is written
+func createArchive(ctx context.Context, archivePath string, project Project, timestamp time.Time, total int64, opts Options) (*archiveContents, error) {
+	contents := &archiveContents{}
+	manifest := newManifest(project, timestamp, opts)
+
+	// ja: ArchiveModeFull では backup_paths の代わりに Dir 全体を集める
+	// en: With ArchiveModeFull, gather the whole Dir in place of the backup_paths
+	var entries, dirs []archiveEntry
+	var links []ManifestLink
+	backupPaths := project.BackupPaths
+	if project.ArchiveMode == ArchiveModeFull {
+		tree, err := collectTree(ctx, project)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range tree.Skipped {
+			opts.emit(Event{Kind: EventFileUnsupported, Path: name})
+		}
+		contents.Paths = []string{fullArchivePath}
+		contents.Unsupported = tree.Skipped
+		entries, dirs, links = tree.Files, tree.Dirs, tree.Links
+		backupPaths = nil
+	}
+
+	// ja: 各バックアップ対象パスを処理
+	// en: Process each backup path
+	for _, backupPath := range backupPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fullPath := filepath.Join(project.Dir, backupPath)
+
+		// ja: ファイルまたはディレクトリが存在するかチェック
+		// en: Check if file or directory exists
+		info, err := os.Stat(fullPath)
+		if os.IsNotExist(err) {
+			opts.emit(Event{Kind: EventFileMissing, Path: backupPath})
+			contents.Skipped = append(contents.Skipped, backupPath)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := collectEntries(ctx, fullPath, backupPath, info)
+		if err != nil {
+			return nil, err
+		}
+
+		contents.Paths = append(contents.Paths, backupPath)
+		entries = append(entries, found...)
+	}
+
+	// ja: 各データベースダンプを実行して一時ファイルに書き出す
+	// en: Run each database dump into a temp file
+	var dumps []archiveEntry
+	defer func() {
+		for _, dump := range dumps {
+			os.Remove(dump.fullPath)
+		}
+	}()
+	for _, dump := range project.Dumps {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		opts.emit(Event{Kind: EventDumpStarted, Path: dump.Name, Provider: dump.Provider})
+		spooled, err := spoolDump(ctx, dump, opts.runner())
+		if err != nil {
+			return nil, err
+		}
+		dumps = append(dumps, spooled)
+		contents.Dumps = append(contents.Dumps, dump.Name)
+	}
+
+	// ja: include_git_state の場合は、backup_paths が扱わない git の作業も書き出す
+	// en: With include_git_state, also write out the git work that backup_paths does not cover
+	var gitWork []archiveEntry
+	if project.IncludeGitState {
+		opts.emit(Event{Kind: EventGitStateSaving, Path: project.Dir})
+		captured, err := captureGitWork(ctx, project.Dir, project.BackupPaths, opts.runner())
+		if err != nil {
+			return nil, err
+		}
+		defer captured.remove()
+		gitWork = append(append(gitWork, captured.spooled...), captured.untracked...)
+		contents.GitWork = &captured.work
+	}
+
+	for _, entry := range entries {
+		manifest.Files = append(manifest.Files, entry.file)
+	}
+	for _, dump := range dumps {
+		manifest.Dumps = append(manifest.Dumps, dump.file)
+	}
+	for _, entry := range gitWork {
+		manifest.GitWork = append(manifest.GitWork, entry.file)
+	}
+	manifest.Links = links
+
+	// ja: アーカイブファイルを作成
+	// en: Create archive file
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archiveFile.Close()
+
+	// ja: gzip ライターと tar ライターを作成し、書き込むバイト列からアーカイブ全体のハッシュも計算する
+	// en: Create gzip and tar writers, hashing the whole archive from the bytes being written
+	archiveHash := sha256.New()
+	gzipWriter := gzip.NewWriter(io.MultiWriter(archiveFile, archiveHash))
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := writeManifest(tarWriter, manifest); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if err := addDirToArchive(tarWriter, dir.fullPath, dir.name); err != nil {
+			return nil, err
+		}
+	}
+
+	counter := &byteCounter{opts: opts, total: total}
+	for _, entry := range entries {
+		if err := addFileToArchive(ctx, tarWriter, entry.fullPath, entry.name, entry.file.SHA256, counter); err != nil {
+			return nil, err
+		}
+		opts.emit(Event{Kind: EventFileAdded, Path: entry.name, Size: entry.file.Size})
+		contents.Files = append(contents.Files, ArchivedFile{Path: entry.file.Path, Size: entry.file.Size})
+	}
+
+	// ja: シンボリックリンクはファイルの後に置き、展開時にリンクを辿ってファイルを書き込まないようにする
+	// en: Symlinks go after the files, so that extracting never writes a file through one
+	for _, link := range links {
+		if err := addLinkToArchive(tarWriter, project.Dir, link); err != nil {
+			return nil, err
+		}
+	}
+
+	// ja: ダンプのサイズは事前に分からないため、進捗のバイト数には含めない
+	// en: The size of a dump is not known up front, so it is left out of the progress byte count
+	for _, dump := range dumps {
+		if err := addFileToArchive(ctx, tarWriter, dump.fullPath, dumpArchivePrefix+dump.name, dump.file.SHA256, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range gitWork {
+		if err := addFileToArchive(ctx, tarWriter, entry.fullPath, entry.name, entry.file.SHA256, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// ja: 書き込みを確定させる（Close の失敗もアーカイブの失敗として扱う）
+	// en: Flush everything (a failing Close means a broken archive too)
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := archiveFile.Close(); err != nil {
+		return nil, err
+	}
+
+	contents.SHA256 = hex.EncodeToString(archiveHash.Sum(nil))
+	return contents, nil
+}
+
+// ja: collectEntries は backup_paths のエントリ（ファイルまたはディレクトリ）に含まれるファイルを集め、ハッシュを計算します
+// en: collectEntries gathers the files of a backup_paths entry (a file or a directory) and hashes them
+func collectEntries(ctx context.Context, fullPath, backupPath string, info os.FileInfo) ([]archiveEntry, error) {
+	if !info.IsDir() {
+		entry, err := describeFile(ctx, fullPath, backupPath, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		return []archiveEntry{entry}, nil
+	}
+
+	var entries []archiveEntry
+	err := filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// ja: ディレクトリ自体はスキップ
+		// en: Skip directories themselves
+		if info.IsDir() {
+			return nil
+		}
+
+		// ja: アーカイブ内のパスを計算
+		// en: Calculate path in archive
+		relPath, err := filepath.Rel(fullPath, path)
+		if err != nil {
+			return err
+		}
+
+		entry, err := describeFile(ctx, path, filepath.Join(backupPath, relPath), info.Size())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// ja: describeFile はファイルのハッシュを計算し、アーカイブのエントリとして返します
+// en: describeFile hashes a file and returns it as an archive entry
+func describeFile(ctx context.Context, fullPath, name string, size int64) (archiveEntry, error) {
+	sum, err := hashFile(ctx, fullPath)
+	if err != nil {
+		return archiveEntry{}, err
+	}
+	return archiveEntry{
+		fullPath: fullPath,
+		name:     name,
+		file:     ManifestFile{Path: filepath.ToSlash(name), Size: size, SHA256: sum},
+	}, nil
+}
+
+// ja: addFileToArchive はファイルをアーカイブに追加します
+// ja: sum が空でなければ、書き込んだ内容の SHA-256 と照合します（ハッシュの計算後に変更されたファイルを検出するため）
+// en: addFileToArchive adds a file to the archive
+// en: When sum is set, the SHA-256 of what was written is checked against it (to catch files changed after they were hashed)
+func addFileToArchive(ctx context.Context, tarWriter *tar.Writer, fullPath, archivePath, sum string, counter *byteCounter) error {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// ja: tar アーカイブではパスを POSIX スタイル（スラッシュ）に正規化
+	// en: Normalize path to POSIX style (forward slashes) for tar archive portability
+	header := &tar.Header{
+		Name:    filepath.ToSlash(archivePath),
+		Size:    info.Size(),
+		Mode:    int64(info.Mode()),
+		ModTime: info.ModTime(),
+	}
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	reader := io.TeeReader(contextReader{ctx: ctx, r: progressReader{r: file, counter: counter}}, hash)
+	if _, err := io.Copy(tarWriter, reader); err != nil {
+		return err
+	}
+	if sum != "" && hex.EncodeToString(hash.Sum(nil)) != sum {
+		return fmt.Errorf(i18n.T("backup.fileChanged"), filepath.ToSlash(archivePath))
+	}
+	return nil
+}
+
+// ja: Prune は新しい順に keep 件を残して古いバックアップを削除し、削除したアーカイブ名を返します
+// en: Prune removes old backups, keeping the newest keep of them, and returns the names of the removed archives
+func Prune(ctx context.Context, project Project, keep int, opts Options) ([]string, error) {
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := LoadMetadata(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	candidates := pruneCandidates(metadata.Backups, keep)
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	// ja: 保持件数を超えるバックアップを削除
+	// en: Delete backups exceeding retention count
+	var pruned []string
+	var removeErr error
+	for _, backup := range candidates {
+		if removeErr = ctx.Err(); removeErr != nil {
+			break
+		}
+		archivePath := filepath.Join(backupDir, backup.Filename)
+		if removeErr = os.Remove(archivePath); removeErr != nil && !os.IsNotExist(removeErr) {
+			break
+		}
+		removeErr = nil
+		opts.emit(Event{Kind: EventArchivePruned, Path: backup.Filename})
+		pruned = append(pruned, backup.Filename)
+	}
+
+	// ja: 途中で止まった場合も、削除できたアーカイブの記録だけはメタデータから取り除く
+	// en: Even when stopped part way, drop the records of the archives that were removed
+	metadata.Backups = append(metadata.Backups[:keep], metadata.Backups[keep+len(pruned):]...)
+	if err := SaveMetadata(backupDir, metadata); err != nil {
+		return pruned, err
+	}
+
+	return pruned, removeErr
+}
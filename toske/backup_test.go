@@ -0,0 +1,341 @@
+package toske
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setupProject creates a project directory with the given files and returns the project and options.
+func setupProject(t *testing.T, files map[string]string) (Project, Options) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	projectDir := filepath.Join(tempDir, "work")
+	for name, content := range files {
+		path := filepath.Join(projectDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+
+	project := Project{Name: "demo", Dir: projectDir}
+	opts := Options{BackupRoot: filepath.Join(tempDir, "backups")}
+	return project, opts
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{
+		".env":          "TEST=value",
+		"config/a.conf": "a",
+	})
+	project.BackupPaths = []string{".env", "config", "missing.txt"}
+
+	var events []EventKind
+	var last Event
+	opts.Progress = func(event Event) {
+		if event.Kind == EventBytes {
+			last = event
+			return
+		}
+		events = append(events, event.Kind)
+	}
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	if len(result.Files) != 2 {
+		t.Errorf("Expected 2 archived files, got %+v", result.Files)
+	}
+	if !reflect.DeepEqual(result.Skipped, []string{"missing.txt"}) {
+		t.Errorf("Expected missing.txt to be skipped, got %v", result.Skipped)
+	}
+	if result.Size == 0 {
+		t.Error("Expected archive size to be reported")
+	}
+	if sum, err := hashFile(context.Background(), result.Archive); err != nil || result.SHA256 != sum {
+		t.Errorf("Expected the archive hash %s to be reported, got %q (%v)", sum, result.SHA256, err)
+	}
+	wantEvents := []EventKind{EventArchiveStarted, EventFileMissing, EventFileAdded, EventFileAdded, EventMetadataUpdating}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("Expected events %v, got %v", wantEvents, events)
+	}
+	if last.Size != 11 || last.Total != 11 {
+		t.Errorf("Expected 11 of 11 bytes to be reported, got %d of %d", last.Size, last.Total)
+	}
+
+	records, err := ListBackups(project, opts)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Filename != filepath.Base(result.Archive) {
+		t.Fatalf("Expected the new backup to be listed, got %+v", records)
+	}
+	if !reflect.DeepEqual(records[0].Files, []string{".env", "config"}) {
+		t.Errorf("Expected recorded paths [.env config], got %v", records[0].Files)
+	}
+
+	// ja: ファイルを書き換えてから復元し、元の内容に戻ることを確認
+	// en: Change a file, restore and check that the original content is back
+	if err := os.WriteFile(filepath.Join(project.Dir, ".env"), []byte("CHANGED"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+
+	restored, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if info, err := os.Stat(result.Archive); err != nil || last.Total != info.Size() {
+		t.Errorf("Expected the archive size as the restore total, got %d", last.Total)
+	}
+	if len(restored.Files) != 2 {
+		t.Errorf("Expected 2 restored files, got %v", restored.Files)
+	}
+	data, err := os.ReadFile(filepath.Join(project.Dir, ".env"))
+	if err != nil {
+		t.Fatalf("Failed to read restored file: %v", err)
+	}
+	if string(data) != "TEST=value" {
+		t.Errorf("Expected restored content 'TEST=value', got %q", string(data))
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	project, opts := setupProject(t, nil)
+
+	tests := []struct {
+		name  string
+		run   func() error
+		check func(error) bool
+	}{
+		{
+			name: "nothing to back up",
+			run: func() error {
+				_, err := Backup(context.Background(), project, opts)
+				return err
+			},
+			check: func(err error) bool {
+				var target *NothingToBackupError
+				return errors.As(err, &target) && target.Project == "demo"
+			},
+		},
+		{
+			name: "missing project directory",
+			run: func() error {
+				missing := project
+				missing.Dir = filepath.Join(project.Dir, "missing")
+				missing.BackupPaths = []string{".env"}
+				_, err := Backup(context.Background(), missing, opts)
+				return err
+			},
+			check: func(err error) bool {
+				var target *ProjectDirNotFoundError
+				return errors.As(err, &target)
+			},
+		},
+		{
+			name: "no backup directory",
+			run: func() error {
+				_, err := ListBackups(project, opts)
+				return err
+			},
+			check: func(err error) bool {
+				var target *NoBackupsError
+				return errors.As(err, &target) && target.DirMissing
+			},
+		},
+		{
+			name: "restore without backups",
+			run: func() error {
+				_, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+				return err
+			},
+			check: func(err error) bool {
+				var target *NoBackupsError
+				return errors.As(err, &target)
+			},
+		},
+		{
+			name: "invalid backup index",
+			run: func() error {
+				_, err := SelectBackup([]BackupRecord{{Filename: "a"}}, 2)
+				return err
+			},
+			check: func(err error) bool {
+				var target *InvalidBackupIndexError
+				return errors.As(err, &target) && target.Index == 2 && target.Count == 1
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatal("Expected error but got nil")
+			}
+			if !tt.check(err) {
+				t.Errorf("Unexpected error type %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestRestoreMissingArchive(t *testing.T) {
+	project, opts := setupProject(t, nil)
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		t.Fatalf("BackupDir failed: %v", err)
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatalf("Failed to create backup directory: %v", err)
+	}
+	metadata := BackupMetadata{Project: project.Name, Backups: []BackupRecord{{Filename: "backup_gone.tar.gz", Timestamp: time.Now()}}}
+	if err := SaveMetadata(backupDir, metadata); err != nil {
+		t.Fatalf("SaveMetadata failed: %v", err)
+	}
+
+	_, err = Restore(context.Background(), project, RestoreOptions{Options: opts})
+	var target *ArchiveNotFoundError
+	if !errors.As(err, &target) || target.Filename != "backup_gone.tar.gz" {
+		t.Errorf("Expected ArchiveNotFoundError, got %v", err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	project, opts := setupProject(t, nil)
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		t.Fatalf("BackupDir failed: %v", err)
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatalf("Failed to create backup directory: %v", err)
+	}
+
+	now := time.Now()
+	metadata := BackupMetadata{Project: project.Name}
+	for i, name := range []string{"backup_3.tar.gz", "backup_2.tar.gz", "backup_1.tar.gz"} {
+		if err := os.WriteFile(filepath.Join(backupDir, name), []byte("archive"), 0644); err != nil {
+			t.Fatalf("Failed to create archive: %v", err)
+		}
+		metadata.Backups = append(metadata.Backups, BackupRecord{Filename: name, Timestamp: now.Add(-time.Duration(i) * time.Hour)})
+	}
+	if err := SaveMetadata(backupDir, metadata); err != nil {
+		t.Fatalf("SaveMetadata failed: %v", err)
+	}
+
+	pruned, err := Prune(context.Background(), project, 1, opts)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if !reflect.DeepEqual(pruned, []string{"backup_2.tar.gz", "backup_1.tar.gz"}) {
+		t.Errorf("Expected the two oldest backups to be pruned, got %v", pruned)
+	}
+
+	records, err := ListBackups(project, opts)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(records) != 1 || records[0].Filename != "backup_3.tar.gz" {
+		t.Errorf("Expected only the newest backup to remain, got %+v", records)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, "backup_1.tar.gz")); !os.IsNotExist(err) {
+		t.Error("Expected pruned archive to be removed")
+	}
+}
+
+func TestBackupCancelled(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{".env": "TEST=value", "db.sqlite3": "data"})
+	project.BackupPaths = []string{".env", "db.sqlite3"}
+
+	// ja: 最初のファイルを追加したところで中断する
+	// en: Interrupt right after the first file is added
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts.Progress = func(event Event) {
+		if event.Kind == EventFileAdded {
+			cancel()
+		}
+	}
+
+	_, err := Backup(ctx, project, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		t.Fatalf("BackupDir failed: %v", err)
+	}
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		t.Fatalf("Failed to read backup directory: %v", err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Expected no archive or metadata to be left behind, found %s", entry.Name())
+	}
+}
+
+func TestRestoreCancelled(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{"a.txt": "a1", "b.txt": "b1", "c.txt": "c1"})
+	project.BackupPaths = []string{"a.txt", "b.txt", "c.txt"}
+
+	if _, err := Backup(context.Background(), project, opts); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	for _, name := range project.BackupPaths {
+		if err := os.WriteFile(filepath.Join(project.Dir, name), []byte("changed"), 0644); err != nil {
+			t.Fatalf("Failed to modify %s: %v", name, err)
+		}
+	}
+
+	// ja: 2 つ目のファイルの展開を始めたところで中断する
+	// en: Interrupt as the second file starts being extracted
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts.Progress = func(event Event) {
+		if event.Kind == EventFileExtracting && event.Path == "b.txt" {
+			cancel()
+		}
+	}
+
+	result, err := Restore(ctx, project, RestoreOptions{Options: opts})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if result == nil || !reflect.DeepEqual(result.Files, []string{"a.txt"}) {
+		t.Fatalf("Expected exactly a.txt to be reported as written, got %+v", result)
+	}
+
+	want := map[string]string{"a.txt": "a1", "b.txt": "changed", "c.txt": "changed"}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(project.Dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, string(data))
+		}
+	}
+
+	// ja: 書き込み途中の一時ファイルが残らないこと
+	// en: No temp files from the interrupted write may be left behind
+	matches, _ := filepath.Glob(filepath.Join(project.Dir, restoreTempPattern))
+	if len(matches) != 0 {
+		t.Errorf("Expected temp files to be removed, found %v", matches)
+	}
+}
@@ -0,0 +1,305 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: toskeArchivePrefix はアーカイブ内で toske が予約しているディレクトリです
+// en: toskeArchivePrefix is the directory inside the archive reserved for toske itself
+const toskeArchivePrefix = ".toske/"
+
+// ja: dumpArchivePrefix はアーカイブ内でダンプを格納するディレクトリです
+// en: dumpArchivePrefix is the directory inside the archive that holds dumps
+const dumpArchivePrefix = toskeArchivePrefix + "dumps/"
+
+// ja: Dump はバックアップ時に取得するデータベースダンプの設定を表します
+// en: Dump represents a database dump taken during backup
+type Dump struct {
+	// ja: Name はアーカイブ内での出力名です
+	// en: Name is the output name inside the archive
+	Name     string `mapstructure:"name" yaml:"name" json:"name"`
+	Provider string `mapstructure:"provider" yaml:"provider" json:"provider"`
+	Host     string `mapstructure:"host" yaml:"host,omitempty" json:"host,omitempty"`
+	Port     int    `mapstructure:"port" yaml:"port,omitempty" json:"port,omitempty"`
+	User     string `mapstructure:"user" yaml:"user,omitempty" json:"user,omitempty"`
+	// ja: PasswordEnv はパスワードを保持する環境変数名です（パスワード自体は設定ファイルに書きません）
+	// en: PasswordEnv names the environment variable holding the password (the password itself is never stored in the config)
+	PasswordEnv string `mapstructure:"password_env" yaml:"password_env,omitempty" json:"password_env,omitempty"`
+	Database    string `mapstructure:"database" yaml:"database,omitempty" json:"database,omitempty"`
+	// ja: Container が指定された場合、ダンプコマンドを docker exec 経由でコンテナ内で実行します
+	// en: When Container is set, dump commands run inside that container via docker exec
+	Container      string `mapstructure:"container" yaml:"container,omitempty" json:"container,omitempty"`
+	Command        string `mapstructure:"command" yaml:"command,omitempty" json:"command,omitempty"`
+	RestoreCommand string `mapstructure:"restore_command" yaml:"restore_command,omitempty" json:"restore_command,omitempty"`
+}
+
+// ja: ダンププロバイダー名
+// en: Dump provider names
+const (
+	DumpProviderPostgres = "postgres"
+	DumpProviderMySQL    = "mysql"
+	DumpProviderCommand  = "command"
+)
+
+// ja: dumpProvider はデータベースのダンプと復元を行います
+// en: dumpProvider dumps and restores a database
+type dumpProvider interface {
+	Dump(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// ja: newDumpProvider はダンプ設定に対応するプロバイダーを返します
+// en: newDumpProvider returns the provider for a dump configuration
+func newDumpProvider(dump Dump, runner Runner) (dumpProvider, error) {
+	switch dump.Provider {
+	case DumpProviderPostgres:
+		return &postgresDumpProvider{dump: dump, runner: runner}, nil
+	case DumpProviderMySQL:
+		return &mysqlDumpProvider{dump: dump, runner: runner}, nil
+	case DumpProviderCommand:
+		return &commandDumpProvider{dump: dump, runner: runner}, nil
+	default:
+		return nil, fmt.Errorf(i18n.T("dump.unknownProvider"), dump.Provider)
+	}
+}
+
+// ja: postgresDumpProvider は pg_dump / psql を使用します
+// en: postgresDumpProvider uses pg_dump / psql
+type postgresDumpProvider struct {
+	dump   Dump
+	runner Runner
+}
+
+func (p *postgresDumpProvider) Dump(w io.Writer) error {
+	args := append(p.connectionArgs(), "--no-owner", "--clean", "--if-exists")
+	return p.runner.Run(containerCommand(p.dump, "pg_dump", args, p.passwordEnv(), nil, w))
+}
+
+func (p *postgresDumpProvider) Restore(r io.Reader) error {
+	args := append(p.connectionArgs(), "--quiet", "--set", "ON_ERROR_STOP=1")
+	return p.runner.Run(containerCommand(p.dump, "psql", args, p.passwordEnv(), r, io.Discard))
+}
+
+func (p *postgresDumpProvider) connectionArgs() []string {
+	var args []string
+	if p.dump.Host != "" {
+		args = append(args, "--host", p.dump.Host)
+	}
+	if p.dump.Port > 0 {
+		args = append(args, "--port", strconv.Itoa(p.dump.Port))
+	}
+	if p.dump.User != "" {
+		args = append(args, "--username", p.dump.User)
+	}
+	return append(args, "--dbname", p.dump.Database)
+}
+
+func (p *postgresDumpProvider) passwordEnv() []string {
+	return passwordEnv(p.dump, "PGPASSWORD")
+}
+
+// ja: mysqlDumpProvider は mysqldump / mysql を使用します
+// en: mysqlDumpProvider uses mysqldump / mysql
+type mysqlDumpProvider struct {
+	dump   Dump
+	runner Runner
+}
+
+func (p *mysqlDumpProvider) Dump(w io.Writer) error {
+	args := append(p.connectionArgs(), "--single-transaction", "--routines", p.dump.Database)
+	return p.runner.Run(containerCommand(p.dump, "mysqldump", args, p.passwordEnv(), nil, w))
+}
+
+func (p *mysqlDumpProvider) Restore(r io.Reader) error {
+	args := append(p.connectionArgs(), p.dump.Database)
+	return p.runner.Run(containerCommand(p.dump, "mysql", args, p.passwordEnv(), r, io.Discard))
+}
+
+func (p *mysqlDumpProvider) connectionArgs() []string {
+	var args []string
+	if p.dump.Host != "" {
+		args = append(args, "--host="+p.dump.Host)
+	}
+	if p.dump.Port > 0 {
+		args = append(args, "--port="+strconv.Itoa(p.dump.Port))
+	}
+	if p.dump.User != "" {
+		args = append(args, "--user="+p.dump.User)
+	}
+	return args
+}
+
+func (p *mysqlDumpProvider) passwordEnv() []string {
+	return passwordEnv(p.dump, "MYSQL_PWD")
+}
+
+// ja: commandDumpProvider は任意のシェルコマンドを使用します
+// en: commandDumpProvider uses arbitrary shell commands
+type commandDumpProvider struct {
+	dump   Dump
+	runner Runner
+}
+
+func (p *commandDumpProvider) Dump(w io.Writer) error {
+	name, args := shellCommand(p.dump.Command)
+	return p.runner.Run(containerCommand(p.dump, name, args, nil, nil, w))
+}
+
+func (p *commandDumpProvider) Restore(r io.Reader) error {
+	if p.dump.RestoreCommand == "" {
+		return fmt.Errorf(i18n.T("dump.noRestoreCommand"), p.dump.Name)
+	}
+	name, args := shellCommand(p.dump.RestoreCommand)
+	return p.runner.Run(containerCommand(p.dump, name, args, nil, r, io.Discard))
+}
+
+// ja: shellCommand はコマンド文字列をシェル経由で実行するための引数を返します
+// en: shellCommand returns the arguments to run a command string through the shell
+func shellCommand(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
+
+// ja: passwordEnv は password_env で指定された環境変数の値を、ツールが読む変数名で渡します
+// en: passwordEnv forwards the value of password_env under the variable name the tool reads
+func passwordEnv(dump Dump, name string) []string {
+	if dump.PasswordEnv == "" {
+		return nil
+	}
+	return []string{name + "=" + os.Getenv(dump.PasswordEnv)}
+}
+
+// ja: containerCommand はコンテナが指定されていれば docker exec でコマンドをラップします
+// en: containerCommand wraps the command in docker exec when a container is configured
+func containerCommand(dump Dump, name string, args, env []string, stdin io.Reader, stdout io.Writer) Command {
+	if dump.Container == "" {
+		return Command{Name: name, Args: args, Env: env, Stdin: stdin, Stdout: stdout}
+	}
+
+	dockerArgs := []string{"exec"}
+	if stdin != nil {
+		dockerArgs = append(dockerArgs, "-i")
+	}
+	for _, kv := range env {
+		// ja: 値はコマンドライン引数に載せず、docker に環境変数から引き継がせる
+		// en: Keep the value off the command line and let docker inherit it from the environment
+		dockerArgs = append(dockerArgs, "-e", strings.SplitN(kv, "=", 2)[0])
+	}
+	dockerArgs = append(dockerArgs, dump.Container, name)
+	dockerArgs = append(dockerArgs, args...)
+
+	return Command{Name: "docker", Args: dockerArgs, Env: env, Stdin: stdin, Stdout: stdout}
+}
+
+// ja: spoolDump はダンプを実行し、その出力を一時ファイルに書き出します（削除は呼び出し側が行います）
+// ja: tar ヘッダーにはサイズが、マニフェストにはハッシュが事前に必要なため、出力は一時ファイルを経由します
+// en: spoolDump runs a dump and writes its output to a temp file (the caller removes it)
+// en: tar headers need the size and the manifest the hash up front, so the output goes through a temp file
+func spoolDump(ctx context.Context, dump Dump, runner Runner) (archiveEntry, error) {
+	provider, err := newDumpProvider(dump, contextRunner{ctx: ctx, runner: runner})
+	if err != nil {
+		return archiveEntry{}, err
+	}
+
+	tmpFile, err := os.CreateTemp("", "toske-dump-*")
+	if err != nil {
+		return archiveEntry{}, err
+	}
+	defer tmpFile.Close()
+
+	if err := provider.Dump(tmpFile); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return archiveEntry{}, fmt.Errorf(i18n.T("dump.dumpError"), dump.Name, err)
+	}
+	info, err := tmpFile.Stat()
+	if err == nil {
+		err = tmpFile.Close()
+	}
+	var entry archiveEntry
+	if err == nil {
+		entry, err = describeFile(ctx, tmpFile.Name(), dump.Name, info.Size())
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return archiveEntry{}, err
+	}
+	return entry, nil
+}
+
+// ja: restoreDumps はアーカイブ内のダンプを対応するプロバイダーに流し込みます
+// en: restoreDumps feeds the dumps in the archive back into their providers
+func restoreDumps(ctx context.Context, archivePath string, dumps []Dump, opts Options) (int, error) {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return 0, fmt.Errorf(i18n.T("restore.openArchiveError"), err)
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+
+	byName := make(map[string]Dump, len(dumps))
+	for _, dump := range dumps {
+		byName[dump.Name] = dump
+	}
+
+	restored := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return restored, err
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return restored, err
+		}
+
+		if !strings.HasPrefix(header.Name, dumpArchivePrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(header.Name, dumpArchivePrefix)
+
+		// ja: 設定にないダンプはプロバイダーが分からないためスキップ
+		// en: Skip dumps that are not configured, since their provider is unknown
+		dump, ok := byName[name]
+		if !ok {
+			opts.emit(Event{Kind: EventDumpSkipped, Path: name})
+			continue
+		}
+
+		provider, err := newDumpProvider(dump, contextRunner{ctx: ctx, runner: opts.runner()})
+		if err != nil {
+			return restored, err
+		}
+
+		opts.emit(Event{Kind: EventDumpRestoring, Path: dump.Name, Provider: dump.Provider})
+		if err := provider.Restore(tarReader); err != nil {
+			return restored, fmt.Errorf(i18n.T("dump.restoreError"), dump.Name, err)
+		}
+		restored++
+	}
+
+	return restored, nil
+}
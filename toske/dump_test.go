@@ -0,0 +1,147 @@
+package toske
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records command invocations and plays back canned output.
+type fakeRunner struct {
+	calls  []Command
+	stdins []string
+	output string
+	err    error
+}
+
+func (f *fakeRunner) Run(c Command) error {
+	f.calls = append(f.calls, c)
+	if c.Stdin != nil {
+		data, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return err
+		}
+		f.stdins = append(f.stdins, string(data))
+	}
+	if c.Stdout != nil && f.output != "" {
+		if _, err := io.WriteString(c.Stdout, f.output); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func TestDumpProviderCommands(t *testing.T) {
+	t.Setenv("TEST_DB_PASSWORD", "secret")
+
+	tests := []struct {
+		name        string
+		dump        Dump
+		restore     bool
+		wantName    string
+		wantArgs    []string
+		wantEnv     []string
+		expectError bool
+	}{
+		{
+			name:     "postgres dump",
+			dump:     Dump{Name: "db.sql", Provider: "postgres", Host: "localhost", Port: 5432, User: "app", PasswordEnv: "TEST_DB_PASSWORD", Database: "app_dev"},
+			wantName: "pg_dump",
+			wantArgs: []string{"--host", "localhost", "--port", "5432", "--username", "app", "--dbname", "app_dev", "--no-owner", "--clean", "--if-exists"},
+			wantEnv:  []string{"PGPASSWORD=secret"},
+		},
+		{
+			name:     "postgres restore",
+			dump:     Dump{Name: "db.sql", Provider: "postgres", Database: "app_dev"},
+			restore:  true,
+			wantName: "psql",
+			wantArgs: []string{"--dbname", "app_dev", "--quiet", "--set", "ON_ERROR_STOP=1"},
+		},
+		{
+			name:     "mysql dump",
+			dump:     Dump{Name: "db.sql", Provider: "mysql", Host: "127.0.0.1", Port: 3306, User: "root", PasswordEnv: "TEST_DB_PASSWORD", Database: "app"},
+			wantName: "mysqldump",
+			wantArgs: []string{"--host=127.0.0.1", "--port=3306", "--user=root", "--single-transaction", "--routines", "app"},
+			wantEnv:  []string{"MYSQL_PWD=secret"},
+		},
+		{
+			name:     "mysql restore",
+			dump:     Dump{Name: "db.sql", Provider: "mysql", Database: "app"},
+			restore:  true,
+			wantName: "mysql",
+			wantArgs: []string{"app"},
+		},
+		{
+			name:     "postgres in container",
+			dump:     Dump{Name: "db.sql", Provider: "postgres", User: "postgres", PasswordEnv: "TEST_DB_PASSWORD", Database: "app", Container: "app-db-1"},
+			restore:  true,
+			wantName: "docker",
+			wantArgs: []string{"exec", "-i", "-e", "PGPASSWORD", "app-db-1", "psql", "--username", "postgres", "--dbname", "app", "--quiet", "--set", "ON_ERROR_STOP=1"},
+			wantEnv:  []string{"PGPASSWORD=secret"},
+		},
+		{
+			name:     "custom command",
+			dump:     Dump{Name: "data.json", Provider: "command", Command: "cat data.json"},
+			wantName: "sh",
+			wantArgs: []string{"-c", "cat data.json"},
+		},
+		{
+			name:        "custom command without restore_command",
+			dump:        Dump{Name: "data.json", Provider: "command", Command: "cat data.json"},
+			restore:     true,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantName == "sh" && filepath.Separator == '\\' {
+				t.Skip("shell commands use cmd /C on Windows")
+			}
+
+			runner := &fakeRunner{}
+			provider, err := newDumpProvider(tt.dump, runner)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tt.restore {
+				err = provider.Restore(strings.NewReader("-- dump"))
+			} else {
+				err = provider.Dump(io.Discard)
+			}
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(runner.calls) != 1 {
+				t.Fatalf("Expected 1 command, got %d", len(runner.calls))
+			}
+			call := runner.calls[0]
+			if call.Name != tt.wantName {
+				t.Errorf("Expected command %q, got %q", tt.wantName, call.Name)
+			}
+			if !reflect.DeepEqual(call.Args, tt.wantArgs) {
+				t.Errorf("Expected args %v, got %v", tt.wantArgs, call.Args)
+			}
+			if !reflect.DeepEqual(call.Env, tt.wantEnv) {
+				t.Errorf("Expected env %v, got %v", tt.wantEnv, call.Env)
+			}
+		})
+	}
+}
+
+func TestNewDumpProviderUnknown(t *testing.T) {
+	if _, err := newDumpProvider(Dump{Name: "x", Provider: "oracle"}, &fakeRunner{}); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
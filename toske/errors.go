@@ -0,0 +1,99 @@
+package toske
+
+import (
+	"fmt"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: ProjectNotFoundError は指定された名前のプロジェクトが見つからないことを表します
+// en: ProjectNotFoundError reports that no project has the given name
+type ProjectNotFoundError struct {
+	Name string
+}
+
+func (e *ProjectNotFoundError) Error() string {
+	return fmt.Sprintf(i18n.T("config.projectNotFound"), e.Name)
+}
+
+// ja: NothingToBackupError はプロジェクトに backup_paths もダンプも設定されていないことを表します
+// en: NothingToBackupError reports that a project has neither backup_paths nor dumps
+type NothingToBackupError struct {
+	Project string
+}
+
+func (e *NothingToBackupError) Error() string {
+	return fmt.Sprintf(i18n.T("backup.noBackupPaths"), e.Project)
+}
+
+// ja: ProjectDirNotFoundError はプロジェクトのディレクトリが存在しないことを表します
+// en: ProjectDirNotFoundError reports that the project directory does not exist
+type ProjectDirNotFoundError struct {
+	Dir string
+}
+
+func (e *ProjectDirNotFoundError) Error() string {
+	return fmt.Sprintf(i18n.T("backup.noProjectDir"), e.Dir)
+}
+
+// ja: NoBackupsError はプロジェクトのバックアップが 1 つもないことを表します
+// ja: DirMissing と MetadataMissing で、バックアップディレクトリやメタデータ自体がないのかを区別できます
+// en: NoBackupsError reports that a project has no backups
+// en: DirMissing and MetadataMissing tell whether the backup directory or the metadata itself is absent
+type NoBackupsError struct {
+	Project         string
+	DirMissing      bool
+	MetadataMissing bool
+}
+
+func (e *NoBackupsError) Error() string {
+	switch {
+	case e.DirMissing:
+		return fmt.Sprintf(i18n.T("restore.noBackupDir"), e.Project)
+	case e.MetadataMissing:
+		return fmt.Sprintf(i18n.T("restore.noMetadata"), e.Project)
+	default:
+		return fmt.Sprintf(i18n.T("restore.noBackups"), e.Project)
+	}
+}
+
+// ja: InvalidBackupIndexError はバックアップ番号が範囲外であることを表します
+// en: InvalidBackupIndexError reports that a backup number is out of range
+type InvalidBackupIndexError struct {
+	Index int
+	Count int
+}
+
+func (e *InvalidBackupIndexError) Error() string {
+	return fmt.Sprintf(i18n.T("restore.invalidBackupIndex"), e.Index, e.Count)
+}
+
+// ja: ArchiveNotFoundError はメタデータに記録されたアーカイブファイルが存在しないことを表します
+// en: ArchiveNotFoundError reports that an archive recorded in the metadata does not exist
+type ArchiveNotFoundError struct {
+	Filename string
+}
+
+func (e *ArchiveNotFoundError) Error() string {
+	return fmt.Sprintf(i18n.T("restore.backupNotFound"), e.Filename)
+}
+
+// ja: UnsupportedManifestError はアーカイブのマニフェストがこのバージョンより新しい形式であることを表します
+// en: UnsupportedManifestError reports that an archive's manifest is newer than this version understands
+type UnsupportedManifestError struct {
+	Format int
+}
+
+func (e *UnsupportedManifestError) Error() string {
+	return fmt.Sprintf(i18n.T("manifest.unsupportedFormat"), e.Format, ManifestFormat)
+}
+
+// ja: ChecksumMismatchError はファイルの内容がマニフェストの SHA-256 と一致しないことを表します
+// en: ChecksumMismatchError reports that a file's content does not match the SHA-256 in the manifest
+type ChecksumMismatchError struct {
+	Path string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf(i18n.T("manifest.checksumMismatch"), e.Path)
+}
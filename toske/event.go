@@ -0,0 +1,119 @@
+package toske
+
+import "io"
+
+// ja: EventKind は進捗イベントの種類です
+// en: EventKind is the kind of a progress event
+type EventKind string
+
+// ja: 進捗イベントの種類
+// en: Progress event kinds
+const (
+	// ja: EventArchiveStarted はアーカイブの作成開始です（Path: アーカイブのパス）
+	// en: EventArchiveStarted is sent when archive creation starts (Path: the archive path)
+	EventArchiveStarted EventKind = "archive_started"
+	// ja: EventFileAdded はファイルをアーカイブに追加したことを表します（Path, Size）
+	// en: EventFileAdded is sent for each file added to the archive (Path, Size)
+	EventFileAdded EventKind = "file_added"
+	// ja: EventFileMissing は backup_paths のエントリが存在しなかったことを表します（Path）
+	// en: EventFileMissing is sent when a backup_paths entry does not exist (Path)
+	EventFileMissing EventKind = "file_missing"
+	// ja: EventDumpStarted はダンプの実行開始です（Path: ダンプ名, Provider）
+	// en: EventDumpStarted is sent when a dump starts (Path: the dump name, Provider)
+	EventDumpStarted EventKind = "dump_started"
+	// ja: EventMetadataUpdating はメタデータの更新開始です
+	// en: EventMetadataUpdating is sent before the metadata is updated
+	EventMetadataUpdating EventKind = "metadata_updating"
+	// ja: EventPruneStarted は保持件数を超えたバックアップの削除開始です（Count: 保持件数）
+	// en: EventPruneStarted is sent before backups beyond the retention are removed (Count: the retention)
+	EventPruneStarted EventKind = "prune_started"
+	// ja: EventArchivePruned はアーカイブを削除したことを表します（Path: ファイル名）
+	// en: EventArchivePruned is sent for each removed archive (Path: the file name)
+	EventArchivePruned EventKind = "archive_pruned"
+	// ja: EventPruneFailed はバックアップ後の削除に失敗したことを表します（Err）
+	// ja: バックアップ自体は成功しているため、Backup はエラーを返しません
+	// en: EventPruneFailed is sent when pruning after a backup fails (Err)
+	// en: The backup itself succeeded, so Backup does not return an error
+	EventPruneFailed EventKind = "prune_failed"
+	// ja: EventFileExtracting はファイルの展開開始です（Path）
+	// en: EventFileExtracting is sent before a file is extracted (Path)
+	EventFileExtracting EventKind = "file_extracting"
+	// ja: EventFileSkipped はファイルを復元しなかったことを表します（Path, Reason, Err）
+	// en: EventFileSkipped is sent when a file is not restored (Path, Reason, Err)
+	EventFileSkipped EventKind = "file_skipped"
+	// ja: EventChmodFailed はパーミッションの設定に失敗したことを表します（Path, Err）
+	// en: EventChmodFailed is sent when setting permissions fails (Path, Err)
+	EventChmodFailed EventKind = "chmod_failed"
+	// ja: EventDumpRestoring はダンプの復元開始です（Path: ダンプ名, Provider）
+	// en: EventDumpRestoring is sent when a dump restore starts (Path: the dump name, Provider)
+	EventDumpRestoring EventKind = "dump_restoring"
+	// ja: EventDumpSkipped は設定にないダンプをスキップしたことを表します（Path: ダンプ名）
+	// en: EventDumpSkipped is sent when a dump that is not configured is skipped (Path: the dump name)
+	EventDumpSkipped EventKind = "dump_skipped"
+	// ja: EventGitStateSaving は git の作業（ブランチ・stash・変更）の保存開始です（Path: プロジェクトのディレクトリ）
+	// en: EventGitStateSaving is sent before the git work (branches, stashes, changes) is saved (Path: the project directory)
+	EventGitStateSaving EventKind = "git_state_saving"
+	// ja: EventGitStateRestoring は保存した git の作業を戻す前に送られます
+	// en: EventGitStateRestoring is sent before the saved git work is put back
+	EventGitStateRestoring EventKind = "git_state_restoring"
+	// ja: EventBranchSkipped は同じ名前のブランチが別のコミットを指していたため、ブランチを戻さなかったことを表します（Path: ブランチ名）
+	// en: EventBranchSkipped is sent when a branch is not put back because a branch of that name points elsewhere (Path: the branch name)
+	EventBranchSkipped EventKind = "branch_skipped"
+	// ja: EventFileUnsupported は ArchiveModeFull でアーカイブできないファイル（ソケットやデバイスなど）をスキップしたことを表します（Path）
+	// en: EventFileUnsupported is sent when a file ArchiveModeFull cannot archive (a socket, a device and so on) is skipped (Path)
+	EventFileUnsupported EventKind = "file_unsupported"
+	// ja: EventArchiveVerifying は書き込んだアーカイブを読み直して確認する前に送られます（Path: アーカイブのパス）
+	// en: EventArchiveVerifying is sent before the written archive is read back to verify it (Path: the archive path)
+	EventArchiveVerifying EventKind = "archive_verifying"
+	// ja: EventBytes は処理済みのバイト数の更新です（Size: ここまでのバイト数, Total: 全体のバイト数）
+	// ja: バックアップでは backup_paths のファイルを、復元ではアーカイブを読み込んだ量を表します
+	// en: EventBytes updates the number of bytes processed (Size: bytes so far, Total: bytes overall)
+	// en: For a backup it counts the backup_paths files read, for a restore the archive read
+	EventBytes EventKind = "bytes"
+)
+
+// ja: Event は進捗イベントです。どのフィールドが設定されるかは Kind によります
+// en: Event is a progress event. Which fields are set depends on Kind
+type Event struct {
+	Kind     EventKind
+	Path     string
+	Size     int64
+	Total    int64
+	Count    int
+	Provider string
+	Reason   string
+	Err      error
+}
+
+// ja: byteCounter は処理したバイト数を数え、EventBytes として通知します
+// ja: nil の場合は何も数えません
+// en: byteCounter counts the bytes processed and reports them as EventBytes
+// en: A nil counter counts nothing
+type byteCounter struct {
+	opts  Options
+	done  int64
+	total int64
+}
+
+func (c *byteCounter) add(n int) {
+	if c == nil {
+		return
+	}
+	c.done += int64(n)
+	c.opts.emit(Event{Kind: EventBytes, Size: c.done, Total: c.total})
+}
+
+// ja: progressReader は読み込んだバイト数を byteCounter に加算します
+// en: progressReader adds the bytes it reads to a byteCounter
+type progressReader struct {
+	r       io.Reader
+	counter *byteCounter
+}
+
+func (p progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.counter.add(n)
+	}
+	return n, err
+}
@@ -0,0 +1,48 @@
+package toske_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yk-lab/toske/toske"
+)
+
+func ExampleBackup() {
+	project := toske.Project{
+		Name:        "myapp",
+		Dir:         "/home/me/src/myapp",
+		BackupPaths: []string{".env", "db.sqlite3"},
+		Retention:   5,
+	}
+
+	opts := toske.Options{
+		Progress: func(event toske.Event) {
+			if event.Kind == toske.EventFileAdded {
+				fmt.Println("added", event.Path)
+			}
+		},
+	}
+
+	result, err := toske.Backup(context.Background(), project, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("archive:", result.Archive)
+}
+
+func ExampleRestore() {
+	project := toske.Project{Name: "myapp", Dir: "/home/me/src/myapp"}
+
+	result, err := toske.Restore(context.Background(), project, toske.RestoreOptions{Backup: 1})
+	var noBackups *toske.NoBackupsError
+	switch {
+	case errors.As(err, &noBackups):
+		fmt.Println("nothing to restore for", noBackups.Project)
+	case err != nil:
+		log.Fatal(err)
+	default:
+		fmt.Println("restored", len(result.Files), "files")
+	}
+}
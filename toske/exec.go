@@ -0,0 +1,82 @@
+package toske
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// ja: Command は外部コマンドの実行内容を表します
+// ja: Context が設定されている場合、キャンセルされるとコマンドを終了させます
+// en: Command describes an external command invocation
+// en: When Context is set, the command is killed once it is cancelled
+type Command struct {
+	Context context.Context
+	Name    string
+	Args    []string
+	Env     []string
+	Dir     string
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// ja: Runner は外部コマンドを実行します（テストでは偽物に差し替え可能）
+// en: Runner runs external commands (can be swapped for a fake in tests)
+type Runner interface {
+	Run(c Command) error
+}
+
+// ja: ExecRunner は os/exec を使って実際にコマンドを実行します
+// en: ExecRunner runs commands for real using os/exec
+type ExecRunner struct{}
+
+func (ExecRunner) Run(c Command) error {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	command := exec.CommandContext(ctx, c.Name, c.Args...)
+	command.Dir = c.Dir
+	command.Stdin = c.Stdin
+	command.Stdout = c.Stdout
+	command.Stderr = c.Stderr
+	if command.Stderr == nil {
+		command.Stderr = os.Stderr
+	}
+	if len(c.Env) > 0 {
+		command.Env = append(os.Environ(), c.Env...)
+	}
+	return command.Run()
+}
+
+// ja: contextRunner は全てのコマンドに Context を設定してから実行します
+// en: contextRunner sets Context on every command before running it
+type contextRunner struct {
+	ctx    context.Context
+	runner Runner
+}
+
+func (r contextRunner) Run(c Command) error {
+	if c.Context == nil {
+		c.Context = r.ctx
+	}
+	return r.runner.Run(c)
+}
+
+// ja: contextReader はコンテキストがキャンセルされると読み込みを止めます
+// ja: 大きなファイルのコピーを途中で中断できるようにするためのものです
+// en: contextReader stops reading once the context is cancelled
+// en: This lets the copy of a large file be interrupted part way
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
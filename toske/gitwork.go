@@ -0,0 +1,455 @@
+package toske
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: アーカイブ内で git の作業を格納する場所
+// en: Where git work is kept inside the archive
+const (
+	gitArchivePrefix       = toskeArchivePrefix + "git/"
+	gitBundleEntryName     = gitArchivePrefix + "refs.bundle"
+	gitPatchEntryName      = gitArchivePrefix + "worktree.patch"
+	untrackedArchivePrefix = gitArchivePrefix + "untracked/"
+)
+
+// ja: stashRefPrefix は stash を git bundle に含めるために一時的に作る参照の名前空間です
+// ja: bundle は参照の名前でしかコミットを含められず、古い stash は refs/stash の reflog にしかないためです
+// en: stashRefPrefix is the namespace of the refs temporarily created to put stashes into the git bundle
+// en: A bundle can only carry commits by ref name, and older stashes live only in the reflog of refs/stash
+const stashRefPrefix = "refs/toske/stash/"
+
+// ja: GitWorkResult は Restore で戻した git の作業です
+// en: GitWorkResult is the git work put back by Restore
+type GitWorkResult struct {
+	Branches []string `json:"branches" yaml:"branches"`
+	// ja: SkippedBranches は同じ名前のブランチが別のコミットを指していたため戻さなかったブランチです
+	// en: SkippedBranches are the branches not put back because a branch of that name points elsewhere
+	SkippedBranches []string `json:"skipped_branches" yaml:"skipped_branches"`
+	Stashes         int      `json:"stashes" yaml:"stashes"`
+	Patch           bool     `json:"patch" yaml:"patch"`
+}
+
+// ja: capturedGitWork は captureGitWork が集めた、アーカイブに追加する git の作業です
+// en: capturedGitWork is the git work gathered by captureGitWork, to be added to the archive
+type capturedGitWork struct {
+	work GitWork
+	// ja: spooled は一時ファイルに書き出した bundle とパッチです（削除は呼び出し側が行います）
+	// en: spooled are the bundle and patch written to temp files (the caller removes them)
+	spooled   []archiveEntry
+	untracked []archiveEntry
+}
+
+// ja: remove は一時ファイルを削除します
+// en: remove deletes the temp files
+func (c *capturedGitWork) remove() {
+	for _, entry := range c.spooled {
+		os.Remove(entry.fullPath)
+	}
+}
+
+// ja: captureGitWork は dir のリポジトリから、リモートにないブランチと stash を git bundle に、
+// ja: 追跡中のファイルへの変更をパッチに書き出し、未追跡のファイルを集めます
+// ja: backupPaths に含まれる未追跡のファイルは、通常のファイルとしてアーカイブされるので除きます
+// en: captureGitWork writes the branches not on any remote and the stashes of the repository at dir
+// en: into a git bundle, the changes to tracked files into a patch, and gathers the untracked files
+// en: Untracked files under backupPaths are left out, as they are archived as regular files anyway
+func captureGitWork(ctx context.Context, dir string, backupPaths []string, runner Runner) (captured *capturedGitWork, err error) {
+	captured = &capturedGitWork{}
+	defer func() {
+		if err != nil {
+			captured.remove()
+			err = fmt.Errorf(i18n.T("backup.gitStateError"), dir, err)
+		}
+	}()
+
+	if _, err := runGit(ctx, runner, dir, "rev-parse", "--verify", "HEAD"); err != nil {
+		return nil, err
+	}
+
+	if err := captured.bundleRefs(ctx, dir, runner); err != nil {
+		return nil, err
+	}
+
+	patch, err := spoolGitOutput(ctx, runner, dir, gitPatchEntryName, "diff", "--binary", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if patch.file.Size > 0 {
+		captured.spooled = append(captured.spooled, patch)
+		captured.work.Patch = true
+	} else {
+		os.Remove(patch.fullPath)
+	}
+
+	out, err := runGit(ctx, runner, dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(out, "\x00") {
+		// ja: 末尾が / のものは入れ子のリポジトリなので含めない
+		// en: Names ending in / are nested repositories, which are left out
+		if name == "" || strings.HasSuffix(name, "/") || coveredByBackupPaths(name, backupPaths) {
+			continue
+		}
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		info, err := os.Lstat(fullPath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		entry, err := describeFile(ctx, fullPath, untrackedArchivePrefix+name, info.Size())
+		if err != nil {
+			return nil, err
+		}
+		captured.untracked = append(captured.untracked, entry)
+		captured.work.Untracked = append(captured.work.Untracked, name)
+	}
+
+	return captured, nil
+}
+
+// ja: bundleRefs はリモートにないブランチと全ての stash を git bundle に書き出します
+// en: bundleRefs writes the branches not on any remote and all stashes into a git bundle
+func (c *capturedGitWork) bundleRefs(ctx context.Context, dir string, runner Runner) error {
+	out, err := runGit(ctx, runner, dir, "stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	stashes := strings.Fields(out)
+
+	// ja: 一時的な参照は、失敗した場合も含めて必ず削除する
+	// en: The temporary refs are always removed, failure or not
+	var refs []string
+	defer func() {
+		for _, ref := range refs {
+			runGit(context.WithoutCancel(ctx), runner, dir, "update-ref", "-d", ref)
+		}
+	}()
+	for i, stash := range stashes {
+		ref := stashRefPrefix + strconv.Itoa(i)
+		if _, err := runGit(ctx, runner, dir, "update-ref", ref, stash); err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+	}
+
+	// ja: リモートにないコミットがなければ bundle は作らない（空の bundle は git が拒否する）
+	// en: Without commits missing from the remotes there is no bundle (git refuses to create an empty one)
+	revs := append(append([]string{"--branches"}, refs...), "--not", "--remotes")
+	count, err := runGit(ctx, runner, dir, append([]string{"rev-list", "--count"}, revs...)...)
+	if err != nil {
+		return err
+	}
+	if count == "0" {
+		return nil
+	}
+
+	tmpFile, err := os.CreateTemp("", "toske-bundle-*")
+	if err != nil {
+		return err
+	}
+	tmpFile.Close()
+	entry := archiveEntry{fullPath: tmpFile.Name(), name: gitBundleEntryName}
+	c.spooled = append(c.spooled, entry)
+
+	if _, err := runGit(ctx, runner, dir, append([]string{"bundle", "create", "--quiet", entry.fullPath}, revs...)...); err != nil {
+		return err
+	}
+	heads, err := bundleHeads(ctx, runner, dir, entry.fullPath)
+	if err != nil {
+		return err
+	}
+	for _, head := range heads {
+		if branch, ok := strings.CutPrefix(head.ref, "refs/heads/"); ok {
+			c.work.Branches = append(c.work.Branches, branch)
+		}
+	}
+	c.work.Stashes = len(stashes)
+
+	info, err := os.Stat(entry.fullPath)
+	if err != nil {
+		return err
+	}
+	described, err := describeFile(ctx, entry.fullPath, entry.name, info.Size())
+	if err != nil {
+		return err
+	}
+	c.spooled[len(c.spooled)-1] = described
+	return nil
+}
+
+// ja: restoreGitWork はアーカイブに保存された git の作業を dir のリポジトリに戻します
+// ja: ブランチは同じ名前のものがない場合だけ作り、stash は既にあるものを除いて古い順に積み直し、
+// ja: パッチはまだ当たっていない場合だけ適用します（何度実行しても同じ結果になります）
+// en: restoreGitWork puts the git work saved in the archive back into the repository at dir
+// en: Branches are only created when no branch of that name exists, stashes not already present are
+// en: stored again oldest first, and the patch is applied unless it already is (running it again changes nothing)
+func restoreGitWork(ctx context.Context, archivePath, dir string, opts Options) (*GitWorkResult, error) {
+	runner := opts.runner()
+	if _, err := runGit(ctx, runner, dir, "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf(i18n.T("restore.gitNotRepository"), dir)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "toske-git-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bundlePath := filepath.Join(tmpDir, "refs.bundle")
+	patchPath := filepath.Join(tmpDir, "worktree.patch")
+	found, err := extractEntries(archivePath, map[string]string{gitBundleEntryName: bundlePath, gitPatchEntryName: patchPath})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GitWorkResult{}
+	if found[gitBundleEntryName] {
+		if err := restoreBundle(ctx, runner, dir, bundlePath, result, opts); err != nil {
+			return result, fmt.Errorf(i18n.T("restore.gitBundleError"), err)
+		}
+	}
+	if found[gitPatchEntryName] {
+		// ja: 逆向きに当てられるなら、パッチは既に適用済み
+		// en: When the patch applies in reverse, it is already applied
+		if _, err := runGit(ctx, runner, dir, "apply", "--check", "--reverse", patchPath); err != nil {
+			if _, err := runGit(ctx, runner, dir, "apply", "--binary", patchPath); err != nil {
+				return result, fmt.Errorf(i18n.T("restore.gitPatchError"), err, gitPatchEntryName)
+			}
+			result.Patch = true
+		}
+	}
+	return result, nil
+}
+
+// ja: restoreBundle は bundle のブランチと stash をリポジトリに戻します
+// en: restoreBundle puts the branches and stashes of a bundle back into the repository
+func restoreBundle(ctx context.Context, runner Runner, dir, bundlePath string, result *GitWorkResult, opts Options) error {
+	heads, err := bundleHeads(ctx, runner, dir, bundlePath)
+	if err != nil {
+		return err
+	}
+
+	var stashes []bundleHead
+	for _, head := range heads {
+		branch, ok := strings.CutPrefix(head.ref, "refs/heads/")
+		if !ok {
+			if strings.HasPrefix(head.ref, stashRefPrefix) {
+				stashes = append(stashes, head)
+			}
+			continue
+		}
+
+		existing, err := runGit(ctx, runner, dir, "rev-parse", "--verify", "--quiet", head.ref)
+		switch {
+		case err != nil:
+			if _, err := runGit(ctx, runner, dir, "fetch", "--quiet", bundlePath, head.ref+":"+head.ref); err != nil {
+				return err
+			}
+			result.Branches = append(result.Branches, branch)
+		case existing != head.commit:
+			opts.emit(Event{Kind: EventBranchSkipped, Path: branch})
+			result.SkippedBranches = append(result.SkippedBranches, branch)
+		}
+	}
+
+	if len(stashes) == 0 {
+		return nil
+	}
+
+	// ja: stash のコミットを取り込んでから、既にないものを古い順（番号の大きい順）に積み直す
+	// en: Fetch the stash commits, then store those not already there oldest first (highest number first)
+	fetch := []string{"fetch", "--quiet", bundlePath}
+	for _, stash := range stashes {
+		fetch = append(fetch, stash.ref)
+	}
+	if _, err := runGit(ctx, runner, dir, fetch...); err != nil {
+		return err
+	}
+	out, err := runGit(ctx, runner, dir, "stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	present := strings.Fields(out)
+
+	for i := len(stashes) - 1; i >= 0; i-- {
+		stash := stashes[i]
+		if containsString(present, stash.commit) {
+			continue
+		}
+		message, err := runGit(ctx, runner, dir, "log", "-1", "--format=%s", stash.commit)
+		if err != nil {
+			return err
+		}
+		if _, err := runGit(ctx, runner, dir, "stash", "store", "-m", message, stash.commit); err != nil {
+			return err
+		}
+		result.Stashes++
+	}
+	return nil
+}
+
+// ja: bundleHead は bundle に含まれる参照です
+// en: bundleHead is a ref carried by a bundle
+type bundleHead struct {
+	commit string
+	ref    string
+}
+
+// ja: bundleHeads は bundle に含まれる参照を記録された順（stash は番号順）に返します
+// en: bundleHeads returns the refs carried by a bundle in the order they were recorded (stashes by number)
+func bundleHeads(ctx context.Context, runner Runner, dir, bundlePath string) ([]bundleHead, error) {
+	out, err := runGit(ctx, runner, dir, "bundle", "list-heads", bundlePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var heads []bundleHead
+	for _, line := range strings.Split(out, "\n") {
+		commit, ref, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			heads = append(heads, bundleHead{commit: commit, ref: ref})
+		}
+	}
+
+	// ja: list-heads は参照を名前順に並べるので、stash/10 が stash/2 より前に来ないよう番号で並べ直す
+	// en: list-heads sorts refs by name, so put the stashes back in numeric order (stash/10 after stash/2)
+	stashNumber := func(ref string) int {
+		n, _ := strconv.Atoi(strings.TrimPrefix(ref, stashRefPrefix))
+		return n
+	}
+	var stashes []bundleHead
+	var others []bundleHead
+	for _, head := range heads {
+		if strings.HasPrefix(head.ref, stashRefPrefix) {
+			stashes = append(stashes, head)
+		} else {
+			others = append(others, head)
+		}
+	}
+	for i := 1; i < len(stashes); i++ {
+		for j := i; j > 0 && stashNumber(stashes[j].ref) < stashNumber(stashes[j-1].ref); j-- {
+			stashes[j], stashes[j-1] = stashes[j-1], stashes[j]
+		}
+	}
+	return append(others, stashes...), nil
+}
+
+// ja: runGit は dir で git を実行し、前後の空白を除いた標準出力を返します
+// en: runGit runs git in dir and returns its trimmed standard output
+func runGit(ctx context.Context, runner Runner, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := runner.Run(Command{Context: ctx, Name: "git", Args: args, Dir: dir, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ja: spoolGitOutput は git の出力を一時ファイルに書き出し、アーカイブのエントリ name として返します
+// en: spoolGitOutput writes the output of git to a temp file and returns it as the archive entry name
+func spoolGitOutput(ctx context.Context, runner Runner, dir, name string, args ...string) (archiveEntry, error) {
+	tmpFile, err := os.CreateTemp("", "toske-git-*")
+	if err != nil {
+		return archiveEntry{}, err
+	}
+	defer tmpFile.Close()
+
+	var stderr bytes.Buffer
+	err = runner.Run(Command{Context: ctx, Name: "git", Args: args, Dir: dir, Stdout: tmpFile, Stderr: &stderr})
+	if err == nil {
+		err = tmpFile.Close()
+	}
+	var entry archiveEntry
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(tmpFile.Name()); err == nil {
+			entry, err = describeFile(ctx, tmpFile.Name(), name, info.Size())
+		}
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return archiveEntry{}, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+		}
+		return archiveEntry{}, err
+	}
+	return entry, nil
+}
+
+// ja: extractEntries はアーカイブのエントリのうち targets にあるものを、対応するパスに書き出します
+// ja: 見つかったエントリ名を返します
+// en: extractEntries writes the archive entries listed in targets to their paths
+// en: Returns the names of the entries that were found
+func extractEntries(archivePath string, targets map[string]string) (map[string]bool, error) {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("restore.openArchiveError"), err)
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	found := make(map[string]bool)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return found, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		target, ok := targets[header.Name]
+		if !ok {
+			continue
+		}
+		if err := copyToFile(target, tarReader); err != nil {
+			return nil, err
+		}
+		found[header.Name] = true
+	}
+}
+
+// ja: coveredByBackupPaths は name（スラッシュ区切り）が backup_paths のいずれかに含まれるかを返します
+// en: coveredByBackupPaths reports whether name (slash-separated) falls under one of the backup_paths
+func coveredByBackupPaths(name string, backupPaths []string) bool {
+	for _, backupPath := range backupPaths {
+		cleaned := strings.TrimPrefix(path.Clean(filepath.ToSlash(backupPath)), "./")
+		if name == cleaned || strings.HasPrefix(name, cleaned+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ja: containsString は values に value が含まれるかを返します
+// en: containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package toske
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ja: gitCmd はテスト用のリポジトリで git を実行し、出力を返します
+// en: gitCmd runs git in a test repository and returns its output
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitWorkRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "toske")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "toske@example.com")
+	}
+
+	project, opts := setupProject(t, map[string]string{"a.txt": "one\n", ".env": "TEST=value"})
+	project.BackupPaths = []string{".env"}
+	project.IncludeGitState = true
+	work := project.Dir
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	origin := filepath.Join(filepath.Dir(work), "origin.git")
+
+	gitCmd(t, work, "init", "--quiet", "--initial-branch=main")
+	gitCmd(t, work, "add", "a.txt")
+	gitCmd(t, work, "commit", "--quiet", "-m", "initial")
+	gitCmd(t, work, "init", "--quiet", "--bare", "--initial-branch=main", origin)
+	gitCmd(t, work, "remote", "add", "origin", origin)
+	gitCmd(t, work, "push", "--quiet", "origin", "main")
+
+	// ja: プッシュしていないブランチ、stash 2 件、コミットしていない変更、未追跡のファイルを作る
+	// en: Make an unpushed branch, two stashes, an uncommitted change and an untracked file
+	gitCmd(t, work, "checkout", "--quiet", "-b", "feature")
+	write("b.txt", "feature\n")
+	gitCmd(t, work, "add", "b.txt")
+	gitCmd(t, work, "commit", "--quiet", "-m", "feature work")
+	feature := gitCmd(t, work, "rev-parse", "HEAD")
+	gitCmd(t, work, "checkout", "--quiet", "main")
+	for _, content := range []string{"stash one\n", "stash two\n"} {
+		write("a.txt", content)
+		gitCmd(t, work, "stash", "push", "--quiet", "-m", strings.TrimSpace(content))
+	}
+	stashes := gitCmd(t, work, "stash", "list", "--format=%H %s")
+	write("a.txt", "changed\n")
+	write("notes.txt", "remember this")
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	want := &GitWork{Branches: []string{"feature"}, Stashes: 2, Patch: true, Untracked: []string{"notes.txt"}}
+	if !reflect.DeepEqual(result.GitWork, want) || !reflect.DeepEqual(result.Record.GitWork, want) {
+		t.Fatalf("Expected git work %+v, got %+v (record %+v)", want, result.GitWork, result.Record.GitWork)
+	}
+	if refs := gitCmd(t, work, "for-each-ref", stashRefPrefix); refs != "" {
+		t.Errorf("Expected the temporary stash refs to be removed, got %q", refs)
+	}
+
+	// ja: 新しいクローンに復元すると、全て戻る
+	// en: Restoring into a fresh clone puts everything back
+	clone := filepath.Join(filepath.Dir(work), "clone")
+	gitCmd(t, work, "clone", "--quiet", origin, clone)
+	project.Dir = clone
+
+	plan, err := PlanRestore(project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("PlanRestore failed: %v", err)
+	}
+	var planned []string
+	for _, file := range plan.Files {
+		planned = append(planned, file.Path)
+	}
+	if !reflect.DeepEqual(planned, []string{".env", "notes.txt"}) || !reflect.DeepEqual(plan.GitWork, want) {
+		t.Errorf("Unexpected plan: files %v, git work %+v", planned, plan.GitWork)
+	}
+
+	restored, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := (GitWorkResult{Branches: []string{"feature"}, Stashes: 2, Patch: true}); !reflect.DeepEqual(*restored.GitWork, got) {
+		t.Errorf("Expected %+v, got %+v", got, *restored.GitWork)
+	}
+	if got := gitCmd(t, clone, "rev-parse", "refs/heads/feature"); got != feature {
+		t.Errorf("Expected feature at %s, got %s", feature, got)
+	}
+	if got := gitCmd(t, clone, "stash", "list", "--format=%H %s"); got != stashes {
+		t.Errorf("Expected stashes %q, got %q", stashes, got)
+	}
+	for name, content := range map[string]string{"a.txt": "changed\n", "notes.txt": "remember this", ".env": "TEST=value"} {
+		if data, err := os.ReadFile(filepath.Join(clone, name)); err != nil || string(data) != content {
+			t.Errorf("Expected %s to be %q, got %q (%v)", name, content, data, err)
+		}
+	}
+
+	// ja: もう一度復元しても何も重複しない
+	// en: Restoring again duplicates nothing
+	again, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("Second restore failed: %v", err)
+	}
+	if again.GitWork.Stashes != 0 || again.GitWork.Patch || len(again.GitWork.Branches) != 0 {
+		t.Errorf("Expected nothing to be put back twice, got %+v", *again.GitWork)
+	}
+	if got := gitCmd(t, clone, "stash", "list", "--format=%H %s"); got != stashes {
+		t.Errorf("Expected the stashes to be unchanged, got %q", got)
+	}
+
+	// ja: --skip-git-state 相当では未追跡のファイルも git の作業も扱わない
+	// en: With SkipGitState neither the untracked files nor the git work are touched
+	skipped, err := Restore(context.Background(), project, RestoreOptions{Options: opts, SkipGitState: true})
+	if err != nil {
+		t.Fatalf("Restore with SkipGitState failed: %v", err)
+	}
+	if skipped.GitWork != nil || !reflect.DeepEqual(skipped.Files, []string{".env"}) {
+		t.Errorf("Expected only .env to be restored, got %v (git work %+v)", skipped.Files, skipped.GitWork)
+	}
+}
+
+func TestRestoreGitWorkNeedsRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	project, opts := setupProject(t, nil)
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(project.Dir))
+	archivePath := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := createTestArchive(archivePath, []testFile{{name: gitPatchEntryName, content: "patch"}}); err != nil {
+		t.Fatalf("Failed to create test archive: %v", err)
+	}
+
+	_, err := restoreGitWork(context.Background(), archivePath, project.Dir, opts)
+	if err == nil || !strings.Contains(err.Error(), project.Dir) {
+		t.Errorf("Expected an error naming %s, got %v", project.Dir, err)
+	}
+}
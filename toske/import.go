@@ -0,0 +1,154 @@
+package toske
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: AddArchive は既存のアーカイブ（別のマシンからエクスポートしたものなど）をプロジェクトのバックアップに加えます
+// ja: r の内容を record.Filename として保存し、メタデータに記録します。同じ名前のファイルが既にある場合は
+// ja: 空いている名前（backup_..._2.tar.gz など）に変えます。同じ記録（ファイル名とタイムスタンプが一致）が
+// ja: 既にある場合は何もせず、added に false を返します
+// en: AddArchive adds an existing archive (e.g. one exported from another machine) to a project's backups
+// en: The content of r is stored as record.Filename and recorded in the metadata; when a file of that name
+// en: already exists, a free variant of the name (backup_..._2.tar.gz, ...) is used instead. When the same
+// en: record (same filename and timestamp) is already there, nothing is done and added is false
+func AddArchive(projectName string, r io.Reader, record BackupRecord, opts Options) (stored BackupRecord, added bool, err error) {
+	if name := record.Filename; name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return BackupRecord{}, false, fmt.Errorf(i18n.T("import.invalidArchiveName"), record.Filename)
+	}
+
+	backupDir, err := opts.BackupDir(projectName)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+
+	metadata, err := LoadMetadata(backupDir)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+	if HasRecord(metadata.Backups, record) {
+		return record, false, nil
+	}
+
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return BackupRecord{}, false, fmt.Errorf(i18n.T("backup.createDirError"), err)
+	}
+
+	stored = record
+	stored.Filename = freeArchiveName(backupDir, record.Filename)
+	archivePath := filepath.Join(backupDir, stored.Filename)
+
+	// ja: Backup と同じく .partial に書き、完成してから名前を変える
+	// en: As in Backup, write to a .partial file and rename it once complete
+	partialPath := archivePath + PartialSuffix
+	err = copyToFile(partialPath, r)
+	if err == nil {
+		err = os.Rename(partialPath, archivePath)
+	}
+	if err != nil {
+		os.Remove(partialPath)
+		return BackupRecord{}, false, fmt.Errorf(i18n.T("backup.archiveError"), err)
+	}
+
+	if err := addRecord(backupDir, projectName, stored); err != nil {
+		return BackupRecord{}, false, fmt.Errorf(i18n.T("backup.metadataError"), err)
+	}
+
+	return stored, true, nil
+}
+
+// ja: ImportArchive は任意の tar または tar.gz ファイルを project のバックアップとして取り込みます
+// ja: tar ファイルは取り込む際に gzip 圧縮します。backup_... 形式でない名前は、アーカイブの更新日時から
+// ja: backup_<日時>.tar.gz という名前に変えます。重複や名前の重なりの扱いは AddArchive と同じです
+// en: ImportArchive adopts any tar or tar.gz file as a backup of project
+// en: Plain tar files are gzip-compressed on the way in. Names not of the backup_... form are replaced by
+// en: backup_<time>.tar.gz based on the archive's modification time. Duplicates and name clashes are handled as in AddArchive
+func ImportArchive(project Project, archivePath string, opts Options) (stored BackupRecord, added bool, err error) {
+	record, compressed, err := InspectArchive(archivePath, project.BackupPaths)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if !compressed {
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			gzipWriter := gzip.NewWriter(pw)
+			_, err := io.Copy(gzipWriter, file)
+			if err == nil {
+				err = gzipWriter.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		r = pr
+	}
+
+	return AddArchive(project.Name, r, record, opts)
+}
+
+// ja: InspectArchive は ImportArchive で取り込むアーカイブを読み、記録する内容と gzip 圧縮されているかを返します
+// ja: 記録の Filename は取り込み後の名前です（既に同じ名前がある場合は AddArchive でさらに変わります）
+// en: InspectArchive reads an archive to be adopted by ImportArchive and returns its record and whether it is gzip-compressed
+// en: The record's Filename is the name it will be stored under (AddArchive may still change it on a clash)
+func InspectArchive(archivePath string, backupPaths []string) (BackupRecord, bool, error) {
+	record, compressed, err := readArchive(archivePath, backupPaths)
+	if err != nil {
+		return BackupRecord{}, false, fmt.Errorf(i18n.T("importArchive.readError"), archivePath, err)
+	}
+	if !archiveNamePattern.MatchString(record.Filename) {
+		record.Filename = fmt.Sprintf("backup_%s.tar.gz", record.Timestamp.Format("20060102_150405.000000"))
+	}
+	return record, compressed, nil
+}
+
+// ja: HasRecord は records に record と同じバックアップ（ファイル名とタイムスタンプが一致）があるかを返します
+// en: HasRecord reports whether records holds the same backup as record (same filename and timestamp)
+func HasRecord(records []BackupRecord, record BackupRecord) bool {
+	for _, existing := range records {
+		if existing.Filename == record.Filename && existing.Timestamp.Equal(record.Timestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+// ja: freeArchiveName は backupDir で使われていないアーカイブ名を返します（name が空いていれば name のまま）
+// en: freeArchiveName returns an archive name not yet used in backupDir (name itself when it is free)
+func freeArchiveName(backupDir, name string) string {
+	base := strings.TrimSuffix(name, ".tar.gz")
+	candidate := name
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(filepath.Join(backupDir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d.tar.gz", base, i)
+	}
+}
+
+// ja: copyToFile は r の内容を path に書き込みます
+// en: copyToFile writes the content of r to path
+func copyToFile(path string, r io.Reader) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,52 @@
+package toske
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddArchive(t *testing.T) {
+	project, opts := setupProject(t, nil)
+	record := BackupRecord{Filename: "backup_1.tar.gz", Timestamp: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+	stored, added, err := AddArchive(project.Name, strings.NewReader("first"), record, opts)
+	if err != nil || !added || stored.Filename != "backup_1.tar.gz" {
+		t.Fatalf("Expected the archive to be added as is, got %+v, %v, %v", stored, added, err)
+	}
+
+	// ja: 同じ記録は重複として何もしない
+	// en: The same record is a duplicate and nothing is done
+	if _, added, err := AddArchive(project.Name, strings.NewReader("again"), record, opts); err != nil || added {
+		t.Errorf("Expected a duplicate not to be added, got %v, %v", added, err)
+	}
+
+	// ja: 名前だけが同じ別のバックアップは別名で保存する
+	// en: A different backup with the same name is stored under another name
+	other := record
+	other.Timestamp = record.Timestamp.Add(time.Hour)
+	stored, added, err = AddArchive(project.Name, strings.NewReader("second"), other, opts)
+	if err != nil || !added || stored.Filename != "backup_1_2.tar.gz" {
+		t.Fatalf("Expected the archive to be renamed, got %+v, %v, %v", stored, added, err)
+	}
+
+	records, err := ListBackups(project, opts)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Filename != "backup_1_2.tar.gz" {
+		t.Errorf("Expected both backups, newest first, got %+v", records)
+	}
+	backupDir, _ := opts.BackupDir(project.Name)
+	if content, err := os.ReadFile(filepath.Join(backupDir, "backup_1.tar.gz")); err != nil || string(content) != "first" {
+		t.Errorf("Expected the first archive to be kept, got %q (%v)", content, err)
+	}
+
+	for _, name := range []string{"", "..", "../escape.tar.gz"} {
+		if _, _, err := AddArchive(project.Name, strings.NewReader("x"), BackupRecord{Filename: name}, opts); err == nil {
+			t.Errorf("Expected archive name %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: manifestEntryName はアーカイブの先頭に置くマニフェストのエントリ名です
+// en: manifestEntryName is the name of the manifest entry at the start of an archive
+const manifestEntryName = toskeArchivePrefix + "manifest.yaml"
+
+// ja: ManifestFormat はこのバージョンが書き込むマニフェストの形式です
+// en: ManifestFormat is the manifest format written by this version
+const ManifestFormat = 1
+
+// ja: アーカイブの圧縮・暗号化方式（マニフェストに記録します）
+// en: Compression and encryption of an archive (recorded in the manifest)
+const (
+	CompressionGzip = "gzip"
+	EncryptionNone  = "none"
+)
+
+// ja: Manifest はアーカイブの先頭に埋め込まれる、アーカイブ自身の説明です
+// ja: backups.yaml を失っても、アーカイブだけでどのプロジェクトのものか、何で作られたかが分かります
+// en: Manifest is the description of an archive embedded at its start
+// en: Even without backups.yaml, it tells which project the archive belongs to and what produced it
+type Manifest struct {
+	Format  int    `yaml:"format" json:"format"`
+	Project string `yaml:"project" json:"project"`
+	Repo    string `yaml:"repo,omitempty" json:"repo,omitempty"`
+	Branch  string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// ja: Git はバックアップ時のチェックアウトの状態です
+	// en: Git is the state of the checkout at backup time
+	Git         *GitState      `yaml:"git,omitempty" json:"git,omitempty"`
+	Hostname    string         `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	Created     time.Time      `yaml:"created" json:"created"`
+	Toske       BuildInfo      `yaml:"toske" json:"toske"`
+	Compression string         `yaml:"compression" json:"compression"`
+	Encryption  string         `yaml:"encryption" json:"encryption"`
+	Files       []ManifestFile `yaml:"files" json:"files"`
+	Dumps       []ManifestFile `yaml:"dumps,omitempty" json:"dumps,omitempty"`
+	// ja: GitWork は include_git_state で保存した bundle・パッチ・未追跡のファイルです
+	// en: GitWork are the bundle, patch and untracked files saved for include_git_state
+	GitWork []ManifestFile `yaml:"git_work,omitempty" json:"git_work,omitempty"`
+	// ja: ArchiveMode はアーカイブした範囲です（ArchiveModeFull の場合のみ記録します）
+	// en: ArchiveMode is what the archive covers (only recorded for ArchiveModeFull)
+	ArchiveMode string `yaml:"archive_mode,omitempty" json:"archive_mode,omitempty"`
+	// ja: Links は ArchiveModeFull でアーカイブしたシンボリックリンクとそのリンク先です
+	// en: Links are the symlinks archived with ArchiveModeFull and their targets
+	Links []ManifestLink `yaml:"links,omitempty" json:"links,omitempty"`
+}
+
+// ja: ManifestFile はアーカイブ内のファイル（ダンプの場合はダンプ名）とそのハッシュです
+// en: ManifestFile is a file in the archive (the dump name for dumps) and its hash
+type ManifestFile struct {
+	Path   string `yaml:"path" json:"path"`
+	Size   int64  `yaml:"size" json:"size"`
+	SHA256 string `yaml:"sha256" json:"sha256"`
+}
+
+// ja: ManifestLink はアーカイブ内のシンボリックリンクとそのリンク先です
+// en: ManifestLink is a symlink in the archive and its target
+type ManifestLink struct {
+	Path   string `yaml:"path" json:"path"`
+	Target string `yaml:"target" json:"target"`
+}
+
+// ja: BuildInfo はアーカイブを作成した toske のビルドです
+// en: BuildInfo identifies the toske build that created an archive
+type BuildInfo struct {
+	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	Commit  string `yaml:"commit,omitempty" json:"commit,omitempty"`
+}
+
+// ja: newManifest は project のバックアップのマニフェストを作ります
+// en: newManifest builds the manifest of a backup of project
+func newManifest(project Project, created time.Time, opts Options) *Manifest {
+	hostname, _ := os.Hostname()
+	return &Manifest{
+		Format:      ManifestFormat,
+		Project:     project.Name,
+		Repo:        project.Repo,
+		Branch:      project.Branch,
+		Git:         project.Git,
+		Hostname:    hostname,
+		Created:     created,
+		Toske:       opts.Build,
+		Compression: CompressionGzip,
+		Encryption:  EncryptionNone,
+		Files:       []ManifestFile{},
+		ArchiveMode: fullArchiveMode(project),
+	}
+}
+
+// ja: writeManifest はマニフェストをアーカイブのエントリとして書き込みます
+// en: writeManifest writes the manifest as an archive entry
+func writeManifest(tarWriter *tar.Writer, manifest *Manifest) error {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    manifestEntryName,
+		Size:    int64(len(data)),
+		Mode:    0644,
+		ModTime: manifest.Created,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tarWriter.Write(data)
+	return err
+}
+
+// ja: parseManifest はマニフェストのエントリを読み込み、対応している形式かを確認します
+// en: parseManifest reads a manifest entry and checks that its format is supported
+func parseManifest(r io.Reader) (*Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf(i18n.T("manifest.readError"), err)
+	}
+	if manifest.Format > ManifestFormat {
+		return nil, &UnsupportedManifestError{Format: manifest.Format}
+	}
+	return &manifest, nil
+}
+
+// ja: ReadManifest はアーカイブの先頭にあるマニフェストを読み込みます
+// ja: マニフェストを持たないアーカイブ（以前のバージョンで作成したものなど）では nil を返します
+// en: ReadManifest reads the manifest at the start of an archive
+// en: Returns nil for archives without one (such as those created by earlier versions)
+func ReadManifest(archivePath string) (*Manifest, error) {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	header, err := tarReader.Next()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != manifestEntryName {
+		return nil, nil
+	}
+	return parseManifest(tarReader)
+}
+
+// ja: fileHashes はマニフェストのファイルをパスから SHA-256 を引けるようにします
+// ja: マニフェストがない場合は nil を返します（照合しません）
+// en: fileHashes indexes the manifest's files by path to look up their SHA-256
+// en: Returns nil when there is no manifest (nothing is verified)
+func (m *Manifest) fileHashes() map[string]string {
+	if m == nil {
+		return nil
+	}
+	hashes := make(map[string]string, len(m.Files)+len(m.GitWork))
+	for _, file := range m.Files {
+		hashes[file.Path] = file.SHA256
+	}
+	for _, file := range m.GitWork {
+		hashes[file.Path] = file.SHA256
+	}
+	return hashes
+}
+
+// ja: linkTargets はマニフェストのシンボリックリンクをパスからリンク先を引けるようにします
+// ja: マニフェストがない場合は nil を返します（照合しません）
+// en: linkTargets indexes the manifest's symlinks by path to look up their targets
+// en: Returns nil when there is no manifest (nothing is verified)
+func (m *Manifest) linkTargets() map[string]string {
+	if m == nil {
+		return nil
+	}
+	links := make(map[string]string, len(m.Links))
+	for _, link := range m.Links {
+		links[link.Path] = link.Target
+	}
+	return links
+}
+
+// ja: hashFile はファイルの SHA-256 を 16 進数の文字列で返します
+// en: hashFile returns the SHA-256 of a file as a hex string
+func hashFile(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, contextReader{ctx: ctx, r: file}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
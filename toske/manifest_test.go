@@ -0,0 +1,148 @@
+package toske
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestBackupManifest(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{".env": "TEST=value"})
+	project.BackupPaths = []string{".env"}
+	project.Dumps = []Dump{{Name: "db.sql", Provider: DumpProviderCommand, Command: "dump", RestoreCommand: "load"}}
+	project.Repo, project.Branch = "git@github.com:user/demo.git", "main"
+	project.Git = &GitState{Commit: "0123abcd", Branch: "main", Dirty: true}
+	opts.Runner = &fakeRunner{output: "dump-data"}
+	opts.Build = BuildInfo{Version: "1.2.3", Commit: "feedbeef"}
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	manifest, err := ReadManifest(result.Archive)
+	if err != nil || manifest == nil {
+		t.Fatalf("Expected the archive to start with a manifest, got %v, %v", manifest, err)
+	}
+	if manifest.Project != "demo" || manifest.Repo != project.Repo || manifest.Branch != "main" || !reflect.DeepEqual(manifest.Git, project.Git) {
+		t.Errorf("Unexpected project details: %+v", manifest)
+	}
+	if !reflect.DeepEqual(result.Record.Git, project.Git) {
+		t.Errorf("Expected the git state to be recorded, got %+v", result.Record.Git)
+	}
+	if manifest.Toske != opts.Build || manifest.Compression != CompressionGzip || manifest.Encryption != EncryptionNone {
+		t.Errorf("Unexpected build details: %+v", manifest)
+	}
+	if !manifest.Created.Equal(result.Record.Timestamp) {
+		t.Errorf("Expected the manifest to be created at %v, got %v", result.Record.Timestamp, manifest.Created)
+	}
+	wantFiles := []ManifestFile{{Path: ".env", Size: 10, SHA256: sha256Hex("TEST=value")}}
+	if !reflect.DeepEqual(manifest.Files, wantFiles) {
+		t.Errorf("Expected files %+v, got %+v", wantFiles, manifest.Files)
+	}
+	wantDumps := []ManifestFile{{Path: "db.sql", Size: 9, SHA256: sha256Hex("dump-data")}}
+	if !reflect.DeepEqual(manifest.Dumps, wantDumps) {
+		t.Errorf("Expected dumps %+v, got %+v", wantDumps, manifest.Dumps)
+	}
+
+	// ja: マニフェストはファイルとして展開されない
+	// en: The manifest is not extracted as a file
+	restored, err := Restore(context.Background(), project, RestoreOptions{Options: opts, SkipDumps: true})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Files, []string{".env"}) || restored.Manifest == nil {
+		t.Errorf("Expected only .env to be restored along with the manifest, got %+v", restored)
+	}
+	if _, err := os.Stat(filepath.Join(project.Dir, ".toske")); !os.IsNotExist(err) {
+		t.Errorf("Expected no .toske directory in the project, got %v", err)
+	}
+}
+
+// addManifestArchive adds an archive holding manifest and files to project's backups.
+func addManifestArchive(t *testing.T, project Project, opts Options, manifest Manifest, files []testFile) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err := writeManifest(tarWriter, &manifest); err != nil {
+		t.Fatalf("writeManifest failed: %v", err)
+	}
+	for _, f := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tarWriter.Write([]byte(f.content)); err != nil {
+			t.Fatalf("Failed to write content: %v", err)
+		}
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	record := BackupRecord{Filename: "backup_20250101_000000.000000.tar.gz", Timestamp: manifest.Created}
+	if _, _, err := AddArchive(project.Name, &buf, record, opts); err != nil {
+		t.Fatalf("AddArchive failed: %v", err)
+	}
+}
+
+func TestRestoreVerifiesManifest(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		project, opts := setupProject(t, map[string]string{".env": "OLD=1"})
+		addManifestArchive(t, project, opts, Manifest{
+			Format:  ManifestFormat,
+			Project: project.Name,
+			Created: created,
+			Files: []ManifestFile{
+				{Path: ".env", Size: 5, SHA256: sha256Hex("NEW=1")},
+				{Path: "ok.txt", Size: 2, SHA256: sha256Hex("ok")},
+			},
+		}, []testFile{{name: ".env", content: "BAD=1"}, {name: "ok.txt", content: "ok"}})
+
+		result, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+		if err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if !reflect.DeepEqual(result.Files, []string{"ok.txt"}) {
+			t.Errorf("Expected only ok.txt to be restored, got %v", result.Files)
+		}
+		if len(result.Skipped) != 1 || result.Skipped[0].Reason != SkipChecksumMismatch {
+			t.Errorf("Expected .env to be skipped for its checksum, got %+v", result.Skipped)
+		}
+		if data, _ := os.ReadFile(filepath.Join(project.Dir, ".env")); string(data) != "OLD=1" {
+			t.Errorf("Expected the existing .env to be kept, got %q", data)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		project, opts := setupProject(t, map[string]string{})
+		addManifestArchive(t, project, opts, Manifest{Format: ManifestFormat + 1, Project: project.Name, Created: created}, []testFile{{name: ".env", content: "NEW=1"}})
+
+		var unsupported *UnsupportedManifestError
+		if _, err := PlanRestore(project, RestoreOptions{Options: opts}); !errors.As(err, &unsupported) {
+			t.Errorf("Expected PlanRestore to fail with UnsupportedManifestError, got %v", err)
+		}
+		if _, err := Restore(context.Background(), project, RestoreOptions{Options: opts}); !errors.As(err, &unsupported) {
+			t.Errorf("Expected Restore to fail with UnsupportedManifestError, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(project.Dir, ".env")); !os.IsNotExist(err) {
+			t.Errorf("Expected nothing to be written, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package toske
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+	"gopkg.in/yaml.v3"
+)
+
+// ja: metadataFilename はバックアップディレクトリ内のメタデータファイル名です
+// en: metadataFilename is the name of the metadata file inside a backup directory
+const metadataFilename = "backups.yaml"
+
+// ja: BackupMetadata はバックアップのメタデータを表します
+// en: BackupMetadata represents backup metadata
+type BackupMetadata struct {
+	Project string         `yaml:"project" json:"project"`
+	Backups []BackupRecord `yaml:"backups" json:"backups"`
+}
+
+// ja: BackupRecord は個々のバックアップ記録を表します
+// en: BackupRecord represents an individual backup record
+type BackupRecord struct {
+	Filename  string    `yaml:"filename" json:"filename"`
+	Timestamp time.Time `yaml:"timestamp" json:"timestamp"`
+	Files     []string  `yaml:"files" json:"files"`
+	Dumps     []string  `yaml:"dumps,omitempty" json:"dumps,omitempty"`
+	// ja: Git はバックアップ時のチェックアウトの状態です（git リポジトリでなかった場合は nil）
+	// en: Git is the state of the checkout at backup time (nil when it was not a git repository)
+	Git *GitState `yaml:"git,omitempty" json:"git,omitempty"`
+	// ja: GitWork はバックアップに保存した、リモートにない git の作業です（include_git_state の場合のみ）
+	// en: GitWork is the local-only git work saved with the backup (only with include_git_state)
+	GitWork *GitWork `yaml:"git_work,omitempty" json:"git_work,omitempty"`
+	// ja: ArchiveMode はバックアップした範囲です（ArchiveModeFull の場合のみ記録します）
+	// en: ArchiveMode is what the backup covers (only recorded for ArchiveModeFull)
+	ArchiveMode string `yaml:"archive_mode,omitempty" json:"archive_mode,omitempty"`
+}
+
+// ja: GitState はバックアップ時のプロジェクトの git チェックアウトの状態です
+// en: GitState is the state of a project's git checkout when a backup was taken
+type GitState struct {
+	Commit string `yaml:"commit" json:"commit"`
+	// ja: Branch は HEAD が切り離されていた場合は空です
+	// en: Branch is empty when HEAD was detached
+	Branch string `yaml:"branch,omitempty" json:"branch,omitempty"`
+	// ja: Dirty はコミットされていない変更があったかを表します
+	// en: Dirty tells whether there were uncommitted changes
+	Dirty  bool   `yaml:"dirty,omitempty" json:"dirty,omitempty"`
+	Remote string `yaml:"remote,omitempty" json:"remote,omitempty"`
+}
+
+// ja: GitWork はリモートに push されていない git の作業のうち、バックアップに保存したものです
+// en: GitWork is the git work not pushed to any remote that was saved with a backup
+type GitWork struct {
+	// ja: Branches はリモートにないコミットを持つローカルブランチです（git bundle に保存）
+	// en: Branches are the local branches with commits not on any remote (saved in a git bundle)
+	Branches []string `yaml:"branches,omitempty" json:"branches,omitempty"`
+	Stashes  int      `yaml:"stashes,omitempty" json:"stashes,omitempty"`
+	// ja: Patch は追跡中のファイルへのコミットされていない変更をパッチとして保存したかを表します
+	// en: Patch tells whether uncommitted changes to tracked files were saved as a patch
+	Patch bool `yaml:"patch,omitempty" json:"patch,omitempty"`
+	// ja: Untracked は保存した未追跡（かつ無視されていない）ファイルです
+	// en: Untracked are the untracked (and not ignored) files that were saved
+	Untracked []string `yaml:"untracked,omitempty" json:"untracked,omitempty"`
+}
+
+// ja: LoadMetadata はバックアップディレクトリのメタデータを読み込みます
+// ja: メタデータファイルが存在しない場合は空のメタデータを返します
+// en: LoadMetadata loads the metadata of a backup directory
+// en: Returns empty metadata when the metadata file does not exist
+func LoadMetadata(backupDir string) (BackupMetadata, error) {
+	var metadata BackupMetadata
+
+	data, err := os.ReadFile(filepath.Join(backupDir, metadataFilename))
+	if os.IsNotExist(err) {
+		return metadata, nil
+	}
+	if err != nil {
+		return metadata, err
+	}
+
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return metadata, err
+	}
+
+	return metadata, nil
+}
+
+// ja: SaveMetadata はバックアップディレクトリのメタデータを書き込みます
+// en: SaveMetadata writes the metadata of a backup directory
+func SaveMetadata(backupDir string, metadata BackupMetadata) error {
+	data, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(backupDir, metadataFilename), data, 0644)
+}
+
+// ja: ListBackups はプロジェクトのバックアップ記録を新しい順に返します
+// ja: バックアップが 1 つもない場合は *NoBackupsError を返します
+// en: ListBackups returns the backup records of a project, newest first
+// en: Returns a *NoBackupsError when there are no backups
+func ListBackups(project Project, opts Options) ([]BackupRecord, error) {
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// ja: バックアップディレクトリとメタデータが存在するかチェック
+	// en: Check that the backup directory and the metadata exist
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		return nil, &NoBackupsError{Project: project.Name, DirMissing: true}
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, metadataFilename)); os.IsNotExist(err) {
+		return nil, &NoBackupsError{Project: project.Name, MetadataMissing: true}
+	}
+
+	metadata, err := LoadMetadata(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("restore.readMetadataError"), err)
+	}
+
+	if len(metadata.Backups) == 0 {
+		return nil, &NoBackupsError{Project: project.Name}
+	}
+
+	return metadata.Backups, nil
+}
+
+// ja: SelectBackup は 1 始まりの番号でバックアップ記録を選択します（1 が最新）
+// en: SelectBackup picks a backup record by its 1-based number (1 is the newest)
+func SelectBackup(records []BackupRecord, index int) (BackupRecord, error) {
+	if index < 1 || index > len(records) {
+		return BackupRecord{}, &InvalidBackupIndexError{Index: index, Count: len(records)}
+	}
+	return records[index-1], nil
+}
+
+// ja: addRecord はメタデータにバックアップ記録を追加し、新しい順に並べ替えて保存します
+// en: addRecord adds a backup record to the metadata, sorts it newest first and saves it
+func addRecord(backupDir, projectName string, record BackupRecord) error {
+	metadata, err := LoadMetadata(backupDir)
+	if err != nil {
+		return err
+	}
+
+	metadata.Project = projectName
+	metadata.Backups = append(metadata.Backups, record)
+
+	// ja: タイムスタンプでソート（新しい順）
+	// en: Sort by timestamp (newest first)
+	sort.Slice(metadata.Backups, func(i, j int) bool {
+		return metadata.Backups[i].Timestamp.After(metadata.Backups[j].Timestamp)
+	})
+
+	return SaveMetadata(backupDir, metadata)
+}
@@ -0,0 +1,334 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: BackupPlan は Backup が行う内容です（PlanBackup は何も書き込みません）
+// en: BackupPlan describes what Backup would do (PlanBackup writes nothing)
+type BackupPlan struct {
+	Project   string `json:"project" yaml:"project"`
+	BackupDir string `json:"backup_dir" yaml:"backup_dir"`
+	// ja: Files はアーカイブに追加されるファイル、Size はその合計サイズです
+	// en: Files are the files that would be archived, and Size is their total size
+	Files []ArchivedFile `json:"files" yaml:"files"`
+	Size  int64          `json:"size" yaml:"size"`
+	// ja: Missing は存在しないためスキップされる backup_paths のエントリです
+	// en: Missing are the backup_paths entries that would be skipped because they do not exist
+	Missing []string `json:"missing" yaml:"missing"`
+	Dumps   []string `json:"dumps" yaml:"dumps"`
+	// ja: Prune は保持件数を超えるため削除されるアーカイブです
+	// en: Prune are the archives that would be removed for exceeding the retention
+	Prune []string `json:"prune" yaml:"prune"`
+	// ja: GitState は include_git_state により git の作業も保存されるかを表します
+	// en: GitState tells whether the git work is saved too, for include_git_state
+	GitState bool `json:"git_state" yaml:"git_state"`
+	// ja: ArchiveMode は ArchiveModeFull の場合に設定され、Unsupported はその場合にアーカイブできないファイルです
+	// en: ArchiveMode is set for ArchiveModeFull, and Unsupported are the files it cannot archive
+	ArchiveMode string   `json:"archive_mode,omitempty" yaml:"archive_mode,omitempty"`
+	Unsupported []string `json:"unsupported,omitempty" yaml:"unsupported,omitempty"`
+}
+
+// ja: RestorePlan は Restore が行う内容です（PlanRestore は何も書き込みません）
+// en: RestorePlan describes what Restore would do (PlanRestore writes nothing)
+type RestorePlan struct {
+	Project string        `json:"project" yaml:"project"`
+	Archive string        `json:"archive" yaml:"archive"`
+	Record  BackupRecord  `json:"backup" yaml:"backup"`
+	Files   []PlannedFile `json:"files" yaml:"files"`
+	Skipped []SkippedFile `json:"skipped" yaml:"skipped"`
+	Dumps   []string      `json:"dumps" yaml:"dumps"`
+	// ja: Manifest はアーカイブに埋め込まれたマニフェストです（ない場合は nil）
+	// en: Manifest is the manifest embedded in the archive (nil when there is none)
+	Manifest *Manifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	// ja: GitWork は戻される git の作業です（保存されていない場合や SkipGitState の場合は nil）
+	// en: GitWork is the git work that would be put back (nil when none was saved or with SkipGitState)
+	GitWork *GitWork `json:"git_work,omitempty" yaml:"git_work,omitempty"`
+	// ja: CreateDir は ArchiveModeFull のバックアップから、存在しないプロジェクトのディレクトリを作り直すかを表します
+	// en: CreateDir tells whether the missing project directory is rebuilt from an ArchiveModeFull backup
+	CreateDir bool `json:"create_dir,omitempty" yaml:"create_dir,omitempty"`
+}
+
+// ja: PlannedFile は復元されるファイルです。Overwrite は既存のファイルを上書きするかを表します
+// en: PlannedFile is a file that would be restored; Overwrite tells whether it replaces an existing file
+type PlannedFile struct {
+	Path      string `json:"path" yaml:"path"`
+	Size      int64  `json:"size" yaml:"size"`
+	Overwrite bool   `json:"overwrite" yaml:"overwrite"`
+}
+
+// ja: Overwrites は上書きされるファイルの数を返します
+// en: Overwrites returns the number of existing files that would be replaced
+func (p *RestorePlan) Overwrites() int {
+	count := 0
+	for _, file := range p.Files {
+		if file.Overwrite {
+			count++
+		}
+	}
+	return count
+}
+
+// ja: PlanBackup はバックアップで何がアーカイブされ、何が削除されるかを調べます
+// en: PlanBackup works out what a backup would archive and what it would prune
+func PlanBackup(project Project, opts Options) (*BackupPlan, error) {
+	// ja: バックアップ対象ファイルまたはダンプがあるかチェック
+	// en: Check if there are files or dumps to backup
+	full := project.ArchiveMode == ArchiveModeFull
+	if len(project.BackupPaths) == 0 && len(project.Dumps) == 0 && !project.IncludeGitState && !full {
+		return nil, &NothingToBackupError{Project: project.Name}
+	}
+
+	if _, err := os.Stat(project.Dir); os.IsNotExist(err) {
+		return nil, &ProjectDirNotFoundError{Dir: project.Dir}
+	}
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &BackupPlan{Project: project.Name, BackupDir: backupDir, GitState: project.IncludeGitState}
+	backupPaths := project.BackupPaths
+	if full {
+		// ja: collectTree と同じ順序で数えるが、ハッシュは計算しない
+		// en: Count in the same order as collectTree, but without hashing
+		plan.ArchiveMode = ArchiveModeFull
+		err := walkTree(context.Background(), project.Dir, project.Exclude, func(_, name string, info os.FileInfo) error {
+			if !info.IsDir() && !isSymlink(info) {
+				plan.Files = append(plan.Files, ArchivedFile{Path: filepath.ToSlash(name), Size: info.Size()})
+				plan.Size += info.Size()
+			}
+			return nil
+		}, func(name string) {
+			plan.Unsupported = append(plan.Unsupported, name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		backupPaths = nil
+	}
+	for _, backupPath := range backupPaths {
+		fullPath := filepath.Join(project.Dir, backupPath)
+		info, err := os.Stat(fullPath)
+		if os.IsNotExist(err) {
+			plan.Missing = append(plan.Missing, backupPath)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			plan.Files = append(plan.Files, ArchivedFile{Path: filepath.ToSlash(backupPath), Size: info.Size()})
+			plan.Size += info.Size()
+			continue
+		}
+
+		// ja: addDirToArchive と同じ順序でディレクトリ内のファイルを数える
+		// en: Count the files in the directory in the same order as addDirToArchive
+		err = filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(fullPath, path)
+			if err != nil {
+				return err
+			}
+			plan.Files = append(plan.Files, ArchivedFile{Path: filepath.ToSlash(filepath.Join(backupPath, relPath)), Size: info.Size()})
+			plan.Size += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dump := range project.Dumps {
+		plan.Dumps = append(plan.Dumps, dump.Name)
+	}
+
+	// ja: 新しいバックアップが 1 件加わった後の保持件数で数える
+	// en: Count against the retention once the new backup has been added
+	if project.Retention > 0 {
+		metadata, err := LoadMetadata(backupDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range pruneCandidates(metadata.Backups, project.Retention-1) {
+			plan.Prune = append(plan.Prune, record.Filename)
+		}
+	}
+
+	return plan, nil
+}
+
+// ja: PlanPrune は Prune で削除されるアーカイブの名前を返します
+// en: PlanPrune returns the names of the archives Prune would remove
+func PlanPrune(project Project, keep int, opts Options) ([]string, error) {
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := LoadMetadata(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, record := range pruneCandidates(metadata.Backups, keep) {
+		names = append(names, record.Filename)
+	}
+	return names, nil
+}
+
+// ja: pruneCandidates は新しい順に並んだ記録のうち、keep 件を超える古いものを返します
+// en: pruneCandidates returns the records beyond the newest keep of them (records are newest first)
+func pruneCandidates(records []BackupRecord, keep int) []BackupRecord {
+	if keep < 0 || len(records) <= keep {
+		return nil
+	}
+	return records[keep:]
+}
+
+// ja: PlanRestore は復元で書き込まれるファイルと、上書きされる既存のファイルを調べます
+// ja: アーカイブは読むだけで、展開はしません
+// en: PlanRestore works out which files a restore would write and which existing files it would replace
+// en: The archive is only read, nothing is extracted
+func PlanRestore(project Project, opts RestoreOptions) (*RestorePlan, error) {
+	record, archivePath, err := selectRestore(project, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RestorePlan{Project: project.Name, Archive: archivePath, Record: record}
+	if !opts.SkipGitState {
+		plan.GitWork = record.GitWork
+	}
+
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("restore.openArchiveError"), err)
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	currentDir, err := filepath.Abs(project.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(currentDir); os.IsNotExist(err) {
+		plan.CreateDir = true
+	}
+
+	// ja: シンボリックリンクは Restore と同じく、ArchiveModeFull のマニフェストにあるものだけを数える
+	// en: As in Restore, only symlinks in the manifest of an ArchiveModeFull archive are counted
+	var links map[string]string
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == manifestEntryName {
+			if plan.Manifest, err = parseManifest(tarReader); err != nil {
+				return nil, err
+			}
+			if record.ArchiveMode == ArchiveModeFull {
+				links = plan.Manifest.linkTargets()
+			}
+			continue
+		}
+		name, ok := restoreEntryName(header.Name, plan.GitWork != nil)
+		if header.Typeflag == tar.TypeDir || !ok {
+			continue
+		}
+
+		targetPath, reason, err := restoreTarget(currentDir, name)
+		if reason == "" && header.Typeflag == tar.TypeSymlink {
+			reason, err = checkRestoredLink(currentDir, targetPath, name, header, links)
+		}
+		if reason != "" {
+			skipped := SkippedFile{Path: name, Reason: reason}
+			if err != nil {
+				skipped.Detail = err.Error()
+			}
+			plan.Skipped = append(plan.Skipped, skipped)
+			continue
+		}
+
+		_, statErr := os.Lstat(targetPath)
+		plan.Files = append(plan.Files, PlannedFile{Path: name, Size: header.Size, Overwrite: statErr == nil})
+	}
+
+	if !opts.SkipDumps {
+		for _, name := range record.Dumps {
+			for _, dump := range project.Dumps {
+				if dump.Name == name {
+					plan.Dumps = append(plan.Dumps, name)
+					break
+				}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// ja: selectRestore は復元するバックアップ記録とアーカイブのパスを選び、存在を確認します
+// en: selectRestore picks the backup record and archive path to restore and checks they exist
+func selectRestore(project Project, opts RestoreOptions) (BackupRecord, string, error) {
+	records, err := ListBackups(project, opts.Options)
+	if err != nil {
+		return BackupRecord{}, "", err
+	}
+
+	index := opts.Backup
+	if index == 0 {
+		index = 1
+	}
+	record, err := SelectBackup(records, index)
+	if err != nil {
+		return BackupRecord{}, "", err
+	}
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return BackupRecord{}, "", err
+	}
+	archivePath := filepath.Join(backupDir, record.Filename)
+
+	// ja: アーカイブファイルが存在するかチェック
+	// en: Check if archive file exists
+	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
+		return BackupRecord{}, "", &ArchiveNotFoundError{Filename: record.Filename}
+	}
+
+	// ja: ArchiveModeFull のバックアップはディレクトリを作り直せるので、存在しなくてもよい
+	// en: An ArchiveModeFull backup can rebuild the directory, so it need not exist
+	if _, err := os.Stat(project.Dir); os.IsNotExist(err) && record.ArchiveMode != ArchiveModeFull {
+		return BackupRecord{}, "", &ProjectDirNotFoundError{Dir: project.Dir}
+	}
+
+	return record, archivePath, nil
+}
@@ -0,0 +1,121 @@
+package toske
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlanBackup(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{
+		".env":          "TEST=value",
+		"config/a.conf": "a",
+	})
+	project.BackupPaths = []string{".env", "config", "missing.txt"}
+	project.Retention = 1
+
+	// ja: 保持件数 1 なので、既存のバックアップは次のバックアップで削除される
+	// en: With a retention of 1, the existing backup is pruned by the next one
+	existing, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	plan, err := PlanBackup(project, opts)
+	if err != nil {
+		t.Fatalf("PlanBackup failed: %v", err)
+	}
+
+	wantFiles := []ArchivedFile{{Path: ".env", Size: 10}, {Path: "config/a.conf", Size: 1}}
+	if !reflect.DeepEqual(plan.Files, wantFiles) {
+		t.Errorf("Expected files %+v, got %+v", wantFiles, plan.Files)
+	}
+	if plan.Size != 11 {
+		t.Errorf("Expected a total of 11 bytes, got %d", plan.Size)
+	}
+	if !reflect.DeepEqual(plan.Missing, []string{"missing.txt"}) {
+		t.Errorf("Expected missing.txt to be reported, got %v", plan.Missing)
+	}
+	if !reflect.DeepEqual(plan.Prune, []string{filepath.Base(existing.Archive)}) {
+		t.Errorf("Expected the existing backup to be pruned, got %v", plan.Prune)
+	}
+
+	// ja: 計画だけでは何も書き込まれない
+	// en: Planning alone writes nothing
+	records, err := ListBackups(project, opts)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("Expected PlanBackup to leave the backups untouched, got %+v", records)
+	}
+}
+
+func TestPlanRestore(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{
+		".env":          "TEST=value",
+		"config/a.conf": "a",
+	})
+	project.BackupPaths = []string{".env", "config"}
+
+	if _, err := Backup(context.Background(), project, opts); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := os.RemoveAll(filepath.Join(project.Dir, "config")); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(project.Dir, ".env"), []byte("CHANGED=1"), 0644); err != nil {
+		t.Fatalf("Failed to modify .env: %v", err)
+	}
+
+	plan, err := PlanRestore(project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("PlanRestore failed: %v", err)
+	}
+
+	wantFiles := []PlannedFile{
+		{Path: ".env", Size: 10, Overwrite: true},
+		{Path: "config/a.conf", Size: 1, Overwrite: false},
+	}
+	if !reflect.DeepEqual(plan.Files, wantFiles) {
+		t.Errorf("Expected files %+v, got %+v", wantFiles, plan.Files)
+	}
+	if plan.Overwrites() != 1 {
+		t.Errorf("Expected 1 overwrite, got %d", plan.Overwrites())
+	}
+
+	content, err := os.ReadFile(filepath.Join(project.Dir, ".env"))
+	if err != nil || string(content) != "CHANGED=1" {
+		t.Errorf("Expected PlanRestore to leave .env untouched, got %q (%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(project.Dir, "config")); !os.IsNotExist(err) {
+		t.Error("Expected PlanRestore not to create config/")
+	}
+}
+
+func TestPruneCandidates(t *testing.T) {
+	records := []BackupRecord{{Filename: "c"}, {Filename: "b"}, {Filename: "a"}}
+
+	tests := []struct {
+		keep int
+		want []string
+	}{
+		{keep: 0, want: []string{"c", "b", "a"}},
+		{keep: 2, want: []string{"a"}},
+		{keep: 3, want: nil},
+		{keep: 5, want: nil},
+		{keep: -1, want: nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, record := range pruneCandidates(records, tt.keep) {
+			got = append(got, record.Filename)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pruneCandidates(keep=%d) = %v, want %v", tt.keep, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package toske
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: archiveNamePattern は Backup が付けるアーカイブ名（backup_20060102_150405.000000.tar.gz）に一致します
+// ja: AddArchive が名前の重なりを避けるために付ける _2 などの接尾辞も受け付けます
+// en: archiveNamePattern matches the archive names given by Backup (backup_20060102_150405.000000.tar.gz)
+// en: The _2, ... suffixes AddArchive uses to avoid name clashes are accepted as well
+var archiveNamePattern = regexp.MustCompile(`^backup_(\d{8}_\d{6}(?:\.\d+)?)(?:_\d+)?\.tar\.gz$`)
+
+// ja: ReindexResult は Reindex で作り直したメタデータの内容です
+// en: ReindexResult describes the metadata rebuilt by Reindex
+type ReindexResult struct {
+	Project string `json:"project" yaml:"project"`
+	// ja: Backups は作り直したバックアップ記録です（新しい順）
+	// en: Backups are the rebuilt backup records (newest first)
+	Backups []BackupRecord `json:"backups" yaml:"backups"`
+	// ja: Added は以前のメタデータになかったアーカイブ、Dropped はアーカイブが見つからず取り除いた記録です
+	// en: Added are the archives missing from the previous metadata, Dropped the records whose archive is gone
+	Added   []string `json:"added" yaml:"added"`
+	Dropped []string `json:"dropped" yaml:"dropped"`
+	// ja: Unreadable は読み込めず、メタデータに含めなかったアーカイブです
+	// en: Unreadable are the archives that could not be read and were left out of the metadata
+	Unreadable []UnreadableArchive `json:"unreadable" yaml:"unreadable"`
+}
+
+// ja: UnreadableArchive は読み込めなかったアーカイブとその理由です
+// en: UnreadableArchive is an archive that could not be read and the reason
+type UnreadableArchive struct {
+	Filename string `json:"filename" yaml:"filename"`
+	Error    string `json:"error" yaml:"error"`
+}
+
+// ja: PlanReindex はバックアップディレクトリのアーカイブを読み、メタデータを作り直した結果を返します（書き込みはしません）
+// ja: 各アーカイブの記録は、ファイル名のタイムスタンプ（なければマニフェストまたは更新日時）とアーカイブ内のエントリから作ります
+// en: PlanReindex reads the archives in the backup directory and returns the rebuilt metadata (nothing is written)
+// en: Each record is built from the timestamp in the filename (or the manifest / modification time) and the entries in the archive
+func PlanReindex(project Project, opts Options) (*ReindexResult, error) {
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+		return nil, &NoBackupsError{Project: project.Name, DirMissing: true}
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// ja: 壊れたメタデータは作り直す対象なので、読めなくてもエラーにしない
+	// en: Broken metadata is what gets rebuilt, so failing to read it is not an error
+	previous, _ := LoadMetadata(backupDir)
+	known := make(map[string]bool, len(previous.Backups))
+	for _, record := range previous.Backups {
+		known[record.Filename] = true
+	}
+
+	result := &ReindexResult{Project: project.Name}
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+		found[entry.Name()] = true
+
+		record, err := ReadArchive(filepath.Join(backupDir, entry.Name()), project.BackupPaths)
+		if err != nil {
+			result.Unreadable = append(result.Unreadable, UnreadableArchive{Filename: entry.Name(), Error: err.Error()})
+			continue
+		}
+		result.Backups = append(result.Backups, record)
+		if !known[record.Filename] {
+			result.Added = append(result.Added, record.Filename)
+		}
+	}
+
+	for _, record := range previous.Backups {
+		if !found[record.Filename] {
+			result.Dropped = append(result.Dropped, record.Filename)
+		}
+	}
+
+	sort.SliceStable(result.Backups, func(i, j int) bool {
+		return result.Backups[i].Timestamp.After(result.Backups[j].Timestamp)
+	})
+
+	return result, nil
+}
+
+// ja: Reindex はバックアップディレクトリのアーカイブからメタデータ（backups.yaml）を作り直して保存します
+// ja: backups.yaml をなくした場合や、アーカイブを手でコピーした場合に使います
+// en: Reindex rebuilds the metadata (backups.yaml) from the archives in the backup directory and saves it
+// en: Use it when backups.yaml was lost or archives were copied in by hand
+func Reindex(project Project, opts Options) (*ReindexResult, error) {
+	result, err := PlanReindex(project, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := BackupMetadata{Project: project.Name, Backups: result.Backups}
+	if metadata.Backups == nil {
+		metadata.Backups = []BackupRecord{}
+	}
+	if err := SaveMetadata(backupDir, metadata); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ja: ReadArchive は tar.gz アーカイブのエントリを読み、バックアップ記録を作ります
+// ja: Files には backupPaths のうちアーカイブに含まれるものを、backupPaths に当てはまらないエントリは
+// ja: 先頭のディレクトリ名（またはファイル名）を記録します
+// en: ReadArchive reads the entries of a tar.gz archive and builds a backup record for it
+// en: Files lists the backupPaths found in the archive; entries outside backupPaths are recorded
+// en: by their top-level directory (or file) name
+func ReadArchive(archivePath string, backupPaths []string) (BackupRecord, error) {
+	record, compressed, err := readArchive(archivePath, backupPaths)
+	if err != nil {
+		return BackupRecord{}, err
+	}
+	if !compressed {
+		return BackupRecord{}, errors.New(i18n.T("reindex.notCompressed"))
+	}
+	return record, nil
+}
+
+// ja: readArchive は tar または tar.gz のアーカイブを読み、記録と gzip 圧縮されているかを返します
+// en: readArchive reads a tar or tar.gz archive and returns its record and whether it is gzip-compressed
+func readArchive(archivePath string, backupPaths []string) (BackupRecord, bool, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+
+	reader := bufio.NewReader(file)
+	compressed := isGzip(reader)
+	var tarSource io.Reader = reader
+	if compressed {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return BackupRecord{}, false, err
+		}
+		defer gzipReader.Close()
+		tarSource = gzipReader
+	}
+
+	record := BackupRecord{Filename: filepath.Base(archivePath), Files: []string{}}
+	manifest, err := readArchiveEntries(tar.NewReader(tarSource), backupPaths, &record)
+	if err != nil {
+		return BackupRecord{}, false, err
+	}
+
+	// ja: ファイル名にタイムスタンプがなければ、マニフェストの作成日時、アーカイブの更新日時の順に使う
+	// en: Without a timestamp in the filename, fall back to the manifest's creation time, then the archive's modification time
+	fallback := info.ModTime()
+	if manifest != nil && !manifest.Created.IsZero() {
+		fallback = manifest.Created
+	}
+	if manifest != nil {
+		record.Git = manifest.Git
+		record.ArchiveMode = manifest.ArchiveMode
+		if manifest.ArchiveMode == ArchiveModeFull {
+			record.Files = []string{fullArchivePath}
+		}
+	}
+	record.Timestamp = archiveTimestamp(record.Filename, fallback)
+	return record, compressed, nil
+}
+
+// ja: isGzip は reader の先頭が gzip のマジックナンバーかを返します（読み進めはしません）
+// en: isGzip reports whether reader starts with the gzip magic number (without consuming it)
+func isGzip(reader *bufio.Reader) bool {
+	magic, err := reader.Peek(2)
+	return err == nil && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// ja: readArchiveEntries は tr のエントリを最後まで読み、record の Files と Dumps を埋めます
+// ja: マニフェストがあれば、それも返します
+// en: readArchiveEntries reads tr to the end and fills in the Files and Dumps of record
+// en: The manifest is returned as well when there is one
+func readArchiveEntries(tr *tar.Reader, backupPaths []string, record *BackupRecord) (*Manifest, error) {
+	var manifest *Manifest
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(path.Clean(filepath.ToSlash(header.Name)), "./")
+		if name == manifestEntryName {
+			if manifest, err = parseManifest(tr); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if dump, ok := strings.CutPrefix(name+"/", dumpArchivePrefix); ok {
+			if dump = strings.TrimSuffix(dump, "/"); dump != "" && header.Typeflag != tar.TypeDir {
+				record.Dumps = append(record.Dumps, dump)
+			}
+			continue
+		}
+		if name == "." || strings.HasPrefix(name+"/", toskeArchivePrefix) {
+			continue
+		}
+
+		entry := recordedPath(name, backupPaths)
+		if !seen[entry] {
+			seen[entry] = true
+			record.Files = append(record.Files, entry)
+		}
+	}
+}
+
+// ja: recordedPath はアーカイブ内のエントリ name を、メタデータに記録する backup_paths のエントリに対応付けます
+// en: recordedPath maps the archive entry name to the backup_paths entry recorded in the metadata
+func recordedPath(name string, backupPaths []string) string {
+	for _, backupPath := range backupPaths {
+		cleaned := strings.TrimPrefix(path.Clean(filepath.ToSlash(backupPath)), "./")
+		if name == cleaned || strings.HasPrefix(name, cleaned+"/") {
+			return backupPath
+		}
+	}
+	top, _, _ := strings.Cut(name, "/")
+	return top
+}
+
+// ja: archiveTimestamp はアーカイブ名に含まれるタイムスタンプを返します（なければ modTime）
+// en: archiveTimestamp returns the timestamp in the archive name (modTime when there is none)
+func archiveTimestamp(name string, modTime time.Time) time.Time {
+	match := archiveNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return modTime
+	}
+	// ja: 秒の直後の小数部はレイアウトになくても解析される
+	// en: A fractional second right after the seconds is parsed even though the layout lacks it
+	timestamp, err := time.ParseInLocation("20060102_150405", match[1], time.Local)
+	if err != nil {
+		return modTime
+	}
+	return timestamp
+}
@@ -0,0 +1,151 @@
+package toske
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTar writes a plain tar file holding files to path.
+func writeTar(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create tar: %v", err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{
+		".env":          "TEST=value",
+		"config/a.conf": "a",
+	})
+	project.BackupPaths = []string{".env", "config"}
+
+	first, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	second, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	// ja: メタデータを失い、アーカイブを 1 つ削除し、壊れたファイルを置く
+	// en: Lose the metadata, remove one archive and drop in a broken file
+	backupDir := filepath.Dir(first.Archive)
+	stale := BackupMetadata{Project: project.Name, Backups: []BackupRecord{first.Record, {Filename: "backup_gone.tar.gz"}}}
+	if err := SaveMetadata(backupDir, stale); err != nil {
+		t.Fatalf("SaveMetadata failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, "broken.tar.gz"), []byte("not an archive"), 0644); err != nil {
+		t.Fatalf("Failed to write broken archive: %v", err)
+	}
+
+	result, err := Reindex(project, opts)
+	if err != nil {
+		t.Fatalf("Reindex failed: %v", err)
+	}
+
+	if len(result.Backups) != 2 || result.Backups[0].Filename != second.Record.Filename {
+		t.Fatalf("Expected both archives, newest first, got %+v", result.Backups)
+	}
+	// ja: ファイル名のタイムスタンプはマイクロ秒まで
+	// en: The timestamp in the filename is only precise to the microsecond
+	if want := first.Record.Timestamp.Truncate(time.Microsecond); !result.Backups[1].Timestamp.Equal(want) {
+		t.Errorf("Expected the timestamp %v from the filename, got %v", want, result.Backups[1].Timestamp)
+	}
+	if !reflect.DeepEqual(result.Backups[0].Files, []string{".env", "config"}) {
+		t.Errorf("Expected the backup_paths to be recorded, got %v", result.Backups[0].Files)
+	}
+	if !reflect.DeepEqual(result.Added, []string{second.Record.Filename}) {
+		t.Errorf("Expected the second archive to be added, got %v", result.Added)
+	}
+	if !reflect.DeepEqual(result.Dropped, []string{"backup_gone.tar.gz"}) {
+		t.Errorf("Expected the missing archive to be dropped, got %v", result.Dropped)
+	}
+	if len(result.Unreadable) != 1 || result.Unreadable[0].Filename != "broken.tar.gz" {
+		t.Errorf("Expected broken.tar.gz to be reported, got %+v", result.Unreadable)
+	}
+
+	records, err := ListBackups(project, opts)
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("Expected the rebuilt metadata to be saved, got %+v", records)
+	}
+}
+
+func TestImportArchivePlainTar(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{})
+	project.BackupPaths = []string{"storage/app"}
+
+	source := filepath.Join(t.TempDir(), "handmade.tar")
+	writeTar(t, source, map[string]string{"storage/app/db.sqlite3": "db", ".toske/dumps/main.sql": "dump"})
+	modTime := time.Date(2025, 5, 4, 3, 2, 1, 0, time.Local)
+	if err := os.Chtimes(source, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	record, added, err := ImportArchive(project, source, opts)
+	if err != nil || !added {
+		t.Fatalf("ImportArchive failed: %v (added=%v)", err, added)
+	}
+	if record.Filename != "backup_20250504_030201.000000.tar.gz" || !record.Timestamp.Equal(modTime) {
+		t.Errorf("Expected the archive to be named after its mtime, got %+v", record)
+	}
+	if !reflect.DeepEqual(record.Files, []string{"storage/app"}) || !reflect.DeepEqual(record.Dumps, []string{"main.sql"}) {
+		t.Errorf("Unexpected contents: files %v, dumps %v", record.Files, record.Dumps)
+	}
+
+	// ja: 取り込んだアーカイブは gzip 圧縮され、そのまま読める
+	// en: The adopted archive is gzip-compressed and readable as is
+	backupDir, _ := opts.BackupDir(project.Name)
+	if _, err := ReadArchive(filepath.Join(backupDir, record.Filename), project.BackupPaths); err != nil {
+		t.Errorf("Expected the stored archive to be a valid tar.gz, got %v", err)
+	}
+
+	if _, added, err := ImportArchive(project, source, opts); err != nil || added {
+		t.Errorf("Expected importing the same file again to be a no-op, got %v, %v", added, err)
+	}
+}
+
+func TestArchiveTimestamp(t *testing.T) {
+	modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		want time.Time
+	}{
+		{name: "backup_20250601_120000.123456.tar.gz", want: time.Date(2025, 6, 1, 12, 0, 0, 123456000, time.Local)},
+		{name: "backup_20250601_120000.tar.gz", want: time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)},
+		{name: "backup_20250601_120000.123456_2.tar.gz", want: time.Date(2025, 6, 1, 12, 0, 0, 123456000, time.Local)},
+		{name: "env-backup.tar.gz", want: modTime},
+		{name: "backup_2025.tar.gz", want: modTime},
+	}
+
+	for _, tt := range tests {
+		if got := archiveTimestamp(tt.name, modTime); !got.Equal(tt.want) {
+			t.Errorf("archiveTimestamp(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: スキップ理由のコード（スクリプトから判定しやすいよう固定の文字列）
+// en: Skip reason codes (fixed strings so scripts can match on them)
+const (
+	SkipAbsolutePath = "absolute_path"
+	SkipOutsideDir   = "outside_restore_dir"
+	SkipSymlink      = "symlink_outside_restore_dir"
+	SkipMkdirFailed  = "mkdir_failed"
+	SkipCreateFailed = "create_failed"
+	SkipCopyFailed   = "copy_failed"
+	// ja: SkipChecksumMismatch は内容がマニフェストの SHA-256 と一致しなかったことを表します
+	// en: SkipChecksumMismatch means the content did not match the SHA-256 in the manifest
+	SkipChecksumMismatch = "checksum_mismatch"
+	// ja: SkipUnverifiedLink はマニフェストのある ArchiveModeFull のアーカイブ以外にあったシンボリックリンクを表します
+	// en: SkipUnverifiedLink means a symlink found outside an ArchiveModeFull archive with a manifest
+	SkipUnverifiedLink = "unverified_symlink"
+)
+
+// ja: RestoreOptions は Restore のオプションです
+// en: RestoreOptions holds the options of Restore
+type RestoreOptions struct {
+	Options
+	// ja: Backup は復元するバックアップの番号です（1 始まり、1 が最新、0 は最新）
+	// en: Backup is the number of the backup to restore (1-based, 1 is the newest, 0 means the newest)
+	Backup int
+	// ja: SkipDumps が true の場合、データベースダンプは復元しません
+	// en: When SkipDumps is true, database dumps are not restored
+	SkipDumps bool
+	// ja: SkipGitState が true の場合、include_git_state で保存した git の作業は戻しません
+	// en: When SkipGitState is true, the git work saved for include_git_state is not put back
+	SkipGitState bool
+}
+
+// ja: RestoreResult は Restore の結果です
+// en: RestoreResult is the outcome of Restore
+type RestoreResult struct {
+	Project string        `json:"project" yaml:"project"`
+	Archive string        `json:"archive" yaml:"archive"`
+	Files   []string      `json:"files" yaml:"files"`
+	Skipped []SkippedFile `json:"skipped" yaml:"skipped"`
+	Dumps   int           `json:"dumps" yaml:"dumps"`
+	// ja: Manifest はアーカイブに埋め込まれたマニフェストです（ない場合は nil）
+	// en: Manifest is the manifest embedded in the archive (nil when there is none)
+	Manifest *Manifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+	// ja: GitWork は戻した git の作業です（保存されていない場合や SkipGitState の場合は nil）
+	// en: GitWork is the git work put back (nil when none was saved or with SkipGitState)
+	GitWork *GitWorkResult `json:"git_work,omitempty" yaml:"git_work,omitempty"`
+	Record  BackupRecord   `json:"-" yaml:"-"`
+}
+
+// ja: SkippedFile は復元されなかったファイルとその理由です
+// en: SkippedFile is a file that was not restored, with the reason why
+type SkippedFile struct {
+	Path   string `json:"path" yaml:"path"`
+	Reason string `json:"reason" yaml:"reason"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// ja: restoreTempPattern は復元中のファイルを書き込む一時ファイルの名前のパターンです
+// en: restoreTempPattern is the name pattern of the temp files restored files are written to
+const restoreTempPattern = ".toske-restore-*"
+
+// ja: extractResult は extractArchive の結果です
+// en: extractResult is the outcome of extractArchive
+type extractResult struct {
+	Files    []string
+	Skipped  []SkippedFile
+	Manifest *Manifest
+}
+
+// ja: Restore はバックアップをプロジェクトのディレクトリに展開し、ダンプを流し込みます
+// ja: ctx がキャンセルされると次のファイルに進まずに止まり、それまでに書き込んだファイルを
+// ja: 結果として返します（書き込み途中のファイルは既存のファイルを置き換えません）
+// en: Restore extracts a backup into the project directory and feeds its dumps back
+// en: When ctx is cancelled it stops before the next file and returns the files written so far
+// en: (a file that was being written does not replace the existing one)
+func Restore(ctx context.Context, project Project, opts RestoreOptions) (*RestoreResult, error) {
+	record, archivePath, err := selectRestore(project, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{
+		Project: project.Name,
+		Archive: archivePath,
+		Record:  record,
+	}
+
+	// ja: ArchiveModeFull のバックアップでは、アーカイブだけでディレクトリを作り直す
+	// en: For an ArchiveModeFull backup, rebuild the directory from the archive alone
+	full := record.ArchiveMode == ArchiveModeFull
+	if full {
+		if err := os.MkdirAll(project.Dir, 0755); err != nil {
+			return result, fmt.Errorf(i18n.T("restore.createProjectDirError"), project.Dir, err)
+		}
+	}
+
+	// ja: 中断やエラーで止まった場合も、それまでに書き込んだファイルを結果として返す
+	// en: Even when stopped by an interruption or error, return the files written so far
+	gitWork := record.GitWork != nil && !opts.SkipGitState
+	extracted, err := extractArchive(ctx, archivePath, project.Dir, gitWork, full, opts.Options)
+	if extracted != nil {
+		result.Files = extracted.Files
+		result.Skipped = extracted.Skipped
+		result.Manifest = extracted.Manifest
+	}
+	if err != nil {
+		return result, fmt.Errorf(i18n.T("restore.extractError"), err)
+	}
+
+	// ja: データベースダンプを復元（SkipDumps が指定されていない場合）
+	// en: Restore database dumps (unless SkipDumps is set)
+	if len(record.Dumps) > 0 && !opts.SkipDumps {
+		result.Dumps, err = restoreDumps(ctx, archivePath, project.Dumps, opts.Options)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// ja: 未追跡のファイルは展開済みなので、ブランチ・stash・変更を戻す
+	// en: The untracked files are already extracted, so put back the branches, stashes and changes
+	if gitWork {
+		opts.emit(Event{Kind: EventGitStateRestoring})
+		result.GitWork, err = restoreGitWork(ctx, archivePath, project.Dir, opts.Options)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// ja: extractArchive はバックアップアーカイブを targetDir に展開します
+// ja: 先頭のマニフェストはファイルとして展開せず、記録された SHA-256 と一致しないファイルは書き込みません
+// en: extractArchive extracts a backup archive into targetDir
+// en: The leading manifest is not extracted as a file, and files that do not match its SHA-256 are not written
+// ja: untracked が true の場合は、include_git_state で保存した未追跡のファイルも元の場所に展開します
+// en: When untracked is true, the untracked files saved for include_git_state are extracted to where they were too
+// ja: シンボリックリンクは full（ArchiveModeFull のアーカイブ）の場合だけ作ります
+// en: Symlinks are only made when full is true (an ArchiveModeFull archive)
+func extractArchive(ctx context.Context, archivePath, targetDir string, untracked, full bool, opts Options) (*extractResult, error) {
+	// ja: アーカイブファイルを開く
+	// en: Open archive file
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.T("restore.openArchiveError"), err)
+	}
+	defer archiveFile.Close()
+
+	// ja: 進捗はアーカイブ（圧縮後）を読み込んだバイト数で数える
+	// en: Progress is counted in bytes of the (compressed) archive read
+	counter := &byteCounter{opts: opts}
+	if info, err := archiveFile.Stat(); err == nil {
+		counter.total = info.Size()
+	}
+
+	// ja: gzip リーダーを作成
+	// en: Create gzip reader
+	gzipReader, err := gzip.NewReader(progressReader{r: archiveFile, counter: counter})
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	// ja: tar リーダーを作成
+	// en: Create tar reader
+	tarReader := tar.NewReader(gzipReader)
+
+	// ja: 展開先ディレクトリを絶対パスに正規化
+	// en: Normalize the target directory to an absolute path
+	currentDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &extractResult{}
+	skip := func(name, reason string, err error) {
+		skipped := SkippedFile{Path: name, Reason: reason}
+		if err != nil {
+			skipped.Detail = err.Error()
+		}
+		result.Skipped = append(result.Skipped, skipped)
+		opts.emit(Event{Kind: EventFileSkipped, Path: name, Reason: reason, Err: err})
+	}
+
+	// ja: アーカイブ内の各ファイルを処理
+	// en: Process each file in the archive
+	var hashes, links map[string]string
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if header.Name == manifestEntryName {
+			if result.Manifest, err = parseManifest(tarReader); err != nil {
+				return result, err
+			}
+			hashes = result.Manifest.fileHashes()
+			if full {
+				links = result.Manifest.linkTargets()
+			}
+			continue
+		}
+
+		// ja: toske 内部のエントリ（ダンプなど）はファイルとして展開しない
+		// en: Do not extract toske-internal entries (dumps, etc.) as files
+		name, ok := restoreEntryName(header.Name, untracked)
+		if !ok {
+			continue
+		}
+
+		targetPath, reason, err := restoreTarget(currentDir, name)
+		if reason != "" {
+			skip(name, reason, err)
+			continue
+		}
+
+		// ja: ディレクトリのエントリ（ArchiveModeFull のアーカイブにある）は、空のディレクトリも残るよう作成だけする
+		// ja: その他のディレクトリはファイル作成時に自動的に作成される
+		// en: Directory entries (found in ArchiveModeFull archives) are only created, so that empty directories come back too
+		// en: Other directories are created automatically when creating files
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(targetPath, 0755); err != nil {
+				skip(name, SkipMkdirFailed, err)
+			}
+			continue
+		}
+
+		opts.emit(Event{Kind: EventFileExtracting, Path: name, Size: header.Size})
+
+		targetDir := filepath.Dir(targetPath)
+
+		// ja: ディレクトリを作成
+		// en: Create directory
+		if err := os.MkdirAll(targetDir, 0755); err != nil {
+			// ja: ディレクトリ作成エラーの場合、このファイルをスキップして次へ
+			// en: Skip this file if directory creation fails and continue with next
+			skip(name, SkipMkdirFailed, err)
+			continue
+		}
+
+		// ja: シンボリックリンク（ArchiveModeFull のアーカイブにある）は、マニフェストのリンク先と一致する場合だけ作る
+		// en: Symlinks (found in ArchiveModeFull archives) are only made when they match the target in the manifest
+		// ja: 置き換えるのはリンク自体なので、親ディレクトリだけを再検証する
+		// en: It is the link itself that gets replaced, so only its parent directory is re-validated
+		if header.Typeflag == tar.TypeSymlink {
+			if err := validatePathNoSymlinks(currentDir, targetDir); err != nil {
+				skip(name, SkipSymlink, err)
+				continue
+			}
+			if reason, err := checkRestoredLink(currentDir, targetPath, name, header, links); reason != "" {
+				skip(name, reason, err)
+				continue
+			}
+			if err := writeRestoredLink(targetPath, header.Linkname); err != nil {
+				skip(name, SkipCreateFailed, err)
+				continue
+			}
+			result.Files = append(result.Files, name)
+			continue
+		}
+
+		// ja: ファイルパス全体を再検証（MkdirAll後の安全性確認）
+		// en: Re-validate full file path after directory creation for additional safety
+		if err := validatePathNoSymlinks(currentDir, targetPath); err != nil {
+			skip(name, SkipSymlink, err)
+			continue
+		}
+
+		// ja: 同じディレクトリの一時ファイルに書き込んでから置き換える
+		// ja: 途中で中断・失敗しても既存のファイルが中途半端な内容で上書きされない
+		// en: Write to a temp file in the same directory, then move it into place
+		// en: An interruption or failure part way never leaves an existing file half-written
+		if reason, err := writeRestoredFile(ctx, targetPath, tarReader, os.FileMode(header.Mode), name, hashes[header.Name], opts); err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			skip(name, reason, err)
+			continue
+		}
+
+		result.Files = append(result.Files, name)
+	}
+
+	return result, nil
+}
+
+// ja: restoreEntryName はアーカイブのエントリを展開する名前を返します
+// ja: toske 内部のエントリは展開しませんが、untracked が true の場合は未追跡のファイルを元の名前で展開します
+// en: restoreEntryName returns the name an archive entry is extracted as
+// en: toske-internal entries are not extracted, except that with untracked the untracked files are, under their original names
+func restoreEntryName(name string, untracked bool) (string, bool) {
+	if original, ok := strings.CutPrefix(name, untrackedArchivePrefix); ok {
+		return original, untracked
+	}
+	return name, !strings.HasPrefix(name, toskeArchivePrefix)
+}
+
+// ja: restoreTarget はアーカイブ内のエントリ名から書き込み先のパスを決めます
+// ja: 安全に書き込めない場合はスキップ理由のコード（と原因のエラー）を返します
+// en: restoreTarget works out where an archive entry would be written
+// en: When it cannot be written safely, it returns the skip reason code (and the underlying error)
+func restoreTarget(currentDir, name string) (string, string, error) {
+	// ja: セキュリティチェック：絶対パスとパストラバーサル攻撃を防ぐ
+	// en: Security check: prevent absolute paths and path traversal attacks
+	if filepath.IsAbs(name) {
+		return "", SkipAbsolutePath, nil
+	}
+
+	// ja: ファイルパスを決定
+	// en: Determine file path
+	targetPath := filepath.Join(currentDir, name)
+
+	// ja: 相対パスでの追加セキュリティチェック
+	// en: Additional security check with relative path
+	relPath, err := filepath.Rel(currentDir, targetPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", SkipOutsideDir, nil
+	}
+
+	// ja: シンボリックリンク攻撃を防ぐため、ディレクトリパスを事前に検証
+	// en: Validate directory path before creation to prevent symlink attacks
+	if err := validatePathNoSymlinks(currentDir, filepath.Dir(targetPath)); err != nil {
+		return "", SkipSymlink, err
+	}
+
+	return targetPath, "", nil
+}
+
+// ja: checkRestoredLink はシンボリックリンクのエントリを targetPath に作ってよいかを確かめます
+// ja: links（マニフェストのリンク先）に記録されていないリンクや、絶対パス・currentDir の外を指すリンクは作りません
+// ja: 作れない場合はスキップ理由のコード（と原因のエラー）を返します
+// en: checkRestoredLink checks whether a symlink entry may be made at targetPath
+// en: Links missing from links (the targets in the manifest), and links that are absolute or point outside currentDir, are not made
+// en: When it cannot be made, it returns the skip reason code (and the underlying error)
+func checkRestoredLink(currentDir, targetPath, name string, header *tar.Header, links map[string]string) (string, error) {
+	if links == nil {
+		return SkipUnverifiedLink, nil
+	}
+	if target, ok := links[header.Name]; !ok || target != header.Linkname {
+		return SkipChecksumMismatch, &ChecksumMismatchError{Path: name}
+	}
+
+	if filepath.IsAbs(header.Linkname) {
+		return SkipSymlink, fmt.Errorf("%s", i18n.T("restore.symlinkOutsideDir"))
+	}
+	relPath, err := filepath.Rel(currentDir, filepath.Join(filepath.Dir(targetPath), header.Linkname))
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return SkipSymlink, fmt.Errorf("%s", i18n.T("restore.symlinkOutsideDir"))
+	}
+	return "", nil
+}
+
+// ja: writeRestoredFile は r の内容を一時ファイルに書き込み、パーミッションを設定してから targetPath に移動します
+// ja: sum が空でなければ、内容の SHA-256 が一致しない場合は既存のファイルを置き換えません
+// ja: 失敗した場合はスキップ理由のコードとエラーを返します
+// en: writeRestoredFile writes r to a temp file, sets its permissions and then moves it to targetPath
+// en: When sum is set, a content whose SHA-256 does not match it does not replace the existing file
+// en: On failure it returns the skip reason code along with the error
+func writeRestoredFile(ctx context.Context, targetPath string, r io.Reader, mode os.FileMode, name, sum string, opts Options) (string, error) {
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), restoreTempPattern)
+	if err != nil {
+		return SkipCreateFailed, err
+	}
+	tmpPath := tmpFile.Name()
+
+	hash := sha256.New()
+	if _, err := io.Copy(tmpFile, io.TeeReader(contextReader{ctx: ctx, r: r}, hash)); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return SkipCopyFailed, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return SkipCopyFailed, err
+	}
+	if sum != "" && hex.EncodeToString(hash.Sum(nil)) != sum {
+		os.Remove(tmpPath)
+		return SkipChecksumMismatch, &ChecksumMismatchError{Path: name}
+	}
+
+	// ja: パーミッション設定エラー - 通知するが、ファイルは保持してカウントする
+	// en: Chmod error - report it but keep and count the file
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		opts.emit(Event{Kind: EventChmodFailed, Path: name, Err: err})
+	}
+
+	// ja: 中断された場合は既存のファイルを置き換えない
+	// en: Once interrupted, do not replace the existing file
+	if err := ctx.Err(); err != nil {
+		os.Remove(tmpPath)
+		return SkipCopyFailed, err
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.Remove(tmpPath)
+		return SkipCreateFailed, err
+	}
+	return "", nil
+}
+
+// ja: writeRestoredLink は target を指すシンボリックリンクを一時的な名前で作り、targetPath に移動します
+// ja: 既存のファイルやシンボリックリンクは置き換えますが、ディレクトリは置き換えません
+// en: writeRestoredLink makes a symlink to target under a temp name and then moves it to targetPath
+// en: An existing file or symlink is replaced, but a directory is not
+func writeRestoredLink(targetPath, target string) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), restoreTempPattern)
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	tmpFile.Close()
+	os.Remove(tmpPath)
+
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// ja: validatePathNoSymlinks はパスにシンボリックリンクが含まれていないことを検証します
+// en: validatePathNoSymlinks validates that the path contains no symlinks
+func validatePathNoSymlinks(baseDir, targetPath string) error {
+	// ja: シンボリックリンクを解決して実際のパスを取得
+	// en: Resolve symlinks to get the actual path
+	resolvedPath, err := filepath.EvalSymlinks(targetPath)
+	if err != nil {
+		// ja: パスが存在しない場合、親ディレクトリまで確認
+		// en: If path doesn't exist, check parent directory
+		parentDir := filepath.Dir(targetPath)
+		if parentDir == targetPath {
+			return nil // Root directory reached
+		}
+		return validatePathNoSymlinks(baseDir, parentDir)
+	}
+
+	// ja: 解決されたパスが base ディレクトリ内にあることを確認
+	// en: Ensure resolved path is within base directory
+	// ja: base ディレクトリがまだない場合（作り直す前の計画など）は、途中にシンボリックリンクもない
+	// en: When the base directory does not exist yet (planning before it is rebuilt), no symlink can be in the way either
+	resolvedBase, err := filepath.EvalSymlinks(baseDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	relPath, err := filepath.Rel(resolvedBase, resolvedPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return fmt.Errorf("%s", i18n.T("restore.symlinkOutsideDir"))
+	}
+
+	return nil
+}
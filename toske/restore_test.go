@@ -0,0 +1,315 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExtractBackupArchive(t *testing.T) {
+	// Setup temporary directory
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+
+	// Create a test archive
+	archivePath := filepath.Join(tempDir, "test.tar.gz")
+	testFiles := []testFile{
+		{name: ".env", content: "TEST=value"},
+		{name: "config/app.conf", content: "config content"},
+	}
+
+	if err := createTestArchive(archivePath, testFiles); err != nil {
+		t.Fatalf("Failed to create test archive: %v", err)
+	}
+
+	// Change to work directory
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	// Extract archive
+	result, err := extractArchive(context.Background(), archivePath, workDir, false, false, Options{})
+	if err != nil {
+		t.Fatalf("Failed to extract archive: %v", err)
+	}
+
+	if len(result.Files) != len(testFiles) {
+		t.Errorf("Expected %d files to be extracted, got %d", len(testFiles), len(result.Files))
+	}
+
+	// Verify extracted files
+	for _, tf := range testFiles {
+		filePath := filepath.Join(workDir, tf.name)
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			t.Errorf("Failed to read extracted file %s: %v", tf.name, err)
+			continue
+		}
+
+		if string(data) != tf.content {
+			t.Errorf("File %s content mismatch. Expected: %s, Got: %s", tf.name, tf.content, string(data))
+		}
+	}
+}
+
+func TestExtractBackupArchivePathTraversal(t *testing.T) {
+	// Setup temporary directory
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	siblingDir := filepath.Join(tempDir, "sibling")
+
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+	if err := os.MkdirAll(siblingDir, 0755); err != nil {
+		t.Fatalf("Failed to create sibling directory: %v", err)
+	}
+
+	// Create an archive with path traversal attempts
+	archivePath := filepath.Join(tempDir, "malicious.tar.gz")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive file: %v", err)
+	}
+	defer archiveFile.Close()
+
+	gzipWriter := gzip.NewWriter(archiveFile)
+	defer gzipWriter.Close()
+
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	// Attempt various path traversal attacks
+	maliciousFiles := []struct {
+		name    string
+		content string
+	}{
+		{name: "../escape.txt", content: "escaped"},
+		{name: "../../escape2.txt", content: "escaped2"},
+		{name: "../sibling/attack.txt", content: "attacked"},
+		{name: filepath.Join(tempDir, "absolute_attack.txt"), content: "absolute path attack"},
+	}
+
+	for _, mf := range maliciousFiles {
+		header := &tar.Header{
+			Name:    mf.name,
+			Size:    int64(len(mf.content)),
+			Mode:    0644,
+			ModTime: time.Now(),
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tarWriter.Write([]byte(mf.content)); err != nil {
+			t.Fatalf("Failed to write tar content: %v", err)
+		}
+	}
+
+	tarWriter.Close()
+	gzipWriter.Close()
+	archiveFile.Close()
+
+	// Change to work directory
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	// Extract archive - should skip all malicious files
+	result, err := extractArchive(context.Background(), archivePath, workDir, false, false, Options{})
+	if err != nil {
+		t.Fatalf("Failed to extract archive: %v", err)
+	}
+
+	// Should extract 0 files (all were malicious)
+	if len(result.Files) != 0 {
+		t.Errorf("Expected 0 files to be extracted (all malicious), got %d", len(result.Files))
+	}
+
+	// Verify no files escaped the work directory
+	escapedFiles := []string{
+		filepath.Join(tempDir, "escape.txt"),
+		filepath.Join(tempDir, "escape2.txt"),
+		filepath.Join(siblingDir, "attack.txt"),
+		filepath.Join(tempDir, "absolute_attack.txt"),
+	}
+
+	for _, escapedFile := range escapedFiles {
+		data, err := os.ReadFile(escapedFile)
+		if err == nil {
+			t.Errorf("Security vulnerability: file escaped to %s with content: %s", escapedFile, string(data))
+		}
+	}
+
+	// Verify work directory is still empty
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatalf("Failed to read work directory: %v", err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("Expected work directory to be empty, but found %d entries", len(entries))
+	}
+}
+
+func TestExtractBackupArchiveSymlinkAttack(t *testing.T) {
+	// Setup temporary directory
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	attackTarget := filepath.Join(tempDir, "attack_target")
+
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("Failed to create work directory: %v", err)
+	}
+	if err := os.MkdirAll(attackTarget, 0755); err != nil {
+		t.Fatalf("Failed to create attack target directory: %v", err)
+	}
+
+	// Create a symlink in work directory pointing to attack target
+	symlinkPath := filepath.Join(workDir, "backup")
+	if err := os.Symlink(attackTarget, symlinkPath); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	// Create an archive that tries to write through the symlink
+	archivePath := filepath.Join(tempDir, "symlink_attack.tar.gz")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive file: %v", err)
+	}
+	defer archiveFile.Close()
+
+	gzipWriter := gzip.NewWriter(archiveFile)
+	defer gzipWriter.Close()
+
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	// Try to write a file through the symlink
+	maliciousContent := "SYMLINK ATTACK SUCCESS"
+	header := &tar.Header{
+		Name:    "backup/passwd",
+		Size:    int64(len(maliciousContent)),
+		Mode:    0644,
+		ModTime: time.Now(),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		t.Fatalf("Failed to write tar header: %v", err)
+	}
+	if _, err := tarWriter.Write([]byte(maliciousContent)); err != nil {
+		t.Fatalf("Failed to write tar content: %v", err)
+	}
+
+	tarWriter.Close()
+	gzipWriter.Close()
+	archiveFile.Close()
+
+	// Change to work directory
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	// Extract archive - should skip the file due to symlink
+	result, err := extractArchive(context.Background(), archivePath, workDir, false, false, Options{})
+	if err != nil {
+		t.Fatalf("Failed to extract archive: %v", err)
+	}
+
+	// Should extract 0 files (symlink should be detected)
+	if len(result.Files) != 0 {
+		t.Errorf("Expected 0 files to be extracted (symlink detected), got %d", len(result.Files))
+	}
+
+	// Verify the attack target was not modified
+	attackFile := filepath.Join(attackTarget, "passwd")
+	if data, err := os.ReadFile(attackFile); err == nil {
+		t.Errorf("Security vulnerability: symlink was followed, file created with content: %s", string(data))
+	}
+
+	// Verify symlink still exists and wasn't replaced
+	linkInfo, err := os.Lstat(symlinkPath)
+	if err != nil {
+		t.Fatalf("Symlink was removed or modified: %v", err)
+	}
+	if linkInfo.Mode()&os.ModeSymlink == 0 {
+		t.Error("Symlink was replaced with a regular file or directory")
+	}
+}
+
+// Helper types and functions
+
+type testFile struct {
+	name    string
+	content string
+}
+
+// createTestArchive creates a tar.gz archive with the specified files
+func createTestArchive(archivePath string, files []testFile) error {
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+
+	gzipWriter := gzip.NewWriter(archiveFile)
+	defer gzipWriter.Close()
+
+	tarWriter := tar.NewWriter(gzipWriter)
+	defer tarWriter.Close()
+
+	for _, f := range files {
+		// Create directory for file if needed
+		if dir := filepath.Dir(f.name); dir != "." {
+			header := &tar.Header{
+				Name:     dir + "/",
+				Typeflag: tar.TypeDir,
+				Mode:     0755,
+				ModTime:  time.Now(),
+			}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+		}
+
+		// Add file
+		header := &tar.Header{
+			Name:    filepath.ToSlash(f.name),
+			Size:    int64(len(f.content)),
+			Mode:    0644,
+			ModTime: time.Now(),
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err := tarWriter.Write([]byte(f.content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package toske backs up and restores the files a project keeps outside of git
+// (.env files, local databases, database dumps) as tar.gz archives.
+//
+// ja: toske コマンドはこのパッケージの薄いラッパーです。Go のプログラムからは
+// ja: Backup、Restore、ListBackups、Prune を直接呼び出せます。進捗は Options.Progress に
+// ja: イベントとして通知され、失敗は errors.As で判定できる型付きのエラーで返ります。
+// en: The toske command is a thin layer on top of this package; Go programs can call
+// en: Backup, Restore, ListBackups and Prune directly. Progress is reported as events
+// en: through Options.Progress, and failures are returned as typed errors that can be
+// en: matched with errors.As.
+package toske
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// ja: Project はバックアップ・復元の対象となるプロジェクトです
+// ja: 設定ファイルの内容から、バックアップに必要な部分だけを取り出したものです
+// en: Project is a project to back up or restore
+// en: It carries only the parts of the configuration that backups need
+type Project struct {
+	Name string
+	// ja: Dir はプロジェクトのローカルディレクトリです（backup_paths の基準）
+	// en: Dir is the local directory of the project (backup_paths are relative to it)
+	Dir         string
+	BackupPaths []string
+	// ja: Retention は保持するバックアップの件数です（0 は無制限）
+	// en: Retention is the number of backups to keep (0 keeps all of them)
+	Retention int
+	Dumps     []Dump
+	// ja: Repo と Branch は設定の値で、アーカイブのマニフェストに記録されます
+	// en: Repo and Branch are the configured values, recorded in the archive manifest
+	Repo   string
+	Branch string
+	// ja: Git はバックアップ時の Dir のチェックアウトの状態で、記録とマニフェストに残ります（分からない場合は nil）
+	// en: Git is the state of the checkout in Dir at backup time, kept in the record and the manifest (nil when unknown)
+	Git *GitState
+	// ja: IncludeGitState が true の場合、リモートにないブランチ、stash、コミットされていない変更と
+	// ja: 未追跡のファイルもアーカイブに保存します（Dir は git リポジトリである必要があります）
+	// en: When IncludeGitState is true, branches not on any remote, stashes, uncommitted changes and
+	// en: untracked files are saved in the archive as well (Dir must be a git repository)
+	IncludeGitState bool
+	// ja: ArchiveMode はアーカイブする範囲です（空または ArchiveModePaths は backup_paths、ArchiveModeFull は Dir 全体）
+	// en: ArchiveMode is what gets archived (the backup_paths when empty or ArchiveModePaths, the whole Dir with ArchiveModeFull)
+	ArchiveMode string
+	// ja: Exclude は ArchiveModeFull でアーカイブしないパスのパターンです（ビルドの出力など）
+	// en: Exclude holds the patterns of paths left out with ArchiveModeFull (build outputs and so on)
+	Exclude []string
+}
+
+// ja: Options は全ての操作に共通するオプションです
+// en: Options holds the options shared by every operation
+type Options struct {
+	// ja: BackupRoot は全プロジェクトのバックアップディレクトリを格納するディレクトリです
+	// ja: 空の場合は DefaultBackupRoot を使用します
+	// en: BackupRoot is the directory holding the backup directories of all projects
+	// en: DefaultBackupRoot is used when it is empty
+	BackupRoot string
+	// ja: Runner はダンプの外部コマンドを実行します（nil の場合は ExecRunner）
+	// en: Runner runs the external commands of dumps (ExecRunner when nil)
+	Runner Runner
+	// ja: Progress は進捗イベントを受け取ります（nil の場合は通知しません）
+	// en: Progress receives progress events (nothing is reported when nil)
+	Progress func(Event)
+	// ja: Build はアーカイブのマニフェストに記録する toske のビルドです
+	// en: Build is the toske build recorded in archive manifests
+	Build BuildInfo
+}
+
+// ja: DefaultBackupRoot は既定のバックアップディレクトリ（~/.config/toske/backups）を返します
+// en: DefaultBackupRoot returns the default backup directory (~/.config/toske/backups)
+func DefaultBackupRoot() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".config", "toske", "backups"), nil
+}
+
+// ja: BackupDir はプロジェクトのバックアップディレクトリを返します
+// en: BackupDir returns the backup directory of a project
+func (o Options) BackupDir(projectName string) (string, error) {
+	rootDir := o.BackupRoot
+	if rootDir == "" {
+		var err error
+		if rootDir, err = DefaultBackupRoot(); err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(rootDir, projectName), nil
+}
+
+func (o Options) runner() Runner {
+	if o.Runner == nil {
+		return ExecRunner{}
+	}
+	return o.Runner
+}
+
+func (o Options) emit(event Event) {
+	if o.Progress != nil {
+		o.Progress(event)
+	}
+}
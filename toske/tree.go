@@ -0,0 +1,333 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yk-lab/toske/i18n"
+)
+
+// ja: アーカイブする範囲（Project.ArchiveMode）
+// en: What gets archived (Project.ArchiveMode)
+const (
+	// ja: ArchiveModePaths は backup_paths だけをアーカイブします（空の場合も同じ）
+	// en: ArchiveModePaths archives the backup_paths only (the same as when empty)
+	ArchiveModePaths = "paths"
+	// ja: ArchiveModeFull は Exclude に当てはまるものを除いた Dir 全体をアーカイブします
+	// ja: アーカイブは書き込んだ後に読み直して確認され、復元はアーカイブだけでディレクトリを作り直します
+	// en: ArchiveModeFull archives the whole Dir except what matches Exclude
+	// en: The archive is read back to verify it once written, and a restore rebuilds the directory from it alone
+	ArchiveModeFull = "full"
+)
+
+// ja: fullArchivePath は ArchiveModeFull のバックアップでメタデータに記録するパスです
+// en: fullArchivePath is the path recorded in the metadata for ArchiveModeFull backups
+const fullArchivePath = "."
+
+// ja: treeContents は ArchiveModeFull でアーカイブするディレクトリ・ファイル・シンボリックリンクです
+// en: treeContents are the directories, files and symlinks archived for ArchiveModeFull
+type treeContents struct {
+	// ja: Dirs は空のディレクトリも作り直せるよう、ディレクトリとして書き込むエントリです
+	// en: Dirs are the entries written as directories so that empty ones are rebuilt too
+	Dirs  []archiveEntry
+	Files []archiveEntry
+	// ja: Links はリンク先ごと書き込むシンボリックリンクです
+	// en: Links are the symlinks, written along with their targets
+	Links   []ManifestLink
+	Size    int64
+	Skipped []string
+}
+
+// ja: walkTree は dir 全体を辿り、exclude に当てはまらないディレクトリ・ファイル・シンボリックリンクを fn に渡します
+// ja: それ以外（ソケットやデバイスなど）は unsupported に渡します
+// en: walkTree walks the whole of dir and passes the directories, files and symlinks not matching exclude to fn
+// en: Anything else (sockets, devices and so on) goes to unsupported
+func walkTree(ctx context.Context, dir string, exclude []string, fn func(fullPath, name string, info os.FileInfo) error, unsupported func(name string)) error {
+	return filepath.Walk(dir, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, fullPath)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if excluded(filepath.ToSlash(name), exclude) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() && !isSymlink(info) {
+			unsupported(filepath.ToSlash(name))
+			return nil
+		}
+		return fn(fullPath, name, info)
+	})
+}
+
+// ja: WalkTree は ArchiveModeFull で dir からアーカイブされる通常のファイルを、exclude を適用して順に fn に渡します
+// ja: name は dir からの相対パス（スラッシュ区切り）です
+// en: WalkTree passes each regular file ArchiveModeFull would archive from dir, with exclude applied, to fn in turn
+// en: name is the path relative to dir (slash-separated)
+func WalkTree(dir string, exclude []string, fn func(name string, info os.FileInfo) error) error {
+	return walkTree(context.Background(), dir, exclude, func(_, name string, info os.FileInfo) error {
+		if info.IsDir() || isSymlink(info) {
+			return nil
+		}
+		return fn(filepath.ToSlash(name), info)
+	}, func(string) {})
+}
+
+// ja: isSymlink は info がシンボリックリンクかを返します
+// en: isSymlink reports whether info is a symlink
+func isSymlink(info os.FileInfo) bool {
+	return info.Mode()&os.ModeSymlink != 0
+}
+
+// ja: excluded は name（Dir からの相対パス）が exclude のパターンに当てはまるかを返します
+// ja: / を含まないパターンは各階層の名前と、含むパターンは Dir からのパス全体と照合します（末尾の / は無視します）
+// en: excluded reports whether name (relative to Dir) matches a pattern in exclude
+// en: Patterns without a / are matched against the name at every level, and those with one against the whole path from Dir (a trailing / is ignored)
+func excluded(name string, exclude []string) bool {
+	base := path.Base(name)
+	for _, pattern := range exclude {
+		pattern = strings.TrimPrefix(strings.TrimSuffix(filepath.ToSlash(pattern), "/"), "./")
+		if pattern == "" {
+			continue
+		}
+		target := base
+		if strings.Contains(pattern, "/") {
+			target = name
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ja: collectTree は ArchiveModeFull でアーカイブするディレクトリ・ファイル・シンボリックリンクを集め、ファイルのハッシュを計算します
+// en: collectTree gathers the directories, files and symlinks archived for ArchiveModeFull and hashes the files
+func collectTree(ctx context.Context, project Project) (*treeContents, error) {
+	tree := &treeContents{}
+	err := walkTree(ctx, project.Dir, project.Exclude, func(fullPath, name string, info os.FileInfo) error {
+		if info.IsDir() {
+			tree.Dirs = append(tree.Dirs, archiveEntry{fullPath: fullPath, name: name})
+			return nil
+		}
+		if isSymlink(info) {
+			target, err := os.Readlink(fullPath)
+			if err != nil {
+				return err
+			}
+			tree.Links = append(tree.Links, ManifestLink{Path: filepath.ToSlash(name), Target: target})
+			return nil
+		}
+		entry, err := describeFile(ctx, fullPath, name, info.Size())
+		if err != nil {
+			return err
+		}
+		tree.Files = append(tree.Files, entry)
+		tree.Size += info.Size()
+		return nil
+	}, func(name string) {
+		tree.Skipped = append(tree.Skipped, name)
+	})
+	return tree, err
+}
+
+// ja: addDirToArchive はディレクトリをエントリとしてアーカイブに追加します
+// en: addDirToArchive adds a directory to the archive as an entry of its own
+func addDirToArchive(tarWriter *tar.Writer, fullPath, archivePath string) error {
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	return tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     filepath.ToSlash(archivePath) + "/",
+		Mode:     int64(info.Mode().Perm()),
+		ModTime:  info.ModTime(),
+	})
+}
+
+// ja: addLinkToArchive はシンボリックリンクをリンク先ごとエントリとしてアーカイブに追加します
+// en: addLinkToArchive adds a symlink to the archive as an entry of its own, along with its target
+func addLinkToArchive(tarWriter *tar.Writer, dir string, link ManifestLink) error {
+	info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(link.Path)))
+	if err != nil {
+		return err
+	}
+	return tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     link.Path,
+		Linkname: link.Target,
+		Mode:     int64(info.Mode().Perm()),
+		ModTime:  info.ModTime(),
+	})
+}
+
+// ja: verifyArchive はアーカイブを最後まで読み直し、全てのファイルがマニフェストの SHA-256 と、
+// ja: 全てのシンボリックリンクがマニフェストのリンク先と一致することを確認します
+// en: verifyArchive reads an archive back to the end and checks that every file matches the SHA-256 in its manifest
+// en: and every symlink the target in it
+func verifyArchive(ctx context.Context, archivePath string) error {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	var hashes, links map[string]string
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		if header.Name == manifestEntryName {
+			manifest, err := parseManifest(tarReader)
+			if err != nil {
+				return err
+			}
+			hashes = manifest.fileHashes()
+			links = manifest.linkTargets()
+			continue
+		}
+
+		if header.Typeflag == tar.TypeSymlink {
+			target, ok := links[header.Name]
+			if !ok {
+				continue
+			}
+			if target != header.Linkname {
+				return &ChecksumMismatchError{Path: header.Name}
+			}
+			delete(links, header.Name)
+			continue
+		}
+
+		sum, ok := hashes[header.Name]
+		if !ok {
+			continue
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, contextReader{ctx: ctx, r: tarReader}); err != nil {
+			return err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != sum {
+			return &ChecksumMismatchError{Path: header.Name}
+		}
+		delete(hashes, header.Name)
+	}
+
+	// ja: マニフェストにあるのにアーカイブにないファイルも、壊れたアーカイブとして扱う
+	// en: A file in the manifest but not in the archive means a broken archive too
+	if hashes == nil {
+		return fmt.Errorf("%s", i18n.T("backup.verifyNoManifest"))
+	}
+	if len(hashes)+len(links) > 0 {
+		missing := make([]string, 0, len(hashes)+len(links))
+		for name := range hashes {
+			missing = append(missing, name)
+		}
+		for name := range links {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return fmt.Errorf(i18n.T("backup.verifyMissing"), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ja: CheckRemoveTree は RemoveTree が project のディレクトリを削除してよいかを、バックアップの前に確かめます
+// ja: バックアップディレクトリがプロジェクトのディレクトリの中にある場合は、アーカイブを失わないよう削除しません
+// en: CheckRemoveTree checks, before the backup, that RemoveTree may remove the directory of project
+// en: It refuses when the backup directory is inside the project directory, so the archive is not lost with it
+func CheckRemoveTree(project Project, opts Options) error {
+	dir, err := filepath.Abs(project.Dir)
+	if err != nil {
+		return err
+	}
+	backupDir, err := opts.BackupDir(project.Name)
+	if err != nil {
+		return err
+	}
+	backupDir, err = filepath.Abs(backupDir)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(dir, backupDir); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf(i18n.T("backup.removeTreeHoldsBackups"), dir, backupDir)
+	}
+	return nil
+}
+
+// ja: RemoveTree は ArchiveModeFull で作成して確認済みのバックアップの元になったディレクトリを削除します
+// ja: アーカイブできなかったファイル（ソケットなど）がある場合や、CheckRemoveTree が拒む場合は削除しません
+// en: RemoveTree removes the directory behind a verified ArchiveModeFull backup
+// en: It refuses when some files could not be archived (sockets and so on) or when CheckRemoveTree refuses
+func RemoveTree(project Project, result *BackupResult, opts Options) error {
+	if !result.Verified || result.Project != project.Name {
+		return fmt.Errorf(i18n.T("backup.removeTreeNotVerified"), project.Name)
+	}
+	if len(result.Unsupported) > 0 {
+		return fmt.Errorf(i18n.T("backup.removeTreeUnsupported"), project.Dir, len(result.Unsupported))
+	}
+	if err := CheckRemoveTree(project, opts); err != nil {
+		return err
+	}
+
+	dir, err := filepath.Abs(project.Dir)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf(i18n.T("backup.removeTreeError"), dir, err)
+	}
+	result.TreeRemoved = true
+	return nil
+}
+
+// ja: fullArchiveMode は project が ArchiveModeFull なら ArchiveModeFull を、そうでなければ空を返します（記録用）
+// en: fullArchiveMode returns ArchiveModeFull when project uses it and an empty string otherwise (for records)
+func fullArchiveMode(project Project) string {
+	if project.ArchiveMode == ArchiveModeFull {
+		return ArchiveModeFull
+	}
+	return ""
+}
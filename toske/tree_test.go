@@ -0,0 +1,283 @@
+package toske
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExcluded(t *testing.T) {
+	exclude := []string{"node_modules", "dist/", "*.log", "./build/cache"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"node_modules", true},
+		{"web/node_modules", true},
+		{"dist", true},
+		{"app.log", true},
+		{"logs/app.log", true},
+		{"build/cache", true},
+		{"build/output", false},
+		{"src/build/cache", false},
+		{"src/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := excluded(tt.name, exclude); got != tt.want {
+			t.Errorf("excluded(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFullArchiveRoundTrip(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{
+		"main.go":            "package main",
+		".git/HEAD":          "ref: refs/heads/main\n",
+		"dist/app":           "binary",
+		"web/node_modules/x": "dependency",
+		"web/index.html":     "<html>",
+		"logs/debug.log":     "noise",
+	})
+	project.ArchiveMode = ArchiveModeFull
+	project.Exclude = []string{"dist", "node_modules", "*.log"}
+	if err := os.MkdirAll(filepath.Join(project.Dir, ".git", "refs", "tags"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	plan, err := PlanBackup(project, opts)
+	if err != nil {
+		t.Fatalf("PlanBackup failed: %v", err)
+	}
+	var planned []string
+	for _, file := range plan.Files {
+		planned = append(planned, file.Path)
+	}
+	want := []string{".git/HEAD", "main.go", "web/index.html"}
+	if !reflect.DeepEqual(planned, want) || plan.ArchiveMode != ArchiveModeFull {
+		t.Errorf("Expected a full plan of %v, got %v (%q)", want, planned, plan.ArchiveMode)
+	}
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if !result.Verified || result.Record.ArchiveMode != ArchiveModeFull || !reflect.DeepEqual(result.Record.Files, []string{"."}) {
+		t.Errorf("Expected a verified full backup, got verified %v, record %+v", result.Verified, result.Record)
+	}
+
+	// ja: 確認済みのバックアップがあればディレクトリを削除でき、アーカイブだけで作り直せる
+	// en: With a verified backup the directory can be removed, and the archive alone rebuilds it
+	if err := RemoveTree(project, result, opts); err != nil {
+		t.Fatalf("RemoveTree failed: %v", err)
+	}
+	if _, err := os.Stat(project.Dir); !os.IsNotExist(err) || !result.TreeRemoved {
+		t.Fatalf("Expected %s to be removed, got %v", project.Dir, err)
+	}
+
+	restorePlan, err := PlanRestore(project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("PlanRestore failed: %v", err)
+	}
+	if !restorePlan.CreateDir || len(restorePlan.Files) != len(want) || len(restorePlan.Skipped) != 0 {
+		t.Errorf("Expected to rebuild %d files, got %+v", len(want), restorePlan)
+	}
+
+	restored, err := Restore(context.Background(), project, RestoreOptions{Options: opts})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Files, want) {
+		t.Errorf("Expected %v to be restored, got %v", want, restored.Files)
+	}
+	if info, err := os.Stat(filepath.Join(project.Dir, ".git", "refs", "tags")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the empty directory to be rebuilt, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(project.Dir, "dist")); !os.IsNotExist(err) {
+		t.Errorf("Expected the excluded dist not to be archived, got %v", err)
+	}
+}
+
+func TestRemoveTreeKeepsBackups(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{"main.go": "package main"})
+	project.ArchiveMode = ArchiveModeFull
+	opts.BackupRoot = filepath.Join(project.Dir, ".backups")
+	project.Exclude = []string{".backups"}
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if err := RemoveTree(project, result, opts); err == nil {
+		t.Fatal("Expected RemoveTree to refuse a directory holding the backups")
+	}
+	if _, err := os.Stat(project.Dir); err != nil || result.TreeRemoved {
+		t.Errorf("Expected %s to be kept, got %v", project.Dir, err)
+	}
+
+	if err := RemoveTree(project, &BackupResult{Project: project.Name}, Options{BackupRoot: t.TempDir()}); err == nil {
+		t.Error("Expected RemoveTree to refuse an unverified backup")
+	}
+}
+
+func TestFullArchiveKeepsSymlinks(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{"web/index.html": "<html>"})
+	project.ArchiveMode = ArchiveModeFull
+	if err := os.Symlink("web/index.html", filepath.Join(project.Dir, "index.html")); err != nil {
+		t.Skipf("Symlinks are not available: %v", err)
+	}
+	if err := os.Symlink("web", filepath.Join(project.Dir, "site")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if !result.Verified || len(result.Unsupported) != 0 {
+		t.Fatalf("Expected a verified backup without unsupported files, got %+v", result)
+	}
+	if err := RemoveTree(project, result, opts); err != nil {
+		t.Fatalf("RemoveTree failed: %v", err)
+	}
+
+	if _, err := Restore(context.Background(), project, RestoreOptions{Options: opts}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for name, want := range map[string]string{"index.html": "web/index.html", "site": "web"} {
+		if target, err := os.Readlink(filepath.Join(project.Dir, name)); err != nil || target != want {
+			t.Errorf("Expected %s to link to %s, got %q (%v)", name, want, target, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(project.Dir, "site", "index.html")); err != nil || string(data) != "<html>" {
+		t.Errorf("Expected the file to be reachable through the link, got %q (%v)", data, err)
+	}
+}
+
+func TestRemoveTreeRefusesUnsupported(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{"main.go": "package main"})
+	project.ArchiveMode = ArchiveModeFull
+
+	result, err := Backup(context.Background(), project, opts)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	result.Unsupported = []string{"app.sock"}
+	if err := RemoveTree(project, result, opts); err == nil {
+		t.Fatal("Expected RemoveTree to refuse a directory with files that were not archived")
+	}
+	if _, err := os.Stat(project.Dir); err != nil || result.TreeRemoved {
+		t.Errorf("Expected %s to be kept, got %v", project.Dir, err)
+	}
+}
+
+func TestCheckRemoveTree(t *testing.T) {
+	project, opts := setupProject(t, map[string]string{"main.go": "package main"})
+	if err := CheckRemoveTree(project, opts); err != nil {
+		t.Errorf("Expected a directory without backups to be removable, got %v", err)
+	}
+
+	opts.BackupRoot = filepath.Join(project.Dir, ".backups")
+	if err := CheckRemoveTree(project, opts); err == nil {
+		t.Error("Expected CheckRemoveTree to refuse a directory holding the backups")
+	}
+}
+
+func TestExtractArchiveSkipsUnverifiedSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	links := []ManifestLink{{Path: "site", Target: "web"}}
+
+	// ja: マニフェストのないアーカイブや ArchiveModeFull 以外のアーカイブでは、リンクを作らない
+	// en: Archives without a manifest, and archives that are not ArchiveModeFull, make no links
+	for _, tt := range []struct {
+		name     string
+		manifest *Manifest
+		full     bool
+	}{
+		{"full without manifest", nil, true},
+		{"paths with manifest", &Manifest{Format: ManifestFormat, Links: links}, false},
+	} {
+		archivePath := filepath.Join(tempDir, "links.tar.gz")
+		writeLinkArchive(t, archivePath, tt.manifest, links)
+		result, err := extractArchive(context.Background(), archivePath, workDir, false, tt.full, Options{})
+		if err != nil {
+			t.Fatalf("%s: extractArchive failed: %v", tt.name, err)
+		}
+		if len(result.Files) != 0 || len(result.Skipped) != 1 || result.Skipped[0].Reason != SkipUnverifiedLink {
+			t.Errorf("%s: expected the link to be skipped as unverified, got %+v", tt.name, result)
+		}
+		if _, err := os.Lstat(filepath.Join(workDir, "site")); !os.IsNotExist(err) {
+			t.Errorf("%s: expected no link to be made, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestExtractArchiveRejectsSymlinksOutsideDir(t *testing.T) {
+	tempDir := t.TempDir()
+	workDir := filepath.Join(tempDir, "work")
+	archivePath := filepath.Join(tempDir, "links.tar.gz")
+
+	// ja: マニフェストに記録されていても、絶対パスや展開先の外を指すリンクは作らない
+	// en: Even when listed in the manifest, links that are absolute or point outside the target are not made
+	links := []ManifestLink{
+		{Path: "passwd", Target: "/etc/passwd"},
+		{Path: "config/ssh", Target: "../../.ssh"},
+		{Path: "config/app", Target: "../web"},
+	}
+	writeLinkArchive(t, archivePath, &Manifest{Format: ManifestFormat, Links: links}, links)
+
+	result, err := extractArchive(context.Background(), archivePath, workDir, false, true, Options{})
+	if err != nil {
+		t.Fatalf("extractArchive failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Files, []string{"config/app"}) {
+		t.Errorf("Expected only config/app to be made, got %v", result.Files)
+	}
+	for _, skipped := range result.Skipped {
+		if skipped.Reason != SkipSymlink {
+			t.Errorf("Expected %s to be skipped as %s, got %s", skipped.Path, SkipSymlink, skipped.Reason)
+		}
+	}
+	for _, name := range []string{"passwd", "config/ssh"} {
+		if _, err := os.Lstat(filepath.Join(workDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be made, got %v", name, err)
+		}
+	}
+	if len(result.Skipped) != 2 {
+		t.Errorf("Expected 2 links to be skipped, got %+v", result.Skipped)
+	}
+}
+
+// writeLinkArchive writes an archive holding manifest (when not nil) and symlink entries for links
+func writeLinkArchive(t *testing.T, archivePath string, manifest *Manifest, links []ManifestLink) {
+	t.Helper()
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive file: %v", err)
+	}
+	defer archiveFile.Close()
+	gzipWriter := gzip.NewWriter(archiveFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if manifest != nil {
+		if err := writeManifest(tarWriter, manifest); err != nil {
+			t.Fatalf("Failed to write manifest: %v", err)
+		}
+	}
+	for _, link := range links {
+		header := &tar.Header{Name: link.Path, Typeflag: tar.TypeSymlink, Linkname: link.Target, Mode: 0777}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+}